@@ -75,8 +75,9 @@ func (f *File) adjustHelper(sheet string, dir adjustDirection, num, offset int)
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
+	ws.adjustSpillValues(dir, num, offset)
 	sheetID := f.getSheetID(sheet)
 	if dir == rows {
 		err = f.adjustRowDimensions(sheet, ws, num, offset)
@@ -689,6 +690,7 @@ func (f *File) adjustTable(ws *xlsxWorksheet, sheet string, dir adjustDirection,
 			idx--
 			continue
 		}
+		oldX1 := coordinates[0]
 		coordinates = f.adjustAutoFilterHelper(dir, coordinates, num, offset)
 		x1, y1, x2, y2 := coordinates[0], coordinates[1], coordinates[2], coordinates[3]
 		if y2-y1 < 1 || x2-x1 < 0 {
@@ -701,7 +703,11 @@ func (f *File) adjustTable(ws *xlsxWorksheet, sheet string, dir adjustDirection,
 		if t.AutoFilter != nil {
 			t.AutoFilter.Ref = t.Ref
 		}
+		oldColumns := t.TableColumns
 		_ = f.setTableColumns(sheet, true, x1, y1, x2, &t)
+		if err = f.adjustTableColumns(sheet, &t, oldColumns, dir, num, offset, oldX1); err != nil {
+			return err
+		}
 		// Currently doesn't support query table
 		t.TableType, t.TotalsRowCount, t.ConnectionID = "", 0, 0
 		table, _ := xml.Marshal(t)
@@ -710,6 +716,118 @@ func (f *File) adjustTable(ws *xlsxWorksheet, sheet string, dir adjustDirection,
 	return nil
 }
 
+// adjustTableColumns provides a function to update the structured references
+// in the formulas and defined names when the table columns have been renamed
+// or deleted by inserting or deleting columns. The references to the deleted
+// columns will be replaced with the #REF! error.
+func (f *File) adjustTableColumns(sheet string, t *xlsxTable, oldColumns *xlsxTableColumns, dir adjustDirection, num, offset, oldX1 int) error {
+	if oldColumns == nil || t.TableColumns == nil {
+		return nil
+	}
+	coordinates, err := rangeRefToCoordinates(t.Ref)
+	if err != nil {
+		return err
+	}
+	renames := map[string]string{}
+	for i, column := range oldColumns.TableColumn {
+		col := oldX1 + i
+		if dir == columns && col >= num {
+			if offset < 0 && col < num-offset {
+				renames[column.Name] = ""
+				continue
+			}
+			col += offset
+		}
+		if idx := col - coordinates[0]; idx >= 0 && idx < len(t.TableColumns.TableColumn) &&
+			t.TableColumns.TableColumn[idx].Name != column.Name {
+			renames[column.Name] = t.TableColumns.TableColumn[idx].Name
+		}
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+	for _, sheetName := range f.GetSheetList() {
+		ws, err := f.workSheetReader(sheetName)
+		if err != nil {
+			continue
+		}
+		for r := range ws.SheetData.Row {
+			for c := range ws.SheetData.Row[r].C {
+				cell := &ws.SheetData.Row[r].C[c]
+				if cell.F == nil && cell.f == "" {
+					continue
+				}
+				col, row, err := CellNameToCoordinates(cell.R)
+				if err != nil {
+					return err
+				}
+				inTable := sheetName == sheet && coordinates[0] <= col && col <= coordinates[2] &&
+					coordinates[1] <= row && row <= coordinates[3]
+				cell.f = adjustStructuredRefs(cell.f, t.Name, inTable, renames)
+				if cell.F != nil {
+					cell.F.Content = adjustStructuredRefs(cell.F.Content, t.Name, inTable, renames)
+				}
+			}
+		}
+	}
+	wb, err := f.workbookReader()
+	if err != nil {
+		return err
+	}
+	if wb.DefinedNames != nil {
+		for i, dn := range wb.DefinedNames.DefinedName {
+			wb.DefinedNames.DefinedName[i].Data = adjustStructuredRefs(dn.Data, t.Name, false, renames)
+		}
+	}
+	return nil
+}
+
+// adjustStructuredRefs returns the formula with the renamed or deleted
+// columns in the structured references of the given table. The structured
+// references without table name will be adjusted if the formula is in the
+// table.
+func adjustStructuredRefs(formula, table string, inTable bool, renames map[string]string) string {
+	refs := findStructuredRefs(formula)
+	if len(refs) == 0 {
+		return formula
+	}
+	var (
+		b    strings.Builder
+		prev int
+	)
+	for _, pos := range refs {
+		b.WriteString(formula[prev:pos[0]])
+		prev = pos[1]
+		text := formula[pos[0]:pos[1]]
+		ref, err := parseStructuredRef(text)
+		if err != nil || !strings.EqualFold(ref.Table, table) && !(ref.Table == "" && inTable) {
+			b.WriteString(text)
+			continue
+		}
+		var changed, deleted bool
+		for i, column := range ref.Columns {
+			for oldName, newName := range renames {
+				if !strings.EqualFold(column, oldName) {
+					continue
+				}
+				changed, deleted = true, deleted || newName == ""
+				ref.Columns[i] = newName
+				break
+			}
+		}
+		switch {
+		case deleted:
+			b.WriteString(formulaErrorREF)
+		case changed:
+			b.WriteString(ref.String())
+		default:
+			b.WriteString(text)
+		}
+	}
+	b.WriteString(formula[prev:])
+	return b.String()
+}
+
 // adjustAutoFilter provides a function to update the auto filter when
 // inserting or deleting rows or columns.
 func (f *File) adjustAutoFilter(ws *xlsxWorksheet, sheet string, dir adjustDirection, num, offset, sheetID int) error {
@@ -341,6 +341,81 @@ func TestAdjustTable(t *testing.T) {
 	assert.Equal(t, ErrParameterInvalid, f.RemoveRow(sheetName, 1))
 }
 
+func TestAdjustTableStructuredRefs(t *testing.T) {
+	f, sheetName := NewFile(), "Sheet1"
+	for cell, row := range map[string][]interface{}{
+		"A1": {"Item", "Qty", "Unit Price", "Amount"},
+		"A2": {"Apple", 2, 3},
+		"A3": {"Banana", 4, 5},
+	} {
+		assert.NoError(t, f.SetSheetRow(sheetName, cell, &row))
+	}
+	assert.NoError(t, f.AddTable(sheetName, &Table{Range: "A1:D3", Name: "Sales"}))
+	assert.NoError(t, f.SetCellFormula(sheetName, "D2", "[@Qty]*[@[Unit Price]]"))
+	assert.NoError(t, f.SetCellFormula(sheetName, "F1", "SUM(Sales[Qty],Sales[[#Headers],[Qty]:[Unit Price]])"))
+	assert.NoError(t, f.SetCellFormula(sheetName, "F2", "[@Qty]&\"[Qty]\""))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Quantities", RefersTo: "Sales[Qty]"}))
+	// Test insert column in the table
+	assert.NoError(t, f.InsertCols(sheetName, "C", 1))
+	result, err := f.CalcCellValue(sheetName, "E2")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", result)
+	// Test rename column by the header cell
+	assert.NoError(t, f.SetCellValue(sheetName, "B1", "Quantity"))
+	for cell, expected := range map[string]string{
+		"E2": "[@Quantity]*[@[Unit Price]]",
+		"G1": "SUM(Sales[Quantity],Sales[[#Headers],[Quantity]:[Unit Price]])",
+		"G2": "[@Qty]&\"[Qty]\"",
+	} {
+		formula, err := f.GetCellFormula(sheetName, cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, formula, cell)
+	}
+	assert.Equal(t, "Sales[Quantity]", f.GetDefinedName()[0].RefersTo)
+	for cell, expected := range map[string]string{"E2": "6", "G1": "6"} {
+		result, err = f.CalcCellValue(sheetName, cell)
+		assert.NoError(t, err, cell)
+		assert.Equal(t, expected, result, cell)
+	}
+	// Test set the header cell with the same name and the cell out of the
+	// header row
+	assert.NoError(t, f.SetCellValue(sheetName, "B1", "Quantity"))
+	assert.NoError(t, f.SetCellValue(sheetName, "B2", 2))
+	assert.NoError(t, f.InsertCols(sheetName, "H", 1))
+	formula, err := f.GetCellFormula(sheetName, "E2")
+	assert.NoError(t, err)
+	assert.Equal(t, "[@Quantity]*[@[Unit Price]]", formula)
+	result, err = f.CalcCellValue(sheetName, "E2")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", result)
+	// Test delete column in the table
+	assert.NoError(t, f.RemoveCol(sheetName, "B"))
+	for cell, expected := range map[string]string{
+		"D2": "#REF!*[@[Unit Price]]",
+		"F1": "SUM(#REF!,#REF!)",
+	} {
+		formula, err := f.GetCellFormula(sheetName, cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, formula, cell)
+	}
+	// Test adjust structured references without matched table
+	assert.Equal(t, "Other[Qty]+[@Qty]", adjustStructuredRefs("Other[Qty]+[@Qty]", "Sales", false, map[string]string{"Qty": ""}))
+	assert.Equal(t, "Sales[Bad", adjustStructuredRefs("Sales[Bad", "Sales", false, map[string]string{"Qty": ""}))
+
+	// Test rename column by the header cell of the table without header row
+	f = NewFile()
+	assert.NoError(t, f.AddTable(sheetName, &Table{Range: "A1:B3", ShowHeaderRow: boolPtr(false)}))
+	assert.NoError(t, f.SetCellValue(sheetName, "A1", "Qty"))
+	// Test rename column by the header cell with invalid table
+	for _, content := range [][]byte{MacintoshCyrillicCharset, []byte(`<table ref="-" />`), []byte(`<table ref="A1:B3"><tableColumns count="1"`)} {
+		f.Pkg.Store("xl/tables/table1.xml", content)
+		assert.Error(t, f.SetCellValue(sheetName, "A1", "Qty"))
+	}
+	f.Pkg.Delete("xl/tables/table1.xml")
+	assert.NoError(t, f.SetCellValue(sheetName, "A1", "Qty"))
+	assert.EqualError(t, f.renameTableColumn(sheetName, "A"), newCellNameToCoordinatesError("A", newInvalidCellNameError("A")).Error())
+}
+
 func TestAdjustHelper(t *testing.T) {
 	f := NewFile()
 	_, err := f.NewSheet("Sheet2")
@@ -2,10 +2,10 @@ package excelize
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,6 +13,9 @@ import (
 )
 
 // BatchDebugStats 批量更新的调试统计信息
+//
+// Deprecated: 请使用 Options 的 Metrics 和 LogHandler 字段接收计算的统计信息和
+// 日志，或使用 WithCalcReport 获取单次重新计算的报告。
 type BatchDebugStats struct {
 	TotalCells    int                   // 总计算单元格数
 	CellStats     map[string]*CellStats // 每个单元格的统计
@@ -23,6 +26,9 @@ type BatchDebugStats struct {
 }
 
 // CellStats 单个单元格的统计信息
+//
+// Deprecated: 请使用 Options 的 Metrics 和 LogHandler 字段接收计算的统计信息和
+// 日志，或使用 WithCalcReport 获取单次重新计算的报告。
 type CellStats struct {
 	Cell         string        // 单元格坐标 (Sheet!Cell)
 	CalcCount    int           // 计算次数
@@ -40,49 +46,60 @@ var currentBatchStats *BatchDebugStats
 var batchStatsMu sync.Mutex
 
 // EnableBatchDebug 启用批量更新调试统计
+//
+// Deprecated: 请使用 Options 的 Metrics 字段接收计算的统计信息。
 func EnableBatchDebug() {
 	enableBatchDebug = true
 }
 
 // DisableBatchDebug 禁用批量更新调试统计
+//
+// Deprecated: 请使用 Options 的 Metrics 字段接收计算的统计信息。
 func DisableBatchDebug() {
 	enableBatchDebug = false
 }
 
 // GetBatchDebugStats 获取最近一次批量更新的调试统计
+//
+// Deprecated: 请使用 Options 的 Metrics 字段接收计算的统计信息，或使用
+// WithCalcReport 获取单次重新计算的报告。
 func GetBatchDebugStats() *BatchDebugStats {
 	batchStatsMu.Lock()
 	defer batchStatsMu.Unlock()
 	return currentBatchStats
 }
 
-// recordCellCalc 记录单元格计算
-func recordCellCalc(sheet, cell, formula, result string, duration time.Duration, cacheHit bool) {
-	if !enableBatchDebug || currentBatchStats == nil {
+// recordCellCalc 记录单元格计算，由 recordCalc 在报告计算的统计信息时调用
+func recordCellCalc(f *File, sheet, cell, result string, duration time.Duration, cacheHit bool) {
+	batchStatsMu.Lock()
+	stats := currentBatchStats
+	batchStatsMu.Unlock()
+	if !enableBatchDebug || stats == nil {
 		return
 	}
 
-	currentBatchStats.mu.Lock()
-	defer currentBatchStats.mu.Unlock()
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
 
 	cellKey := sheet + "!" + cell
-	if currentBatchStats.CellStats[cellKey] == nil {
-		currentBatchStats.CellStats[cellKey] = &CellStats{
+	if stats.CellStats[cellKey] == nil {
+		formula, _ := f.getCellFormulaReadOnly(sheet, cell, true)
+		stats.CellStats[cellKey] = &CellStats{
 			Cell:    cellKey,
 			Formula: formula,
 		}
 	}
 
-	stats := currentBatchStats.CellStats[cellKey]
-	stats.CalcCount++
-	stats.CalcDuration += duration
-	stats.CacheHit = cacheHit
-	stats.Result = result
+	cellStats := stats.CellStats[cellKey]
+	cellStats.CalcCount++
+	cellStats.CalcDuration += duration
+	cellStats.CacheHit = cacheHit
+	cellStats.Result = result
 
 	if cacheHit {
-		currentBatchStats.CacheHits++
+		stats.CacheHits++
 	} else {
-		currentBatchStats.CacheMisses++
+		stats.CacheMisses++
 	}
 }
 
@@ -139,6 +156,8 @@ func (f *File) BatchSetCellValue(updates []CellUpdate) error {
 //	sheet: 工作表名称
 //
 // 注意：此函数只会重新计算该工作表中的公式，不会影响其他工作表。
+// 如果工作簿计算属性启用了迭代计算，循环引用将按照 iterateCount 和
+// iterateDelta 迭代计算，否则返回 ErrCircularReference 错误。
 //
 // 示例：
 //
@@ -146,6 +165,21 @@ func (f *File) BatchSetCellValue(updates []CellUpdate) error {
 //	f.BatchSetCellValue(updates)
 //	err := f.RecalculateSheet("Sheet1")
 func (f *File) RecalculateSheet(sheet string) error {
+	return f.RecalculateSheetContext(context.Background(), sheet)
+}
+
+// RecalculateSheetContext 与 RecalculateSheet 相同，但在给定的 context 被取消
+// 或超过截止时间时立即停止计算并返回 context 的错误，尚未计算的公式保留原有
+// 缓存值。单个公式的计算时长受 Options 的 CalcTimeout 限制（未设置时默认为
+// 5 秒），超时的公式将被停止，该单元格的缓存值被清除，并在计算完其余公式后
+// 返回 ErrFormulaTimeout 错误。
+//
+// 示例：
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	err := f.RecalculateSheetContext(ctx, "Sheet1")
+func (f *File) RecalculateSheetContext(ctx context.Context, sheet string) error {
 	// Get sheet ID (1-based, matches calcChain)
 	sheetID := f.getSheetID(sheet)
 	if sheetID == -1 {
@@ -164,16 +198,47 @@ func (f *File) RecalculateSheet(sheet string) error {
 	}
 
 	// Recalculate all formulas in the sheet
-	return f.recalculateAllInSheet(calcChain, sheetID)
+	return f.recalculateAllInSheet(withVolatileValues(ctx), calcChain, sheetID)
+}
+
+// buildCellMap 构建工作表单元格引用到单元格的映射，用于快速查找
+func buildCellMap(ws *xlsxWorksheet, capacity int) map[string]*xlsxC {
+	cellMap := make(map[string]*xlsxC, capacity)
+	if ws == nil {
+		return cellMap
+	}
+	for rowIdx := range ws.SheetData.Row {
+		for cellIdx := range ws.SheetData.Row[rowIdx].C {
+			cell := &ws.SheetData.Row[rowIdx].C[cellIdx]
+			cellMap[cell.R] = cell
+		}
+	}
+	return cellMap
 }
 
 // RecalculateAll 重新计算所有工作表中的所有公式并更新缓存值
 //
-// 此函数会遍历 calcChain 中的所有公式单元格，重新计算并更新缓存值。
-// 计算结果会直接更新到工作表的单元格缓存中。
+// 此函数会按依赖关系的拓扑顺序遍历 calcChain 中的所有公式单元格（依赖关系
+// 相同的公式按 calcChain 顺序），重新计算并更新缓存值，因此公式总是读取本次
+// 计算中其引用的公式单元格（包括其他工作表中的公式单元格）的结果。计算结果会
+// 直接更新到工作表的单元格缓存中。
+//
+// 如果 Options 的 MaxCalcWorkers 大于 1，公式将按依赖层级分组，同一层级的
+// 公式由多个 goroutine 并发计算，计算结果按 calcChain 顺序写回。由于每个公式
+// 都在其引用的公式之后计算，因此与串行计算的结果一致。
 //
 // 注意：为了避免内存溢出，此函数不再返回受影响单元格的列表。
 // 所有计算结果已经直接更新到工作表中，可以通过 GetCellValue 读取。
+// 如果工作簿计算属性启用了迭代计算，循环引用将按照 iterateCount 和
+// iterateDelta 迭代计算，否则在计算完其余公式后返回 ErrCircularReference
+// 错误。
+//
+// 调用 RAND、NOW 等易失性函数的公式在每次重新计算中只计算一次，依赖它们的
+// 公式读取同一个计算结果。
+//
+// 单个公式的计算时长受 Options 的 CalcTimeout 限制（未设置时默认为 5 秒），
+// 超时的公式将被停止，其缓存值被清除，并在计算完其余公式后返回
+// ErrFormulaTimeout 错误。
 //
 // 返回：
 //
@@ -188,7 +253,25 @@ func (f *File) RecalculateSheet(sheet string) error {
 //	// 读取计算后的值
 //	value, _ := f.GetCellValue("Sheet1", "A1")
 func (f *File) RecalculateAll() error {
+	return f.RecalculateAllContext(context.Background())
+}
+
+// RecalculateAllContext 与 RecalculateAll 相同，但在给定的 context 被取消或
+// 超过截止时间时立即停止计算并返回 context 的错误，尚未计算的公式保留原有
+// 缓存值。可用于限制不可信工作簿的计算时间。通过 WithCalcReport 在 context
+// 中附加 CalcReport，可以获取计算的公式数量、缓存和批量优化命中次数、出错的
+// 单元格以及最慢的公式。
+//
+// 示例：
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := f.RecalculateAllContext(ctx); errors.Is(err, context.DeadlineExceeded) {
+//	    // 计算未完成
+//	}
+func (f *File) RecalculateAllContext(ctx context.Context) error {
 	totalStart := time.Now()
+	ctx = withVolatileValues(ctx)
 
 	calcChain, err := f.calcChainReader()
 	if err != nil {
@@ -199,18 +282,21 @@ func (f *File) RecalculateAll() error {
 		return nil
 	}
 
-	log.Printf("📊 [RecalculateAll] Starting: %d formulas to calculate", len(calcChain.C))
+	logger := f.logger()
+	logger.Debug("recalculation started", "formulas", len(calcChain.C))
 
-	// === 批量SUMIFS/AVERAGEIFS优化 ===
-	// 在逐个计算之前，先检测并批量计算SUMIFS/AVERAGEIFS公式
+	// === 批量SUMPRODUCT优化 ===
+	// 在逐个计算之前，先检测并批量计算SUMPRODUCT公式。SUMIFS、COUNTIFS等条件
+	// 聚合函数在逐个计算时通过共享相同区域的聚合表批量计算
 	batchStart := time.Now()
-	batchResults := f.detectAndCalculateBatchSUMIFS()
+	batchResults := f.detectAndCalculateBatchSUMPRODUCT()
 	batchDuration := time.Since(batchStart)
 
 	batchCount := len(batchResults)
+	calcReportFromContext(ctx).markBatched(batchResults)
 	if batchCount > 0 {
-		log.Printf("⚡ [RecalculateAll] Batch SUMIFS/AVERAGEIFS/SUMPRODUCT optimization: %d formulas calculated in %v (avg: %v/formula)",
-			batchCount, batchDuration, batchDuration/time.Duration(batchCount))
+		f.metrics().Count(MetricCalcBatchFormulas, int64(batchCount))
+		logger.Debug("batch formulas calculated", "formulas", batchCount, "duration", batchDuration)
 
 		// 将批量结果存入calcCache，这样后续逐个计算时会直接使用缓存
 		for fullCell, value := range batchResults {
@@ -220,23 +306,37 @@ func (f *File) RecalculateAll() error {
 		}
 	}
 
-	sheetList := f.GetSheetList()
-	currentSheetIndex := -1
+	// 按依赖层级并发计算
+	if workers := f.options.MaxCalcWorkers; workers > 1 {
+		return f.recalculateConcurrently(ctx, workers)
+	}
+
+	// The deadline of calculating a single formula
+	timeout := f.calcTimeout()
+	calcOpts := Options{RawCellValue: true, CalcTimeout: timeout}
+
+	// The formulas are calculated in the same order as the incremental
+	// recalculation, so the formulas read the results of their precedents
+	// on the other worksheets calculated in this recalculation
+	order, err := f.recalculationOrder()
+	if err != nil {
+		return err
+	}
 	var currentWs *xlsxWorksheet
 	var currentSheetName string
 	sheetFormulaCount := 0 // Track formulas within current sheet
 
-	// Pre-build cell map for current sheet to avoid O(n²) lookups
+	// Pre-build cell maps for the worksheets to avoid O(n²) lookups
 	cellMap := make(map[string]*xlsxC)
+	cellMaps := make(map[string]map[string]*xlsxC)
 
 	sheetBuildTime := time.Duration(0)
 	calcTime := time.Duration(0)
 	formulaCount := 0
-	batchHitCount := 0                        // Track how many formulas used batch results
-	progressInterval := len(calcChain.C) / 20 // Report every 5% (changed from 10%)
-	slowFormulaCount := 0                     // Track slow formulas (>100ms)
-	timeoutCount := 0                         // Track timeout formulas (>5s)
-	skippedComplexFormulas := 0               // Track skipped complex formulas
+	batchHitCount := 0                  // Track how many formulas used batch results
+	progressInterval := len(order) / 20 // Report every 5% (changed from 10%)
+	slowFormulaCount := 0               // Track slow formulas (>100ms)
+	timeoutCount := 0                   // Track timeout formulas
 
 	// Track slow formulas with details
 	type slowFormulaInfo struct {
@@ -278,55 +378,38 @@ func (f *File) RecalculateAll() error {
 	// Map: "SheetName!Column" -> true (e.g., "Sheet1!H" -> true)
 	timeoutColumns := make(map[string]bool)
 
-	// Track columns with circular references detected
-	circularRefColumns := make(map[string]bool)
-
-	for i := range calcChain.C {
-		c := calcChain.C[i]
-		if c.I != 0 {
-			currentSheetIndex = c.I
-		}
+	// Track cells with circular references detected while the iterative
+	// calculation is disabled, and cells exceeded the calculation deadline
+	var cellErrs calcCellErrors
 
-		if currentSheetIndex < 0 || currentSheetIndex >= len(sheetList) {
-			continue
+	for _, fc := range order {
+		if err = ctx.Err(); err != nil {
+			return err
 		}
+		c, sheetName := xlsxCalcChainC{R: fc.cell}, fc.sheet
 
-		sheetName := sheetList[currentSheetIndex]
-
-		// If sheet changed, rebuild cell map
+		// If sheet changed, switch to the cell map of the sheet
 		if sheetName != currentSheetName {
-			buildStart := time.Now()
-
-			// 🔥 MEMORY OPTIMIZATION: Clear previous sheet's cellMap to free memory
-			if len(cellMap) > 0 {
-				cellMap = nil // Allow GC to collect old map
-			}
-
 			currentSheetName = sheetName
 			sheetFormulaCount = 0 // Reset counter for new sheet
 			currentWs, err = f.workSheetReader(sheetName)
 			if err != nil {
 				continue
 			}
-
-			// Build cell map for fast lookup
-			// Pre-allocate with estimated capacity to reduce allocations
-			estimatedCells := 0
-			if currentWs != nil && currentWs.SheetData.Row != nil {
-				estimatedCells = len(currentWs.SheetData.Row) * 50 // Estimate ~50 cells per row
-			}
-			cellMap = make(map[string]*xlsxC, estimatedCells)
-
-			if currentWs != nil && currentWs.SheetData.Row != nil {
-				for rowIdx := range currentWs.SheetData.Row {
-					for cellIdx := range currentWs.SheetData.Row[rowIdx].C {
-						cell := &currentWs.SheetData.Row[rowIdx].C[cellIdx]
-						cellMap[cell.R] = cell
-					}
+			var ok bool
+			if cellMap, ok = cellMaps[sheetName]; !ok {
+				buildStart := time.Now()
+				// Build cell map for fast lookup
+				// Pre-allocate with estimated capacity to reduce allocations
+				estimatedCells := 0
+				if currentWs != nil && currentWs.SheetData.Row != nil {
+					estimatedCells = len(currentWs.SheetData.Row) * 50 // Estimate ~50 cells per row
 				}
+				cellMap = buildCellMap(currentWs, estimatedCells)
+				cellMaps[sheetName] = cellMap
+				buildDuration := time.Since(buildStart)
+				sheetBuildTime += buildDuration
 			}
-			buildDuration := time.Since(buildStart)
-			sheetBuildTime += buildDuration
 		}
 
 		// Fast lookup using cellMap
@@ -349,15 +432,6 @@ func (f *File) RecalculateAll() error {
 
 		columnKey := sheetName + "!" + colLetter
 
-		// Check if this column has circular reference
-		if circularRefColumns[columnKey] {
-			cellRef.V = ""
-			cellRef.T = ""
-			formulaCount++
-			skippedComplexFormulas++
-			continue
-		}
-
 		// Get formula content (will be used for circular ref detection and dependency checks)
 		var formula string
 		if cellRef.F.Content != "" {
@@ -366,75 +440,6 @@ func (f *File) RecalculateAll() error {
 			formula, _ = getSharedFormula(currentWs, *cellRef.F.Si, c.R)
 		}
 
-		// 🔥 AUTO-DETECT circular reference in formula
-		// IMPORTANT: Only check for self-reference within the SAME sheet
-		// A formula in Sheet1!B2 can reference Sheet2!B2 without circular dependency
-		// Pattern 1: Direct self-reference like "B2", "$B2", "B$2", "$B$2" (WITHOUT sheet prefix)
-		// Pattern 2: INDEX with self cell reference as parameter (e.g., INDEX(..., B2))
-		hasCircularRef := false
-
-		// Only check for circular reference if formula does NOT contain cross-sheet references
-		// If formula has '!' it might be referencing other sheets, need more careful check
-		hasCrossSheetRef := strings.Contains(formula, "!")
-
-		if !hasCrossSheetRef {
-			// No cross-sheet references, safe to check for self-reference
-			// Use word boundary to avoid false positives (e.g., AC2 matching C2)
-			// Match patterns: \bC2\b, \b$C2\b, \bC$2\b, \b$C$2\b
-			selfRefPattern := regexp.MustCompile(`\b\$?` + regexp.QuoteMeta(colLetter) + `\$?` + regexp.QuoteMeta(c.R[len(colLetter):]) + `\b`)
-			if selfRefPattern.MatchString(formula) {
-				hasCircularRef = true
-			}
-
-			// Check for column reference in INDEX/OFFSET that could cause circular dependency
-			// e.g., INDEX(..., colLetter+rowNum) or INDEX(...+H2)
-			if !hasCircularRef && (strings.Contains(formula, "INDEX") || strings.Contains(formula, "OFFSET")) {
-				// Check if cell reference is used as a parameter (not part of range)
-				// Pattern: INDEX(..., +C2) or INDEX(..., C2+...)
-				indexPattern := regexp.MustCompile(`[+\-*/,\(]\s*\$?` + regexp.QuoteMeta(c.R) + `\s*[+\-*/,\)]`)
-				if indexPattern.MatchString(formula) {
-					hasCircularRef = true
-				}
-			}
-		} else {
-			// Has cross-sheet references, need to check more carefully
-			// Only flag as circular if it references the SAME sheet AND cell
-			// Pattern: Sheet!C2 or 'Sheet'!C2 where Sheet is the current sheet
-			selfRef1 := sheetName + "!" + c.R
-			selfRef2 := "'" + sheetName + "'!" + c.R
-
-			if strings.Contains(formula, selfRef1) || strings.Contains(formula, selfRef2) {
-				hasCircularRef = true
-			}
-
-			// Also check for same-sheet plain cell references (without sheet prefix)
-			// IMPORTANT: Prevent false positives like AC2 matching C2
-			// Require: not preceded by letter (to avoid AC2, BC2, etc matching C2)
-			if !hasCircularRef {
-				// Pattern: [^A-Z!\'\"]\$?C\$?2\b
-				// This ensures the cell ref is not part of a longer column name
-				plainCellPattern := regexp.MustCompile(`[^A-Z!\'\"]\$?` + regexp.QuoteMeta(colLetter) + `\$?` + regexp.QuoteMeta(c.R[len(colLetter):]) + `\b`)
-				if plainCellPattern.MatchString(formula) {
-					hasCircularRef = true
-				}
-			}
-		}
-
-		if hasCircularRef {
-			// Mark entire column as having circular reference
-			circularRefColumns[columnKey] = true
-			cellRef.V = ""
-			cellRef.T = ""
-			formulaCount++
-			skippedComplexFormulas++
-
-			// Log first occurrence
-			if len(circularRefColumns) == 1 {
-				log.Printf("  🔄 [RecalculateAll] Circular reference detected: %s!%s (formula references itself)", sheetName, c.R)
-			}
-			continue
-		}
-
 		// Check if this column has already timed out - if so, skip it
 		if timeoutColumns[columnKey] {
 			// Skip this cell silently - column already timed out
@@ -445,46 +450,6 @@ func (f *File) RecalculateAll() error {
 			continue
 		}
 
-		// Check if this formula depends on any circular-ref columns
-		// If so, skip it to prevent cascading errors
-		dependsOnCircular := false
-		for circularCol := range circularRefColumns {
-			// Extract sheet and column from "SheetName!Column"
-			parts := strings.Split(circularCol, "!")
-			if len(parts) == 2 {
-				circularSheet := parts[0]
-				circularColumn := parts[1]
-
-				// Check if formula references this circular column
-				if circularSheet == sheetName {
-					// Same sheet: check for any reference to the column
-					// Patterns: H2, $H2, H$2, $H$2, H:H, etc.
-					// Use word boundary to avoid false positives (e.g., SH2 should not match H)
-					colPattern := regexp.MustCompile(`\b\$?` + regexp.QuoteMeta(circularColumn) + `[\$:\d]`)
-					if colPattern.MatchString(formula) {
-						dependsOnCircular = true
-						break
-					}
-				} else {
-					// Cross-sheet
-					if strings.Contains(formula, "'"+circularSheet+"'!"+circularColumn) ||
-						strings.Contains(formula, circularSheet+"!"+circularColumn) {
-						dependsOnCircular = true
-						break
-					}
-				}
-			}
-		}
-
-		if dependsOnCircular {
-			// Skip this cell - it depends on a circular column
-			cellRef.V = ""
-			cellRef.T = ""
-			formulaCount++
-			skippedComplexFormulas++
-			continue
-		}
-
 		dependsOnTimeout := false
 		for timeoutCol := range timeoutColumns {
 			// Extract sheet and column from "SheetName!Column"
@@ -523,51 +488,22 @@ func (f *File) RecalculateAll() error {
 			continue
 		}
 
-		// Calculate the formula value using raw values with timeout
-		// Use context to ensure goroutine cleanup
+		// Calculate the formula value using raw values with timeout, the
+		// calculation will be stopped when the deadline is exceeded
 		calcStart := time.Now()
-
-		type calcResult struct {
-			result string
-			err    error
+		result, err := f.calcCellResult(ctx, sheetName, c.R, true, calcOpts)
+		calcDuration := time.Since(calcStart)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
 		}
 
-		// Create context with timeout for proper goroutine lifecycle management
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel() // Ensure context is always cancelled to free resources
-
-		resultChan := make(chan calcResult, 1)
-
-		// Run calculation in goroutine
-		// The buffered channel ensures the goroutine can exit even if we timeout
-		go func() {
-			res, err := f.CalcCellValue(sheetName, c.R, Options{RawCellValue: true})
-			select {
-			case resultChan <- calcResult{result: res, err: err}:
-				// Result sent successfully
-			case <-ctx.Done():
-				// Context cancelled, don't block on send
-				return
-			}
-		}()
-
-		// Wait for result with timeout
-		var result string
-		var err error
-		var timedOut bool
-
-		select {
-		case calcRes := <-resultChan:
-			result = calcRes.result
-			err = calcRes.err
-		case <-ctx.Done():
-			timedOut = true
+		timedOut := errors.Is(err, ErrFormulaTimeout)
+		if timedOut {
 			// Mark this entire column as timed out
 			timeoutColumns[columnKey] = true
+			_ = cellErrs.add(sheetName, c.R, err)
 		}
 
-		calcDuration := time.Since(calcStart)
-
 		// Track slow formulas (>100ms) to help identify bottlenecks
 		if timedOut {
 			slowFormulaCount++
@@ -576,7 +512,7 @@ func (f *File) RecalculateAll() error {
 			insertSlowFormula(slowFormulaInfo{
 				sheet:    sheetName,
 				cell:     c.R,
-				duration: 5 * time.Second, // Timeout
+				duration: timeout,
 				formula:  truncateString(formula, 100),
 			})
 			// Clear the cell value and continue to next formula
@@ -602,10 +538,23 @@ func (f *File) RecalculateAll() error {
 
 		calcTime += calcDuration
 
+		// The dynamic array formula spills the result into the neighbouring
+		// cells, the cells of the worksheet may be reallocated
+		if cellRef.F != nil && cellRef.F.T == STCellFormulaTypeArray {
+			cellMap = buildCellMap(currentWs, len(cellMap))
+			cellMaps[sheetName] = cellMap
+			if cellRef = cellMap[c.R]; cellRef == nil {
+				continue
+			}
+		}
+
 		if err != nil {
 			// If calculation fails, clear the cache
 			cellRef.V = ""
 			cellRef.T = ""
+			if errors.Is(err, ErrCircularReference) {
+				_ = cellErrs.add(sheetName, c.R, err)
+			}
 			continue
 		}
 
@@ -633,12 +582,13 @@ func (f *File) RecalculateAll() error {
 
 		// Progress logging - every 5%
 		if progressInterval > 0 && formulaCount%progressInterval == 0 {
-			progress := float64(formulaCount) / float64(len(calcChain.C)) * 100
+			progress := float64(formulaCount) / float64(len(order)) * 100
 			elapsed := time.Since(totalStart)
 			avgPerFormula := elapsed / time.Duration(formulaCount)
-			remaining := time.Duration(len(calcChain.C)-formulaCount) * avgPerFormula
-			log.Printf("  ⏳ [RecalculateAll] Progress: %.0f%% (%d/%d), sheet: '%s', elapsed: %v, avg: %v/formula, remaining: ~%v, slow formulas: %d",
-				progress, formulaCount, len(calcChain.C), currentSheetName, elapsed, avgPerFormula, remaining, slowFormulaCount)
+			remaining := time.Duration(len(order)-formulaCount) * avgPerFormula
+			logger.Debug("recalculation progress", "progress", progress, "formulas", formulaCount,
+				"total", len(order), "sheet", currentSheetName, "elapsed", elapsed,
+				"remaining", remaining, "slowFormulas", slowFormulaCount)
 
 			// 🔥 MEMORY OPTIMIZATION: Force GC at progress checkpoints to free memory
 			// This helps prevent OOM on large files (200k+ formulas)
@@ -649,73 +599,41 @@ func (f *File) RecalculateAll() error {
 	}
 
 	// 🔥 MEMORY OPTIMIZATION: Clear cellMap before final GC
-	cellMap = nil
+	cellMap, cellMaps = nil, nil
 	currentWs = nil
 
-	totalDuration := time.Since(totalStart)
-	log.Printf("✅ [RecalculateAll] Completed: %d formulas in %v", formulaCount, totalDuration)
-
-	// Avoid division by zero
-	avgPerFormula := time.Duration(0)
-	if formulaCount > 0 {
-		avgPerFormula = calcTime / time.Duration(formulaCount)
-	}
-	log.Printf("  📊 Breakdown: CellMap build: %v, Formula calc: %v, Avg per formula: %v",
-		sheetBuildTime, calcTime, avgPerFormula)
+	logger.Info("recalculation completed", "formulas", formulaCount, "duration", time.Since(totalStart),
+		"cellMapDuration", sheetBuildTime, "calcDuration", calcTime, "batchFormulas", batchCount,
+		"batchHits", batchHitCount)
 
-	// Log slow formula statistics
+	// Log slow formulas, only the top 20 slow formulas will be logged
 	if slowFormulaCount > 0 {
-		log.Printf("  ⚠️  Slow formulas detected: %d formulas took >100ms to calculate", slowFormulaCount)
-
-		// Print top slow formulas
-		if len(slowFormulas) > 0 {
-			log.Printf("  📋 Top %d slow formulas:", len(slowFormulas))
-			for i, sf := range slowFormulas {
-				if i >= 20 { // Only show top 20
-					log.Printf("  ... and %d more slow formulas", len(slowFormulas)-20)
-					break
-				}
-				log.Printf("    %2d. %s!%s - %v - %s", i+1, sf.sheet, sf.cell, sf.duration, sf.formula)
+		logger.Warn("slow formulas detected", "formulas", slowFormulaCount)
+		for i, sf := range slowFormulas {
+			if i >= 20 {
+				break
 			}
+			logger.Warn("slow formula", "sheet", sf.sheet, "cell", sf.cell, "duration", sf.duration,
+				"formula", sf.formula)
 		}
 	}
 
 	// Log timeout statistics
 	if timeoutCount > 0 {
-		log.Printf("  ⏱️  Timeout formulas: %d formulas exceeded 5s timeout or depend on timed-out columns", timeoutCount)
-		if len(timeoutColumns) > 0 {
-			log.Printf("  📋 Timed-out columns: %v", timeoutColumns)
+		columns := make([]string, 0, len(timeoutColumns))
+		for column := range timeoutColumns {
+			columns = append(columns, column)
 		}
+		sort.Strings(columns)
+		logger.Warn("formulas timed out", "formulas", timeoutCount, "timeout", timeout, "columns", columns)
 	}
 
-	// Log skipped complex formulas
-	if skippedComplexFormulas > 0 {
-		log.Printf("  🚫 Skipped formulas with circular references: %d formulas", skippedComplexFormulas)
-		if len(circularRefColumns) > 0 {
-			log.Printf("  📋 Circular reference columns: %v", getMapKeys(circularRefColumns))
-		}
-	}
-
-	// Log batch optimization statistics
-	if batchCount > 0 {
-		log.Printf("  ⚡ Batch SUMIFS/AVERAGEIFS/SUMPRODUCT stats: %d formulas batched, %d cache hits during calculation",
-			batchCount, batchHitCount)
-		if batchHitCount > 0 {
-			batchSavings := batchDuration
-			log.Printf("  💰 Estimated time saved by batch optimization: %v", batchSavings)
-		}
+	// Log circular references
+	if len(cellErrs.circular) > 0 {
+		logger.Warn("circular references detected", "formulas", len(cellErrs.circular))
 	}
 
-	return nil
-}
-
-// getMapKeys returns keys from a map[string]bool as a slice
-func getMapKeys(m map[string]bool) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	return keys
+	return cellErrs.err()
 }
 
 // truncateString truncates a string to maxLen characters
@@ -740,8 +658,8 @@ type AffectedCell struct {
 //
 // 重要特性：
 // 1. ✅ 支持跨工作表依赖：如果 Sheet2 引用 Sheet1 的值，更新 Sheet1 后会自动重新计算 Sheet2
-// 2. ✅ 只遍历一次 calcChain
-// 3. ✅ 每个公式只计算一次（即使被多个更新影响）
+// 2. ✅ 通过持久的依赖图查找受影响的公式，无需扫描 calcChain 或匹配公式文本
+// 3. ✅ 每个公式只计算一次（即使被多个更新影响），并按依赖的拓扑顺序计算
 // 4. ✅ 性能提升可达 10-100 倍（取决于更新数量）
 // 5. ✅ 自动更新所有受影响单元格的缓存值
 //
@@ -768,6 +686,15 @@ type AffectedCell struct {
 //	// 读取计算后的值
 //	value, _ := f.GetCellValue("Sheet2", "B1")
 func (f *File) BatchUpdateAndRecalculate(updates []CellUpdate) error {
+	return f.BatchUpdateAndRecalculateContext(context.Background(), updates)
+}
+
+// BatchUpdateAndRecalculateContext 与 BatchUpdateAndRecalculate 相同，但在给定
+// 的 context 被取消或超过截止时间时立即停止重新计算并返回 context 的错误，
+// 单元格的值已经更新，尚未计算的公式保留原有缓存值。单个公式的计算时长受
+// Options 的 CalcTimeout 限制（未设置时默认为 5 秒），超时的公式将被停止，
+// 并在计算完其余公式后返回 ErrFormulaTimeout 错误。
+func (f *File) BatchUpdateAndRecalculateContext(ctx context.Context, updates []CellUpdate) error {
 	// 初始化调试统计
 	if enableBatchDebug {
 		batchStatsMu.Lock()
@@ -795,43 +722,23 @@ func (f *File) BatchUpdateAndRecalculate(updates []CellUpdate) error {
 		return nil
 	}
 
-	// 3. 收集所有被更新的单元格（用于依赖检查）
-	// 优化：同时建立列索引，加速列引用检查
-	updatedCells := make(map[string]map[string]bool)   // sheet -> cell -> true
-	updatedColumns := make(map[string]map[string]bool) // sheet -> column -> true
+	// 3. 通过依赖图找出受影响的公式单元格，并按拓扑顺序重新计算
+	cells := make([]formulaCell, 0, len(updates))
 	for _, update := range updates {
-		if updatedCells[update.Sheet] == nil {
-			updatedCells[update.Sheet] = make(map[string]bool)
-			updatedColumns[update.Sheet] = make(map[string]bool)
-		}
-		updatedCells[update.Sheet][update.Cell] = true
-
-		// 提取列名
-		col, _, err := CellNameToCoordinates(update.Cell)
-		if err == nil {
-			colName, _ := ColumnNumberToName(col)
-			updatedColumns[update.Sheet][colName] = true
-		}
-	}
-
-	// 4. 找出所有受影响的公式单元格（通过依赖分析）
-	affectedFormulas := f.findAffectedFormulas(calcChain, updatedCells, updatedColumns)
-
-	// 5. 只清除受影响公式的缓存
-	for cellKey := range affectedFormulas {
-		cacheKey := cellKey + "!raw=false"
-		f.calcCache.Delete(cacheKey)
+		cells = append(cells, formulaCell{sheet: update.Sheet, cell: update.Cell})
 	}
-
-	// 6. 重新计算受影响的公式
-	err = f.recalculateAffectedCells(calcChain, affectedFormulas)
+	affected, err := f.recalculateDirtyCells(ctx, cells)
 
 	// 记录总耗时
-	if enableBatchDebug && currentBatchStats != nil {
-		currentBatchStats.TotalDuration = time.Since(batchStart)
-		currentBatchStats.TotalCells = len(affectedFormulas)
+	if stats := GetBatchDebugStats(); enableBatchDebug && stats != nil {
+		stats.mu.Lock()
+		stats.TotalDuration = time.Since(batchStart)
+		stats.TotalCells = affected
+		stats.mu.Unlock()
 	}
 
+	f.logger().Debug("batch update recalculated", "updates", len(updates), "formulas", affected,
+		"duration", time.Since(batchStart))
 	return err
 }
 
@@ -908,93 +815,18 @@ func (f *File) BatchSetFormulasAndRecalculate(formulas []FormulaUpdate) error {
 		return err
 	}
 
-	// 2. 收集所有受影响的工作表和单元格
-	affectedSheets := make(map[string][]string)
-	for _, formula := range formulas {
-		affectedSheets[formula.Sheet] = append(affectedSheets[formula.Sheet], formula.Cell)
-	}
-
-	// 3. 为每个工作表更新 calcChain
+	// 2. 更新 calcChain
 	if err := f.updateCalcChainForFormulas(formulas); err != nil {
 		return err
 	}
 
-	// 4. 收集被设置公式的单元格
-	setFormulaCells := make(map[string]map[string]bool)
+	// 3. 通过依赖图重新计算被设置的公式及依赖于它们的公式
+	cells := make([]formulaCell, 0, len(formulas))
 	for _, formula := range formulas {
-		if setFormulaCells[formula.Sheet] == nil {
-			setFormulaCells[formula.Sheet] = make(map[string]bool)
-		}
-		setFormulaCells[formula.Sheet][formula.Cell] = true
-	}
-
-	// 5. 重新计算所有公式
-	for sheet := range affectedSheets {
-		if err := f.RecalculateSheet(sheet); err != nil {
-			return err
-		}
-	}
-
-	// 6. 读取 calcChain 并找出依赖于新公式的其他单元格
-	calcChain, err := f.calcChainReader()
-	if err != nil {
-		return err
-	}
-
-	if calcChain == nil || len(calcChain.C) == 0 {
-		return nil
-	}
-
-	// 构建列索引
-	setFormulaColumns := make(map[string]map[string]bool)
-	for sheet, cells := range setFormulaCells {
-		setFormulaColumns[sheet] = make(map[string]bool)
-		for cell := range cells {
-			col, _, err := CellNameToCoordinates(cell)
-			if err == nil {
-				colName, _ := ColumnNumberToName(col)
-				setFormulaColumns[sheet][colName] = true
-			}
-		}
+		cells = append(cells, formulaCell{sheet: formula.Sheet, cell: formula.Cell})
 	}
-
-	affectedFormulas := f.findAffectedFormulas(calcChain, setFormulaCells, setFormulaColumns)
-
-	// 7. 只排除那些不依赖于同批其他公式的被设置单元格
-	// 如果 C1 依赖 B1，且 B1 和 C1 都被设置，则保留 C1
-	for sheet, cells := range setFormulaCells {
-		for cell := range cells {
-			cellKey := sheet + "!" + cell
-			// 检查这个单元格是否依赖于同批的其他公式
-			isDependentOnOthers := false
-
-			// 获取这个单元格的公式
-			ws, err := f.workSheetReader(sheet)
-			if err == nil {
-				col, row, _ := CellNameToCoordinates(cell)
-				cellData := f.getCellFromWorksheet(ws, col, row)
-				if cellData != nil && cellData.F != nil {
-					formula := cellData.F.Content
-					if formula == "" && cellData.F.T == STCellFormulaTypeShared && cellData.F.Si != nil {
-						formula, _ = getSharedFormula(ws, *cellData.F.Si, cell)
-					}
-
-					if formula != "" {
-						// 检查公式是否引用了同批的其他单元格
-						isDependentOnOthers = f.formulaReferencesUpdatedCells(formula, sheet, setFormulaCells, setFormulaColumns)
-					}
-				}
-			}
-
-			// 如果不依赖于同批其他公式，则排除
-			if !isDependentOnOthers {
-				delete(affectedFormulas, cellKey)
-			}
-		}
-	}
-
-	// 7. 不再构建affected列表，所有计算结果已经更新到工作表缓存中
-	return nil
+	_, err := f.recalculateDirtyCells(context.Background(), cells)
+	return err
 }
 
 // updateCalcChainForFormulas 更新 calcChain 以包含新设置的公式
@@ -1052,6 +884,7 @@ func (f *File) updateCalcChainForFormulas(formulas []FormulaUpdate) error {
 
 	// 保存更新后的 calcChain
 	f.CalcChain = calcChain
+	f.clearDependencyGraph()
 
 	return nil
 }
@@ -1093,7 +926,7 @@ func (f *File) recalculateAllSheetsWithTracking(calcChain *xlsxCalcChain) ([]Aff
 		_, hadCache := f.calcCache.Load(cacheKey)
 
 		// Recalculate the cell
-		if err := f.recalculateCell(sheetName, c.R); err != nil {
+		if err := f.recalculateCell(context.Background(), sheetName, c.R); err != nil {
 			// Continue even if one cell fails
 			continue
 		}
@@ -1120,271 +953,6 @@ func (f *File) recalculateAllSheetsWithTracking(calcChain *xlsxCalcChain) ([]Aff
 	return affected, nil
 }
 
-// findAffectedFormulas 找出所有受影响的公式单元格（包括间接依赖
-// findAffectedFormulas 找出所有受影响的公式单元格（包括间接依赖）
-// 通过解析公式中的单元格引用，找出哪些公式依赖于被更新的单元格
-func (f *File) findAffectedFormulas(calcChain *xlsxCalcChain, updatedCells map[string]map[string]bool, updatedColumns map[string]map[string]bool) map[string]bool {
-	affected := make(map[string]bool)
-	currentSheetID := -1
-
-	// 第一轮：找出直接依赖
-	for i := range calcChain.C {
-		c := calcChain.C[i]
-		if c.I != 0 {
-			currentSheetID = c.I
-		}
-
-		sheetName := f.GetSheetMap()[currentSheetID]
-		if sheetName == "" {
-			continue
-		}
-
-		// 获取公式内容
-		ws, err := f.workSheetReader(sheetName)
-		if err != nil {
-			continue
-		}
-
-		col, row, _ := CellNameToCoordinates(c.R)
-		cellData := f.getCellFromWorksheet(ws, col, row)
-		if cellData == nil || cellData.F == nil {
-			continue
-		}
-
-		formula := cellData.F.Content
-		if formula == "" && cellData.F.T == STCellFormulaTypeShared && cellData.F.Si != nil {
-			formula, _ = getSharedFormula(ws, *cellData.F.Si, c.R)
-		}
-
-		if formula == "" {
-			continue
-		}
-
-		// 检查公式是否引用了被更新的单元格
-		if f.formulaReferencesUpdatedCells(formula, sheetName, updatedCells, updatedColumns) {
-			cellKey := sheetName + "!" + c.R
-			affected[cellKey] = true
-		}
-	}
-
-	// 递归查找间接依赖：如果公式引用了受影响的单元格，它也受影响
-	changed := true
-	for changed {
-		changed = false
-		currentSheetID = -1
-
-		for i := range calcChain.C {
-			c := calcChain.C[i]
-			if c.I != 0 {
-				currentSheetID = c.I
-			}
-
-			sheetName := f.GetSheetMap()[currentSheetID]
-			if sheetName == "" {
-				continue
-			}
-
-			cellKey := sheetName + "!" + c.R
-			if affected[cellKey] {
-				continue // 已经标记为受影响
-			}
-
-			// 获取公式内容
-			ws, err := f.workSheetReader(sheetName)
-			if err != nil {
-				continue
-			}
-
-			col, row, _ := CellNameToCoordinates(c.R)
-			cellData := f.getCellFromWorksheet(ws, col, row)
-			if cellData == nil || cellData.F == nil {
-				continue
-			}
-
-			formula := cellData.F.Content
-			if formula == "" && cellData.F.T == STCellFormulaTypeShared && cellData.F.Si != nil {
-				formula, _ = getSharedFormula(ws, *cellData.F.Si, c.R)
-			}
-
-			if formula == "" {
-				continue
-			}
-
-			// 检查公式是否引用了受影响的单元格
-			if f.formulaReferencesAffectedCells(formula, sheetName, affected) {
-				affected[cellKey] = true
-				changed = true
-			}
-		}
-	}
-
-	return affected
-}
-
-// formulaReferencesUpdatedCells 检查公式是否引用了被更新的单元格
-func (f *File) formulaReferencesUpdatedCells(formula, currentSheet string, updatedCells map[string]map[string]bool, updatedColumns map[string]map[string]bool) bool {
-	// 去掉公式两端的单引号（如果有）
-	formula = strings.Trim(formula, "'")
-
-	// 检查全列引用（A:A, $A:$A, 'Sheet'!A:A, 中文表名!A:A 等）
-	colRefPattern := regexp.MustCompile(`(?:'([^']+)'!|([^\s\(\)!]+!))?(\$?[A-Z]+):(\$?[A-Z]+)`)
-	colMatches := colRefPattern.FindAllStringSubmatch(formula, -1)
-
-	for _, match := range colMatches {
-		refSheet := currentSheet
-		if match[1] != "" {
-			refSheet = match[1] // 单引号表名
-		} else if match[2] != "" {
-			refSheet = strings.TrimSuffix(match[2], "!")
-		}
-
-		// 优化：直接检查列索引，而不是遍历所有单元格
-		if updatedColumns[refSheet] != nil {
-			startCol := strings.ReplaceAll(match[3], "$", "")
-			endCol := strings.ReplaceAll(match[4], "$", "")
-
-			// 检查是否有更新的列在这个范围内
-			for colName := range updatedColumns[refSheet] {
-				if colName >= startCol && colName <= endCol {
-					return true
-				}
-			}
-		}
-	}
-
-	// 单元格引用匹配（支持单引号表名和中文表名）
-	// 使用\b单词边界或(?:^|[^A-Za-z0-9_])确保不会匹配到运算符
-	cellRefPattern := regexp.MustCompile(`(?:'([^']+)'!|(?:^|[^A-Za-z0-9_])([A-Za-z0-9_]+!))?(\$?[A-Z]+\$?[0-9]+)`)
-	matches := cellRefPattern.FindAllStringSubmatch(formula, -1)
-
-	for _, match := range matches {
-		refSheet := currentSheet
-		if match[1] != "" {
-			refSheet = match[1] // 单引号表名
-		} else if match[2] != "" {
-			// 移除尾部的!，并且移除前面的非字母数字字符（如=, +等）
-			refSheet = strings.TrimSuffix(match[2], "!")
-			// 移除前导的非字母数字字符
-			refSheet = strings.TrimLeft(refSheet, "=+-*/^&|<>(),")
-		}
-		refCell := strings.ReplaceAll(match[3], "$", "")
-
-		if updatedCells[refSheet] != nil && updatedCells[refSheet][refCell] {
-			return true
-		}
-	}
-
-	return false
-}
-
-// formulaReferencesAffectedCells 检查公式是否引用了受影响的单元格
-func (f *File) formulaReferencesAffectedCells(formula, currentSheet string, affectedCells map[string]bool) bool {
-	// 去掉公式两端的单引号（如果有）
-	formula = strings.Trim(formula, "'")
-
-	// 检查全列引用（A:A, $A:$A, 'Sheet'!A:A, 中文表名!A:A 等）
-	colRefPattern := regexp.MustCompile(`(?:'([^']+)'!|([^\s\(\)!]+!))?(\$?[A-Z]+):(\$?[A-Z]+)`)
-	colMatches := colRefPattern.FindAllStringSubmatch(formula, -1)
-
-	for _, match := range colMatches {
-		refSheet := currentSheet
-		if match[1] != "" {
-			refSheet = match[1] // 单引号表名
-		} else if match[2] != "" {
-			refSheet = strings.TrimSuffix(match[2], "!")
-		}
-
-		// 检查受影响的单元格是否在这个列范围内
-		for cellKey := range affectedCells {
-			// 解析 cellKey (Sheet!Cell)
-			parts := strings.Split(cellKey, "!")
-			if len(parts) == 2 && parts[0] == refSheet {
-				col, _, err := CellNameToCoordinates(parts[1])
-				if err == nil {
-					colName, _ := ColumnNumberToName(col)
-					startCol := strings.ReplaceAll(match[3], "$", "")
-					endCol := strings.ReplaceAll(match[4], "$", "")
-
-					if colName >= startCol && colName <= endCol {
-						return true
-					}
-				}
-			}
-		}
-	}
-
-	// 单元格引用匹配（支持单引号表名和中文表名）
-	// 使用\b单词边界或(?:^|[^A-Za-z0-9_])确保不会匹配到运算符
-	cellRefPattern := regexp.MustCompile(`(?:'([^']+)'!|(?:^|[^A-Za-z0-9_])([A-Za-z0-9_]+!))?(\$?[A-Z]+\$?[0-9]+)`)
-	matches := cellRefPattern.FindAllStringSubmatch(formula, -1)
-
-	for _, match := range matches {
-		refSheet := currentSheet
-		if match[1] != "" {
-			refSheet = match[1] // 单引号表名
-		} else if match[2] != "" {
-			// 移除尾部的!，并且移除前面的非字母数字字符（如=, +等）
-			refSheet = strings.TrimSuffix(match[2], "!")
-			// 移除前导的非字母数字字符
-			refSheet = strings.TrimLeft(refSheet, "=+-*/^&|<>(),")
-		}
-		refCell := strings.ReplaceAll(match[3], "$", "")
-		cellKey := refSheet + "!" + refCell
-
-		if affectedCells[cellKey] {
-			return true
-		}
-	}
-
-	// 检查范围引用（A1:B10, Sheet!A1:B10 等）
-	rangeRefPattern := regexp.MustCompile(`(?:'([^']+)'!|([^\s\(\)!]+!))?(\$?[A-Z]+\$?[0-9]+):(\$?[A-Z]+\$?[0-9]+)`)
-	rangeMatches := rangeRefPattern.FindAllStringSubmatch(formula, -1)
-
-	for _, match := range rangeMatches {
-		refSheet := currentSheet
-		if match[1] != "" {
-			refSheet = match[1]
-		} else if match[2] != "" {
-			refSheet = strings.TrimSuffix(match[2], "!")
-		}
-
-		startCell := strings.ReplaceAll(match[3], "$", "")
-		endCell := strings.ReplaceAll(match[4], "$", "")
-
-		// 检查受影响的单元格是否在这个范围内
-		for cellKey := range affectedCells {
-			parts := strings.Split(cellKey, "!")
-			if len(parts) == 2 && parts[0] == refSheet {
-				if f.cellInRange(parts[1], startCell, endCell) {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
-}
-
-// cellInRange 检查单元格是否在范围内
-func (f *File) cellInRange(cell, startCell, endCell string) bool {
-	col, row, err := CellNameToCoordinates(cell)
-	if err != nil {
-		return false
-	}
-
-	startCol, startRow, err := CellNameToCoordinates(startCell)
-	if err != nil {
-		return false
-	}
-
-	endCol, endRow, err := CellNameToCoordinates(endCell)
-	if err != nil {
-		return false
-	}
-
-	return col >= startCol && col <= endCol && row >= startRow && row <= endRow
-}
-
 // getCellFromWorksheet 从工作表中获取单元格数据
 func (f *File) getCellFromWorksheet(ws *xlsxWorksheet, col, row int) *xlsxC {
 	for i := range ws.SheetData.Row {
@@ -1402,43 +970,14 @@ func (f *File) getCellFromWorksheet(ws *xlsxWorksheet, col, row int) *xlsxC {
 	return nil
 }
 
-// recalculateAffectedCells 只重新计算受影响的单元格
-func (f *File) recalculateAffectedCells(calcChain *xlsxCalcChain, affectedFormulas map[string]bool) error {
-	currentSheetID := -1
-
-	for i := range calcChain.C {
-		c := calcChain.C[i]
-		if c.I != 0 {
-			currentSheetID = c.I
-		}
-
-		sheetName := f.GetSheetMap()[currentSheetID]
-		if sheetName == "" {
-			continue
-		}
-
-		cellKey := sheetName + "!" + c.R
-
-		// 只处理受影响的单元格
-		if !affectedFormulas[cellKey] {
-			continue
-		}
-
-		// 重新计算 - 结果已经直接更新到工作表缓存
-		if err := f.recalculateCell(sheetName, c.R); err != nil {
-			continue
-		}
-	}
-
-	return nil
-}
-
 // RebuildCalcChain 扫描所有工作表的公式并重建 calcChain
 func (f *File) RebuildCalcChain() error {
 	calcChain := &xlsxCalcChain{}
 	sheetList := f.GetSheetList()
 
-	for sheetIndex, sheetName := range sheetList {
+	for _, sheetName := range sheetList {
+		// I is the sheet ID (1-based), the same as the calcChain in the file
+		sheetID := f.getSheetID(sheetName)
 		ws, err := f.workSheetReader(sheetName)
 		if err != nil || ws.SheetData.Row == nil {
 			continue
@@ -1455,7 +994,7 @@ func (f *File) RebuildCalcChain() error {
 					if formula != "" {
 						calcChain.C = append(calcChain.C, xlsxCalcChainC{
 							R: cell.R,
-							I: sheetIndex,
+							I: sheetID,
 						})
 					}
 				}
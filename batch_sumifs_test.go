@@ -2,6 +2,8 @@ package excelize
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestExtractSUMIFSFromFormula(t *testing.T) {
@@ -46,3 +48,17 @@ func TestExtractSUMIFSFromFormula(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractSUMIFS2DPatternExport(t *testing.T) {
+	assert.Equal(t, "SUMIFS(Sheet2!$J:$J,Sheet2!$I:$I,$A2)", ExtractSUMIFSFromFormulaExport("=1+SUMIFS(Sheet2!$J:$J,Sheet2!$I:$I,$A2)"))
+	assert.Equal(t, &Sumifs2DPatternExport{SumRangeRef: "Sheet2!$J:$J", CriteriaRange1Ref: "Sheet2!$I:$I", CriteriaRange2Ref: "Sheet2!$L:$L"},
+		TestExtractSUMIFS2DPattern(nil, "Sheet1", "C2", "SUMIFS(Sheet2!$J:$J,Sheet2!$I:$I,$A2, Sheet2!$L:$L,C$1)"))
+	for _, formula := range []string{
+		"SUM(A1)",
+		"SUMIFS(Sheet2!$J:$J,Sheet2!$I:$I,$A2)",
+		"SUMIFS(Sheet2!$J:$J,$I:$I,$A2,Sheet2!$L:$L,C$1)",
+		"SUMIFS(Sheet2!$J:$J,Sheet2!$I:$I,Sheet2!$A2,Sheet2!$L:$L,C$1)",
+	} {
+		assert.Nil(t, TestExtractSUMIFS2DPattern(nil, "Sheet1", "C2", formula), formula)
+	}
+}
@@ -1,7 +1,6 @@
 package excelize
 
 import (
-	"log"
 	"runtime"
 	"strconv"
 	"strings"
@@ -196,8 +195,8 @@ func (f *File) calculateSUMPRODUCTPattern(pattern *sumproductMatchPattern) map[s
 
 	numCols := endColIdx - startColIdx + 1
 
-	log.Printf("  🔍 [SUMPRODUCT Batch] Processing %d formulas in sheet '%s', scanning columns %s-%s (%d columns)",
-		len(pattern.formulas), sheet, pattern.startCol, pattern.endCol, numCols)
+	f.logger().Debug("batch SUMPRODUCT started", "formulas", len(pattern.formulas), "sheet", sheet,
+		"startCol", pattern.startCol, "endCol", pattern.endCol, "columns", numCols)
 
 	// Read all rows from the sheet
 	rows, err := f.GetRows(sheet)
@@ -315,8 +314,7 @@ func (f *File) calculateSUMPRODUCTPattern(pattern *sumproductMatchPattern) map[s
 	}
 
 	duration := time.Since(startTime)
-	log.Printf("  ⚡ [SUMPRODUCT Batch] Completed %d formulas in %v (avg: %v/formula)",
-		len(results), duration, duration/time.Duration(len(results)))
+	f.logger().Debug("batch SUMPRODUCT completed", "formulas", len(results), "duration", duration)
 
 	return results
 }
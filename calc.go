@@ -14,12 +14,12 @@ package excelize
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"math/cmplx"
-	"math/rand"
 	"net/url"
 	"reflect"
 	"regexp"
@@ -240,10 +240,20 @@ var (
 // calcContext defines the formula execution context.
 type calcContext struct {
 	mu                sync.Mutex
+	context           context.Context
 	entry             string
 	maxCalcIterations uint
 	iterations        map[string]uint
 	iterationsCache   map[string]formulaArg
+	evaluating        map[string]bool
+	circular          string
+	parent            *calcContext
+	names             map[string]formulaArg
+	references        bool
+	tables            *[]tableDefinition
+	pivotTables       map[string][]pivotTableDefinition
+	reads             []formulaArea
+	rangeReads        map[*list.List][]formulaArea
 }
 
 // cellRef defines the structure of a cell reference.
@@ -277,6 +287,7 @@ const (
 	ArgMatrix
 	ArgError
 	ArgEmpty
+	ArgLambda
 )
 
 // formulaArg is the argument of a formula or function.
@@ -290,6 +301,7 @@ type formulaArg struct {
 	Error                string
 	Type                 ArgType
 	cellRefs, cellRanges *list.List
+	lambda               *formulaLambda
 }
 
 // Value returns a string data type of the formula argument.
@@ -311,6 +323,8 @@ func (fa formulaArg) Value() (value string) {
 		}
 	case ArgError:
 		return fa.Error
+	case ArgLambda:
+		return formulaErrorCALC
 	}
 	return
 }
@@ -386,9 +400,16 @@ type formulaFuncs struct {
 }
 
 // CalcCellValue provides a function to get calculated cell value. This feature
-// is currently in working processing. Iterative calculation, implicit
-// intersection, explicit intersection, array formula, table formula and some
-// other formulas are not supported currently.
+// is currently in working processing. Array formula and some other formulas
+// are not supported currently. The implicit intersection will be applied to
+// the reference result of the shared formula or the reference to the entire
+// columns or rows, such as the legacy formula =A:A. The top-left value of the
+// array result will be returned for the dynamic array formula without writing
+// the spill range, which will be written by the RecalculateAll and
+// RecalculateSheet functions. The circular references will be calculated
+// iteratively if the iterative calculation is enabled in the workbook
+// calculation properties, which could be set by the SetCalcProps function,
+// otherwise the ErrCircularReference error will be returned.
 //
 // Supported formula functions:
 //
@@ -404,6 +425,7 @@ type formulaFuncs struct {
 //	AI (requires build tag: ai_formula)
 //	AMORDEGRC
 //	AMORLINC
+//	ANCHORARRAY
 //	AND
 //	ARABIC
 //	ARRAYTOTEXT
@@ -439,6 +461,8 @@ type formulaFuncs struct {
 //	BITOR
 //	BITRSHIFT
 //	BITXOR
+//	BYCOL
+//	BYROW
 //	CEILING
 //	CEILING.MATH
 //	CEILING.PRECISE
@@ -452,6 +476,8 @@ type formulaFuncs struct {
 //	CHISQ.TEST
 //	CHITEST
 //	CHOOSE
+//	CHOOSECOLS
+//	CHOOSEROWS
 //	CLEAN
 //	CODE
 //	COLUMN
@@ -516,6 +542,7 @@ type formulaFuncs struct {
 //	DOLLARDE
 //	DOLLARFR
 //	DPRODUCT
+//	DROP
 //	DSTDEV
 //	DSTDEVP
 //	DSUM
@@ -535,6 +562,7 @@ type formulaFuncs struct {
 //	EVEN
 //	EXACT
 //	EXP
+//	EXPAND
 //	EXPON.DIST
 //	EXPONDIST
 //	F.DIST
@@ -557,6 +585,10 @@ type formulaFuncs struct {
 //	FLOOR.MATH
 //	FLOOR.PRECISE
 //	FORECAST
+//	FORECAST.ETS
+//	FORECAST.ETS.CONFINT
+//	FORECAST.ETS.SEASONALITY
+//	FORECAST.ETS.STAT
 //	FORECAST.LINEAR
 //	FORMULATEXT
 //	FREQUENCY
@@ -574,6 +606,8 @@ type formulaFuncs struct {
 //	GCD
 //	GEOMEAN
 //	GESTEP
+//	GETPIVOTDATA
+//	GROUPBY
 //	GROWTH
 //	HARMEAN
 //	HEX2BIN
@@ -581,6 +615,7 @@ type formulaFuncs struct {
 //	HEX2OCT
 //	HLOOKUP
 //	HOUR
+//	HSTACK
 //	HYPERLINK
 //	HYPGEOM.DIST
 //	HYPGEOMDIST
@@ -636,21 +671,27 @@ type formulaFuncs struct {
 //	ISREF
 //	ISTEXT
 //	KURT
+//	LAMBDA
 //	LARGE
 //	LCM
 //	LEFT
 //	LEFTB
 //	LEN
 //	LENB
+//	LET
+//	LINEST
 //	LN
 //	LOG
 //	LOG10
+//	LOGEST
 //	LOGINV
 //	LOGNORM.DIST
 //	LOGNORM.INV
 //	LOGNORMDIST
 //	LOOKUP
 //	LOWER
+//	MAKEARRAY
+//	MAP
 //	MATCH
 //	MAX
 //	MAXA
@@ -709,6 +750,7 @@ type formulaFuncs struct {
 //	PERCENTILE
 //	PERCENTILE.EXC
 //	PERCENTILE.INC
+//	PERCENTOF
 //	PERCENTRANK
 //	PERCENTRANK.EXC
 //	PERCENTRANK.INC
@@ -716,6 +758,7 @@ type formulaFuncs struct {
 //	PERMUTATIONA
 //	PHI
 //	PI
+//	PIVOTBY
 //	PMT
 //	POISSON
 //	POISSON.DIST
@@ -734,11 +777,16 @@ type formulaFuncs struct {
 //	QUOTIENT
 //	RADIANS
 //	RAND
+//	RANDARRAY
 //	RANDBETWEEN
 //	RANK
 //	RANK.EQ
 //	RATE
 //	RECEIVED
+//	REDUCE
+//	REGEXEXTRACT
+//	REGEXREPLACE
+//	REGEXTEST
 //	REPLACE
 //	REPLACEB
 //	REPT
@@ -752,16 +800,19 @@ type formulaFuncs struct {
 //	ROWS
 //	RRI
 //	RSQ
+//	SCAN
 //	SEARCH
 //	SEARCHB
 //	SEC
 //	SECH
 //	SECOND
+//	SEQUENCE
 //	SERIESSUM
 //	SHEET
 //	SHEETS
 //	SIGN
 //	SIN
+//	SINGLE
 //	SINH
 //	SKEW
 //	SKEW.P
@@ -769,6 +820,7 @@ type formulaFuncs struct {
 //	SLOPE
 //	SMALL
 //	SORT
+//	SORTBY
 //	SQRT
 //	SQRTPI
 //	STANDARDIZE
@@ -798,6 +850,7 @@ type formulaFuncs struct {
 //	T.INV
 //	T.INV.2T
 //	T.TEST
+//	TAKE
 //	TAN
 //	TANH
 //	TBILLEQ
@@ -811,7 +864,9 @@ type formulaFuncs struct {
 //	TIME
 //	TIMEVALUE
 //	TINV
+//	TOCOL
 //	TODAY
+//	TOROW
 //	TRANSPOSE
 //	TREND
 //	TRIM
@@ -834,14 +889,18 @@ type formulaFuncs struct {
 //	VARPA
 //	VDB
 //	VLOOKUP
+//	VSTACK
 //	WEEKDAY
 //	WEEKNUM
 //	WEIBULL
 //	WEIBULL.DIST
 //	WORKDAY
 //	WORKDAY.INTL
+//	WRAPCOLS
+//	WRAPROWS
 //	XIRR
 //	XLOOKUP
+//	XMATCH
 //	XNPV
 //	XOR
 //	YEAR
@@ -852,27 +911,124 @@ type formulaFuncs struct {
 //	Z.TEST
 //	ZTEST
 func (f *File) CalcCellValue(sheet, cell string, opts ...Options) (result string, err error) {
-	options := f.getOptions(opts...)
-	var (
-		rawCellValue = options.RawCellValue
-		styleIdx     int
-		token        formulaArg
-	)
+	return f.calcCellResult(context.Background(), sheet, cell, false, opts...)
+}
+
+// CalcCellValueContext provides a function to get calculated cell value like
+// the CalcCellValue function, the calculation will be stopped and the error of
+// the context will be returned when the given context is canceled or its
+// deadline is exceeded. The ErrFormulaTimeout error will be returned if the
+// calculation of the formula exceeds the deadline specified by the
+// CalcTimeout option. For example, stop the calculation after 3 seconds:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+//	defer cancel()
+//	result, err := f.CalcCellValueContext(ctx, "Sheet1", "A1")
+func (f *File) CalcCellValueContext(ctx context.Context, sheet, cell string, opts ...Options) (result string, err error) {
+	return f.calcCellResult(ctx, sheet, cell, false, opts...)
+}
+
+// calcCellResult calculates the formatted value of the cell by given
+// worksheet name and cell reference, the array result of the dynamic array
+// formula will be spilled into the neighbouring cells if spill is true. The
+// circular reference error will be returned if the iterative calculation is
+// disabled.
+func (f *File) calcCellResult(ctx context.Context, sheet, cell string, spill bool, opts ...Options) (result string, err error) {
+	options, start, report := f.getOptions(opts...), time.Now(), calcReportFromContext(ctx)
 	// Include rawCellValue in cache key to ensure different formatting options
 	// produce separate cache entries
-	cacheKey := fmt.Sprintf("%s!%s!raw=%t", sheet, cell, rawCellValue)
+	cacheKey := fmt.Sprintf("%s!%s!raw=%t", sheet, cell, options.RawCellValue)
 	if cachedResult, found := f.calcCache.Load(cacheKey); found {
+		f.recordCalc(report, sheet, cell, time.Since(start), true, cachedResult.(string), nil)
 		return cachedResult.(string), nil
 	}
-	if token, err = f.calcCellValue(&calcContext{
+	token, reads, err := f.calcCellToken(ctx, sheet, cell, options)
+	if err == nil {
+		result, err = f.calcTokenResult(sheet, cell, token, spill, reads, options)
+	} else {
+		result = token.String
+	}
+	if ctx.Err() == nil {
+		f.recordCalc(report, sheet, cell, time.Since(start), false, result, err)
+	}
+	return
+}
+
+// calcCellToken evaluates the formula of the cell by given worksheet name and
+// cell reference without changing the worksheet, and returns the cell areas
+// read by the formula, which is nil if the result couldn't be cached. The
+// circular reference error will be returned if the iterative calculation is
+// disabled. The error of the given context will be returned if it was
+// canceled, and the ErrFormulaTimeout error will be returned if the
+// calculation exceeds the deadline specified by the CalcTimeout option.
+func (f *File) calcCellToken(ctx context.Context, sheet, cell string, options *Options) (token formulaArg, reads []formulaArea, err error) {
+	calcCtx := &calcContext{
+		context:           ctx,
 		entry:             fmt.Sprintf("%s!%s", sheet, cell),
 		maxCalcIterations: options.MaxCalcIterations,
 		iterations:        make(map[string]uint),
 		iterationsCache:   make(map[string]formulaArg),
-	}, sheet, cell); err != nil {
+		evaluating:        make(map[string]bool),
+	}
+	if options.CalcTimeout > 0 {
+		var cancel context.CancelFunc
+		calcCtx.context, cancel = context.WithTimeout(ctx, options.CalcTimeout)
+		defer cancel()
+	}
+	// the volatile formula cell calculated as a precedent of the other formula
+	// cells in the recalculation keeps the same result
+	volatile := volatileValuesFromContext(ctx)
+	if arg, ok := volatile.load(calcCtx.entry); ok {
+		f.setVolatileCell(sheet, cell, true)
+		return arg, []formulaArea{volatileRead}, nil
+	}
+	token, err = f.calcCellIterate(calcCtx, sheet, cell)
+	// the references evaluated with errors fall back to the cached values, so
+	// check the context after the calculation
+	if calcCtx.canceled() != nil {
+		if err = ctx.Err(); err == nil {
+			err = newFormulaTimeoutError(calcCtx.entry)
+		}
+		return newEmptyFormulaArg(), nil, err
+	}
+	if err != nil {
+		return
+	}
+	if calcCtx.volatileSince(0) {
+		volatile.store(calcCtx.entry, token)
+	}
+	f.setVolatileCell(sheet, cell, calcCtx.volatileSince(0))
+	return token, calcCtx.readAreas(0), nil
+}
+
+// calcTokenResult returns the formatted value of the evaluated formula result
+// of the cell, the array result of the dynamic array formula will be spilled
+// into the neighbouring cells if spill is true, and the value will be stored
+// in the calculation cache with the cell areas read by the formula if the
+// reads isn't nil. The array result without spilling will not be cached, so
+// that the recalculation could write the spill range later.
+func (f *File) calcTokenResult(sheet, cell string, token formulaArg, spill bool, reads []formulaArea, options *Options) (result string, err error) {
+	var (
+		rawCellValue = options.RawCellValue
+		styleIdx     int
+		cacheKey     = fmt.Sprintf("%s!%s!raw=%t", sheet, cell, rawCellValue)
+	)
+	if !spill && token.Type == ArgMatrix {
+		reads = nil
+	}
+	if reads != nil {
+		reads = resultReads(sheet, cell, token, spill, reads)
+	}
+	if !spill {
+		token = f.formulaCellValue(sheet, cell, token)
+	} else if token, err = f.spillFormulaResult(sheet, cell, token); err != nil {
 		result = token.String
 		return
 	}
+	if token.Type == ArgError {
+		result, err = token.String, errors.New(token.Value())
+		return
+	}
 	if !rawCellValue {
 		// OPTIMIZATION: Use GetCellStyleReadOnly to avoid creating rows/cols
 		styleIdx, _ = f.GetCellStyleReadOnly(sheet, cell)
@@ -881,26 +1037,119 @@ func (f *File) CalcCellValue(sheet, cell string, opts ...Options) (result string
 		_, precision, decimal := isNumeric(token.Value())
 		if precision > 15 {
 			result, err = f.formattedValue(&xlsxC{S: styleIdx, V: strings.ToUpper(strconv.FormatFloat(decimal, 'G', 15, 64))}, rawCellValue, CellTypeNumber)
-			if err == nil {
-				f.calcCache.Store(cacheKey, result)
+			if err == nil && reads != nil {
+				f.calcCache.StoreReads(cacheKey, result, reads)
 			}
 			return
 		}
 		if !strings.HasPrefix(result, "0") {
 			result, err = f.formattedValue(&xlsxC{S: styleIdx, V: strings.ToUpper(strconv.FormatFloat(decimal, 'f', -1, 64))}, rawCellValue, CellTypeNumber)
 		}
-		if err == nil {
-			f.calcCache.Store(cacheKey, result)
+		if err == nil && reads != nil {
+			f.calcCache.StoreReads(cacheKey, result, reads)
 		}
 		return
 	}
 	result, err = f.formattedValue(&xlsxC{S: styleIdx, V: token.Value()}, rawCellValue, CellTypeInlineString)
-	if err == nil {
-		f.calcCache.Store(cacheKey, result)
+	if err == nil && reads != nil {
+		f.calcCache.StoreReads(cacheKey, result, reads)
 	}
 	return
 }
 
+// getCalcIterateProps returns whether the iterative calculation is enabled in
+// the workbook calculation properties, the maximum number of iterations and
+// the maximum change between iterations, which default to 100 and 0.001.
+func (f *File) getCalcIterateProps() (iterate bool, count int, delta float64) {
+	count, delta = 100, 0.001
+	wb, err := f.workbookReader()
+	if err != nil || wb.CalcPr == nil {
+		return
+	}
+	iterate = wb.CalcPr.Iterate
+	if wb.CalcPr.IterateCount > 0 {
+		count = wb.CalcPr.IterateCount
+	}
+	if wb.CalcPr.IterateDelta > 0 {
+		delta = wb.CalcPr.IterateDelta
+	}
+	return
+}
+
+// calcIterationChange returns the maximum change of the cell values between
+// two iterations, the change of the non-numeric values is infinite unless
+// they are equal.
+func calcIterationChange(prev, next map[string]formulaArg) float64 {
+	var change float64
+	for ref, arg := range next {
+		last, ok := prev[ref]
+		if !ok {
+			return math.Inf(1)
+		}
+		arg, last = arrayTopLeftValue(arg), arrayTopLeftValue(last)
+		if arg.Type == ArgNumber && last.Type == ArgNumber {
+			change = math.Max(change, math.Abs(arg.Number-last.Number))
+			continue
+		}
+		if arg.Type != last.Type || arg.Value() != last.Value() {
+			return math.Inf(1)
+		}
+	}
+	return change
+}
+
+// canceled returns the error of the context of the calculation if it was
+// canceled or its deadline was exceeded.
+func (ctx *calcContext) canceled() error {
+	if ctx = ctx.root(); ctx == nil || ctx.context == nil {
+		return nil
+	}
+	select {
+	case <-ctx.context.Done():
+		return ctx.context.Err()
+	default:
+		return nil
+	}
+}
+
+// calcCellIterate evaluates the formula of the cell by given context. If a
+// circular reference was detected, the cells in the cycle will be evaluated
+// repeatedly until the maximum change between iterations is less than the
+// iterate delta or the iterate count is reached, following the workbook
+// calculation properties. The circular reference error will be returned if
+// the iterative calculation is disabled.
+func (f *File) calcCellIterate(ctx *calcContext, sheet, cell string) (formulaArg, error) {
+	token, err := f.calcCellValue(ctx, sheet, cell)
+	if err != nil || ctx.circular == "" {
+		return token, err
+	}
+	iterate, count, delta := f.getCalcIterateProps()
+	if !iterate {
+		return newEmptyFormulaArg(), newCircularReferenceError(ctx.circular)
+	}
+	for i := 1; i < count; i++ {
+		ctx.mu.Lock()
+		ctx.iterationsCache[ctx.entry] = token
+		prev := make(map[string]formulaArg, len(ctx.iterationsCache))
+		for ref, arg := range ctx.iterationsCache {
+			prev[ref] = arg
+		}
+		ctx.iterations = make(map[string]uint)
+		ctx.mu.Unlock()
+		if token, err = f.calcCellValue(ctx, sheet, cell); err != nil {
+			return token, err
+		}
+		ctx.mu.Lock()
+		ctx.iterationsCache[ctx.entry] = token
+		change := calcIterationChange(prev, ctx.iterationsCache)
+		ctx.mu.Unlock()
+		if change < delta {
+			break
+		}
+	}
+	return token, err
+}
+
 // CalcCellValues calculates multiple cell values efficiently by leveraging cache.
 // This function is optimized for batch calculation scenarios where multiple cells
 // need to be calculated. It provides better performance than calling CalcCellValue
@@ -919,34 +1168,48 @@ func (f *File) CalcCellValue(sheet, cell string, opts ...Options) (result string
 //	results, err := f.CalcCellValues("Sheet1", cells)
 //	// results is a map: {"A1": "value1", "A2": "value2", ...}
 func (f *File) CalcCellValues(sheet string, cells []string, opts ...Options) (map[string]string, error) {
+	return f.CalcCellValuesContext(context.Background(), sheet, cells, opts...)
+}
+
+// CalcCellValuesContext calculates multiple cell values like the
+// CalcCellValues function. When the given context is canceled or its deadline
+// is exceeded, the remaining cells will not be calculated, and the results
+// calculated so far will be returned with the error of the context. The
+// cells whose calculation exceeds the deadline specified by the CalcTimeout
+// option will be skipped, and the returned error wraps the ErrFormulaTimeout
+// error.
+func (f *File) CalcCellValuesContext(ctx context.Context, sheet string, cells []string, opts ...Options) (map[string]string, error) {
 	if len(cells) == 0 {
 		return make(map[string]string), nil
 	}
 
 	results := make(map[string]string, len(cells))
-	var errors []error
+	var (
+		formats []string
+		errs    []interface{}
+	)
 
 	// Calculate all cells, benefiting from cache
 	// Skip cells that fail to calculate and collect errors
 	for _, cell := range cells {
-		result, err := f.CalcCellValue(sheet, cell, opts...)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, err := f.CalcCellValueContext(ctx, sheet, cell, opts...)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to calculate %s: %w", cell, err))
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			formats = append(formats, "%w")
+			errs = append(errs, fmt.Errorf("failed to calculate %s: %w", cell, err))
 			continue
 		}
 		results[cell] = result
 	}
 
 	// Return partial results with combined errors if any
-	if len(errors) > 0 {
-		var sb strings.Builder
-		for i, err := range errors {
-			if i > 0 {
-				sb.WriteString("; ")
-			}
-			sb.WriteString(err.Error())
-		}
-		return results, fmt.Errorf("%s", sb.String())
+	if len(errs) > 0 {
+		return results, fmt.Errorf(strings.Join(formats, "; "), errs...)
 	}
 
 	return results, nil
@@ -956,15 +1219,21 @@ func (f *File) CalcCellValues(sheet string, cells []string, opts ...Options) (ma
 // reference.
 func (f *File) calcCellValue(ctx *calcContext, sheet, cell string) (result formulaArg, err error) {
 	var formula string
+	if err = ctx.canceled(); err != nil {
+		return
+	}
 	if formula, err = f.getCellFormulaReadOnly(sheet, cell, true); err != nil {
 		return
 	}
 	ps := efp.ExcelParser()
-	tokens := ps.Parse(formula)
+	tokens := ps.Parse(escapeStructuredRefs(replaceSpillRangeOperator(replaceImplicitIntersectionOperator(formula))))
 	if tokens == nil {
 		return f.cellResolver(ctx, sheet, cell)
 	}
-	result, err = f.evalInfixExp(ctx, sheet, cell, tokens)
+	if result, err = f.evalInfixExp(ctx, sheet, cell, tokens); err == nil && result.Type == ArgLambda {
+		result = newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+		err = errors.New(formulaErrorCALC)
+	}
 	return
 }
 
@@ -1044,12 +1313,16 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 		opdStack, optStack, opfStack    = NewStack(), NewStack(), NewStack()
 		opfdStack, opftStack, argsStack = NewStack(), NewStack(), NewStack()
 	)
+	tokens = foldIntersectionTokens(tokens)
 	for i := 0; i < len(tokens); i++ {
 		token := tokens[i]
+		if err = ctx.canceled(); err != nil {
+			return newEmptyFormulaArg(), err
+		}
 
 		// out of function stack
 		if opfStack.Len() == 0 {
-			if err = f.parseToken(ctx, sheet, token, opdStack, optStack); err != nil {
+			if err = f.parseToken(ctx, sheet, cell, token, opdStack, optStack); err != nil {
 				return newEmptyFormulaArg(), err
 			}
 		}
@@ -1064,8 +1337,18 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 				inArrayRow, formulaArrayRow = true, []formulaArg{}
 				continue
 			}
+			argsList := list.New().Init()
+			if name := formulaFuncName(token.TValue); name == "LET" || name == "LAMBDA" {
+				// evaluate the arguments lazily and keep the function stop
+				// token only, the result will be taken on the function stop
+				result, end := f.evalLazyFunc(ctx, sheet, cell, tokens, i)
+				argsList.PushBack(result)
+				tokens = append(append(tokens[:i+1:i+1], efp.Token{
+					TType: efp.TokenTypeFunction, TSubType: efp.TokenSubTypeStop,
+				}), tokens[end+1:]...)
+			}
 			opfStack.Push(token)
-			argsStack.Push(list.New().Init())
+			argsStack.Push(argsList)
 			opftStack.Push(token) // to know which operators belong to a function use the function as a separator
 			continue
 		}
@@ -1088,12 +1371,8 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 					token.TValue = upperValue
 					// 继续正常处理，让它走 parseToken 路径
 				} else if opftStack.Peek().(efp.Token) != opfStack.Peek().(efp.Token) {
-					refTo := f.getDefinedNameRefTo(token.TValue, sheet)
-					if refTo != "" {
-						token.TValue = refTo
-					}
 					// parse reference: must reference at here
-					result, err := f.parseReference(ctx, sheet, token.TValue)
+					result, err := f.parseOperandRange(ctx, sheet, cell, token.TValue)
 					if err != nil {
 						return result, err
 					}
@@ -1101,11 +1380,7 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 					continue
 				} else if nextToken.TType == efp.TokenTypeArgument || nextToken.TType == efp.TokenTypeFunction {
 					// parse reference: reference or range at here
-					refTo := f.getDefinedNameRefTo(token.TValue, sheet)
-					if refTo != "" {
-						token.TValue = refTo
-					}
-					result, err := f.parseReference(ctx, sheet, token.TValue)
+					result, err := f.parseOperandRange(ctx.argsContext(opfStack.Peek().(efp.Token).TValue), sheet, cell, token.TValue)
 					if err != nil {
 						return result, err
 					}
@@ -1121,12 +1396,17 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 			}
 
 			// check current token is opft
-			if err = f.parseToken(ctx, sheet, token, opfdStack, opftStack); err != nil {
+			if err = f.parseToken(ctx, sheet, cell, token, opfdStack, opftStack); err != nil {
 				return newEmptyFormulaArg(), err
 			}
 
 			// current token is arg
 			if token.TType == efp.TokenTypeArgument {
+				if prev := tokens[i-1]; prev.TType == efp.TokenTypeArgument || isFunctionStartToken(prev) {
+					// the omitted argument
+					argsStack.Peek().(*list.List).PushBack(newEmptyFormulaArg())
+					continue
+				}
 				for opftStack.Peek().(efp.Token) != opfStack.Peek().(efp.Token) {
 					// calculate trigger
 					topOpt := opftStack.Peek().(efp.Token)
@@ -1140,6 +1420,10 @@ func (f *File) evalInfixExp(ctx *calcContext, sheet, cell string, tokens []efp.T
 				}
 				continue
 			}
+			if !inArray && isFunctionStopToken(token) && tokens[i-1].TType == efp.TokenTypeArgument {
+				// the omitted last argument
+				argsStack.Peek().(*list.List).PushBack(newEmptyFormulaArg())
+			}
 
 			if inArrayRow && isOperand(token) {
 				formulaArrayRow = append(formulaArrayRow, opfdStack.Pop().(formulaArg))
@@ -1188,10 +1472,7 @@ func (f *File) evalInfixExpFunc(ctx *calcContext, sheet, cell string, token, nex
 	prepareEvalInfixExp(opfStack, opftStack, opfdStack, argsStack)
 	// call formula function to evaluate
 	funcName := opfStack.Peek().(efp.Token).TValue
-	funcName = strings.ToUpper(funcName)
-	funcName = strings.NewReplacer("_XLFN.", "", "_xlfn.", "", ".", "dot").Replace(funcName)
-	arg := callFuncByName(&formulaFuncs{f: f, sheet: sheet, cell: cell, ctx: ctx}, funcName,
-		[]reflect.Value{reflect.ValueOf(argsStack.Peek().(*list.List))})
+	arg := f.callFormulaFunc(ctx, sheet, cell, funcName, argsStack.Peek().(*list.List))
 	if arg.Type == ArgError && opfStack.Len() == 1 {
 		return arg
 	}
@@ -1207,10 +1488,6 @@ func (f *File) evalInfixExpFunc(ctx *calcContext, sheet, cell string, token, nex
 		argsStack.Peek().(*list.List).PushBack(arg)
 		return newEmptyFormulaArg()
 	}
-	if arg.Type == ArgMatrix && len(arg.Matrix) > 0 && len(arg.Matrix[0]) > 0 {
-		opdStack.Push(arg.Matrix[0][0])
-		return newEmptyFormulaArg()
-	}
 	opdStack.Push(arg)
 	return newEmptyFormulaArg()
 }
@@ -1896,6 +2173,95 @@ func calcDiv(rOpd, lOpd formulaArg, opdStack *Stack) error {
 	return nil
 }
 
+// elementWiseOperators defined the infix operators which evaluated by the
+// element-wise calculation in the calculate function when any of the operands
+// is an array.
+var elementWiseOperators = map[string]struct{}{"^": {}, "/": {}, "+": {}, "&": {}}
+
+// isBroadcastMatrix returns if both of the operands are arrays with different
+// size, which should be broadcast in the element-wise calculation.
+func isBroadcastMatrix(rOpd, lOpd formulaArg) bool {
+	if rOpd.Type != ArgMatrix || lOpd.Type != ArgMatrix {
+		return false
+	}
+	if len(rOpd.Matrix) != len(lOpd.Matrix) {
+		return true
+	}
+	for i := range rOpd.Matrix {
+		if len(rOpd.Matrix[i]) != len(lOpd.Matrix[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matrixOperandElement returns the element of the operand at the given
+// position in the element-wise calculation, the scalar operand and the
+// single row or column array will be broadcast to the result array.
+func matrixOperandElement(opd formulaArg, row, col int) formulaArg {
+	if opd.Type != ArgMatrix {
+		return opd
+	}
+	if len(opd.Matrix) == 1 {
+		row = 0
+	}
+	if row >= len(opd.Matrix) {
+		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	}
+	if len(opd.Matrix[row]) == 1 {
+		col = 0
+	}
+	if col >= len(opd.Matrix[row]) {
+		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	}
+	return opd.Matrix[row][col]
+}
+
+// calcMatrix evaluate the arithmetic operations element-wise by given
+// operands which at least one of them is an array, and push the array result
+// into the operands stack.
+func calcMatrix(rOpd, lOpd formulaArg, opdStack *Stack, splice bool, fn func(rOpd, lOpd formulaArg, opdStack *Stack) error) {
+	var rows, cols int
+	for _, opd := range []formulaArg{lOpd, rOpd} {
+		if opd.Type == ArgMatrix {
+			rows = max(rows, len(opd.Matrix))
+			for _, row := range opd.Matrix {
+				cols = max(cols, len(row))
+			}
+		}
+	}
+	result := make([][]formulaArg, rows)
+	for i := range result {
+		result[i] = make([]formulaArg, cols)
+		for j := range result[i] {
+			l, r := matrixOperandElement(lOpd, i, j), matrixOperandElement(rOpd, i, j)
+			if l.Type == ArgError {
+				result[i][j] = l
+				continue
+			}
+			if r.Type == ArgError {
+				result[i][j] = r
+				continue
+			}
+			if !splice {
+				if l.Value() == "" {
+					l = newNumberFormulaArg(0)
+				}
+				if r.Value() == "" {
+					r = newNumberFormulaArg(0)
+				}
+			}
+			stack := NewStack()
+			if err := fn(r, l, stack); err != nil || stack.Len() == 0 {
+				result[i][j] = newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+				continue
+			}
+			result[i][j] = stack.Pop().(formulaArg)
+		}
+	}
+	opdStack.Push(newMatrixFormulaArg(result))
+}
+
 // calculate evaluate basic arithmetic operations.
 func calculate(opdStack *Stack, opt efp.Token) error {
 	if opt.TValue == "-" && opt.TType == efp.TokenTypeOperatorPrefix {
@@ -1903,6 +2269,10 @@ func calculate(opdStack *Stack, opt efp.Token) error {
 			return ErrInvalidFormula
 		}
 		opd := opdStack.Pop().(formulaArg)
+		if opd.Type == ArgMatrix {
+			calcMatrix(opd, newNumberFormulaArg(0), opdStack, false, calcSubtract)
+			return nil
+		}
 		opdStack.Push(newNumberFormulaArg(0 - opd.ToNumber().Number))
 	}
 	if opt.TValue == "-" && opt.TType == efp.TokenTypeOperatorInfix {
@@ -1911,6 +2281,10 @@ func calculate(opdStack *Stack, opt efp.Token) error {
 		}
 		rOpd := opdStack.Pop().(formulaArg)
 		lOpd := opdStack.Pop().(formulaArg)
+		if rOpd.Type == ArgMatrix || lOpd.Type == ArgMatrix {
+			calcMatrix(rOpd, lOpd, opdStack, false, calcSubtract)
+			return nil
+		}
 		if err := calcSubtract(rOpd, lOpd, opdStack); err != nil {
 			return err
 		}
@@ -1934,6 +2308,11 @@ func calculate(opdStack *Stack, opt efp.Token) error {
 		}
 		rOpd := opdStack.Pop().(formulaArg)
 		lOpd := opdStack.Pop().(formulaArg)
+		if _, ok := elementWiseOperators[opt.TValue]; ok && (rOpd.Type == ArgMatrix || lOpd.Type == ArgMatrix) ||
+			opt.TValue == "*" && isBroadcastMatrix(rOpd, lOpd) {
+			calcMatrix(rOpd, lOpd, opdStack, opt.TValue == "&", fn)
+			return nil
+		}
 		if opt.TValue != "&" {
 			if rOpd.Value() == "" {
 				rOpd = newNumberFormulaArg(0)
@@ -2048,7 +2427,7 @@ func formulaArgToToken(arg formulaArg) efp.Token {
 
 // parseToken parse basic arithmetic operator priority and evaluate based on
 // operators and operands.
-func (f *File) parseToken(ctx *calcContext, sheet string, token efp.Token, opdStack, optStack *Stack) error {
+func (f *File) parseToken(ctx *calcContext, sheet, cell string, token efp.Token, opdStack, optStack *Stack) error {
 	// parse reference: must reference at here
 	if token.TSubType == efp.TokenSubTypeRange {
 		// 检查是否是 TRUE/FALSE 关键字（大小写不敏感）
@@ -2059,14 +2438,14 @@ func (f *File) parseToken(ctx *calcContext, sheet string, token efp.Token, opdSt
 			token.TSubType = efp.TokenSubTypeLogical
 			token.TValue = upperValue
 		} else {
-			refTo := f.getDefinedNameRefTo(token.TValue, sheet)
-			if refTo != "" {
-				token.TValue = refTo
-			}
-			result, err := f.parseReference(ctx, sheet, token.TValue)
+			result, err := f.parseOperandRange(ctx, sheet, cell, token.TValue)
 			if err != nil {
 				return errors.New(formulaErrorNAME)
 			}
+			if result.Type == ArgMatrix || result.Type == ArgLambda {
+				opdStack.Push(result)
+				return nil
+			}
 			token = formulaArgToToken(result)
 		}
 	}
@@ -2234,6 +2613,9 @@ func (f *File) cellResolver(ctx *calcContext, sheet, cell string) (formulaArg, e
 		err   error
 	)
 	ref := fmt.Sprintf("%s!%s", sheet, cell)
+	// the names bound by the LET and LAMBDA functions are not visible in the
+	// other cells
+	ctx = ctx.root()
 
 	// Check calcCache first at cellResolver layer to avoid redundant work
 	// Only use cache if value is formulaArg type (safe type assertion)
@@ -2243,6 +2625,13 @@ func (f *File) cellResolver(ctx *calcContext, sheet, cell string) (formulaArg, e
 		}
 	}
 
+	// the volatile formula cell has been calculated in the recalculation
+	volatile := volatileValuesFromContext(ctx.context)
+	if cached, ok := volatile.load(ref); ok {
+		ctx.markVolatile()
+		return f.formulaCellValue(sheet, cell, cached), nil
+	}
+
 	// 检查是否是跨工作表引用（当前计算的工作表与单元格所在工作表不同）
 	isCrossSheet := ctx.entry != "" && !strings.HasPrefix(ctx.entry, sheet+"!")
 
@@ -2250,6 +2639,14 @@ func (f *File) cellResolver(ctx *calcContext, sheet, cell string) (formulaArg, e
 		// 对于跨工作表引用，优先使用缓存值
 		if isCrossSheet {
 			if cachedValue, err := f.GetCellValue(sheet, cell, Options{RawCellValue: true}); err == nil && cachedValue != "" {
+				// the cached value of the volatile formula cell will be
+				// changed on the next calculation
+				if f.isVolatileCell(sheet, cell, formula) {
+					ctx.markVolatile()
+				}
+				// the result depends on the cells read by the formula cell, so
+				// that it will be removed from the cache once they were changed
+				ctx.readArea(f.storedValueReads(ref)...)
 				arg := newStringFormulaArg(cachedValue)
 				// 根据cell类型转换arg类型，确保数值类型正确
 				if cellType, _ := f.GetCellType(sheet, cell); cellType == CellTypeNumber || cellType == CellTypeUnset {
@@ -2261,32 +2658,53 @@ func (f *File) cellResolver(ctx *calcContext, sheet, cell string) (formulaArg, e
 		}
 
 		ctx.mu.Lock()
-		if ctx.entry != ref {
-			if ctx.iterations[ref] <= f.options.MaxCalcIterations {
-				ctx.iterations[ref]++
-				ctx.mu.Unlock()
-				arg, calcErr := f.calcCellValue(ctx, sheet, cell)
-				// 修复: 写入 iterationsCache 需要加锁保护
-				ctx.mu.Lock()
-				ctx.iterationsCache[ref] = arg
+		if ctx.entry == ref || ctx.evaluating[ref] {
+			// the formula refers to the cell being evaluated, use the value of
+			// the previous iteration or the stored value of the cell
+			if ctx.circular == "" {
+				ctx.circular = ref
+			}
+			ctx.reads = append(ctx.reads, circularRead)
+			if cachedResult, ok := ctx.iterationsCache[ref]; ok {
 				ctx.mu.Unlock()
+				return arrayTopLeftValue(cachedResult), nil
+			}
+		} else if ctx.iterations[ref] <= f.options.MaxCalcIterations {
+			ctx.iterations[ref]++
+			if ctx.evaluating == nil {
+				ctx.evaluating = make(map[string]bool)
+			}
+			ctx.evaluating[ref] = true
+			ctx.mu.Unlock()
+			from := ctx.readCount()
+			arg, calcErr := f.calcCellValue(ctx, sheet, cell)
+			isVolatile := ctx.volatileSince(from)
+			f.setVolatileCell(sheet, cell, isVolatile)
+			// 修复: 写入 iterationsCache 需要加锁保护
+			ctx.mu.Lock()
+			if isVolatile && calcErr == nil && ctx.circular == "" {
+				volatile.store(ref, arg)
+			}
+			delete(ctx.evaluating, ref)
+			ctx.iterationsCache[ref] = arg
+			ctx.mu.Unlock()
 
-				// 如果计算失败，回退到缓存值
-				if calcErr != nil || arg.Type == ArgError {
-					if cachedValue, err := f.GetCellValue(sheet, cell, Options{RawCellValue: true}); err == nil && cachedValue != "" {
-						fallbackArg := newStringFormulaArg(cachedValue)
-						// 根据cell类型转换arg类型
-						if cellType, _ := f.GetCellType(sheet, cell); cellType == CellTypeNumber || cellType == CellTypeUnset {
-							return fallbackArg.ToNumber(), nil
-						}
-						return fallbackArg, nil
+			// 如果计算失败，回退到缓存值
+			if calcErr != nil || arg.Type == ArgError {
+				if cachedValue, err := f.GetCellValue(sheet, cell, Options{RawCellValue: true}); err == nil && cachedValue != "" {
+					fallbackArg := newStringFormulaArg(cachedValue)
+					// 根据cell类型转换arg类型
+					if cellType, _ := f.GetCellType(sheet, cell); cellType == CellTypeNumber || cellType == CellTypeUnset {
+						return fallbackArg.ToNumber(), nil
 					}
+					return fallbackArg, nil
 				}
-				return arg, nil
 			}
+			return f.formulaCellValue(sheet, cell, arg), nil
+		} else {
 			cachedResult := ctx.iterationsCache[ref]
 			ctx.mu.Unlock()
-			return cachedResult, nil
+			return arrayTopLeftValue(cachedResult), nil
 		}
 		ctx.mu.Unlock()
 	}
@@ -2362,47 +2780,6 @@ func (f *File) optimizeValueRange(sheet string, valueRange []int) []int {
 	return valueRange
 }
 
-// clearCellCache clears cache for a specific cell and related range caches.
-// This is a fine-grained cache invalidation strategy that only clears affected caches
-// instead of clearing the entire cache.
-func (f *File) clearCellCache(sheet, cell string) {
-	ref := fmt.Sprintf("%s!%s", sheet, cell)
-
-	// Clear calcCache for this cell
-	// Need to clear both raw and formatted cache entries
-	f.calcCache.Delete(fmt.Sprintf("%s!raw=true", ref))
-	f.calcCache.Delete(fmt.Sprintf("%s!raw=false", ref))
-
-	// Clear rangeCache entries that might include this cell
-	col, row, err := CellNameToCoordinates(cell)
-	if err != nil {
-		return
-	}
-
-	// Collect keys to delete first to avoid deadlock
-	// (Range holds RLock, Delete needs Lock)
-	var keysToDelete []string
-	f.rangeCache.Range(func(key string, value interface{}) bool {
-		// Check if cache key belongs to this sheet and might contain the cell
-		if len(key) > len(sheet) && key[:len(sheet)] == sheet {
-			// Parse range from cache key: "Sheet!R1C1:R100C5"
-			// If the cell is within this range, delete the cache entry
-			// For simplicity, we'll delete all range caches for this sheet
-			// A more sophisticated approach would parse and check exact ranges
-			if strings.HasPrefix(key, sheet+"!") {
-				keysToDelete = append(keysToDelete, key)
-			}
-		}
-		return true
-	})
-
-	// Delete keys after Range completes
-	for _, key := range keysToDelete {
-		f.rangeCache.Delete(key)
-	}
-	_, _ = col, row // Use variables to avoid unused variable error
-}
-
 // rangeResolverParallel reads cell values in parallel for large ranges
 // Only used when numRows >= parallelThreshold to avoid goroutine overhead
 func (f *File) rangeResolverParallel(ctx *calcContext, sheet string, ws *xlsxWorksheet, valueRange []int) ([][]formulaArg, error) {
@@ -2438,6 +2815,14 @@ func (f *File) rangeResolverParallel(ctx *calcContext, sheet string, ws *xlsxWor
 			endRow := min(startRow+chunkSize-1, valueRange[1])
 
 			for row := startRow; row <= endRow; row++ {
+				if err := ctx.canceled(); err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+					return
+				}
 				// 获取列最大值
 				colMax := 0
 				if row <= len(ws.SheetData.Row) {
@@ -2488,6 +2873,9 @@ func (f *File) rangeResolverSerial(ctx *calcContext, sheet string, ws *xlsxWorks
 	var matrix [][]formulaArg
 
 	for row := valueRange[0]; row <= valueRange[1]; row++ {
+		if err := ctx.canceled(); err != nil {
+			return nil, err
+		}
 		colMax := 0
 		if row <= len(ws.SheetData.Row) {
 			rowData := &ws.SheetData.Row[row-1]
@@ -2523,6 +2911,7 @@ func (f *File) rangeResolver(ctx *calcContext, cellRefs, cellRanges *list.List)
 	// value range order: from row, to row, from column, to column
 	valueRange := []int{0, 0, 0, 0}
 	var sheet string
+	from := ctx.readCount()
 	// prepare value range
 	for temp := cellRanges.Front(); temp != nil; temp = temp.Next() {
 		cr := temp.Value.(cellRange)
@@ -2533,6 +2922,7 @@ func (f *File) rangeResolver(ctx *calcContext, cellRefs, cellRanges *list.List)
 		if cr.From.Sheet != "" {
 			sheet = cr.From.Sheet
 		}
+		ctx.readArea(cellRangeArea(cr))
 	}
 	for temp := cellRefs.Front(); temp != nil; temp = temp.Next() {
 		cr := temp.Value.(cellRef)
@@ -2540,6 +2930,14 @@ func (f *File) rangeResolver(ctx *calcContext, cellRefs, cellRanges *list.List)
 			sheet = cr.Sheet
 		}
 		prepareValueRef(cr, valueRange)
+		ctx.readArea(formulaArea{sheet: cr.Sheet, x1: cr.Col, y1: cr.Row, x2: cr.Col, y2: cr.Row})
+	}
+	// the formula function reads the references instead of the cell values
+	if ctx.references {
+		if cellRanges.Len() > 0 {
+			arg.Type = ArgMatrix
+		}
+		return
 	}
 	// extract value from ranges
 	if cellRanges.Len() > 0 {
@@ -2550,7 +2948,10 @@ func (f *File) rangeResolver(ctx *calcContext, cellRefs, cellRanges *list.List)
 
 		// Check range cache first
 		cacheKey := generateRangeCacheKey(sheet, valueRange)
-		if cached, ok := f.rangeCache.Load(cacheKey); ok {
+		if cached, reads, ok := f.rangeCache.LoadReads(cacheKey); ok {
+			// the formula cells in the range read the other cells
+			ctx.readArea(reads...)
+			ctx.resolvedRange(cellRanges, from)
 			arg.Matrix = cached.([][]formulaArg)
 			arg.cellRefs, arg.cellRanges = cellRefs, cellRanges
 			return
@@ -2568,10 +2969,14 @@ func (f *File) rangeResolver(ctx *calcContext, cellRefs, cellRanges *list.List)
 			return
 		}
 
-		// Store result in LRU range cache
-		// Old entries are automatically evicted when capacity is reached
+		// Store result in LRU range cache with the cells read by the range
+		// and its formula cells, old entries are automatically evicted when
+		// capacity is reached, the range contains the volatile formula
+		// cells will not be cached
 		// Note: Cache can be cleared at the end of batch calculation in CalcCellValuesDependencyAware
-		f.rangeCache.Store(cacheKey, arg.Matrix)
+		if reads := ctx.resolvedRange(cellRanges, from); reads != nil {
+			f.rangeCache.StoreReads(cacheKey, arg.Matrix, reads)
+		}
 		return
 	}
 	// extract value from references
@@ -6002,7 +6407,9 @@ func (fn *formulaFuncs) RAND(argsList *list.List) formulaArg {
 	if argsList.Len() != 0 {
 		return newErrorFormulaArg(formulaErrorVALUE, "RAND accepts no arguments")
 	}
-	return newNumberFormulaArg(rand.New(rand.NewSource(time.Now().UnixNano())).Float64())
+	r, release := fn.f.random()
+	defer release()
+	return newNumberFormulaArg(r.Float64())
 }
 
 // RANDBETWEEN function generates a random integer between two supplied
@@ -6024,7 +6431,9 @@ func (fn *formulaFuncs) RANDBETWEEN(argsList *list.List) formulaArg {
 	if top.Number < bottom.Number {
 		return newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
 	}
-	num := rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(int64(top.Number - bottom.Number + 1))
+	r, release := fn.f.random()
+	defer release()
+	num := r.Int63n(int64(top.Number - bottom.Number + 1))
 	return newNumberFormulaArg(float64(num + int64(bottom.Number)))
 }
 
@@ -6731,6 +7140,9 @@ func (fn *formulaFuncs) SUMIF(argsList *list.List) formulaArg {
 	if argsList.Len() < 2 {
 		return newErrorFormulaArg(formulaErrorVALUE, "SUMIF requires at least 2 arguments")
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindSumIf, argsList); ok {
+		return newNumberFormulaArg(agg.sum)
+	}
 	criteria := formulaCriteriaParser(argsList.Front().Next().Value.(formulaArg))
 	rangeMtx := argsList.Front().Value.(formulaArg).Matrix
 	var sumRange [][]formulaArg
@@ -6772,6 +7184,9 @@ func (fn *formulaFuncs) SUMIFS(argsList *list.List) formulaArg {
 	if argsList.Len()%2 != 1 {
 		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindIfs, argsList); ok {
+		return newNumberFormulaArg(agg.sum)
+	}
 	var args []formulaArg
 	sum, sumRange := 0.0, argsList.Front().Value.(formulaArg).Matrix
 	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
@@ -7118,6 +7533,9 @@ func (fn *formulaFuncs) AVERAGEIF(argsList *list.List) formulaArg {
 	if argsList.Len() < 2 {
 		return newErrorFormulaArg(formulaErrorVALUE, "AVERAGEIF requires at least 2 arguments")
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindAverageIf, argsList); ok {
+		return agg.average(formulaErrorDIV)
+	}
 	var (
 		criteria  = formulaCriteriaParser(argsList.Front().Next().Value.(formulaArg))
 		rangeMtx  = argsList.Front().Value.(formulaArg).Matrix
@@ -7173,6 +7591,9 @@ func (fn *formulaFuncs) AVERAGEIFS(argsList *list.List) formulaArg {
 	if argsList.Len()%2 != 1 {
 		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindIfs, argsList); ok {
+		return agg.average("AVERAGEIF divide by zero")
+	}
 	var args []formulaArg
 	sum, sumRange := 0.0, argsList.Front().Value.(formulaArg).Matrix
 	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
@@ -8833,6 +9254,9 @@ func (fn *formulaFuncs) COUNTIF(argsList *list.List) formulaArg {
 	if argsList.Len() != 2 {
 		return newErrorFormulaArg(formulaErrorVALUE, "COUNTIF requires 2 arguments")
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindCountIf, argsList); ok {
+		return newNumberFormulaArg(float64(agg.count))
+	}
 	var (
 		criteria = formulaCriteriaParser(argsList.Front().Next().Value.(formulaArg))
 		count    float64
@@ -8848,6 +9272,46 @@ func (fn *formulaFuncs) COUNTIF(argsList *list.List) formulaArg {
 	return newNumberFormulaArg(count)
 }
 
+// rangeIndexKey returns the key of the value index of the first cell range
+// in the formula argument, returns an empty string if the argument is not a
+// cell range.
+func rangeIndexKey(arg formulaArg) string {
+	if arg.cellRanges == nil || arg.cellRanges.Len() == 0 {
+		return ""
+	}
+	return cellRangeIndexKey(arg.cellRanges.Front().Value.(cellRange))
+}
+
+// cellRangeIndexKey returns the key of the value index by given cell range.
+func cellRangeIndexKey(cr cellRange) string {
+	return fmt.Sprintf("%s:%d:%d-%d:%d", cr.From.Sheet, cr.From.Row, cr.From.Col, cr.To.Row, cr.To.Col)
+}
+
+// rangeValueIndex returns the index of the non-empty values to the relative
+// positions in the given matrix, the index will be cached by the given key
+// of the cell range and shared by the formulas which refer to the same range,
+// and it will be dropped once any cell in the given read areas was changed.
+// The index will not be cached if the read areas is nil.
+func (fn *formulaFuncs) rangeValueIndex(indexKey string, matrix [][]formulaArg, reads []formulaArea) map[string][]cellRef {
+	if indexKey != "" {
+		if cached, ok := fn.f.rangeIndexCache.Load(indexKey); ok {
+			return cached.(map[string][]cellRef)
+		}
+	}
+	rangeIndex := make(map[string][]cellRef)
+	for rowIdx, row := range matrix {
+		for colIdx, col := range row {
+			if val := col.Value(); val != "" {
+				rangeIndex[val] = append(rangeIndex[val], cellRef{Col: colIdx, Row: rowIdx})
+			}
+		}
+	}
+	if indexKey != "" && reads != nil {
+		fn.f.rangeIndexCache.StoreReads(indexKey, rangeIndex, reads)
+	}
+	return rangeIndex
+}
+
 // formulaIfsMatch function returns cells reference array which match criteria.
 // This is now a method of formulaFuncs to enable caching optimization.
 func (fn *formulaFuncs) formulaIfsMatch(args []formulaArg) (cellRefs []cellRef) {
@@ -8929,36 +9393,9 @@ func (fn *formulaFuncs) formulaIfsMatch(args []formulaArg) (cellRefs []cellRef)
 
 		if i == 0 {
 			// First criteria - build or use index
-			// Generate index key from range info
-			var indexKey string
-			rangeArg := args[i]
-			if rangeArg.cellRanges != nil && rangeArg.cellRanges.Len() > 0 {
-				for r := rangeArg.cellRanges.Front(); r != nil; r = r.Next() {
-					cr := r.Value.(cellRange)
-					indexKey = fmt.Sprintf("%s:%d:%d-%d:%d", cr.From.Sheet, cr.From.Row, cr.From.Col, cr.To.Row, cr.To.Col)
-					break // Use first range
-				}
-			}
-
-			// Try to get or build index for this range
 			var rangeIndex map[string][]cellRef
-			if indexKey != "" {
-				if cached, ok := fn.f.rangeIndexCache.Load(indexKey); ok {
-					rangeIndex = cached.(map[string][]cellRef)
-				} else {
-					// Build index for this range
-					rangeIndex = make(map[string][]cellRef)
-					for rowIdx, row := range matrix {
-						for colIdx, col := range row {
-							val := col.Value()
-							if val != "" { // Only index non-empty values
-								rangeIndex[val] = append(rangeIndex[val], cellRef{Col: colIdx, Row: rowIdx})
-							}
-						}
-					}
-					// Store index in cache
-					fn.f.rangeIndexCache.Store(indexKey, rangeIndex)
-				}
+			if indexKey := rangeIndexKey(args[i]); indexKey != "" {
+				rangeIndex = fn.rangeValueIndex(indexKey, matrix, fn.argReads(args[i]))
 			}
 
 			// Use index for equality criteria
@@ -8989,15 +9426,7 @@ func (fn *formulaFuncs) formulaIfsMatch(args []formulaArg) (cellRefs []cellRef)
 			}
 		} else {
 			// Subsequent criteria - filter existing matches using index
-			var indexKey string
-			rangeArg := args[i]
-			if rangeArg.cellRanges != nil && rangeArg.cellRanges.Len() > 0 {
-				for r := rangeArg.cellRanges.Front(); r != nil; r = r.Next() {
-					cr := r.Value.(cellRange)
-					indexKey = fmt.Sprintf("%s:%d:%d-%d:%d", cr.From.Sheet, cr.From.Row, cr.From.Col, cr.To.Row, cr.To.Col)
-					break
-				}
-			}
+			indexKey := rangeIndexKey(args[i])
 
 			// Try to use index for filtering
 			if criteria.Type == criteriaEq && indexKey != "" {
@@ -9050,8 +9479,15 @@ func (fn *formulaFuncs) formulaIfsMatch(args []formulaArg) (cellRefs []cellRef)
 		cellRefs = match[:]
 	}
 
-	// Store in cache
-	fn.f.ifsMatchCache.Store(key, cellRefs)
+	// Store in cache with the cells read by the ranges, the ranges which
+	// are not cell ranges can't be identified by the key
+	var ranges []formulaArg
+	for i := 0; i < len(args)-1; i += 2 {
+		ranges = append(ranges, args[i])
+	}
+	if reads := fn.argReads(ranges...); reads != nil {
+		fn.f.ifsMatchCache.StoreReads(key, cellRefs, reads)
+	}
 
 	return
 }
@@ -9067,6 +9503,9 @@ func (fn *formulaFuncs) COUNTIFS(argsList *list.List) formulaArg {
 	if argsList.Len()%2 != 0 {
 		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindIfs, argsList); ok {
+		return newNumberFormulaArg(float64(agg.count))
+	}
 	var args []formulaArg
 	for arg := argsList.Front(); arg != nil; arg = arg.Next() {
 		args = append(args, arg.Value.(formulaArg))
@@ -11374,6 +11813,9 @@ func (fn *formulaFuncs) MAXIFS(argsList *list.List) formulaArg {
 	if argsList.Len()%2 != 1 {
 		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindIfs, argsList); ok {
+		return agg.maxValue()
+	}
 	var args []formulaArg
 	maxVal, maxRange := -math.MaxFloat64, argsList.Front().Value.(formulaArg).Matrix
 	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
@@ -11518,6 +11960,9 @@ func (fn *formulaFuncs) MINIFS(argsList *list.List) formulaArg {
 	if argsList.Len()%2 != 1 {
 		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
 	}
+	if agg, ok := fn.ifsAggregate(ifsKindIfs, argsList); ok {
+		return agg.minValue()
+	}
 	var args []formulaArg
 	minVal, minRange := math.MaxFloat64, argsList.Front().Value.(formulaArg).Matrix
 	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
@@ -14541,7 +14986,7 @@ func (fn *formulaFuncs) NOW(argsList *list.List) formulaArg {
 	if argsList.Len() != 0 {
 		return newErrorFormulaArg(formulaErrorVALUE, "NOW accepts no arguments")
 	}
-	now := time.Now()
+	now := fn.f.now()
 	_, offset := now.Zone()
 	return newNumberFormulaArg(25569.0 + float64(now.Unix()+int64(offset))/86400)
 }
@@ -14637,7 +15082,7 @@ func (fn *formulaFuncs) TODAY(argsList *list.List) formulaArg {
 	if argsList.Len() != 0 {
 		return newErrorFormulaArg(formulaErrorVALUE, "TODAY accepts no arguments")
 	}
-	now := time.Now()
+	now := fn.f.now()
 	_, offset := now.Zone()
 	return newNumberFormulaArg(daysBetween(excelMinTime1900.Unix(), now.Unix()+int64(offset)) + 1)
 }
@@ -16318,7 +16763,7 @@ func (fn *formulaFuncs) ADDRESS(argsList *list.List) formulaArg {
 		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
 	}
 	absNum := newNumberFormulaArg(1)
-	if argsList.Len() >= 3 {
+	if argsList.Len() >= 3 && argsList.Front().Next().Next().Value.(formulaArg).Type != ArgEmpty {
 		absNum = argsList.Front().Next().Next().Value.(formulaArg).ToNumber()
 		if absNum.Type != ArgNumber {
 			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
@@ -16363,6 +16808,24 @@ func (fn *formulaFuncs) ANCHORARRAY(argsList *list.List) formulaArg {
 	if cell.F == nil {
 		return newEmptyFormulaArg()
 	}
+	if cell.F.Content != "" && (cell.F.T != STCellFormulaTypeArray || cell.Cm != nil) {
+		// the dynamic array formula, calculate the whole array result of the
+		// formula in the anchor cell
+		if ref.Sheet == "" {
+			ref.Sheet = fn.sheet
+		}
+		result, err := fn.f.anchorArrayResolver(fn.ctx, ref.Sheet, cell.R)
+		if result.Type == ArgError {
+			return result
+		}
+		if err != nil {
+			return newErrorFormulaArg(formulaErrorVALUE, err.Error())
+		}
+		if result.Type != ArgMatrix {
+			return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+		}
+		return result
+	}
 	coordinates, err := rangeRefToCoordinates(cell.F.Ref)
 	if err != nil {
 		return newErrorFormulaArg(formulaErrorVALUE, err.Error())
@@ -16598,6 +17061,12 @@ func calcColsRowsMinMax(cols bool, argsList *list.List) (minVal, maxVal int) {
 			}
 		}
 	}
+	// the array returned by the formula function without references
+	if arg := argsList.Front().Value.(formulaArg); minVal == 0 && arg.Type == ArgMatrix && len(arg.Matrix) > 0 {
+		if minVal, maxVal = 1, len(arg.Matrix); cols {
+			maxVal = len(arg.Matrix[0])
+		}
+	}
 	return
 }
 
@@ -16700,15 +17169,10 @@ func (fn *formulaFuncs) HLOOKUP(argsList *list.List) formulaArg {
 	}
 	var matchIdx int
 	var wasExact bool
-
-	// Use hash index for exact match mode with large datasets (>100 columns)
-	// Hash lookup is O(1) vs O(n) for linear search
-	if matchMode.Number == matchModeExact && len(tableArray.Matrix) > 0 && len(tableArray.Matrix[0]) > 100 {
-		matchIdx, wasExact = fn.lookupHashSearch(false, lookupValue, tableArray)
-	} else if matchMode.Number == matchModeWildcard || len(tableArray.Matrix) == TotalRows {
-		matchIdx, wasExact = lookupLinearSearch(false, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeLinear))
+	if matchMode.Number == matchModeWildcard || len(tableArray.Matrix) == TotalRows {
+		matchIdx, wasExact = fn.lookupLinear(false, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeLinear))
 	} else {
-		matchIdx, wasExact = lookupBinarySearch(false, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeAscBinary))
+		matchIdx, wasExact = lookupBinarySearch(false, lookupValue, tableArray, fn.lookupCells(false, tableArray), matchMode, newNumberFormulaArg(searchModeAscBinary))
 	}
 	if matchIdx == -1 {
 		return newErrorFormulaArg(formulaErrorNA, "HLOOKUP no result found")
@@ -16789,88 +17253,10 @@ func calcMatchMatrix(vertical bool, matchType int, criteria *formulaCriteria, lo
 
 // calcMatch returns the position of the value by given match type, criteria
 // and lookup array for the formula function MATCH.
+// matchType only contains -1, and 1.
 func (fn *formulaFuncs) calcMatch(matchType int, criteria *formulaCriteria, lookupArray []formulaArg) formulaArg {
 	idx := -1
 	switch matchType {
-	case 0: // Exact match
-		// Use hash index for large datasets (>100 elements)
-		// Hash lookup is O(1) vs O(n) for linear search
-		if len(lookupArray) > 100 {
-			// Generate cache key based on first and last values
-			// This is a heuristic: assumes lookup array is stable
-			lookupIsNumber := criteria.Condition.Type == ArgNumber
-			var cacheKey string
-			if len(lookupArray) > 0 {
-				// Use strings.Builder for efficient string construction
-				var b strings.Builder
-				b.Grow(64) // Pre-allocate
-				b.WriteString("match:")
-				b.WriteString(strconv.Itoa(len(lookupArray)))
-				b.WriteString(":")
-				if lookupIsNumber {
-					b.WriteString("n:")
-				} else {
-					b.WriteString("s:")
-				}
-				b.WriteString(lookupArray[0].Value())
-				b.WriteString(":")
-				b.WriteString(lookupArray[len(lookupArray)-1].Value())
-				cacheKey = b.String()
-			} else {
-				cacheKey = "match:0:empty"
-			}
-
-			// Try to get cached index
-			var hashIndex map[string]int
-			if cached, found := fn.f.matchIndexCache.Load(cacheKey); found {
-				hashIndex = cached.(map[string]int)
-			} else {
-				// Build hash index with type-aware keys
-				hashIndex = make(map[string]int, len(lookupArray))
-
-				for i, arg := range lookupArray {
-					var key string
-					if lookupIsNumber {
-						// Convert to number for comparison
-						if numArg := arg.ToNumber(); numArg.Type == ArgNumber {
-							key = "N:" + numArg.Value()
-						} else {
-							continue // Skip non-numeric cells when looking for numbers
-						}
-					} else {
-						// Use string comparison
-						key = "S:" + arg.Value()
-					}
-
-					if _, exists := hashIndex[key]; !exists {
-						hashIndex[key] = i
-					}
-				}
-
-				// Store in cache
-				fn.f.matchIndexCache.Store(cacheKey, hashIndex)
-			}
-
-			// Perform hash lookup with type-aware key
-			var lookupKey string
-			if lookupIsNumber {
-				lookupKey = "N:" + criteria.Condition.Value()
-			} else {
-				lookupKey = "S:" + criteria.Condition.Value()
-			}
-
-			if foundIdx, found := hashIndex[lookupKey]; found {
-				return newNumberFormulaArg(float64(foundIdx + 1))
-			}
-			return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
-		}
-
-		// Small datasets use linear search
-		for i, arg := range lookupArray {
-			if ok, _ := formulaCriteriaEval(arg, criteria); ok {
-				return newNumberFormulaArg(float64(i + 1))
-			}
-		}
 	case -1:
 		for i, arg := range lookupArray {
 			if ok, _ := formulaCriteriaEval(arg, &formulaCriteria{
@@ -16951,6 +17337,21 @@ func (fn *formulaFuncs) MATCH(argsList *list.List) formulaArg {
 	default:
 		return newErrorFormulaArg(formulaErrorNA, lookupArrayErr)
 	}
+	if matchType == 0 && len(lookupArray) > 0 {
+		// The exact match is the same as the wildcard match of the XMATCH
+		// function, the lookup vector of the cell range is indexed and shared
+		// by the function calls which refer to the same range
+		vector := lookupArrayArg
+		if len(lookupArrayArg.Matrix) > 1 && len(lookupArrayArg.Matrix[0]) > 1 {
+			vector = newMatrixFormulaArg([][]formulaArg{lookupArray})
+		}
+		idx, _ := fn.lookupLinear(len(vector.Matrix) > 1, argsList.Front().Value.(formulaArg), vector,
+			newNumberFormulaArg(matchModeWildcard), newNumberFormulaArg(searchModeLinear))
+		if idx == -1 {
+			return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+		}
+		return newNumberFormulaArg(float64(idx + 1))
+	}
 	return fn.calcMatch(matchType, formulaCriteriaParser(argsList.Front().Value.(formulaArg)), lookupArray)
 }
 
@@ -17030,121 +17431,6 @@ func lookupLinearSearch(vertical bool, lookupValue, lookupArray, matchMode, sear
 	return matchIdx, wasExact
 }
 
-// lookupHashSearch uses a hash map for O(1) exact match lookups.
-// This is particularly efficient for large datasets with exact match mode.
-func (fn *formulaFuncs) lookupHashSearch(vertical bool, lookupValue, lookupArray formulaArg) (int, bool) {
-	// Determine lookup value type for proper comparison
-	lookupIsNumber := lookupValue.Type == ArgNumber
-
-	// Generate cache key based on lookup array characteristics
-	var cacheKey string
-	if vertical && len(lookupArray.Matrix) > 0 {
-		// Vertical lookup: use first column characteristics
-		var b strings.Builder
-		b.Grow(64)
-		b.WriteString("xlookup:v:")
-		b.WriteString(strconv.Itoa(len(lookupArray.Matrix)))
-		b.WriteString(":")
-		if lookupIsNumber {
-			b.WriteString("n:")
-		} else {
-			b.WriteString("s:")
-		}
-		if len(lookupArray.Matrix[0]) > 0 {
-			b.WriteString(lookupArray.Matrix[0][0].Value())
-			b.WriteString(":")
-			lastIdx := len(lookupArray.Matrix) - 1
-			if len(lookupArray.Matrix[lastIdx]) > 0 {
-				b.WriteString(lookupArray.Matrix[lastIdx][0].Value())
-			}
-		}
-		cacheKey = b.String()
-	} else if !vertical && len(lookupArray.Matrix) > 0 && len(lookupArray.Matrix[0]) > 0 {
-		// Horizontal lookup: use first row characteristics
-		var b strings.Builder
-		b.Grow(64)
-		b.WriteString("xlookup:h:")
-		b.WriteString(strconv.Itoa(len(lookupArray.Matrix[0])))
-		b.WriteString(":")
-		if lookupIsNumber {
-			b.WriteString("n:")
-		} else {
-			b.WriteString("s:")
-		}
-		b.WriteString(lookupArray.Matrix[0][0].Value())
-		b.WriteString(":")
-		b.WriteString(lookupArray.Matrix[0][len(lookupArray.Matrix[0])-1].Value())
-		cacheKey = b.String()
-	} else {
-		cacheKey = "xlookup:empty"
-	}
-
-	// Try to get cached index
-	var hashIndex map[string]int
-	if cached, found := fn.f.matchIndexCache.Load(cacheKey); found {
-		hashIndex = cached.(map[string]int)
-	} else {
-		// Build hash index with type-aware keys
-		hashIndex = make(map[string]int)
-
-		if vertical {
-			for i, row := range lookupArray.Matrix {
-				if len(row) > 0 {
-					cell := row[0]
-					var key string
-					if lookupIsNumber {
-						if numCell := cell.ToNumber(); numCell.Type == ArgNumber {
-							key = "N:" + numCell.Value()
-						} else {
-							continue
-						}
-					} else {
-						key = "S:" + cell.Value()
-					}
-
-					if _, exists := hashIndex[key]; !exists {
-						hashIndex[key] = i
-					}
-				}
-			}
-		} else {
-			for i, cell := range lookupArray.Matrix[0] {
-				var key string
-				if lookupIsNumber {
-					if numCell := cell.ToNumber(); numCell.Type == ArgNumber {
-						key = "N:" + numCell.Value()
-					} else {
-						continue
-					}
-				} else {
-					key = "S:" + cell.Value()
-				}
-
-				if _, exists := hashIndex[key]; !exists {
-					hashIndex[key] = i
-				}
-			}
-		}
-
-		// Store in cache
-		fn.f.matchIndexCache.Store(cacheKey, hashIndex)
-	}
-
-	// Perform hash lookup with type-aware key
-	var lookupKey string
-	if lookupIsNumber {
-		lookupKey = "N:" + lookupValue.Value()
-	} else {
-		lookupKey = "S:" + lookupValue.Value()
-	}
-
-	if idx, found := hashIndex[lookupKey]; found {
-		return idx, true
-	}
-
-	return -1, false
-}
-
 // VLOOKUP function 'looks up' a given value in the left-hand column of a
 // data array (or table), and returns the corresponding value from another
 // column of the array. The syntax of the function is:
@@ -17157,15 +17443,10 @@ func (fn *formulaFuncs) VLOOKUP(argsList *list.List) formulaArg {
 	}
 	var matchIdx int
 	var wasExact bool
-
-	// Use hash index for exact match mode with large datasets (>100 rows)
-	// Hash lookup is O(1) vs O(n) for linear search
-	if matchMode.Number == matchModeExact && len(tableArray.Matrix) > 100 {
-		matchIdx, wasExact = fn.lookupHashSearch(true, lookupValue, tableArray)
-	} else if matchMode.Number == matchModeWildcard || len(tableArray.Matrix) == TotalRows {
-		matchIdx, wasExact = lookupLinearSearch(true, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeLinear))
+	if matchMode.Number == matchModeWildcard || len(tableArray.Matrix) == TotalRows {
+		matchIdx, wasExact = fn.lookupLinear(true, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeLinear))
 	} else {
-		matchIdx, wasExact = lookupBinarySearch(true, lookupValue, tableArray, matchMode, newNumberFormulaArg(searchModeAscBinary))
+		matchIdx, wasExact = lookupBinarySearch(true, lookupValue, tableArray, fn.lookupCells(true, tableArray), matchMode, newNumberFormulaArg(searchModeAscBinary))
 	}
 	if matchIdx == -1 {
 		return newErrorFormulaArg(formulaErrorNA, "VLOOKUP no result found")
@@ -17180,18 +17461,10 @@ func (fn *formulaFuncs) VLOOKUP(argsList *list.List) formulaArg {
 	return newErrorFormulaArg(formulaErrorNA, "VLOOKUP no result found")
 }
 
-// lookupBinarySearch finds the position of a target value when range lookup
-// is TRUE, if the data of table array can't guarantee be sorted, it will
-// return wrong result.
-func lookupBinarySearch(vertical bool, lookupValue, lookupArray, matchMode, searchMode formulaArg) (matchIdx int, wasExact bool) {
-	var tableArray []formulaArg
-	if vertical {
-		for _, row := range lookupArray.Matrix {
-			tableArray = append(tableArray, row[0])
-		}
-	} else {
-		tableArray = lookupArray.Matrix[0]
-	}
+// lookupBinarySearch finds the position of a target value in the lookup
+// vector of the lookup array when range lookup is TRUE, if the data of table
+// array can't guarantee be sorted, it will return wrong result.
+func lookupBinarySearch(vertical bool, lookupValue, lookupArray formulaArg, tableArray []formulaArg, matchMode, searchMode formulaArg) (matchIdx int, wasExact bool) {
 	low, high, lastMatchIdx := 0, len(tableArray)-1, -1
 	count := high
 	for low <= high {
@@ -17406,6 +17679,18 @@ func (fn *formulaFuncs) xlookup(lookupRows, lookupCols, returnArrayRows, returnA
 	return array
 }
 
+// lookupSearch returns the position of the lookup value in the lookup array
+// by given match mode and search mode for the formula functions XLOOKUP and
+// XMATCH.
+func (fn *formulaFuncs) lookupSearch(vertical bool, lookupValue, lookupArray, matchMode, searchMode formulaArg) (int, bool) {
+	switch searchMode.Number {
+	case searchModeLinear, searchModeReverseLinear:
+		return fn.lookupLinear(vertical, lookupValue, lookupArray, matchMode, searchMode)
+	default:
+		return lookupBinarySearch(vertical, lookupValue, lookupArray, fn.lookupCells(vertical, lookupArray), matchMode, searchMode)
+	}
+}
+
 // XLOOKUP function searches a range or an array, and then returns the item
 // corresponding to the first match it finds. If no match exists, then
 // XLOOKUP can return the closest (approximate) match. The syntax of the
@@ -17426,21 +17711,7 @@ func (fn *formulaFuncs) XLOOKUP(argsList *list.List) formulaArg {
 		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
 	}
 	verticalLookup := lookupRows >= lookupCols
-	var matchIdx int
-	switch searchMode.Number {
-	case searchModeLinear, searchModeReverseLinear:
-		// Use hash index for exact match mode with large datasets (>100 rows/cols)
-		// Hash lookup is O(1) vs O(n) for linear search
-		if matchMode.Number == matchModeExact &&
-			searchMode.Number == searchModeLinear &&
-			len(lookupArray.Matrix) > 100 {
-			matchIdx, _ = fn.lookupHashSearch(verticalLookup, lookupValue, lookupArray)
-		} else {
-			matchIdx, _ = lookupLinearSearch(verticalLookup, lookupValue, lookupArray, matchMode, searchMode)
-		}
-	default:
-		matchIdx, _ = lookupBinarySearch(verticalLookup, lookupValue, lookupArray, matchMode, searchMode)
-	}
+	matchIdx, _ := fn.lookupSearch(verticalLookup, lookupValue, lookupArray, matchMode, searchMode)
 	if matchIdx == -1 {
 		return ifNotFond
 	}
@@ -17452,6 +17723,125 @@ func (fn *formulaFuncs) XLOOKUP(argsList *list.List) formulaArg {
 	return fn.xlookup(lookupRows, lookupCols, returnArrayRows, returnArrayCols, matchIdx, condition1, condition2, condition3, condition4, returnArray)
 }
 
+// xmatchCompare compares the cell of the lookup array with the lookup value,
+// the numeric cell will be compared as a number when the lookup value is a
+// number.
+func xmatchCompare(cell, lookupValue formulaArg) byte {
+	if num := cell.ToNumber(); lookupValue.Type == ArgNumber && num.Type == ArgNumber {
+		cell = num
+	}
+	return compareFormulaArg(cell, lookupValue, newNumberFormulaArg(matchModeExact), false)
+}
+
+// xmatchApproximate returns the position of the exact match or the next
+// smaller or larger item by given match mode in the unsorted lookup array for
+// the formula function XMATCH. The ties will be resolved by the search mode.
+func xmatchApproximate(lookupValue formulaArg, cells []formulaArg, matchMode, searchMode formulaArg) int {
+	matchIdx := -1
+	for i := range cells {
+		idx := i
+		if searchMode.Number == searchModeReverseLinear {
+			idx = len(cells) - 1 - i
+		}
+		cell := cells[idx]
+		if cell.Type == ArgEmpty || (lookupValue.Type == ArgNumber) != (cell.ToNumber().Type == ArgNumber) {
+			continue
+		}
+		result := xmatchCompare(cell, lookupValue)
+		if result == criteriaEq {
+			return idx
+		}
+		if matchMode.Number == matchModeMaxLess && result != criteriaL ||
+			matchMode.Number == matchModeMinGreater && result != criteriaG {
+			continue
+		}
+		if matchIdx == -1 {
+			matchIdx = idx
+			continue
+		}
+		if better := xmatchCompare(cell, cells[matchIdx]); matchMode.Number == matchModeMaxLess && better == criteriaG ||
+			matchMode.Number == matchModeMinGreater && better == criteriaL {
+			matchIdx = idx
+		}
+	}
+	return matchIdx
+}
+
+// XMATCH function searches for a specified item in an array or range of
+// cells, and then returns the item's relative position. The syntax of the
+// function is:
+//
+//	XMATCH(lookup_value,lookup_array,[match_mode],[search_mode])
+func (fn *formulaFuncs) XMATCH(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "XMATCH requires at least 2 arguments")
+	}
+	if argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, "XMATCH allows at most 4 arguments")
+	}
+	lookupValue := argsList.Front().Value.(formulaArg)
+	lookupArray := argsList.Front().Next().Value.(formulaArg)
+	matchMode, searchMode := newNumberFormulaArg(matchModeExact), newNumberFormulaArg(searchModeLinear)
+	if argsList.Len() > 2 {
+		if matchMode = argsList.Front().Next().Next().Value.(formulaArg).ToNumber(); matchMode.Type != ArgNumber {
+			return matchMode
+		}
+	}
+	if argsList.Len() > 3 {
+		if searchMode = argsList.Back().Value.(formulaArg).ToNumber(); searchMode.Type != ArgNumber {
+			return searchMode
+		}
+	}
+	if lookupArray.Type != ArgMatrix {
+		return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	}
+	if !validateMatchMode(matchMode.Number) || !validateSearchMode(searchMode.Number) {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	lookupRows, lookupCols := len(lookupArray.Matrix), 0
+	if lookupRows > 0 {
+		lookupCols = len(lookupArray.Matrix[0])
+	}
+	if lookupRows != 1 && lookupCols != 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	vertical := lookupRows >= lookupCols
+	cells := lookupArray.ToList()
+	xmatch := func(lookupValue formulaArg) formulaArg {
+		var matchIdx int
+		switch {
+		case matchMode.Number == matchModeMinGreater || matchMode.Number == matchModeMaxLess:
+			if searchMode.Number == searchModeLinear || searchMode.Number == searchModeReverseLinear {
+				matchIdx = xmatchApproximate(lookupValue, cells, matchMode, searchMode)
+				break
+			}
+			fallthrough
+		default:
+			matchIdx, _ = fn.lookupSearch(vertical, lookupValue, lookupArray, matchMode, searchMode)
+		}
+		if matchIdx == -1 {
+			return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+		}
+		if matchMode.Number == matchModeExact && (searchMode.Number == searchModeAscBinary || searchMode.Number == searchModeDescBinary) {
+			if xmatchCompare(cells[matchIdx], lookupValue) != criteriaEq {
+				return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+			}
+		}
+		return newNumberFormulaArg(float64(matchIdx + 1))
+	}
+	if lookupValue.Type != ArgMatrix {
+		return xmatch(lookupValue)
+	}
+	result := make([][]formulaArg, len(lookupValue.Matrix))
+	for r, row := range lookupValue.Matrix {
+		result[r] = make([]formulaArg, len(row))
+		for c, cell := range row {
+			result[r][c] = xmatch(cell)
+		}
+	}
+	return newMatrixFormulaArg(result)
+}
+
 // INDEX function returns a reference to a cell that lies in a specified row
 // and column of a range of cells. The syntax of the function is:
 //
@@ -17986,6 +18376,9 @@ func (fn *formulaFuncs) INDIRECT(argsList *list.List) formulaArg {
 		if err != nil {
 			return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
 		}
+		if col, row, err := CellNameToCoordinates(fromRef); err == nil {
+			fn.ctx.readArea(formulaArea{sheet: fn.sheet, x1: col, y1: row, x2: col, y2: row})
+		}
 		return newStringFormulaArg(value)
 	}
 	arg, _ := fn.f.parseReference(fn.ctx, fn.sheet, fromRef+":"+toRef)
@@ -18079,6 +18472,20 @@ func (fn *formulaFuncs) ROWS(argsList *list.List) formulaArg {
 	return newNumberFormulaArg(float64(result))
 }
 
+// SINGLE function returns a single value using the implicit intersection
+// with the formula cell, it's the form in which the spreadsheet application
+// stores the implicit intersection operator (@). The syntax of the function
+// is:
+//
+//	SINGLE(value)
+func (fn *formulaFuncs) SINGLE(argsList *list.List) formulaArg {
+	if argsList.Len() != 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, "SINGLE requires 1 argument")
+	}
+	col, row, _ := CellNameToCoordinates(fn.cell)
+	return implicitIntersection(argsList.Front().Value.(formulaArg), col, row)
+}
+
 // Web Functions
 
 // ENCODEURL function returns a URL-encoded string, replacing certain
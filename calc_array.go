@@ -0,0 +1,603 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// padFormulaArgsMatrix returns a rectangular matrix with the given size by
+// the given matrix, the missing elements will be filled with the pad value.
+func padFormulaArgsMatrix(mtx [][]formulaArg, rows, cols int, pad formulaArg) [][]formulaArg {
+	result := make([][]formulaArg, rows)
+	for r := range result {
+		result[r] = make([]formulaArg, cols)
+		for c := range result[r] {
+			if r < len(mtx) && c < len(mtx[r]) {
+				result[r][c] = mtx[r][c]
+				continue
+			}
+			result[r][c] = pad
+		}
+	}
+	return result
+}
+
+// arrayArgMatrix returns a rectangular matrix of the formula argument, the
+// ragged rows will be padded with the #N/A error.
+func arrayArgMatrix(arg formulaArg) [][]formulaArg {
+	mtx := formulaArgToMatrix(arg)
+	var cols int
+	for _, row := range mtx {
+		cols = max(cols, len(row))
+	}
+	return padFormulaArgsMatrix(mtx, len(mtx), cols, newErrorFormulaArg(formulaErrorNA, formulaErrorNA))
+}
+
+// arrayIntArg returns the integer value of the formula argument, the number
+// will be truncated.
+func arrayIntArg(arg formulaArg) (int, formulaArg) {
+	if arg.Type == ArgError {
+		return 0, arg
+	}
+	num := arg.ToNumber()
+	if num.Type != ArgNumber {
+		return 0, num
+	}
+	return int(math.Trunc(num.Number)), num
+}
+
+// VSTACK function appends arrays vertically and in sequence to return a
+// larger array. The syntax of the function is:
+//
+//	VSTACK(array1,[array2],...)
+func (fn *formulaFuncs) VSTACK(argsList *list.List) formulaArg {
+	return fn.stack("VSTACK", argsList, false)
+}
+
+// HSTACK function appends arrays horizontally and in sequence to return a
+// larger array. The syntax of the function is:
+//
+//	HSTACK(array1,[array2],...)
+func (fn *formulaFuncs) HSTACK(argsList *list.List) formulaArg {
+	return fn.stack("HSTACK", argsList, true)
+}
+
+// stack is an implementation of the formula functions HSTACK and VSTACK, the
+// smaller arrays will be padded with the #N/A error.
+func (fn *formulaFuncs) stack(name string, argsList *list.List, horizontal bool) formulaArg {
+	if argsList.Len() < 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires at least 1 argument", name))
+	}
+	var (
+		result [][]formulaArg
+		size   int
+	)
+	for arg := argsList.Front(); arg != nil; arg = arg.Next() {
+		mtx := arrayArgMatrix(arg.Value.(formulaArg))
+		if horizontal {
+			mtx = transposeFormulaArgsMatrix(mtx)
+		}
+		for _, row := range mtx {
+			size = max(size, len(row))
+		}
+		result = append(result, mtx...)
+	}
+	result = padFormulaArgsMatrix(result, len(result), size, newErrorFormulaArg(formulaErrorNA, formulaErrorNA))
+	if horizontal {
+		result = transposeFormulaArgsMatrix(result)
+	}
+	return newMatrixFormulaArg(result)
+}
+
+// TAKE function returns a specified number of contiguous rows or columns
+// from the start or end of an array. The syntax of the function is:
+//
+//	TAKE(array,rows,[columns])
+func (fn *formulaFuncs) TAKE(argsList *list.List) formulaArg {
+	return fn.takeDrop("TAKE", argsList, true)
+}
+
+// DROP function excludes a specified number of rows or columns from the start
+// or end of an array. The syntax of the function is:
+//
+//	DROP(array,rows,[columns])
+func (fn *formulaFuncs) DROP(argsList *list.List) formulaArg {
+	return fn.takeDrop("DROP", argsList, false)
+}
+
+// takeDrop is an implementation of the formula functions TAKE and DROP.
+func (fn *formulaFuncs) takeDrop(name string, argsList *list.List, take bool) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires 2 or 3 arguments", name))
+	}
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	mtx := arrayArgMatrix(array)
+	rows, cols := len(mtx), 0
+	if rows > 0 {
+		cols = len(mtx[0])
+	}
+	span := func(arg formulaArg, size int) (int, int, formulaArg) {
+		if arg.Type == ArgEmpty {
+			return 0, size, arg
+		}
+		n, err := arrayIntArg(arg)
+		if err.Type != ArgNumber {
+			return 0, 0, err
+		}
+		if take {
+			if n == 0 {
+				return 0, 0, newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+			}
+			if n > 0 {
+				return 0, min(n, size), err
+			}
+			return max(size+n, 0), size, err
+		}
+		if n >= 0 {
+			return min(n, size), size, err
+		}
+		return 0, max(size+n, 0), err
+	}
+	rowStart, rowEnd, err := span(argsList.Front().Next().Value.(formulaArg), rows)
+	if err.Type == ArgError {
+		return err
+	}
+	colStart, colEnd := 0, cols
+	if argsList.Len() == 3 {
+		if colStart, colEnd, err = span(argsList.Back().Value.(formulaArg), cols); err.Type == ArgError {
+			return err
+		}
+	}
+	if rowStart >= rowEnd || colStart >= colEnd {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	result := make([][]formulaArg, 0, rowEnd-rowStart)
+	for _, row := range mtx[rowStart:rowEnd] {
+		result = append(result, row[colStart:colEnd])
+	}
+	return newMatrixFormulaArg(result)
+}
+
+// CHOOSECOLS function returns the specified columns from an array. The
+// syntax of the function is:
+//
+//	CHOOSECOLS(array,col_num1,[col_num2],...)
+func (fn *formulaFuncs) CHOOSECOLS(argsList *list.List) formulaArg {
+	return fn.chooseRowsCols("CHOOSECOLS", argsList, true)
+}
+
+// CHOOSEROWS function returns the specified rows from an array. The syntax
+// of the function is:
+//
+//	CHOOSEROWS(array,row_num1,[row_num2],...)
+func (fn *formulaFuncs) CHOOSEROWS(argsList *list.List) formulaArg {
+	return fn.chooseRowsCols("CHOOSEROWS", argsList, false)
+}
+
+// chooseRowsCols is an implementation of the formula functions CHOOSECOLS and
+// CHOOSEROWS, the negative index counts from the end of the array.
+func (fn *formulaFuncs) chooseRowsCols(name string, argsList *list.List, byCol bool) formulaArg {
+	if argsList.Len() < 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires at least 2 arguments", name))
+	}
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	mtx := arrayArgMatrix(array)
+	if byCol {
+		mtx = transposeFormulaArgsMatrix(mtx)
+	}
+	var result [][]formulaArg
+	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
+		for _, idx := range arg.Value.(formulaArg).ToList() {
+			n, err := arrayIntArg(idx)
+			if err.Type != ArgNumber {
+				return err
+			}
+			if n < 0 {
+				n += len(mtx) + 1
+			}
+			if n < 1 || n > len(mtx) {
+				return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+			}
+			result = append(result, mtx[n-1])
+		}
+	}
+	if byCol {
+		result = transposeFormulaArgsMatrix(result)
+	}
+	return newMatrixFormulaArg(result)
+}
+
+// TOCOL function returns the array in a single column. The syntax of the
+// function is:
+//
+//	TOCOL(array,[ignore],[scan_by_column])
+func (fn *formulaFuncs) TOCOL(argsList *list.List) formulaArg {
+	return fn.toRowCol("TOCOL", argsList, true)
+}
+
+// TOROW function returns the array in a single row. The syntax of the
+// function is:
+//
+//	TOROW(array,[ignore],[scan_by_column])
+func (fn *formulaFuncs) TOROW(argsList *list.List) formulaArg {
+	return fn.toRowCol("TOROW", argsList, false)
+}
+
+// toRowCol is an implementation of the formula functions TOCOL and TOROW. The
+// ignore argument specifies whether to ignore certain types of values: 0 keep
+// all values, 1 ignore blanks, 2 ignore errors and 3 ignore blanks and errors.
+func (fn *formulaFuncs) toRowCol(name string, argsList *list.List, toCol bool) formulaArg {
+	if argsList.Len() < 1 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires 1 to 3 arguments", name))
+	}
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	ignore, scanByCol := 0, newBoolFormulaArg(false)
+	if argsList.Len() > 1 {
+		var err formulaArg
+		if ignore, err = arrayIntArg(argsList.Front().Next().Value.(formulaArg)); err.Type != ArgNumber {
+			return err
+		}
+		if ignore < 0 || ignore > 3 {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+	}
+	if argsList.Len() == 3 {
+		if scanByCol = argsList.Back().Value.(formulaArg).ToBool(); scanByCol.Type != ArgNumber {
+			return scanByCol
+		}
+	}
+	mtx := arrayArgMatrix(array)
+	if scanByCol.Number == 1 {
+		mtx = transposeFormulaArgsMatrix(mtx)
+	}
+	var values []formulaArg
+	for _, row := range mtx {
+		for _, cell := range row {
+			if ignore&1 == 1 && cell.Type == ArgEmpty || ignore&2 == 2 && cell.Type == ArgError {
+				continue
+			}
+			values = append(values, cell)
+		}
+	}
+	if len(values) == 0 {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	if toCol {
+		return newMatrixFormulaArg(transposeFormulaArgsMatrix([][]formulaArg{values}))
+	}
+	return newMatrixFormulaArg([][]formulaArg{values})
+}
+
+// WRAPROWS function wraps the provided row or column of values by rows after
+// a specified number of elements to form a new array. The syntax of the
+// function is:
+//
+//	WRAPROWS(vector,wrap_count,[pad_with])
+func (fn *formulaFuncs) WRAPROWS(argsList *list.List) formulaArg {
+	return fn.wrapRowsCols("WRAPROWS", argsList, false)
+}
+
+// WRAPCOLS function wraps the provided row or column of values by columns
+// after a specified number of elements to form a new array. The syntax of the
+// function is:
+//
+//	WRAPCOLS(vector,wrap_count,[pad_with])
+func (fn *formulaFuncs) WRAPCOLS(argsList *list.List) formulaArg {
+	return fn.wrapRowsCols("WRAPCOLS", argsList, true)
+}
+
+// wrapRowsCols is an implementation of the formula functions WRAPROWS and
+// WRAPCOLS.
+func (fn *formulaFuncs) wrapRowsCols(name string, argsList *list.List, byCol bool) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires 2 or 3 arguments", name))
+	}
+	vector := argsList.Front().Value.(formulaArg)
+	if vector.Type == ArgError {
+		return vector
+	}
+	mtx := arrayArgMatrix(vector)
+	if len(mtx) > 1 && len(mtx[0]) > 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	count, err := arrayIntArg(argsList.Front().Next().Value.(formulaArg))
+	if err.Type != ArgNumber {
+		return err
+	}
+	if count < 1 {
+		return newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	pad := newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	if argsList.Len() == 3 {
+		pad = argsList.Back().Value.(formulaArg)
+	}
+	var values, result [][]formulaArg
+	for _, row := range mtx {
+		for _, cell := range row {
+			if len(values) == 0 || len(values[len(values)-1]) == count {
+				values = append(values, make([]formulaArg, 0, count))
+			}
+			values[len(values)-1] = append(values[len(values)-1], cell)
+		}
+	}
+	result = padFormulaArgsMatrix(values, len(values), count, pad)
+	if byCol {
+		result = transposeFormulaArgsMatrix(result)
+	}
+	return newMatrixFormulaArg(result)
+}
+
+// EXPAND function expands or pads an array to specified row and column
+// dimensions. The syntax of the function is:
+//
+//	EXPAND(array,rows,[columns],[pad_with])
+func (fn *formulaFuncs) EXPAND(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, "EXPAND requires 2 to 4 arguments")
+	}
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	mtx := arrayArgMatrix(array)
+	rows, cols := len(mtx), 0
+	if rows > 0 {
+		cols = len(mtx[0])
+	}
+	size := func(arg formulaArg, current int) (int, formulaArg) {
+		if arg.Type == ArgEmpty {
+			return current, newNumberFormulaArg(float64(current))
+		}
+		n, err := arrayIntArg(arg)
+		if err.Type != ArgNumber {
+			return n, err
+		}
+		if n < current {
+			return n, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		return n, err
+	}
+	newRows, err := size(argsList.Front().Next().Value.(formulaArg), rows)
+	if err.Type != ArgNumber {
+		return err
+	}
+	newCols := cols
+	if argsList.Len() > 2 {
+		if newCols, err = size(argsList.Front().Next().Next().Value.(formulaArg), cols); err.Type != ArgNumber {
+			return err
+		}
+	}
+	if newRows > TotalRows || newCols > MaxColumns {
+		return newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	pad := newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	if argsList.Len() == 4 {
+		pad = argsList.Back().Value.(formulaArg)
+	}
+	return newMatrixFormulaArg(padFormulaArgsMatrix(mtx, newRows, newCols, pad))
+}
+
+// arraySizeArgs returns the rows and columns of the array by given optional
+// formula arguments, the omitted arguments default to 1.
+func arraySizeArgs(argsList *list.List) (int, int, formulaArg) {
+	size := []int{1, 1}
+	for i, arg := 0, argsList.Front(); i < len(size) && arg != nil; i, arg = i+1, arg.Next() {
+		if arg.Value.(formulaArg).Type == ArgEmpty {
+			continue
+		}
+		n, err := arrayIntArg(arg.Value.(formulaArg))
+		if err.Type != ArgNumber {
+			return 0, 0, err
+		}
+		if n < 0 {
+			return 0, 0, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		if n == 0 {
+			return 0, 0, newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+		}
+		size[i] = n
+	}
+	if size[0] > TotalRows || size[1] > MaxColumns {
+		return 0, 0, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	return size[0], size[1], newNumberFormulaArg(float64(size[0] * size[1]))
+}
+
+// SEQUENCE function generates a list of sequential numbers in an array. The
+// syntax of the function is:
+//
+//	SEQUENCE(rows,[columns],[start],[step])
+func (fn *formulaFuncs) SEQUENCE(argsList *list.List) formulaArg {
+	if argsList.Len() < 1 || argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, "SEQUENCE requires 1 to 4 arguments")
+	}
+	rows, cols, err := arraySizeArgs(argsList)
+	if err.Type != ArgNumber {
+		return err
+	}
+	args, arg := []float64{1, 1}, argsList.Front()
+	for i := 0; i < 2 && arg != nil; i++ {
+		arg = arg.Next()
+	}
+	for i := 0; arg != nil; i, arg = i+1, arg.Next() {
+		if arg.Value.(formulaArg).Type == ArgEmpty {
+			continue
+		}
+		num := arg.Value.(formulaArg).ToNumber()
+		if num.Type != ArgNumber {
+			return num
+		}
+		args[i] = num.Number
+	}
+	mtx := make([][]formulaArg, rows)
+	for r := range mtx {
+		mtx[r] = make([]formulaArg, cols)
+		for c := range mtx[r] {
+			mtx[r][c] = newNumberFormulaArg(args[0] + float64(r*cols+c)*args[1])
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// RANDARRAY function returns an array of random numbers. The user can
+// specify the number of rows and columns to fill, minimum and maximum values,
+// and whether to return whole numbers or decimal values. The syntax of the
+// function is:
+//
+//	RANDARRAY([rows],[columns],[min],[max],[integer])
+func (fn *formulaFuncs) RANDARRAY(argsList *list.List) formulaArg {
+	if argsList.Len() > 5 {
+		return newErrorFormulaArg(formulaErrorVALUE, "RANDARRAY allows at most 5 arguments")
+	}
+	rows, cols, err := arraySizeArgs(argsList)
+	if err.Type != ArgNumber {
+		return err
+	}
+	var (
+		bounds  = []float64{0, 1}
+		integer = newBoolFormulaArg(false)
+		arg     = argsList.Front()
+	)
+	for i := 0; i < 2 && arg != nil; i++ {
+		arg = arg.Next()
+	}
+	for i := 0; i < len(bounds) && arg != nil; i, arg = i+1, arg.Next() {
+		if arg.Value.(formulaArg).Type == ArgEmpty {
+			continue
+		}
+		num := arg.Value.(formulaArg).ToNumber()
+		if num.Type != ArgNumber {
+			return num
+		}
+		bounds[i] = num.Number
+	}
+	if arg != nil {
+		if integer = arg.Value.(formulaArg).ToBool(); integer.Type != ArgNumber {
+			return integer
+		}
+	}
+	if bounds[0] > bounds[1] {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if integer.Number == 1 && (bounds[0] != math.Trunc(bounds[0]) || bounds[1] != math.Trunc(bounds[1])) {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	mtx := make([][]formulaArg, rows)
+	for r := range mtx {
+		mtx[r] = make([]formulaArg, cols)
+		for c := range mtx[r] {
+			if integer.Number == 1 {
+				mtx[r][c] = newNumberFormulaArg(bounds[0] + float64(rnd.Int63n(int64(bounds[1]-bounds[0])+1)))
+				continue
+			}
+			mtx[r][c] = newNumberFormulaArg(bounds[0] + rnd.Float64()*(bounds[1]-bounds[0]))
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// SORTBY function sorts the contents of a range or array based on the values
+// in a corresponding range or array. The syntax of the function is:
+//
+//	SORTBY(array,by_array1,[sort_order1],[by_array2,sort_order2],...)
+func (fn *formulaFuncs) SORTBY(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "SORTBY requires at least 2 arguments")
+	}
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	mtx := arrayArgMatrix(array)
+	type sortKey struct {
+		values []formulaArg
+		order  int
+	}
+	var (
+		keys  []sortKey
+		byCol bool
+	)
+	for arg := argsList.Front().Next(); arg != nil; arg = arg.Next() {
+		byArray := arg.Value.(formulaArg)
+		if byArray.Type == ArgError {
+			return byArray
+		}
+		byMtx := arrayArgMatrix(byArray)
+		if len(byMtx) == 0 || len(byMtx) > 1 && len(byMtx[0]) > 1 {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		keyByCol := len(byMtx) == 1 && len(byMtx[0]) > 1 || len(mtx) == 1 && len(mtx[0]) > 1
+		if len(keys) > 0 && keyByCol != byCol {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		byCol = keyByCol
+		key := sortKey{values: byArray.ToList(), order: 1}
+		size := len(mtx)
+		if byCol {
+			size = len(mtx[0])
+		}
+		if size != len(key.values) {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		if arg.Next() != nil {
+			arg = arg.Next()
+			order, err := arrayIntArg(arg.Value.(formulaArg))
+			if err.Type != ArgNumber {
+				return err
+			}
+			if order != 1 && order != -1 {
+				return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+			}
+			key.order = order
+		}
+		keys = append(keys, key)
+	}
+	if byCol {
+		mtx = transposeFormulaArgsMatrix(mtx)
+	}
+	indexes := make([]int, len(mtx))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		for _, key := range keys {
+			if cmp := fn.compareFormulaArgs(key.values[indexes[i]], key.values[indexes[j]]); cmp != 0 {
+				return cmp*key.order < 0
+			}
+		}
+		return false
+	})
+	result := make([][]formulaArg, len(mtx))
+	for i, idx := range indexes {
+		result[i] = mtx[idx]
+	}
+	if byCol {
+		result = transposeFormulaArgsMatrix(result)
+	}
+	return newMatrixFormulaArg(result)
+}
@@ -0,0 +1,280 @@
+package excelize
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcArrayShapingFunctions(t *testing.T) {
+	cellData := [][]interface{}{
+		{1, 2, 3},
+		{4, 5, 6},
+		{7, 8, 9},
+		{"a", nil, "b"},
+	}
+	formulaList := map[string]string{
+		// VSTACK
+		"TEXTJOIN(\",\",FALSE,VSTACK(A1:C1,A2:C2))": "1,2,3,4,5,6",
+		"ROWS(VSTACK(A1:C2,A3:C3))":                 "3",
+		"COLUMNS(VSTACK(A1:C1,A2:B2))":              "3",
+		"_xlfn.VSTACK(A1)":                          "1",
+		// HSTACK
+		"TEXTJOIN(\",\",FALSE,HSTACK(A1:A2,C1:C2))": "1,3,4,6",
+		"COLUMNS(HSTACK(A1:B1,C1))":                 "3",
+		"ROWS(HSTACK(A1:A2,B1))":                    "2",
+		// TAKE
+		"TEXTJOIN(\",\",FALSE,TAKE(A1:C3,2))":    "1,2,3,4,5,6",
+		"TEXTJOIN(\",\",FALSE,TAKE(A1:C3,-1))":   "7,8,9",
+		"TEXTJOIN(\",\",FALSE,TAKE(A1:C3,2,-2))": "2,3,5,6",
+		"TEXTJOIN(\",\",FALSE,TAKE(A1:C3,10,1))": "1,4,7",
+		// DROP
+		"TEXTJOIN(\",\",FALSE,DROP(A1:C3,2))":    "7,8,9",
+		"TEXTJOIN(\",\",FALSE,DROP(A1:C3,-2))":   "1,2,3",
+		"TEXTJOIN(\",\",FALSE,DROP(A1:C3,1,1))":  "5,6,8,9",
+		"TEXTJOIN(\",\",FALSE,DROP(A1:C3,0,-2))": "1,4,7",
+		// CHOOSECOLS
+		"TEXTJOIN(\",\",FALSE,CHOOSECOLS(A1:C3,1,3))":   "1,3,4,6,7,9",
+		"TEXTJOIN(\",\",FALSE,CHOOSECOLS(A1:C3,-1))":    "3,6,9",
+		"TEXTJOIN(\",\",FALSE,CHOOSECOLS(A1:C2,A1:B1))": "1,2,4,5",
+		// CHOOSEROWS
+		"TEXTJOIN(\",\",FALSE,CHOOSEROWS(A1:C3,3,1))": "7,8,9,1,2,3",
+		"TEXTJOIN(\",\",FALSE,CHOOSEROWS(A1:C3,-2))":  "4,5,6",
+		// TOCOL
+		"TEXTJOIN(\",\",FALSE,TOCOL(A1:C2))":        "1,2,3,4,5,6",
+		"ROWS(TOCOL(A1:C2))":                        "6",
+		"TEXTJOIN(\",\",FALSE,TOCOL(A1:C2,0,TRUE))": "1,4,2,5,3,6",
+		"TEXTJOIN(\",\",FALSE,TOCOL(A3:C4,1))":      "7,8,9,a,b",
+		"ROWS(TOCOL(A3:C4))":                        "6",
+		"ROWS(TOCOL(A3:C4,3))":                      "5",
+		// TOROW
+		"TEXTJOIN(\",\",FALSE,TOROW(A1:B3))":        "1,2,4,5,7,8",
+		"COLUMNS(TOROW(A1:B3))":                     "6",
+		"TEXTJOIN(\",\",FALSE,TOROW(A1:B3,0,TRUE))": "1,4,7,2,5,8",
+		// WRAPROWS
+		"COLUMNS(WRAPROWS(A1:C1,2))":                "2",
+		"TEXTJOIN(\",\",FALSE,WRAPROWS(A1:A3,2,0))": "1,4,7,0",
+		"ROWS(WRAPROWS(A1:A3,2))":                   "2",
+		// WRAPCOLS
+		"TEXTJOIN(\",\",FALSE,WRAPCOLS(A1:C1,2,\"-\"))": "1,3,2,-",
+		"COLUMNS(WRAPCOLS(A1:C1,2))":                    "2",
+		// EXPAND
+		"ROWS(EXPAND(A1:B1,2))":                     "2",
+		"TEXTJOIN(\",\",FALSE,EXPAND(A1:B1,2,3,0))": "1,2,0,0,0,0",
+		"COLUMNS(EXPAND(A1,1,4))":                   "4",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, err := f.CalcCellValue("Sheet1", "E1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	formulaErrorList := map[string][]string{
+		"VSTACK()":               {"#VALUE!", "VSTACK requires at least 1 argument"},
+		"HSTACK()":               {"#VALUE!", "HSTACK requires at least 1 argument"},
+		"TAKE(A1:C3)":            {"#VALUE!", "TAKE requires 2 or 3 arguments"},
+		"TAKE(1/0,1)":            {"#DIV/0!", "#DIV/0!"},
+		"TAKE(A1:C3,0)":          {"#CALC!", "#CALC!"},
+		"TAKE(A1:C3,\"\")":       {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"TAKE(A1:C3,1,\"\")":     {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"DROP(A1:C3)":            {"#VALUE!", "DROP requires 2 or 3 arguments"},
+		"DROP(A1:C3,3)":          {"#CALC!", "#CALC!"},
+		"DROP(A1:C3,0,-5)":       {"#CALC!", "#CALC!"},
+		"CHOOSECOLS(A1:C3)":      {"#VALUE!", "CHOOSECOLS requires at least 2 arguments"},
+		"CHOOSECOLS(1/0,1)":      {"#DIV/0!", "#DIV/0!"},
+		"CHOOSECOLS(A1:C3,4)":    {"#VALUE!", "#VALUE!"},
+		"CHOOSECOLS(A1:C3,0)":    {"#VALUE!", "#VALUE!"},
+		"CHOOSECOLS(A1:C3,\"\")": {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"CHOOSEROWS(A1:C3,-4)":   {"#VALUE!", "#VALUE!"},
+		"TOCOL()":                {"#VALUE!", "TOCOL requires 1 to 3 arguments"},
+		"TOCOL(1/0)":             {"#DIV/0!", "#DIV/0!"},
+		"TOCOL(A1:C3,4)":         {"#VALUE!", "#VALUE!"},
+		"TOCOL(A1:C3,\"\")":      {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"TOCOL(A1:C3,0,\"x\")":   {"#VALUE!", "strconv.ParseBool: parsing \"x\": invalid syntax"},
+		"TOROW(B4,1)":            {"#CALC!", "#CALC!"},
+		"WRAPROWS(A1:C1)":        {"#VALUE!", "WRAPROWS requires 2 or 3 arguments"},
+		"WRAPROWS(1/0,1)":        {"#DIV/0!", "#DIV/0!"},
+		"WRAPROWS(A1:C2,2)":      {"#VALUE!", "#VALUE!"},
+		"WRAPROWS(A1:C1,0)":      {"#NUM!", "#NUM!"},
+		"WRAPROWS(A1:C1,\"\")":   {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"WRAPCOLS(A1:C1,1,2,3)":  {"#VALUE!", "WRAPCOLS requires 2 or 3 arguments"},
+		"EXPAND(A1:C1)":          {"#VALUE!", "EXPAND requires 2 to 4 arguments"},
+		"EXPAND(1/0,1)":          {"#DIV/0!", "#DIV/0!"},
+		"EXPAND(A1:C3,2)":        {"#VALUE!", "#VALUE!"},
+		"EXPAND(A1:C3,3,2)":      {"#VALUE!", "#VALUE!"},
+		"EXPAND(A1:C3,\"\")":     {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"EXPAND(A1:C3,3,\"\")":   {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"EXPAND(A1,1048577)":     {"#NUM!", "#NUM!"},
+	}
+	for formula, expected := range formulaErrorList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, err := f.CalcCellValue("Sheet1", "E1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+}
+
+func TestCalcArrayShapingSpill(t *testing.T) {
+	for formula, expected := range map[string][][]string{
+		"VSTACK(A1:B1,A2)":     {{"1", "2"}, {"3", "#N/A"}},
+		"HSTACK(A1,A1:A2)":     {{"1", "1"}, {"#N/A", "3"}},
+		"WRAPROWS(A1:A2,3)":    {{"1", "3", "#N/A"}},
+		"WRAPCOLS(A1:B1,3,0)":  {{"1"}, {"2"}, {"0"}},
+		"EXPAND(A1,2,2,\"-\")": {{"1", "-"}, {"-", "-"}},
+	} {
+		f := prepareCalcData([][]interface{}{{1, 2}, {3}})
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", formula))
+		result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "D1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected[0][0], result, formula)
+		for r, row := range expected {
+			for c, value := range row {
+				if r == 0 && c == 0 {
+					continue
+				}
+				cell, err := CoordinatesToCellName(c+4, r+1)
+				assert.NoError(t, err)
+				result, err := f.GetCellValue("Sheet1", cell)
+				assert.NoError(t, err)
+				assert.Equal(t, value, result, formula+" "+cell)
+			}
+		}
+	}
+}
+
+func TestCalcSequenceRandArraySortBy(t *testing.T) {
+	cellData := [][]interface{}{
+		{"b", 2, "x"},
+		{"a", 3, "y"},
+		{"c", 1, "x"},
+		{"d", 2, "y"},
+	}
+	formulaList := map[string]string{
+		// SEQUENCE
+		"TEXTJOIN(\",\",FALSE,SEQUENCE(3))":         "1,2,3",
+		"TEXTJOIN(\",\",FALSE,SEQUENCE(2,3))":       "1,2,3,4,5,6",
+		"TEXTJOIN(\",\",FALSE,SEQUENCE(2,2,10,-5))": "10,5,0,-5",
+		"COLUMNS(SEQUENCE(1,4))":                    "4",
+		"SUM(SEQUENCE(4,1,0.5,0.5))":                "5",
+		"_xlfn.SEQUENCE(1)":                         "1",
+		// RANDARRAY
+		"ROWS(RANDARRAY(3,2))":                     "3",
+		"COLUMNS(RANDARRAY(3,2))":                  "2",
+		"COUNTIF(RANDARRAY(5,5,1,3,TRUE),\">=1\")": "25",
+		"SUM(RANDARRAY(4,4,5,5,TRUE))":             "80",
+		"RANDARRAY(1,1,2,2)":                       "2",
+		"ROWS(RANDARRAY())":                        "1",
+		// SORTBY
+		"TEXTJOIN(\",\",FALSE,SORTBY(A1:A4,B1:B4))":                  "c,b,d,a",
+		"TEXTJOIN(\",\",FALSE,SORTBY(A1:A4,B1:B4,-1))":               "a,b,d,c",
+		"TEXTJOIN(\",\",FALSE,SORTBY(A1:A4,C1:C4,1,B1:B4,-1))":       "b,c,a,d",
+		"TEXTJOIN(\",\",FALSE,SORTBY(A1:B4,A1:A4))":                  "a,3,b,2,c,1,d,2",
+		"TEXTJOIN(\",\",FALSE,SORTBY(A1:C1,A2:C2,-1))":               "x,b,2",
+		"TEXTJOIN(\",\",FALSE,_xlfn.SORTBY(A1:A4,B1:B4,1,A1:A4,-1))": "c,d,b,a",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, err := f.CalcCellValue("Sheet1", "E1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	formulaErrorList := map[string][]string{
+		"SEQUENCE()":                    {"#VALUE!", "SEQUENCE requires 1 to 4 arguments"},
+		"SEQUENCE(0)":                   {"#CALC!", "#CALC!"},
+		"SEQUENCE(-1)":                  {"#VALUE!", "#VALUE!"},
+		"SEQUENCE(1048577)":             {"#VALUE!", "#VALUE!"},
+		"SEQUENCE(\"\")":                {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"SEQUENCE(1,1,\"\")":            {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"RANDARRAY(1,1,1,1,1,1)":        {"#VALUE!", "RANDARRAY allows at most 5 arguments"},
+		"RANDARRAY(\"\")":               {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"RANDARRAY(1,1,\"\")":           {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"RANDARRAY(1,1,0,1,\"x\")":      {"#VALUE!", "strconv.ParseBool: parsing \"x\": invalid syntax"},
+		"RANDARRAY(1,1,2,1)":            {"#VALUE!", "#VALUE!"},
+		"RANDARRAY(1,1,0.5,1,TRUE)":     {"#VALUE!", "#VALUE!"},
+		"SORTBY(A1:A4)":                 {"#VALUE!", "SORTBY requires at least 2 arguments"},
+		"SORTBY(1/0,A1:A4)":             {"#DIV/0!", "#DIV/0!"},
+		"SORTBY(A1:A4,1/0)":             {"#DIV/0!", "#DIV/0!"},
+		"SORTBY(A1:A4,B1:C4)":           {"#VALUE!", "#VALUE!"},
+		"SORTBY(A1:A4,B1:B3)":           {"#VALUE!", "#VALUE!"},
+		"SORTBY(A1:A4,B1:B4,2)":         {"#VALUE!", "#VALUE!"},
+		"SORTBY(A1:A4,B1:B4,\"\")":      {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"SORTBY(A1:C4,A1:A4,1,A1:C1,1)": {"#VALUE!", "#VALUE!"},
+	}
+	for formula, expected := range formulaErrorList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, err := f.CalcCellValue("Sheet1", "E1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+}
+
+func TestCalcXMATCH(t *testing.T) {
+	cellData := [][]interface{}{
+		{"apple", 10, "b"},
+		{"banana", 20, "a"},
+		{"cherry", 30, "b"},
+		{"banana", 40},
+	}
+	formulaList := map[string]string{
+		"XMATCH(\"banana\",A1:A4)":                  "2",
+		"XMATCH(\"banana\",A1:A4,0,-1)":             "4",
+		"XMATCH(\"ch*\",A1:A4,2)":                   "3",
+		"XMATCH(\"?anana\",A1:A4,2,-1)":             "4",
+		"XMATCH(25,B1:B4,-1)":                       "2",
+		"XMATCH(25,B1:B4,1)":                        "3",
+		"XMATCH(30,B1:B4,0,2)":                      "3",
+		"XMATCH(20,A1:C1)":                          "#N/A",
+		"XMATCH(\"b\",A1:C1)":                       "3",
+		"XMATCH(10,A2:C2)":                          "#N/A",
+		"XMATCH(\"a\",C1:C3)":                       "2",
+		"_xlfn.XMATCH(40,B1:B4)":                    "4",
+		"TEXTJOIN(\",\",FALSE,XMATCH(C1:C2,C1:C3))": "1,2",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, _ := f.CalcCellValue("Sheet1", "E1")
+		assert.Equal(t, expected, result, formula)
+	}
+	formulaErrorList := map[string][]string{
+		"XMATCH(1)":               {"#VALUE!", "XMATCH requires at least 2 arguments"},
+		"XMATCH(1,A1:A4,0,1,1)":   {"#VALUE!", "XMATCH allows at most 4 arguments"},
+		"XMATCH(1,A1:A4,\"\")":    {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"XMATCH(1,A1:A4,0,\"\")":  {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"XMATCH(1,A1:A4,3)":       {"#VALUE!", "#VALUE!"},
+		"XMATCH(1,A1:A4,0,3)":     {"#VALUE!", "#VALUE!"},
+		"XMATCH(1,A1:B4)":         {"#VALUE!", "#VALUE!"},
+		"XMATCH(1,1)":             {"#N/A", "#N/A"},
+		"XMATCH(35,B1:B4,0,2)":    {"#N/A", "#N/A"},
+		"XMATCH(\"grape\",A1:A4)": {"#N/A", "#N/A"},
+	}
+	for formula, expected := range formulaErrorList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "E1", formula))
+		result, err := f.CalcCellValue("Sheet1", "E1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+	// Test exact match on large lookup array with the hash index
+	f := NewFile()
+	for r := 1; r <= 200; r++ {
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("A%d", r), r%50))
+	}
+	for formula, expected := range map[string]string{
+		"XMATCH(7,A1:A200)":      "7",
+		"XMATCH(7,A1:A200,0,-1)": "157",
+		"XMATCH(0,A1:A200)":      "50",
+		"XMATCH(0,A1:A200,0,-1)": "200",
+		"XMATCH(60,A1:A200)":     "#N/A",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "B1", formula))
+		result, _ := f.CalcCellValue("Sheet1", "B1")
+		assert.Equal(t, expected, result, formula)
+	}
+}
@@ -0,0 +1,504 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// CalcCache is the name of the caches used by the formula calculation.
+type CalcCache string
+
+// Calculation cache names enumeration.
+const (
+	// CalcCacheResults is the cache of the calculated formula results and the
+	// resolved cell values.
+	CalcCacheResults CalcCache = "results"
+	// CalcCacheRanges is the cache of the resolved range matrices, and the
+	// criteria-aggregation tables built on them.
+	CalcCacheRanges CalcCache = "ranges"
+	// CalcCacheCriteria is the cache of the cells matching the criteria of
+	// the criteria functions, and the group keys of the GROUPBY and PIVOTBY
+	// functions.
+	CalcCacheCriteria CalcCache = "criteria"
+	// CalcCacheRangeIndexes is the cache of the value indexes of the ranges.
+	CalcCacheRangeIndexes CalcCache = "range_indexes"
+	// CalcCacheRegexps is the cache of the compiled regular expressions of the
+	// REGEX functions.
+	CalcCacheRegexps CalcCache = "regexps"
+	// CalcCacheLookupIndexes is the cache of the indexes of the lookup vectors
+	// shared by the lookup functions.
+	CalcCacheLookupIndexes CalcCache = "lookup_indexes"
+)
+
+// calcCacheNames is the names of the calculation caches in reporting order.
+var calcCacheNames = []CalcCache{
+	CalcCacheResults, CalcCacheRanges, CalcCacheCriteria, CalcCacheRangeIndexes, CalcCacheRegexps,
+	CalcCacheLookupIndexes,
+}
+
+// defaultCalcCacheLimits defined the default limits of the calculation
+// caches, the caches not listed are unlimited by default.
+var defaultCalcCacheLimits = map[CalcCache]CalcCacheLimit{
+	CalcCacheRanges:        {MaxEntries: 50},
+	CalcCacheRegexps:       {MaxEntries: 256},
+	CalcCacheLookupIndexes: {MaxEntries: 100},
+}
+
+// CalcCacheLimit directly maps the limits of a calculation cache, which could
+// be specified by the CalcCacheLimits field of the Options. MaxEntries is the
+// maximum number of the cached items, and MaxBytes is the maximum estimated
+// memory size of the cached items in bytes. The least recently used items
+// will be evicted once the cache exceeds any of the limits. The zero value
+// uses the default limit of the cache, and the negative value means no limit.
+type CalcCacheLimit struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// CalcCacheStats directly maps the statistics of a calculation cache. Entries
+// is the number of the cached items, and Bytes is the estimated memory size
+// of them. Hits and Misses are the numbers of the cache lookups found and not
+// found the items, and HitRate is the ratio of the hits to all lookups.
+// Evictions is the number of the items evicted by the limits. The counters
+// are accumulated since the workbook was opened, and are kept after the cache
+// was cleared.
+type CalcCacheStats struct {
+	Name       CalcCache
+	Entries    int
+	Bytes      int64
+	MaxEntries int
+	MaxBytes   int64
+	Hits       int64
+	Misses     int64
+	HitRate    float64
+	Evictions  int64
+}
+
+// calcCacheByName returns the calculation cache by given name, or nil if the
+// cache doesn't exist.
+func (f *File) calcCacheByName(name CalcCache) *lruCache {
+	switch name {
+	case CalcCacheResults:
+		return f.calcCache
+	case CalcCacheRanges:
+		return f.rangeCache
+	case CalcCacheCriteria:
+		return f.ifsMatchCache
+	case CalcCacheRangeIndexes:
+		return f.rangeIndexCache
+	case CalcCacheRegexps:
+		return f.regexpCache
+	case CalcCacheLookupIndexes:
+		return f.lookupIndexCache
+	}
+	return nil
+}
+
+// calcCacheLimit returns the limit of the calculation cache by given name,
+// the zero value fields of the CalcCacheLimits option use the default limits.
+func (f *File) calcCacheLimit(name CalcCache) CalcCacheLimit {
+	limit := defaultCalcCacheLimits[name]
+	if f.options == nil {
+		return limit
+	}
+	if opt, ok := f.options.CalcCacheLimits[name]; ok {
+		if opt.MaxEntries != 0 {
+			limit.MaxEntries = opt.MaxEntries
+		}
+		if opt.MaxBytes != 0 {
+			limit.MaxBytes = opt.MaxBytes
+		}
+	}
+	limit.MaxEntries, limit.MaxBytes = max(limit.MaxEntries, 0), max(limit.MaxBytes, 0)
+	return limit
+}
+
+// setCalcCacheLimits applies the CalcCacheLimits option to the calculation
+// caches of the workbook.
+func (f *File) setCalcCacheLimits() {
+	for _, name := range calcCacheNames {
+		limit := f.calcCacheLimit(name)
+		f.calcCacheByName(name).SetLimits(limit.MaxEntries, limit.MaxBytes)
+	}
+}
+
+// GetCalcCacheStats provides a function to get the statistics of the
+// calculation caches of the workbook, such as the number of the cached
+// items, the estimated memory size and the hit rate. For example, print the
+// statistics of each cache:
+//
+//	for _, stats := range f.GetCalcCacheStats() {
+//	    fmt.Println(stats.Name, stats.Entries, stats.Bytes, stats.HitRate)
+//	}
+func (f *File) GetCalcCacheStats() []CalcCacheStats {
+	result := make([]CalcCacheStats, 0, len(calcCacheNames))
+	for _, name := range calcCacheNames {
+		stats, limit := f.calcCacheByName(name).Stats(), f.calcCacheLimit(name)
+		item := CalcCacheStats{
+			Name:       name,
+			Entries:    stats.entries,
+			Bytes:      stats.bytes,
+			MaxEntries: limit.MaxEntries,
+			MaxBytes:   limit.MaxBytes,
+			Hits:       stats.hits,
+			Misses:     stats.misses,
+			Evictions:  stats.evictions,
+		}
+		if lookups := stats.hits + stats.misses; lookups > 0 {
+			item.HitRate = float64(stats.hits) / float64(lookups)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// ClearCalcCache provides a function to remove the cached items of the
+// calculation caches by given cache names, all calculation caches will be
+// cleared if no name was given. The formulas will be calculated again on the
+// next calculation. For example, clear the cached range matrices and the
+// range value indexes:
+//
+//	err := f.ClearCalcCache(excelize.CalcCacheRanges, excelize.CalcCacheRangeIndexes)
+func (f *File) ClearCalcCache(names ...CalcCache) error {
+	if len(names) == 0 {
+		names = calcCacheNames
+	}
+	for _, name := range names {
+		if f.calcCacheByName(name) == nil {
+			return newInvalidCalcCacheError(name)
+		}
+	}
+	for _, name := range names {
+		f.calcCacheByName(name).Clear()
+	}
+	return nil
+}
+
+// readArea records the cell areas read by the calculation in the top-level
+// formula execution context.
+func (ctx *calcContext) readArea(areas ...formulaArea) {
+	if ctx = ctx.root(); ctx == nil || len(areas) == 0 {
+		return
+	}
+	ctx.mu.Lock()
+	ctx.reads = append(ctx.reads, areas...)
+	ctx.mu.Unlock()
+}
+
+// readCount returns the number of the cell areas read by the calculation.
+func (ctx *calcContext) readCount() int {
+	if ctx = ctx.root(); ctx == nil {
+		return 0
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return len(ctx.reads)
+}
+
+// circularRead is the mark of the circular reference in the cell areas read
+// by the calculation, the results calculated with the mark will not be
+// cached, so that the circular reference could be detected again.
+var circularRead = formulaArea{x1: -2, y1: -2, x2: -2, y2: -2}
+
+// storedValueRead is the mark of reading the stored value of the formula cell
+// which the cell areas read by it are unknown, the results calculated with the
+// mark will not be cached, so that they will be calculated again after the
+// formula cell was recalculated.
+var storedValueRead = formulaArea{x1: -3, y1: -3, x2: -3, y2: -3}
+
+// storedValueReads returns the cell areas read by the formula cell of the
+// given reference, which are recorded with the cached result of the cell. It
+// returns the stored value mark if the formula cell has no cached result.
+func (f *File) storedValueReads(ref string) []formulaArea {
+	for _, raw := range []bool{true, false} {
+		if _, reads, ok := f.calcCache.LoadReads(fmt.Sprintf("%s!raw=%t", ref, raw)); ok && reads != nil {
+			return reads
+		}
+	}
+	return []formulaArea{storedValueRead}
+}
+
+// readAreas returns the distinct cell areas read by the calculation since
+// the given number of the read areas. It returns nil if the result shouldn't
+// be cached, since the volatile functions were called, the circular
+// reference was detected, the stored values of the formula cells with unknown
+// reads were used, or the reads couldn't be tracked without the
+// formula execution context.
+func (ctx *calcContext) readAreas(from int) []formulaArea {
+	if ctx = ctx.root(); ctx == nil {
+		return nil
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	reads := distinctAreas([]formulaArea{}, ctx.reads[min(from, len(ctx.reads)):]...)
+	if slices.Contains(reads, volatileRead) || slices.Contains(reads, circularRead) ||
+		slices.Contains(reads, storedValueRead) {
+		return nil
+	}
+	return reads
+}
+
+// distinctAreas appends the cell areas which are not in the given areas.
+func distinctAreas(reads []formulaArea, areas ...formulaArea) []formulaArea {
+	if len(reads)+len(areas) <= 16 {
+		for _, area := range areas {
+			if !slices.Contains(reads, area) {
+				reads = append(reads, area)
+			}
+		}
+		return reads
+	}
+	seen := make(map[formulaArea]bool, len(reads)+len(areas))
+	for _, area := range reads {
+		seen[area] = true
+	}
+	for _, area := range areas {
+		if !seen[area] {
+			seen[area] = true
+			reads = append(reads, area)
+		}
+	}
+	return reads
+}
+
+// resolvedRange records the distinct cell areas read to resolve the cell
+// ranges since the given number of the read areas, and returns them. It
+// returns nil if the resolved ranges shouldn't be cached.
+func (ctx *calcContext) resolvedRange(cellRanges *list.List, from int) []formulaArea {
+	reads := ctx.readAreas(from)
+	if ctx = ctx.root(); ctx != nil {
+		ctx.mu.Lock()
+		if ctx.rangeReads == nil {
+			ctx.rangeReads = make(map[*list.List][]formulaArea)
+		}
+		ctx.rangeReads[cellRanges] = reads
+		ctx.mu.Unlock()
+	}
+	return reads
+}
+
+// argReads returns the distinct cell areas read to resolve the cell ranges of
+// the given formula arguments, including the cells read by the formula cells
+// in the ranges. It returns nil if any argument isn't a resolved cell range,
+// or the items created from the arguments shouldn't be cached.
+func (fn *formulaFuncs) argReads(args ...formulaArg) []formulaArea {
+	ctx := fn.ctx.root()
+	if ctx == nil {
+		return nil
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	reads := []formulaArea{}
+	for _, arg := range args {
+		areas := ctx.rangeReads[arg.cellRanges]
+		if arg.cellRanges == nil || areas == nil {
+			return nil
+		}
+		reads = distinctAreas(reads, areas...)
+	}
+	return reads
+}
+
+// cellRangeArea returns the cell area of the given cell range.
+func cellRangeArea(cr cellRange) formulaArea {
+	rng := []int{cr.From.Col, cr.From.Row, cr.To.Col, cr.To.Row}
+	_ = sortCoordinates(rng)
+	return formulaArea{sheet: cr.From.Sheet, x1: rng[0], y1: rng[1], x2: rng[2], y2: rng[3]}
+}
+
+// resultReads returns the cell areas which the cached result of the formula
+// cell depends on, including the formula cell itself and the spill range of
+// the dynamic array formula.
+func resultReads(sheet, cell string, token formulaArg, spill bool, reads []formulaArea) []formulaArea {
+	col, row, err := CellNameToCoordinates(cell)
+	if err != nil {
+		return reads
+	}
+	area := formulaArea{sheet: sheet, x1: col, y1: row, x2: col, y2: row}
+	if spill && token.Type == ArgMatrix && len(token.Matrix) > 0 {
+		area.x2, area.y2 = col+len(token.Matrix[0])-1, row+len(token.Matrix)-1
+	}
+	return append(reads, area)
+}
+
+// changedCells is the changed cells of the worksheets for the cache
+// invalidation, the cells are grouped by the lower-cased worksheet name and
+// sorted by the row and column number.
+type changedCells map[string][]cellRef
+
+// newChangedCells creates the changed cells by given worksheet name and cell
+// references, it returns false if any cell reference is invalid.
+func newChangedCells(sheet string, cells []string) (changedCells, bool) {
+	key, changed := strings.ToLower(sheet), changedCells{}
+	for _, cell := range cells {
+		col, row, err := CellNameToCoordinates(cell)
+		if err != nil {
+			return changed, false
+		}
+		changed[key] = append(changed[key], cellRef{Col: col, Row: row})
+	}
+	refs := changed[key]
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Row < refs[j].Row || refs[i].Row == refs[j].Row && refs[i].Col < refs[j].Col
+	})
+	return changed, true
+}
+
+// covers returns whether any changed cell is in the given cell area, the
+// worksheet name of the area should be lower-cased.
+func (changed changedCells) covers(area formulaArea) bool {
+	refs := changed[area.sheet]
+	i := sort.Search(len(refs), func(i int) bool { return refs[i].Row >= area.y1 })
+	for ; i < len(refs) && refs[i].Row <= area.y2; i++ {
+		if refs[i].Col >= area.x1 && refs[i].Col <= area.x2 {
+			return true
+		}
+	}
+	return false
+}
+
+// clearCellCache removes the cached formula results, range matrices, criteria
+// matches and indexes which read the given cells of the worksheet, directly
+// or through the other formula cells, and keeps the other cached items. It
+// should be called after the values or formulas of the cells were changed,
+// all cached items will be removed if any cell reference is invalid.
+func (f *File) clearCellCache(sheet string, cells ...string) {
+	changed, ok := newChangedCells(sheet, cells)
+	if !ok {
+		f.clearCalcCaches()
+		return
+	}
+	if len(cells) == 0 {
+		return
+	}
+	for _, cache := range []*lruCache{f.calcCache, f.rangeCache, f.ifsMatchCache, f.rangeIndexCache, f.lookupIndexCache} {
+		cache.DeleteReading(changed)
+	}
+}
+
+// clearCalcCaches removes all cached items of the calculation caches which
+// depend on the cells, it should be called when the cells were moved or the
+// references of the formulas were changed.
+func (f *File) clearCalcCaches() {
+	for _, cache := range []*lruCache{f.calcCache, f.rangeCache, f.ifsMatchCache, f.rangeIndexCache, f.lookupIndexCache} {
+		cache.Clear()
+	}
+}
+
+// cacheEntrySize returns the estimated memory size of the cached item in
+// bytes by given key and value.
+func cacheEntrySize(key string, value interface{}) int64 {
+	return int64(len(key)) + cacheValueSize(value)
+}
+
+// cacheValueSize returns the estimated memory size of the cached value in
+// bytes, the size of the shared structures is ignored.
+func cacheValueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case formulaArg:
+		return formulaArgSize(v)
+	case []formulaArg:
+		return formulaArgsSize(v)
+	case [][]formulaArg:
+		return matrixSize(v)
+	case []cellRef:
+		return int64(len(v)) * int64(unsafe.Sizeof(cellRef{}))
+	case map[string][]cellRef:
+		size := int64(len(v)) * 48
+		for key, refs := range v {
+			size += int64(len(key)) + cacheValueSize(refs)
+		}
+		return size
+	case [][]string:
+		var size int64
+		for _, row := range v {
+			size += int64(len(row)) * int64(unsafe.Sizeof(""))
+			for _, s := range row {
+				size += int64(len(s))
+			}
+		}
+		return size
+	case *ifsTable:
+		return v.size()
+	case *lookupIndex:
+		return v.size()
+	case *regexp.Regexp:
+		return int64(len(v.String())) * 32
+	}
+	return int64(unsafe.Sizeof(value))
+}
+
+// formulaAreasSize returns the estimated memory size of the cell areas.
+func formulaAreasSize(areas []formulaArea) int64 {
+	size := int64(len(areas)) * int64(unsafe.Sizeof(formulaArea{}))
+	for _, area := range areas {
+		size += int64(len(area.sheet))
+	}
+	return size
+}
+
+// formulaArgSize returns the estimated memory size of the formula argument.
+func formulaArgSize(arg formulaArg) int64 {
+	return int64(unsafe.Sizeof(arg)) + int64(len(arg.String)+len(arg.Error)+len(arg.SheetName)) +
+		formulaArgsSize(arg.List) + matrixSize(arg.Matrix)
+}
+
+// formulaArgsSize returns the estimated memory size of the formula arguments.
+func formulaArgsSize(args []formulaArg) int64 {
+	var size int64
+	for _, arg := range args {
+		size += formulaArgSize(arg)
+	}
+	return size
+}
+
+// matrixSize returns the estimated memory size of the matrix of the formula
+// arguments.
+func matrixSize(matrix [][]formulaArg) int64 {
+	var size int64
+	for _, row := range matrix {
+		size += formulaArgsSize(row)
+	}
+	return size
+}
+
+// size returns the estimated memory size of the criteria-aggregation table.
+func (t *ifsTable) size() int64 {
+	size := int64(len(t.values))*9 + int64(len(t.ids))*48
+	for _, col := range t.columns {
+		size += formulaArgsSize(col.values) + int64(len(col.index))*48
+	}
+	for key, agg := range t.groups {
+		size += int64(len(key)) + int64(unsafe.Sizeof(*agg)) + int64(len(agg.positions))*8
+	}
+	return size
+}
+
+// size returns the estimated memory size of the lookup index.
+func (idx *lookupIndex) size() int64 {
+	size := formulaArgsSize(idx.cells) + int64(len(idx.first)+len(idx.last))*48
+	for _, t := range idx.texts {
+		size += int64(len(t.text)) + int64(unsafe.Sizeof(t))
+	}
+	return size
+}
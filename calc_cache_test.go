@@ -0,0 +1,171 @@
+package excelize
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcCacheStats(t *testing.T) {
+	f := NewFile(Options{CalcCacheLimits: map[CalcCache]CalcCacheLimit{
+		CalcCacheResults: {MaxEntries: 4},
+		CalcCacheRanges:  {MaxBytes: 1 << 20},
+		CalcCacheRegexps: {MaxEntries: -1},
+	}})
+	for r := 1; r <= 10; r++ {
+		row := strconv.Itoa(r)
+		assert.NoError(t, f.SetCellValue("Sheet1", "A"+row, r))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "B"+row, fmt.Sprintf("SUM(A1:A%d)", r)))
+	}
+	for i := 0; i < 2; i++ {
+		for r := 1; r <= 10; r++ {
+			result, err := f.CalcCellValue("Sheet1", "B"+strconv.Itoa(r))
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(r*(r+1)/2), result)
+		}
+	}
+	stats := f.GetCalcCacheStats()
+	require.Len(t, stats, 6)
+	results, ranges := stats[0], stats[1]
+	assert.Equal(t, CalcCacheResults, results.Name)
+	assert.Equal(t, 4, results.Entries)
+	assert.Equal(t, 4, results.MaxEntries)
+	assert.Positive(t, results.Evictions)
+	assert.Positive(t, results.Bytes)
+	assert.Equal(t, CalcCacheRanges, ranges.Name)
+	assert.Equal(t, 50, ranges.MaxEntries)
+	assert.Equal(t, int64(1<<20), ranges.MaxBytes)
+	assert.Equal(t, 10, ranges.Entries)
+	assert.Positive(t, ranges.Hits)
+	assert.Equal(t, float64(ranges.Hits)/float64(ranges.Hits+ranges.Misses), ranges.HitRate)
+	assert.Equal(t, CalcCacheStats{Name: CalcCacheCriteria}, stats[2])
+	assert.Equal(t, CalcCacheStats{Name: CalcCacheRangeIndexes}, stats[3])
+	assert.Equal(t, CalcCacheStats{Name: CalcCacheRegexps}, stats[4])
+	assert.Equal(t, CalcCacheStats{Name: CalcCacheLookupIndexes, MaxEntries: 100}, stats[5])
+
+	// Test clear the calculation caches selectively
+	assert.NoError(t, f.ClearCalcCache(CalcCacheRanges))
+	stats = f.GetCalcCacheStats()
+	assert.Equal(t, 4, stats[0].Entries)
+	assert.Zero(t, stats[1].Entries)
+	assert.Zero(t, stats[1].Bytes)
+	assert.Equal(t, ranges.Hits, stats[1].Hits)
+	assert.NoError(t, f.ClearCalcCache())
+	assert.Zero(t, f.GetCalcCacheStats()[0].Entries)
+	assert.EqualError(t, f.ClearCalcCache(CalcCacheRanges, "unknown"), `invalid calculation cache name "unknown"`)
+
+	// Test the items larger than the bytes limit will not be cached
+	f = NewFile(Options{CalcCacheLimits: map[CalcCache]CalcCacheLimit{CalcCacheRanges: {MaxBytes: 64}}})
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]int{1, 2, 3}))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "SUM(A1:C1)"))
+	result, err := f.CalcCellValue("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", result)
+	assert.Zero(t, f.GetCalcCacheStats()[1].Entries)
+
+	// Test apply the limits on open the workbook
+	buf, err := f.WriteToBuffer()
+	assert.NoError(t, err)
+	f, err = OpenReader(bytes.NewReader(buf.Bytes()), Options{CalcCacheLimits: map[CalcCache]CalcCacheLimit{CalcCacheCriteria: {MaxEntries: 8}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, f.GetCalcCacheStats()[2].MaxEntries)
+	assert.NoError(t, f.Close())
+}
+
+func TestCalcCacheNames(t *testing.T) {
+	// Test all calculation caches of the workbook are limited and reported
+	f, caches := NewFile(), map[*lruCache]bool{}
+	for _, name := range calcCacheNames {
+		caches[f.calcCacheByName(name)] = true
+	}
+	v := reflect.ValueOf(f).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if strings.HasSuffix(field.Name, "Cache") {
+			cache, ok := reflect.NewAt(field.Type, unsafe.Pointer(v.Field(i).UnsafeAddr())).Elem().Interface().(*lruCache)
+			assert.True(t, ok && caches[cache], field.Name)
+		}
+	}
+}
+
+func TestCalcCacheInvalidation(t *testing.T) {
+	f := NewFile()
+	for r := 1; r <= 10; r++ {
+		row := strconv.Itoa(r)
+		assert.NoError(t, f.SetSheetRow("Sheet1", "A"+row, &[]interface{}{r, r * 10, fmt.Sprintf("k%d", r%3)}))
+	}
+	formulas := map[string]string{
+		"E1": "SUM(A1:A10)",
+		"E2": "E1*2",
+		"E3": "SUM(B1:B5)",
+		"E4": `COUNTIFS(C1:C10,"k1")`,
+		"E5": `COUNTIFS(C1:C10,"k2")`,
+		"E6": `SUMIFS(B1:B10,C1:C10,"k0")`,
+		"E7": `MATCH("k2",C1:C10,0)`,
+		"F1": "A1+1",
+		"F2": "F1*10",
+		"F3": "SUM(F1:F2)",
+	}
+	for cell, formula := range formulas {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+	}
+	calc := func(expected map[string]string) {
+		for cell, value := range expected {
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.NoError(t, err, cell)
+			assert.Equal(t, value, result, cell)
+		}
+	}
+	cached := func(cell string) bool {
+		_, ok := f.calcCache.Load("Sheet1!" + cell + "!raw=false")
+		return ok
+	}
+	calc(map[string]string{
+		"E1": "55", "E2": "110", "E3": "150", "E4": "4", "E5": "3", "E6": "180", "E7": "2", "F2": "20", "F3": "22",
+	})
+	ranges := f.rangeCache.Len()
+	assert.Positive(t, f.ifsMatchCache.Len())
+
+	// Test only the cached items which read the changed cell are removed
+	assert.NoError(t, f.SetCellValue("Sheet1", "A3", 30))
+	assert.False(t, cached("E1"))
+	assert.False(t, cached("E2"))
+	for _, cell := range []string{"E3", "E4", "E5", "E6", "E7", "F2", "F3"} {
+		assert.True(t, cached(cell), cell)
+	}
+	assert.Equal(t, ranges-1, f.rangeCache.Len())
+	calc(map[string]string{"E1": "82", "E2": "164"})
+
+	// Test remove the cached items which read the changed cell through the
+	// other formula cells and the ranges contain them
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 2))
+	assert.False(t, cached("F2"))
+	assert.False(t, cached("F3"))
+	assert.True(t, cached("E3"))
+	calc(map[string]string{"E1": "83", "F2": "30", "F3": "33"})
+
+	// Test remove the criteria matches and indexes of the changed ranges
+	criteria, indexes := f.ifsMatchCache.Len(), f.rangeIndexCache.Len()
+	assert.NoError(t, f.SetCellValues("Sheet1", map[string]interface{}{"C1": "k2", "C2": "k0"}))
+	assert.Less(t, f.ifsMatchCache.Len(), criteria)
+	assert.Less(t, f.rangeIndexCache.Len(), indexes)
+	for _, cell := range []string{"E4", "E5", "E6", "E7"} {
+		assert.False(t, cached(cell), cell)
+	}
+	assert.True(t, cached("E3"))
+	calc(map[string]string{"E4": "3", "E5": "3", "E6": "200", "E7": "1", "E3": "150"})
+
+	// Test remove the cached result of the formula cell which was replaced
+	assert.NoError(t, f.SetCellValue("Sheet1", "E1", 1))
+	assert.False(t, cached("E2"))
+	calc(map[string]string{"E2": "2"})
+	assert.NoError(t, f.SetCellFormula("Sheet1", "E1", "A1*5"))
+	calc(map[string]string{"E1": "10", "E2": "20"})
+}
@@ -11,7 +11,8 @@ import (
 
 // CalcCellValuesDependencyAwareOptions provides options for dependency-aware calculation
 type CalcCellValuesDependencyAwareOptions struct {
-	// EnableDebug enables debug output for performance monitoring
+	// EnableDebug enables debug logs for performance monitoring, the logs
+	// are written to the handler specified by the LogHandler option
 	EnableDebug bool
 	// Options for cell value calculation
 	CalcOptions Options
@@ -71,7 +72,7 @@ func (f *File) CalcCellValuesDependencyAware(sheet string, cells []string, optio
 	var warmupStart time.Time
 	if options.EnableDebug {
 		warmupStart = time.Now()
-		fmt.Printf("Phase 1: Warming up cache with %d cells (one per column)...\n", len(warmupList))
+		f.logger().Debug("warming up calculation cache", "cells", len(warmupList))
 	}
 
 	// Pre-warm worksheet cache to avoid repeated namespace conversion
@@ -94,7 +95,7 @@ func (f *File) CalcCellValuesDependencyAware(sheet string, cells []string, optio
 			// Collect error but continue (consistent with phase 2)
 			allErrors = append(allErrors, fmt.Errorf("failed to calculate %s: %w", cell, err))
 			if options.EnableDebug {
-				fmt.Printf("  Warning: %s failed: %v\n", cell, err)
+				f.logger().Debug("warmup calculation failed", "cell", cell, "error", err)
 			}
 			continue
 		}
@@ -104,8 +105,8 @@ func (f *File) CalcCellValuesDependencyAware(sheet string, cells []string, optio
 	if options.EnableDebug {
 		warmupElapsed := time.Since(warmupStart)
 		cacheCount := f.rangeCache.Len()
-		fmt.Printf("Phase 1 completed: %d cells calculated, rangeCache entries: %d, elapsed: %v\n",
-			len(warmupList), cacheCount, warmupElapsed)
+		f.logger().Debug("calculation cache warmed up", "cells", len(warmupList), "rangeCacheEntries", cacheCount,
+			"duration", warmupElapsed)
 	}
 
 	// Phase 2: Concurrent calculation of remaining formulas
@@ -131,7 +132,7 @@ func (f *File) CalcCellValuesDependencyAware(sheet string, cells []string, optio
 	var concurrentStart time.Time
 	if options.EnableDebug {
 		concurrentStart = time.Now()
-		fmt.Printf("Phase 2: Concurrent calculation of %d cells...\n", len(remaining))
+		f.logger().Debug("concurrent calculation started", "cells", len(remaining))
 	}
 
 	// Calculate optimal number of workers and chunk size
@@ -198,9 +199,8 @@ func (f *File) CalcCellValuesDependencyAware(sheet string, cells []string, optio
 
 	if options.EnableDebug {
 		concurrentElapsed := time.Since(concurrentStart)
-		fmt.Printf("Phase 2 completed: %d cells calculated, elapsed: %v\n", len(remaining), concurrentElapsed)
-		fmt.Printf("Total: %d cells, %d successful, %d failed\n",
-			len(cells), len(results), len(allErrors))
+		f.logger().Debug("concurrent calculation completed", "cells", len(remaining), "duration", concurrentElapsed,
+			"total", len(cells), "successful", len(results), "failed", len(allErrors))
 	}
 
 	// Clear range cache after batch calculation to free memory
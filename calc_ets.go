@@ -0,0 +1,570 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// etsResolution is the minimum change of the smoothing parameters in the
+	// search of the parameters which minimize the mean squared error.
+	etsResolution = 0.001
+	// etsMaxSeasonality is the maximum supported length of the seasonality.
+	etsMaxSeasonality = 8760
+	// etsMaxMissingRatio is the maximum ratio of the missing points in the
+	// timeline.
+	etsMaxMissingRatio = 0.3
+)
+
+// etsModel defined the additive error, additive trend and additive
+// seasonality (AAA) version of the exponential triple smoothing model, which
+// used by the FORECAST.ETS family functions.
+type etsModel struct {
+	x, y                 []float64
+	level, trend         []float64
+	season, forecast     []float64
+	initSeason           []float64
+	initLevel, initTrend float64
+	step                 float64
+	period, monthDay     int
+	alpha, beta, gamma   float64
+	mae, mase, rmse      float64
+	smape                float64
+}
+
+// etsPoint defined a data point of the timeline and values.
+type etsPoint struct {
+	x, y float64
+}
+
+// etsAggregate returns the aggregated value of the values with the identical
+// timeline by given aggregation type.
+func etsAggregate(values []float64, aggregation int) float64 {
+	switch aggregation {
+	case 2, 3: // COUNT, COUNTA
+		return float64(len(values))
+	case 4: // MAX
+		result := values[0]
+		for _, value := range values {
+			result = math.Max(result, value)
+		}
+		return result
+	case 5: // MEDIAN
+		sort.Float64s(values)
+		if len(values)%2 == 0 {
+			return (values[len(values)/2-1] + values[len(values)/2]) / 2
+		}
+		return values[len(values)/2]
+	case 6: // MIN
+		result := values[0]
+		for _, value := range values {
+			result = math.Min(result, value)
+		}
+		return result
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	if aggregation == 7 { // SUM
+		return sum
+	}
+	return sum / float64(len(values)) // AVERAGE
+}
+
+// etsMonthDay returns the day of the month if all the dates of the timeline
+// are on the same day of the month, that means the timeline is the month
+// intervals, otherwise returns 0.
+func etsMonthDay(points []etsPoint) int {
+	day := timeFromExcelTime(points[0].x, false).Day()
+	for _, point := range points[1:] {
+		if timeFromExcelTime(point.x, false).Day() != day {
+			return 0
+		}
+	}
+	return day
+}
+
+// toMonths converts the date of the timeline to the number of the months for
+// the timeline with month intervals.
+func (m *etsModel) toMonths(x float64) float64 {
+	date := timeFromExcelTime(x, false)
+	days := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return float64(date.Year()*12+int(date.Month())) + float64(date.Day()-m.monthDay)/float64(days)
+}
+
+// etsPoints returns the data points sorted by the timeline, the values with
+// the identical timeline will be aggregated, and the empty values will be
+// skipped.
+func etsPoints(values, timeline formulaArg, aggregation int) ([]etsPoint, formulaArg) {
+	vals, tl := values.ToList(), timeline.ToList()
+	if len(vals) != len(tl) {
+		return nil, newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	}
+	grouped := map[float64][]float64{}
+	for i, arg := range tl {
+		if arg.Type == ArgError {
+			return nil, arg
+		}
+		x := arg.ToNumber()
+		if x.Type != ArgNumber {
+			return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		if vals[i].Type == ArgEmpty {
+			continue
+		}
+		if vals[i].Type == ArgError {
+			return nil, vals[i]
+		}
+		y := vals[i].ToNumber()
+		if y.Type != ArgNumber {
+			return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		grouped[x.Number] = append(grouped[x.Number], y.Number)
+	}
+	points := make([]etsPoint, 0, len(grouped))
+	for x, ys := range grouped {
+		points = append(points, etsPoint{x: x, y: etsAggregate(ys, aggregation)})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].x < points[j].x })
+	if len(points) < 3 {
+		return nil, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	return points, newEmptyFormulaArg()
+}
+
+// newETSModel prepares the model by given values, timeline, seasonality, data
+// completion and aggregation arguments. The timeline should have a constant
+// step, up to 30% missing points of the timeline will be completed.
+func newETSModel(values, timeline formulaArg, seasonality int, completion bool, aggregation int) (*etsModel, formulaArg) {
+	points, errArg := etsPoints(values, timeline, aggregation)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	m := &etsModel{monthDay: etsMonthDay(points)}
+	if m.monthDay != 0 {
+		for i := range points {
+			points[i].x = m.toMonths(points[i].x)
+		}
+	}
+	m.step = math.MaxFloat64
+	for i := 1; i < len(points); i++ {
+		m.step = math.Min(m.step, points[i].x-points[i-1].x)
+	}
+	if m.step <= 0 {
+		return nil, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	var missing int
+	for i, point := range points {
+		if i > 0 {
+			prev := points[i-1]
+			gap := (point.x - prev.x) / m.step
+			if math.Abs(gap-math.Round(gap)) > 1e-9 {
+				return nil, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+			}
+			for j := 1; j < int(math.Round(gap)); j++ {
+				var y float64
+				if completion {
+					y = (prev.y + point.y) / 2
+				}
+				m.x, m.y = append(m.x, prev.x+float64(j)*m.step), append(m.y, y)
+				missing++
+			}
+		}
+		m.x, m.y = append(m.x, point.x), append(m.y, point.y)
+	}
+	if float64(missing) > etsMaxMissingRatio*float64(len(points)) {
+		return nil, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	switch seasonality {
+	case 0:
+	case 1:
+		if m.period = m.detectPeriod(); m.period == 1 {
+			m.period = 0
+		}
+	default:
+		if m.period = seasonality; len(m.y) < 2*m.period {
+			return nil, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+		}
+	}
+	m.fit()
+	return m, newEmptyFormulaArg()
+}
+
+// detectPeriod returns the number of the samples in a period of the values
+// by finding the period length which has the least mean error of the changes
+// of the values between the adjacent periods, the shorter period will be
+// preferred if the mean errors are equal.
+func (m *etsModel) detectPeriod() int {
+	n := len(m.y)
+	best, bestErr := 1, math.MaxFloat64
+	for period := n / 2; period >= 1; period-- {
+		periods := n / period
+		start := n - periods*period + 1
+		var sum float64
+		for i := start; i < n-period; i++ {
+			sum += math.Abs((m.y[i] - m.y[i-1]) - (m.y[i+period] - m.y[i+period-1]))
+		}
+		if cnt := (periods-1)*period - 1; cnt > 0 {
+			if meanErr := sum / float64(cnt); meanErr <= bestErr {
+				best, bestErr = period, meanErr
+			}
+		}
+	}
+	return best
+}
+
+// initialize calculates the initial level, trend and seasonal indices of the
+// model.
+func (m *etsModel) initialize() {
+	n, p := len(m.y), m.period
+	m.level, m.trend = make([]float64, n), make([]float64, n)
+	m.season, m.forecast = make([]float64, n), make([]float64, n)
+	if p == 0 {
+		m.initTrend = (m.y[n-1] - m.y[0]) / float64(n-1)
+		m.initLevel = m.y[0]
+		return
+	}
+	var sum float64
+	for i := 0; i < p; i++ {
+		sum += m.y[i+p] - m.y[i]
+	}
+	m.initTrend = sum / float64(p*p)
+	periods := n / p
+	averages := make([]float64, periods)
+	for i := range averages {
+		for j := 0; j < p; j++ {
+			averages[i] += m.y[i*p+j]
+		}
+		averages[i] /= float64(p)
+	}
+	m.initSeason = make([]float64, p)
+	for j := 0; j < p; j++ {
+		for i := 0; i < periods; i++ {
+			m.initSeason[j] += m.y[i*p+j] - (averages[i] + (float64(j)-0.5*float64(p-1))*m.initTrend)
+		}
+		m.initSeason[j] /= float64(periods)
+	}
+	m.initLevel = m.y[0] - m.initSeason[0]
+}
+
+// seasonal returns the seasonal index for the data point by given index.
+func (m *etsModel) seasonal(i int) float64 {
+	switch {
+	case m.period == 0:
+		return 0
+	case i < m.period:
+		return m.initSeason[i]
+	}
+	return m.season[i-m.period]
+}
+
+// smooth calculates the level, trend, seasonal indices and the one step
+// ahead forecasts by the current smoothing parameters, returns the mean
+// squared error of the forecasts.
+func (m *etsModel) smooth() float64 {
+	m.level[0], m.trend[0], m.season[0] = m.initLevel, m.initTrend, m.seasonal(0)
+	m.forecast[0] = m.y[0]
+	var sse float64
+	for i := 1; i < len(m.y); i++ {
+		s := m.seasonal(i)
+		m.forecast[i] = m.level[i-1] + m.trend[i-1] + s
+		m.level[i] = m.alpha*(m.y[i]-s) + (1-m.alpha)*(m.level[i-1]+m.trend[i-1])
+		m.trend[i] = m.beta*(m.level[i]-m.level[i-1]) + (1-m.beta)*m.trend[i-1]
+		m.season[i] = m.gamma*(m.y[i]-m.level[i]) + (1-m.gamma)*s
+		sse += (m.forecast[i] - m.y[i]) * (m.forecast[i] - m.y[i])
+	}
+	return sse / float64(len(m.y)-1)
+}
+
+// optimize searches the value of the first smoothing parameter in the range
+// [0, 1] by bisection, the rest parameters are optimized for each value of
+// the first parameter. Returns the mean squared error of the model, and the
+// model will be smoothed with the found parameters.
+func (m *etsModel) optimize(params []*float64) float64 {
+	if len(params) == 0 {
+		return m.smooth()
+	}
+	eval := func(value float64) float64 {
+		*params[0] = value
+		return m.optimize(params[1:])
+	}
+	f0, f1, f2 := 0.0, 0.5, 1.0
+	e0, e2 := eval(f0), eval(f2)
+	e1 := eval(f1)
+	if e0 == e1 && e1 == e2 {
+		return eval(0)
+	}
+	for f2-f1 > etsResolution {
+		if e2 > e0 {
+			f2, e2, f1 = f1, e1, (f0+f1)/2
+		} else {
+			f0, e0, f1 = f1, e1, (f1+f2)/2
+		}
+		e1 = eval(f1)
+	}
+	if e2 > e0 {
+		if e0 < e1 {
+			return eval(f0)
+		}
+	} else if e2 < e1 {
+		return eval(f2)
+	}
+	return e1
+}
+
+// fit estimates the smoothing parameters of the model and calculates the
+// accuracy indicators of the model.
+func (m *etsModel) fit() {
+	m.initialize()
+	params := []*float64{&m.alpha, &m.beta}
+	if m.period != 0 {
+		params = []*float64{&m.alpha, &m.gamma, &m.beta}
+	}
+	m.rmse = math.Sqrt(m.optimize(params))
+	n := float64(len(m.y) - 1)
+	var sumAbsErr, sumPercErr, sumDiff float64
+	for i := 1; i < len(m.y); i++ {
+		absErr := math.Abs(m.forecast[i] - m.y[i])
+		sumAbsErr += absErr
+		if denom := math.Abs(m.forecast[i]) + math.Abs(m.y[i]); denom != 0 {
+			sumPercErr += absErr / denom
+		}
+		if i > 1 {
+			sumDiff += math.Abs(m.y[i] - m.y[i-1])
+		}
+	}
+	m.mae, m.smape = sumAbsErr/n, sumPercErr*2/n
+	if sumDiff != 0 {
+		m.mase = sumAbsErr / (n * sumDiff / (n - 1))
+	}
+}
+
+// horizon returns the number of the steps and the fraction of a step from the
+// end of the timeline to the target date.
+func (m *etsModel) horizon(target float64) (int, float64) {
+	if m.monthDay != 0 {
+		target = m.toMonths(target)
+	}
+	steps := (target - m.x[len(m.x)-1]) / m.step
+	h := math.Floor(steps + 1e-9)
+	return int(h), math.Max(steps-h, 0)
+}
+
+// predict returns the forecast of the model by given number of the steps
+// after the end of the timeline.
+func (m *etsModel) predict(h int) float64 {
+	last := len(m.y) - 1
+	value := m.level[last] + float64(h)*m.trend[last]
+	if m.period != 0 {
+		value += m.season[last-m.period+1+(h-1)%m.period]
+	}
+	return value
+}
+
+// forecastAt returns the forecast value of the model by given target date,
+// the value between two steps of the timeline will be interpolated.
+func (m *etsModel) forecastAt(target float64) formulaArg {
+	h, frac := m.horizon(target)
+	if h < 0 {
+		idx := len(m.y) - 1 + h
+		value := m.y[idx]
+		if frac >= etsResolution {
+			value += frac * (m.forecast[idx+1] - value)
+		}
+		return newNumberFormulaArg(value)
+	}
+	value := m.y[len(m.y)-1]
+	if h > 0 {
+		value = m.predict(h)
+	}
+	if frac >= etsResolution {
+		value += frac * (m.predict(h+1) - value)
+	}
+	return newNumberFormulaArg(value)
+}
+
+// confidenceInterval returns the half width of the prediction interval of
+// the forecast by given target date and confidence level. The variance of the
+// forecast errors are accumulated by the smoothing parameters of the model.
+func (m *etsModel) confidenceInterval(target, level float64) formulaArg {
+	h, frac := m.horizon(target)
+	if frac >= etsResolution {
+		h++
+	}
+	h = max(h, 1)
+	variance := 1.0
+	for j := 1; j < h; j++ {
+		c := m.alpha + float64(j)*m.alpha*m.beta
+		if m.period != 0 && j%m.period == 0 {
+			c += m.gamma * (1 - m.alpha)
+		}
+		variance += c * c
+	}
+	z, err := norminv((1 + level) / 2)
+	if err != nil {
+		return newErrorFormulaArg(formulaErrorNUM, err.Error())
+	}
+	return newNumberFormulaArg(z * m.rmse * math.Sqrt(variance))
+}
+
+// etsIntArg returns the integer value of the optional argument for the
+// FORECAST.ETS family functions, returns the #NUM! error if the value out of
+// the given range.
+func etsIntArg(arg formulaArg, defaultValue, minValue, maxValue int) (int, formulaArg) {
+	value, errArg := groupIntArg(arg, defaultValue)
+	if errArg.Type == ArgError {
+		return value, errArg
+	}
+	if value < minValue || value > maxValue {
+		return value, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	return value, errArg
+}
+
+// prepareETSModel checks the seasonality, data completion and aggregation
+// arguments and prepares the model for the FORECAST.ETS family functions.
+func prepareETSModel(values, timeline, seasonalityArg, completionArg, aggregationArg formulaArg) (*etsModel, formulaArg) {
+	seasonality, errArg := etsIntArg(seasonalityArg, 1, 0, etsMaxSeasonality)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	completion, errArg := etsIntArg(completionArg, 1, 0, 1)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	aggregation, errArg := etsIntArg(aggregationArg, 1, 0, 7)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	return newETSModel(values, timeline, seasonality, completion == 1, aggregation)
+}
+
+// etsTarget returns the target date argument, the target date should not be
+// earlier than the start of the timeline.
+func etsTarget(arg formulaArg, m *etsModel) (float64, formulaArg) {
+	if arg.Type == ArgError {
+		return 0, arg
+	}
+	target := arg.ToNumber()
+	if target.Type != ArgNumber {
+		return 0, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	x := target.Number
+	if m.monthDay != 0 {
+		x = m.toMonths(x)
+	}
+	if x < m.x[0] {
+		return 0, newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	}
+	return target.Number, target
+}
+
+// FORECASTdotETS function predicts a future value by the existing values
+// using the AAA version of the exponential triple smoothing algorithm. The
+// syntax of the function is:
+//
+//	FORECAST.ETS(target_date,values,timeline,[seasonality],[data_completion],[aggregation])
+func (fn *formulaFuncs) FORECASTdotETS(argsList *list.List) formulaArg {
+	if argsList.Len() < 3 || argsList.Len() > 6 {
+		return newErrorFormulaArg(formulaErrorVALUE, "FORECAST.ETS requires 3 to 6 arguments")
+	}
+	argv := groupFuncArgs(argsList, 6)
+	m, errArg := prepareETSModel(argv[1], argv[2], argv[3], argv[4], argv[5])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	return mapFormulaArg(argv[0], func(arg formulaArg) formulaArg {
+		target, errArg := etsTarget(arg, m)
+		if errArg.Type == ArgError {
+			return errArg
+		}
+		return m.forecastAt(target)
+	})
+}
+
+// FORECASTdotETSdotCONFINT function returns a confidence interval for the
+// forecast value at the specified target date. The syntax of the function
+// is:
+//
+//	FORECAST.ETS.CONFINT(target_date,values,timeline,[confidence_level],[seasonality],[data_completion],[aggregation])
+func (fn *formulaFuncs) FORECASTdotETSdotCONFINT(argsList *list.List) formulaArg {
+	if argsList.Len() < 3 || argsList.Len() > 7 {
+		return newErrorFormulaArg(formulaErrorVALUE, "FORECAST.ETS.CONFINT requires 3 to 7 arguments")
+	}
+	argv := groupFuncArgs(argsList, 7)
+	level := newNumberFormulaArg(0.95)
+	if argv[3].Type != ArgEmpty {
+		if level = argv[3].ToNumber(); level.Type != ArgNumber {
+			return level
+		}
+		if level.Number <= 0 || level.Number >= 1 {
+			return newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+		}
+	}
+	m, errArg := prepareETSModel(argv[1], argv[2], argv[4], argv[5], argv[6])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	return mapFormulaArg(argv[0], func(arg formulaArg) formulaArg {
+		target, errArg := etsTarget(arg, m)
+		if errArg.Type == ArgError {
+			return errArg
+		}
+		return m.confidenceInterval(target, level.Number)
+	})
+}
+
+// FORECASTdotETSdotSEASONALITY function returns the length of the
+// repetitive pattern detected in the specified time series. The syntax of the
+// function is:
+//
+//	FORECAST.ETS.SEASONALITY(values,timeline,[data_completion],[aggregation])
+func (fn *formulaFuncs) FORECASTdotETSdotSEASONALITY(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, "FORECAST.ETS.SEASONALITY requires 2 to 4 arguments")
+	}
+	argv := groupFuncArgs(argsList, 4)
+	m, errArg := prepareETSModel(argv[0], argv[1], newEmptyFormulaArg(), argv[2], argv[3])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	return newNumberFormulaArg(float64(m.period))
+}
+
+// FORECASTdotETSdotSTAT function returns a statistical value as a result of
+// time series forecasting. The statistic type 1 for the alpha, 2 for the
+// beta, 3 for the gamma, 4 for the MASE, 5 for the SMAPE, 6 for the MAE, 7
+// for the RMSE and 8 for the step size. The syntax of the function is:
+//
+//	FORECAST.ETS.STAT(values,timeline,statistic_type,[seasonality],[data_completion],[aggregation])
+func (fn *formulaFuncs) FORECASTdotETSdotSTAT(argsList *list.List) formulaArg {
+	if argsList.Len() < 3 || argsList.Len() > 6 {
+		return newErrorFormulaArg(formulaErrorVALUE, "FORECAST.ETS.STAT requires 3 to 6 arguments")
+	}
+	argv := groupFuncArgs(argsList, 6)
+	m, errArg := prepareETSModel(argv[0], argv[1], argv[3], argv[4], argv[5])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	return mapFormulaArg(argv[2], func(arg formulaArg) formulaArg {
+		statType, errArg := etsIntArg(arg, 0, 1, 8)
+		if errArg.Type == ArgError {
+			return errArg
+		}
+		return newNumberFormulaArg([]float64{m.alpha, m.beta, m.gamma, m.mase, m.smape, m.mae, m.rmse, m.step}[statType-1])
+	})
+}
@@ -0,0 +1,104 @@
+package excelize
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func prepareETSData(t *testing.T) *File {
+	f := NewFile()
+	season := []float64{10, 4, -6, -8}
+	noise := []float64{1.2, -0.8, 0.5, -1.6, 2.1, 0.3, -0.4, 1.1, -1.9, 0.7, -0.2, 1.5, -1.1, 0.9, -0.6, 0.2, 1.8, -1.3, 0.4, -0.7, 1.0, -0.5, 0.6, -1.2}
+	for i := 1; i <= 24; i++ {
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i), i))
+		assert.NoError(t, f.SetCellFormula("Sheet1", fmt.Sprintf("B%d", i), fmt.Sprintf("DATE(2020,%d,15)", i)))
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i), 100+1.5*float64(i)+season[(i-1)%4]+noise[i-1]))
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i), 2*float64(i)+3))
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i), float64(i)+season[(i-1)%4]))
+	}
+	return f
+}
+
+func TestCalcFORECASTdotETS(t *testing.T) {
+	f := prepareETSData(t)
+	for _, c := range []struct {
+		formula  string
+		expected float64
+	}{
+		// The linear and seasonal values without the noise are fitted exactly
+		{"FORECAST.ETS(25,D1:D24,A1:A24)", 53},
+		{"FORECAST.ETS(26.5,D1:D24,A1:A24)", 56},
+		{"FORECAST.ETS(25,E1:E24,A1:A24)", 35},
+		{"FORECAST.ETS(28,E1:E24,A1:A24)", 20},
+		{"FORECAST.ETS(5.5,E1:E24,A1:A24)", 12.5},
+		{"FORECAST.ETS(24,E1:E24,A1:A24)", 16},
+		{"FORECAST.ETS(25,E1:E24,A1:A24,4)", 35},
+		{"FORECAST.ETS.SEASONALITY(D1:D24,A1:A24)", 0},
+		{"FORECAST.ETS.SEASONALITY(E1:E24,A1:A24)", 4},
+		// The noisy seasonal values
+		{"FORECAST.ETS(25,C1:C24,A1:A24)", 148.147711025019},
+		{"FORECAST.ETS(28.5,C1:C24,A1:A24)", 143.474247029807},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,0)", 131.296461006636},
+		{"FORECAST.ETS(DATE(2022,1,15),C1:C24,B1:B24)", 148.147711025019},
+		{"FORECAST.ETS.SEASONALITY(C1:C24,A1:A24)", 4},
+		{"FORECAST.ETS.CONFINT(25,C1:C24,A1:A24)", 2.62448899358575},
+		{"FORECAST.ETS.CONFINT(30,C1:C24,A1:A24,0.9)", 3.88383232422275},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,1)", 0.25},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,2)", 0.5},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,3)", 0},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,4)", 0.128552023077172},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,5)", 0.00903523221050120},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,6)", 1.06464448203003},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,7)", 1.33904960099854},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,8)", 1},
+		{"FORECAST.ETS.STAT(C1:C24,B1:B24,8)", 1},
+		// Test the missing points and the aggregation of the identical timeline
+		{"FORECAST.ETS(6,{1,2,4,5,6},{1,2,4,5,6})", 6},
+		{"FORECAST.ETS(6,{2,4,6,8,8,12},{1,2,3,4,5,5})", 12},
+		{"FORECAST.ETS(7,{2,4,6,8,4,6},{1,2,3,4,5,5},0,1,7)", 14},
+		{"FORECAST.ETS(6,{2,4,6,8,10,3},{1,2,3,4,5,5},0,1,4)", 12},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "G1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "G1")
+		assert.NoError(t, err, c.formula)
+		actual, err := strconv.ParseFloat(result, 64)
+		assert.NoError(t, err, c.formula)
+		assert.InDelta(t, c.expected, actual, 1e-9, c.formula)
+	}
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"FORECAST.ETS(25,C1:C24)", formulaErrorVALUE},
+		{"FORECAST.ETS(25,C1:C24,A1:A23)", formulaErrorNA},
+		{"FORECAST.ETS(0,C1:C24,A1:A24)", formulaErrorNUM},
+		{"FORECAST.ETS(\"x\",C1:C24,A1:A24)", formulaErrorVALUE},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,-1)", formulaErrorNUM},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,8761)", formulaErrorNUM},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,13)", formulaErrorNUM},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,1,2)", formulaErrorNUM},
+		{"FORECAST.ETS(25,C1:C24,A1:A24,1,1,8)", formulaErrorNUM},
+		{"FORECAST.ETS(4,{1,2,3},{1,2,2.5})", formulaErrorNUM},
+		{"FORECAST.ETS(9,{1,2,3,4},{1,2,6,8})", formulaErrorNUM},
+		{"FORECAST.ETS(4,{1,2},{1,2})", formulaErrorNUM},
+		{"FORECAST.ETS(4,{1,2,\"x\"},{1,2,3})", formulaErrorVALUE},
+		{"FORECAST.ETS(4,{1,2,3},{1,2,\"x\"})", formulaErrorVALUE},
+		{"FORECAST.ETS.CONFINT(25,C1:C24)", formulaErrorVALUE},
+		{"FORECAST.ETS.CONFINT(25,C1:C24,A1:A24,1)", formulaErrorNUM},
+		{"FORECAST.ETS.CONFINT(25,C1:C24,A1:A24,\"x\")", formulaErrorVALUE},
+		{"FORECAST.ETS.CONFINT(25,C1:C24,A1:A24,0.95,-1)", formulaErrorNUM},
+		{"FORECAST.ETS.CONFINT(0,C1:C24,A1:A24)", formulaErrorNUM},
+		{"FORECAST.ETS.SEASONALITY(C1:C24)", formulaErrorVALUE},
+		{"FORECAST.ETS.SEASONALITY(C1:C24,A1:A24,2)", formulaErrorNUM},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24)", formulaErrorVALUE},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,9)", formulaErrorNUM},
+		{"FORECAST.ETS.STAT(C1:C24,A1:A24,1,-1)", formulaErrorNUM},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "G1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "G1")
+		assert.Error(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+}
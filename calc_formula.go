@@ -12,6 +12,7 @@
 package excelize
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -132,7 +133,7 @@ func (f *File) CalcFormulaValue(sheet, cell, formula string, opts ...Options) (s
 	}
 
 	// Calculate the result using the temporary formula
-	result, calcErr := f.CalcCellValue(sheet, cell, opts...)
+	result, calcErr := f.calcCellResult(context.Background(), sheet, cell, false, opts...)
 
 	// Clean up: restore original state
 	if isTemporaryCell {
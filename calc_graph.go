@@ -0,0 +1,726 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// graphBlockRows defined the number of rows in a block of the dependency
+	// graph index.
+	graphBlockRows = 1024
+	// graphBucketSpan defined the maximum number of columns or row blocks of
+	// an area which will be indexed by each column or row block, the wider
+	// areas will be checked one by one for each lookup of the worksheet.
+	graphBucketSpan = 16
+)
+
+// graphBucket defined the key of the dependency graph index, the block is
+// -1 for the areas which span over graphBucketSpan row blocks, and the col
+// is -1 for the areas which span over graphBucketSpan columns.
+type graphBucket struct {
+	sheet      string
+	col, block int
+}
+
+// dependencyGraph defined the persistent dependency graph of the formula
+// cells in the calculation chain. The formula cells are indexed by the
+// areas which they read, so the dependents of any cell can be found without
+// scanning the calculation chain. The references of the formula cells are
+// resolved by the formula tracer, and the formula cells with the volatile
+// functions, such as the NOW, OFFSET and INDIRECT functions, are kept in the
+// dynamic set which will be recalculated on every update.
+type dependencyGraph struct {
+	chain   *xlsxCalcChain
+	tracer  *formulaTracer
+	order   map[formulaCell]int
+	buckets map[graphBucket]map[formulaCell]struct{}
+	dynamic map[formulaCell]struct{}
+}
+
+// graphQueue implements the heap interface for the formula cells which are
+// ready for recalculation, ordered by the calculation chain.
+type graphQueue struct {
+	cells []formulaCell
+	order map[formulaCell]int
+}
+
+func (q *graphQueue) Len() int { return len(q.cells) }
+
+func (q *graphQueue) Less(i, j int) bool {
+	return q.order[q.cells[i]] < q.order[q.cells[j]]
+}
+
+func (q *graphQueue) Swap(i, j int) { q.cells[i], q.cells[j] = q.cells[j], q.cells[i] }
+
+func (q *graphQueue) Push(x interface{}) { q.cells = append(q.cells, x.(formulaCell)) }
+
+func (q *graphQueue) Pop() interface{} {
+	fc := q.cells[len(q.cells)-1]
+	q.cells = q.cells[:len(q.cells)-1]
+	return fc
+}
+
+// bucketsOf returns the index keys of the area.
+func (area formulaArea) bucketsOf() []graphBucket {
+	cols, blocks := []int{-1}, []int{-1}
+	if area.x2-area.x1 < graphBucketSpan {
+		cols = cols[:0]
+		for col := area.x1; col <= area.x2; col++ {
+			cols = append(cols, col)
+		}
+	}
+	if b1, b2 := (area.y1-1)/graphBlockRows, (area.y2-1)/graphBlockRows; b2-b1 < graphBucketSpan {
+		blocks = blocks[:0]
+		for block := b1; block <= b2; block++ {
+			blocks = append(blocks, block)
+		}
+	}
+	buckets := make([]graphBucket, 0, len(cols)*len(blocks))
+	for _, col := range cols {
+		for _, block := range blocks {
+			buckets = append(buckets, graphBucket{sheet: area.sheet, col: col, block: block})
+		}
+	}
+	return buckets
+}
+
+// dependencyGraph returns the dependency graph of the workbook, the graph
+// will be built on the first call and rebuilt if the calculation chain has
+// been replaced. This function should be called with the calcGraphMu lock.
+func (f *File) dependencyGraph() (*dependencyGraph, error) {
+	calcChain, err := f.calcChainReader()
+	if err != nil {
+		return nil, err
+	}
+	if f.calcGraph != nil && f.calcGraph.chain == calcChain {
+		return f.calcGraph, nil
+	}
+	t, err := f.newFormulaTracer()
+	if err != nil {
+		return nil, err
+	}
+	g := &dependencyGraph{
+		chain: calcChain, tracer: t, order: make(map[formulaCell]int, len(t.cells)),
+		buckets: map[graphBucket]map[formulaCell]struct{}{}, dynamic: map[formulaCell]struct{}{},
+	}
+	for idx, fc := range t.cells {
+		if _, ok := g.order[fc]; !ok {
+			g.order[fc] = idx
+			g.addCell(fc)
+		}
+	}
+	f.calcGraph = g
+	return g, nil
+}
+
+// clearDependencyGraph drops the dependency graph of the workbook, which
+// should be called when the references of the formulas could be changed
+// without setting the formulas, such as inserting rows, renaming worksheets
+// or changing defined names.
+func (f *File) clearDependencyGraph() {
+	f.calcGraphMu.Lock()
+	defer f.calcGraphMu.Unlock()
+	f.calcGraph = nil
+}
+
+// updateDependencyGraph updates the references of the formula cell in the
+// dependency graph after the formula of the cell has been changed or
+// removed. The formula cell which is not in the calculation chain will be
+// ignored, since it will not be recalculated.
+func (f *File) updateDependencyGraph(sheet, cell string) {
+	f.calcGraphMu.Lock()
+	defer f.calcGraphMu.Unlock()
+	if g := f.calcGraph; g != nil {
+		fc := formulaCell{sheet: sheet, cell: cell}
+		if _, ok := g.order[fc]; ok {
+			g.removeCell(fc)
+			g.addCell(fc)
+		}
+	}
+}
+
+// deleteDependencyGraphCell removes the formula cell from the dependency
+// graph.
+func (f *File) deleteDependencyGraphCell(sheet, cell string) {
+	f.calcGraphMu.Lock()
+	defer f.calcGraphMu.Unlock()
+	if g := f.calcGraph; g != nil {
+		fc := formulaCell{sheet: sheet, cell: cell}
+		if _, ok := g.order[fc]; ok {
+			g.removeCell(fc)
+			delete(g.order, fc)
+		}
+	}
+}
+
+// addCell resolves the references of the formula cell and adds it to the
+// index of the dependency graph.
+func (g *dependencyGraph) addCell(fc formulaCell) {
+	refs := g.tracer.references(fc)
+	if refs.dynamic || refs.volatile {
+		g.dynamic[fc] = struct{}{}
+	}
+	for _, area := range refs.areas {
+		for _, bucket := range area.bucketsOf() {
+			cells, ok := g.buckets[bucket]
+			if !ok {
+				cells = map[formulaCell]struct{}{}
+				g.buckets[bucket] = cells
+			}
+			cells[fc] = struct{}{}
+		}
+	}
+}
+
+// removeCell removes the formula cell from the index of the dependency graph
+// and drops its resolved references.
+func (g *dependencyGraph) removeCell(fc formulaCell) {
+	if refs, ok := g.tracer.refs[fc]; ok {
+		for _, area := range refs.areas {
+			for _, bucket := range area.bucketsOf() {
+				delete(g.buckets[bucket], fc)
+			}
+		}
+	}
+	delete(g.tracer.refs, fc)
+	delete(g.dynamic, fc)
+}
+
+// dependents returns the formula cells which read the given cell directly.
+func (g *dependencyGraph) dependents(sheet string, col, row int) []formulaCell {
+	var (
+		result []formulaCell
+		seen   = map[formulaCell]bool{}
+		block  = (row - 1) / graphBlockRows
+	)
+	for _, bucket := range []graphBucket{
+		{sheet, col, block}, {sheet, col, -1}, {sheet, -1, block}, {sheet, -1, -1},
+	} {
+		for fc := range g.buckets[bucket] {
+			if seen[fc] {
+				continue
+			}
+			seen[fc] = true
+			for _, area := range g.tracer.refs[fc].areas {
+				if area.contains(sheet, col, row) {
+					result = append(result, fc)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// dirtyCells returns the formula cells in the calculation chain which should
+// be recalculated after the given cells changed, including the given formula
+// cells, the formula cells which read them directly or through the other
+// formula cells, and the dynamic formula cells. The result is ordered
+// topologically, and the formula cells which have the same precedence are
+// ordered by the calculation chain, the formula cells in circular references
+// will be placed at the end by the calculation chain order.
+func (g *dependencyGraph) dirtyCells(cells []formulaCell) []formulaCell {
+	var (
+		queue    []formulaCell
+		dirty    = map[formulaCell]bool{}
+		inDegree = map[formulaCell]int{}
+		edges    = map[formulaCell][]formulaCell{}
+		mark     = func(fc formulaCell) {
+			if _, ok := g.order[fc]; ok && !dirty[fc] {
+				dirty[fc] = true
+				queue = append(queue, fc)
+			}
+		}
+	)
+	for _, fc := range cells {
+		mark(fc)
+	}
+	if len(cells) > 0 {
+		for fc := range g.dynamic {
+			mark(fc)
+		}
+	}
+	visit := func(from formulaCell, dependents []formulaCell) {
+		for _, fc := range dependents {
+			mark(fc)
+			if _, ok := g.order[from]; ok {
+				edges[from] = append(edges[from], fc)
+				inDegree[fc]++
+			}
+		}
+	}
+	for _, fc := range cells {
+		if col, row, err := CellNameToCoordinates(fc.cell); err == nil && !dirty[fc] {
+			visit(fc, g.dependents(fc.sheet, col, row))
+		}
+	}
+	for len(queue) > 0 {
+		fc := queue[0]
+		queue = queue[1:]
+		if col, row, err := CellNameToCoordinates(fc.cell); err == nil {
+			visit(fc, g.dependents(fc.sheet, col, row))
+		}
+	}
+	ready := &graphQueue{order: g.order}
+	for fc := range dirty {
+		if inDegree[fc] == 0 {
+			ready.cells = append(ready.cells, fc)
+		}
+	}
+	heap.Init(ready)
+	result := make([]formulaCell, 0, len(dirty))
+	for ready.Len() > 0 {
+		fc := heap.Pop(ready).(formulaCell)
+		result = append(result, fc)
+		delete(dirty, fc)
+		for _, next := range edges[fc] {
+			if inDegree[next]--; inDegree[next] == 0 {
+				heap.Push(ready, next)
+			}
+		}
+	}
+	remaining := make([]formulaCell, 0, len(dirty))
+	for fc := range dirty {
+		remaining = append(remaining, fc)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		return g.order[remaining[i]] < g.order[remaining[j]]
+	})
+	return append(result, remaining...)
+}
+
+// recalculationOrder returns all formula cells in the calculation chain in
+// the order of the full recalculation, which is the same as the incremental
+// recalculation with all formula cells changed: the formula cells are ordered
+// topologically by the dependency graph, and the formula cells which have the
+// same precedence are ordered by the calculation chain.
+func (f *File) recalculationOrder() ([]formulaCell, error) {
+	f.calcGraphMu.Lock()
+	defer f.calcGraphMu.Unlock()
+	g, err := f.dependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]formulaCell, 0, len(g.order))
+	for fc := range g.order {
+		cells = append(cells, fc)
+	}
+	return g.dirtyCells(cells), nil
+}
+
+// recalculateDirtyCells recalculates the formula cells in the calculation
+// chain which are affected by the given cells in the topological order of
+// the dependency graph, and returns the number of recalculated formula
+// cells. The cached values of the formula cells with calculation errors
+// will be cleared, and the ErrCircularReference or ErrFormulaTimeout error
+// will be returned after recalculating the remaining formula cells if there
+// are circular references or formula calculation timeouts. The
+// recalculation stops when the given context is canceled.
+func (f *File) recalculateDirtyCells(ctx context.Context, cells []formulaCell) (int, error) {
+	ctx = withVolatileValues(ctx)
+	f.calcGraphMu.Lock()
+	g, err := f.dependencyGraph()
+	if err != nil {
+		f.calcGraphMu.Unlock()
+		return 0, err
+	}
+	for i, fc := range cells {
+		if col, row, err := CellNameToCoordinates(fc.cell); err == nil {
+			cells[i].cell, _ = CoordinatesToCellName(col, row)
+		}
+		for _, sheet := range g.tracer.sheets {
+			if strings.EqualFold(sheet, fc.sheet) {
+				cells[i].sheet = sheet
+			}
+		}
+	}
+	dirty := g.dirtyCells(cells)
+	f.calcGraphMu.Unlock()
+	for _, fc := range dirty {
+		f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=true")
+		f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=false")
+	}
+	var (
+		cellErrs calcCellErrors
+		spilled  = map[formulaCell]bool{}
+	)
+	for i := 0; i < len(dirty); i++ {
+		fc := dirty[i]
+		if err = ctx.Err(); err != nil {
+			return len(dirty), err
+		}
+		prev := f.spillArea(fc)
+		// continue with the remaining cells on the other errors
+		_ = cellErrs.add(fc.sheet, fc.cell, f.recalculateCell(ctx, fc.sheet, fc.cell))
+		if spilled[fc] {
+			continue
+		}
+		// The dependency graph doesn't track the cells spilled by the dynamic
+		// array formulas, recalculate the formula cells which read the
+		// previous or current spill range and their dependents after the
+		// anchor cell, as the full recalculation does
+		f.calcGraphMu.Lock()
+		var remaining []formulaCell
+		if readers := g.areaReaders(fc, prev, f.spillArea(fc)); len(readers) > 0 {
+			spilled[fc] = true
+			remaining = g.dirtyCells(append(readers, dirty[i+1:]...))
+		}
+		f.calcGraphMu.Unlock()
+		if remaining == nil {
+			continue
+		}
+		for _, fc := range remaining {
+			f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=true")
+			f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=false")
+		}
+		dirty = append(dirty[:i+1:i+1], remaining...)
+	}
+	return len(dirty), cellErrs.err()
+}
+
+// levels groups the formula cells of the dependency graph into the dependency
+// levels, the formula cells in each level only read the formula cells in the
+// previous levels, and are ordered by the calculation chain. The spills
+// specifies the spill ranges of the dynamic array formulas, the formula
+// cells which read the spilled cells depend on the anchor cells. The dynamic
+// formula cells, the formula cells in circular references and their
+// dependents can't be grouped, and will be returned in the topological
+// order.
+func (g *dependencyGraph) levels(spills map[formulaCell]formulaArea) ([][]formulaCell, []formulaCell) {
+	var (
+		cells    = make([]formulaCell, 0, len(g.order))
+		inDegree = make(map[formulaCell]int, len(g.order))
+		edges    = make(map[formulaCell][]formulaCell, len(g.order))
+		byOrder  = func(cells []formulaCell) {
+			sort.Slice(cells, func(i, j int) bool { return g.order[cells[i]] < g.order[cells[j]] })
+		}
+	)
+	for fc := range g.order {
+		cells = append(cells, fc)
+	}
+	byOrder(cells)
+	for _, fc := range cells {
+		col, row, err := CellNameToCoordinates(fc.cell)
+		if err != nil {
+			continue
+		}
+		seen := map[formulaCell]bool{}
+		addEdges := func(dependents []formulaCell) {
+			for _, dep := range dependents {
+				if _, ok := g.order[dep]; ok && !seen[dep] {
+					seen[dep] = true
+					edges[fc] = append(edges[fc], dep)
+					inDegree[dep]++
+				}
+			}
+		}
+		addEdges(g.dependents(fc.sheet, col, row))
+		if area, ok := spills[fc]; ok {
+			for y := area.y1; y <= area.y2; y++ {
+				for x := area.x1; x <= area.x2; x++ {
+					if x != col || y != row {
+						addEdges(g.dependents(area.sheet, x, y))
+					}
+				}
+			}
+		}
+	}
+	var levels [][]formulaCell
+	grouped := make(map[formulaCell]bool, len(cells))
+	level := make([]formulaCell, 0)
+	for _, fc := range cells {
+		if inDegree[fc] == 0 {
+			level = append(level, fc)
+		}
+	}
+	for len(level) > 0 {
+		levels = append(levels, level)
+		var next []formulaCell
+		for _, fc := range level {
+			grouped[fc] = true
+			for _, dep := range edges[fc] {
+				if inDegree[dep]--; inDegree[dep] == 0 {
+					next = append(next, dep)
+				}
+			}
+		}
+		byOrder(next)
+		level = next
+	}
+	var roots []formulaCell
+	for _, fc := range cells {
+		if _, ok := g.dynamic[fc]; ok || !grouped[fc] {
+			roots = append(roots, fc)
+		}
+	}
+	if len(roots) == 0 {
+		return levels, nil
+	}
+	serial := g.dirtyCells(roots)
+	excluded := make(map[formulaCell]bool, len(serial))
+	for _, fc := range serial {
+		excluded[fc] = true
+	}
+	result := levels[:0]
+	for _, level := range levels {
+		kept := level[:0]
+		for _, fc := range level {
+			if !excluded[fc] {
+				kept = append(kept, fc)
+			}
+		}
+		if len(kept) > 0 {
+			result = append(result, kept)
+		}
+	}
+	return result, serial
+}
+
+// formulaCellTask defined the state of recalculating a formula cell
+// concurrently, the formula is evaluated by the workers, and the result will
+// be formatted, spilled and stored serially.
+type formulaCellTask struct {
+	formulaCell
+	cached   bool
+	reads    []formulaArea
+	spilled  bool
+	result   string
+	token    formulaArg
+	err      error
+	duration time.Duration
+}
+
+// calc evaluates the formula of the cell, or loads the result from the
+// calculation cache.
+func (task *formulaCellTask) calc(ctx context.Context, f *File, options *Options) {
+	start := time.Now()
+	defer func() { task.duration = time.Since(start) }()
+	if result, ok := f.calcCache.Load(task.sheet + "!" + task.cell + "!raw=true"); ok {
+		task.result, task.cached = result.(string), true
+		return
+	}
+	task.token, task.reads, task.err = f.calcCellToken(ctx, task.sheet, task.cell, options)
+}
+
+// store spills the result of the dynamic array formula and updates the cache
+// of the formula cell, and adds the calculation to the given report.
+func (task *formulaCellTask) store(f *File, options *Options, report *CalcReport) error {
+	if !task.cached && task.err == nil {
+		start := time.Now()
+		task.spilled = task.token.Type == ArgMatrix && (len(task.token.Matrix) > 1 ||
+			len(task.token.Matrix) == 1 && len(task.token.Matrix[0]) > 1)
+		task.result, task.err = f.calcTokenResult(task.sheet, task.cell, task.token, true, task.reads, options)
+		task.duration += time.Since(start)
+	} else if !task.cached {
+		task.result = task.token.String
+	}
+	f.recordCalc(report, task.sheet, task.cell, task.duration, task.cached, task.result, task.err)
+	return f.storeCalcResult(task.sheet, task.cell, task.result, task.err)
+}
+
+// formulaCellsInfo returns the formula cells of the dependency graph which
+// exist in the worksheets and the spill ranges of the dynamic array formulas.
+func (f *File) formulaCellsInfo(g *dependencyGraph) (map[formulaCell]bool, map[formulaCell]formulaArea) {
+	exists, spills := map[formulaCell]bool{}, map[formulaCell]formulaArea{}
+	for _, sheet := range g.tracer.sheets {
+		ws, err := f.workSheetReader(sheet)
+		if err != nil {
+			continue
+		}
+		ws.mu.RLock()
+		for _, row := range ws.SheetData.Row {
+			for _, c := range row.C {
+				fc := formulaCell{sheet: sheet, cell: c.R}
+				if _, ok := g.order[fc]; !ok || c.F == nil {
+					continue
+				}
+				exists[fc] = true
+				if c.F.T == STCellFormulaTypeShared || c.F.Ref == "" {
+					continue
+				}
+				if coordinates, ok := formulaRefCoordinates(c.F.Ref); ok {
+					spills[fc] = formulaArea{
+						sheet: sheet, x1: coordinates[0], y1: coordinates[1], x2: coordinates[2], y2: coordinates[3],
+					}
+				}
+			}
+		}
+		ws.mu.RUnlock()
+	}
+	return exists, spills
+}
+
+// spillArea returns the spill range of the dynamic array formula in the
+// given formula cell, the sheet name of the result will be empty if the
+// formula doesn't spill.
+func (f *File) spillArea(fc formulaCell) formulaArea {
+	var area formulaArea
+	ws, err := f.workSheetReader(fc.sheet)
+	if err != nil {
+		return area
+	}
+	col, row, err := CellNameToCoordinates(fc.cell)
+	if err != nil {
+		return area
+	}
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	if c := ws.getCellReadOnly(col, row); c != nil && c.F != nil && c.F.Ref != "" {
+		if coordinates, ok := formulaRefCoordinates(c.F.Ref); ok {
+			area = formulaArea{sheet: fc.sheet, x1: coordinates[0], y1: coordinates[1], x2: coordinates[2], y2: coordinates[3]}
+		}
+	}
+	return area
+}
+
+// areaReaders returns the formula cells after the anchor cell in the
+// calculation chain which read the cells in the given spill ranges.
+func (g *dependencyGraph) areaReaders(anchor formulaCell, areas ...formulaArea) []formulaCell {
+	var (
+		readers []formulaCell
+		seen    = map[formulaCell]bool{anchor: true}
+	)
+	for _, area := range areas {
+		if area.sheet == "" {
+			continue
+		}
+		for y := area.y1; y <= area.y2; y++ {
+			for x := area.x1; x <= area.x2; x++ {
+				for _, fc := range g.dependents(area.sheet, x, y) {
+					if !seen[fc] && g.order[fc] > g.order[anchor] {
+						seen[fc] = true
+						readers = append(readers, fc)
+					}
+				}
+			}
+		}
+	}
+	return readers
+}
+
+// spillReaders returns the formula cells after the anchor cell in the
+// calculation chain which read the cells in the previous or current spill
+// range of the dynamic array formula, if the spill range has been changed.
+func (f *File) spillReaders(g *dependencyGraph, anchor formulaCell, prev formulaArea) []formulaCell {
+	cur := f.spillArea(anchor)
+	if cur == prev {
+		return nil
+	}
+	return g.areaReaders(anchor, prev, cur)
+}
+
+// recalculateConcurrently recalculates the formula cells in the calculation
+// chain by the dependency levels, the formulas in the same level will be
+// evaluated concurrently by the given number of workers, and the results
+// will be stored by the calculation chain order after all formulas in the
+// level have been evaluated. Both the serial recalculation and the levels
+// evaluate each formula cell after the formula cells it reads, so the results
+// are the same as the serial recalculation. The formula cells which can't be
+// grouped into the dependency levels will be recalculated serially at the
+// end. The recalculation stops when the given context is canceled.
+func (f *File) recalculateConcurrently(ctx context.Context, workers int) error {
+	f.calcGraphMu.Lock()
+	g, err := f.dependencyGraph()
+	if err != nil {
+		f.calcGraphMu.Unlock()
+		return err
+	}
+	exists, spills := f.formulaCellsInfo(g)
+	levels, serial := g.levels(spills)
+	f.calcGraphMu.Unlock()
+	var (
+		cellErrs calcCellErrors
+		stale    []formulaCell
+		options  = &Options{RawCellValue: true, CalcTimeout: f.calcTimeout()}
+		reported = map[formulaCell]bool{}
+		report   = calcReportFromContext(ctx)
+		check    = func(fc formulaCell, err error) {
+			if (errors.Is(err, ErrCircularReference) || errors.Is(err, ErrFormulaTimeout)) && !reported[fc] {
+				reported[fc] = true
+				_ = cellErrs.add(fc.sheet, fc.cell, err)
+			}
+		}
+	)
+	for _, level := range levels {
+		tasks := make([]formulaCellTask, 0, len(level))
+		for _, fc := range level {
+			if exists[fc] {
+				tasks = append(tasks, formulaCellTask{formulaCell: fc})
+			}
+		}
+		var (
+			wg    sync.WaitGroup
+			queue = make(chan int, len(tasks))
+		)
+		for idx := range tasks {
+			queue <- idx
+		}
+		close(queue)
+		for i := 0; i < min(workers, len(tasks)); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := range queue {
+					if ctx.Err() == nil {
+						tasks[idx].calc(ctx, f, options)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		for idx := range tasks {
+			check(tasks[idx].formulaCell, tasks[idx].store(f, options, report))
+			if tasks[idx].spilled {
+				f.calcGraphMu.Lock()
+				stale = append(stale, f.spillReaders(g, tasks[idx].formulaCell, spills[tasks[idx].formulaCell])...)
+				f.calcGraphMu.Unlock()
+			}
+		}
+	}
+	for _, fc := range serial {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		check(fc, f.recalculateCell(ctx, fc.sheet, fc.cell))
+	}
+	// The formula cells read the cells spilled by the dynamic array formulas
+	// in the same or later dependency levels have been evaluated with the
+	// previous values, recalculate them and their dependents as the serial
+	// recalculation does
+	if len(stale) > 0 {
+		f.calcGraphMu.Lock()
+		dirty := g.dirtyCells(stale)
+		f.calcGraphMu.Unlock()
+		for _, fc := range dirty {
+			f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=true")
+			f.calcCache.Delete(fc.sheet + "!" + fc.cell + "!raw=false")
+		}
+		for _, fc := range dirty {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			check(fc, f.recalculateCell(ctx, fc.sheet, fc.cell))
+		}
+	}
+	return cellErrs.err()
+}
@@ -0,0 +1,308 @@
+package excelize
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	prepare := func() *File {
+		f := NewFile()
+		_, err := f.NewSheet("Sheet 2")
+		assert.NoError(t, err)
+		for r := 1; r <= 3000; r++ {
+			cell, _ := CoordinatesToCellName(1, r)
+			assert.NoError(t, f.SetCellValue("Sheet1", cell, r))
+		}
+		assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Rate", RefersTo: "Sheet1!$D$1"}))
+		assert.NoError(t, f.SetCellValue("Sheet1", "D1", 2))
+		for cell, formula := range map[string]string{
+			"B1": "A1*Rate",
+			"B2": "B1+A2",
+			"B3": "SUM(A:A)",
+			"B4": "SUM(A2000:A2999)+B3",
+			"B5": "INDIRECT(\"A\"&D1)*10",
+			"B6": "B7+1",
+			"B7": "B6+1",
+			"C1": "SUM(A1:B1)",
+		} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+		}
+		assert.NoError(t, f.SetCellFormula("Sheet 2", "A1", "Sheet1!B2*2+Sheet1!B4"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "B8", "B2*2+'Sheet 2'!A1"))
+		assert.NoError(t, f.RebuildCalcChain())
+		return f
+	}
+	values := func(f *File) map[string]string {
+		result := map[string]string{}
+		for _, sheet := range f.GetSheetList() {
+			rows, err := f.GetRows(sheet)
+			assert.NoError(t, err)
+			for r, row := range rows {
+				for c, value := range row {
+					cell, _ := CoordinatesToCellName(c+1, r+1)
+					result[sheet+"!"+cell] = value
+				}
+			}
+		}
+		return result
+	}
+	f := prepare()
+	assert.ErrorIs(t, f.RecalculateAll(), ErrCircularReference)
+	// Test the dirty cells of the graph are ordered topologically
+	f.calcGraphMu.Lock()
+	g, err := f.dependencyGraph()
+	assert.NoError(t, err)
+	assert.Equal(t, []formulaCell{
+		{"Sheet1", "B1"}, {"Sheet1", "C1"}, {"Sheet1", "B2"}, {"Sheet1", "B3"}, {"Sheet1", "B4"}, {"Sheet1", "B5"},
+		{"Sheet 2", "A1"}, {"Sheet1", "B8"},
+	}, g.dirtyCells([]formulaCell{{"Sheet1", "A1"}}))
+	assert.Equal(t, []formulaCell{
+		{"Sheet1", "B5"}, {"Sheet1", "B6"}, {"Sheet1", "B7"},
+	}, g.dirtyCells([]formulaCell{{"Sheet1", "B7"}}))
+	assert.Equal(t, []formulaCell{
+		{"Sheet1", "B3"}, {"Sheet1", "B4"}, {"Sheet1", "B5"}, {"Sheet 2", "A1"}, {"Sheet1", "B8"},
+	}, g.dirtyCells([]formulaCell{{"Sheet1", "A2500"}}))
+	assert.Empty(t, g.dirtyCells(nil))
+	f.calcGraphMu.Unlock()
+
+	// Test the incremental recalculation has the same results as the full
+	// recalculation, including the formulas read the formula cells on the
+	// other worksheets
+	var applied []CellUpdate
+	for _, updates := range [][]CellUpdate{
+		{{Sheet: "Sheet1", Cell: "A1", Value: 100}},
+		{{Sheet: "Sheet1", Cell: "D1", Value: 3}, {Sheet: "Sheet1", Cell: "A2500", Value: -1}},
+		{{Sheet: "Sheet1", Cell: "$a$3", Value: 7}, {Sheet: "sheet 2", Cell: "B1", Value: 1}},
+	} {
+		applied = append(applied, updates...)
+		expected := prepare()
+		assert.ErrorIs(t, expected.RecalculateAll(), ErrCircularReference)
+		assert.NoError(t, expected.BatchSetCellValue(applied))
+		assert.ErrorIs(t, expected.RecalculateAll(), ErrCircularReference)
+		assert.NoError(t, f.BatchUpdateAndRecalculate(updates))
+		assert.Equal(t, values(expected), values(f))
+	}
+	for cell, expected := range map[string]string{"Sheet 2!A1": "6996705", "Sheet1!B8": "6997309"} {
+		ref := strings.Split(cell, "!")
+		value, err := f.GetCellValue(ref[0], ref[1])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, cell)
+	}
+
+	// Test the graph is maintained as the formulas changed
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B6", "A5*2"))
+	assert.NoError(t, f.SetCellValue("Sheet1", "B7", 1))
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A5", Value: 21}}))
+	for cell, expected := range map[string]string{"B6": "42", "B7": "1"} {
+		value, err := f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, cell)
+	}
+	f.calcGraphMu.Lock()
+	assert.NotContains(t, f.calcGraph.order, formulaCell{"Sheet1", "B7"})
+	f.calcGraphMu.Unlock()
+	assert.NoError(t, f.InsertRows("Sheet1", 1, 1))
+	assert.Nil(t, f.calcGraph)
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A6", Value: 5}}))
+	value, err := f.GetCellValue("Sheet1", "B7")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	// Test the formulas set in batch and their dependents are recalculated
+	assert.NoError(t, f.BatchSetFormulasAndRecalculate([]FormulaUpdate{{Sheet: "Sheet1", Cell: "E1", Formula: "B7+1"}}))
+	assert.NoError(t, f.BatchSetFormulasAndRecalculate([]FormulaUpdate{{Sheet: "Sheet1", Cell: "B7", Formula: "A6*3"}}))
+	value, err = f.GetCellValue("Sheet1", "E1")
+	assert.NoError(t, err)
+	assert.Equal(t, "16", value)
+
+	// Test the formula cells read the spilled cells are recalculated after the
+	// spill range changed
+	spill := func() *File {
+		f := NewFile()
+		assert.NoError(t, f.SetCellValue("Sheet1", "B1", 2))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A1", "SEQUENCE(B1)"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C3", "A3*10"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "C3+1"))
+		assert.NoError(t, f.RebuildCalcChain())
+		assert.NoError(t, f.RecalculateAll())
+		return f
+	}
+	f, expected := spill(), spill()
+	assert.NoError(t, expected.SetCellValue("Sheet1", "B1", 3))
+	assert.NoError(t, expected.RecalculateAll())
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "B1", Value: 3}}))
+	for cell, result := range map[string]string{"A3": "3", "C3": "30", "D1": "31"} {
+		value, err = f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, result, value, cell)
+		value, err = expected.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, result, value, cell)
+	}
+
+	// Test build the graph with the invalid calculation chain
+	f.CalcChain = nil
+	f.Pkg.Store(defaultXMLPathCalcChain, MacintoshCyrillicCharset)
+	_, err = f.recalculateDirtyCells(context.Background(), []formulaCell{{"Sheet1", "A1"}})
+	assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
+}
+
+func TestFormulaAreaBuckets(t *testing.T) {
+	assert.Equal(t, []graphBucket{{"Sheet1", 1, 0}, {"Sheet1", 2, 0}}, formulaArea{"Sheet1", 1, 1, 2, 1024}.bucketsOf())
+	assert.Equal(t, []graphBucket{{"Sheet1", 1, 0}, {"Sheet1", 1, 1}}, formulaArea{"Sheet1", 1, 1, 1, 1025}.bucketsOf())
+	assert.Equal(t, []graphBucket{{"Sheet1", 1, -1}}, formulaArea{"Sheet1", 1, 1, 1, TotalRows}.bucketsOf())
+	assert.Equal(t, []graphBucket{{"Sheet1", -1, 0}}, formulaArea{"Sheet1", 1, 1, MaxColumns, 1}.bucketsOf())
+	assert.Equal(t, []graphBucket{{"Sheet1", -1, -1}}, formulaArea{"Sheet1", 1, 1, MaxColumns, TotalRows}.bucketsOf())
+}
+
+func TestRecalculateConcurrently(t *testing.T) {
+	prepare := func(workers int) *File {
+		f := NewFile(Options{MaxCalcIterations: 100, MaxCalcWorkers: workers})
+		_, err := f.NewSheet("Sheet2")
+		assert.NoError(t, err)
+		for r := 1; r <= 200; r++ {
+			assert.NoError(t, f.SetCellValue("Sheet1", "A"+strconv.Itoa(r), r))
+			assert.NoError(t, f.SetCellFormula("Sheet1", "B"+strconv.Itoa(r), "A"+strconv.Itoa(r)+"*2"))
+			assert.NoError(t, f.SetCellFormula("Sheet1", "C"+strconv.Itoa(r), "B"+strconv.Itoa(r)+"+SUM(B1:B10)"))
+			assert.NoError(t, f.SetCellFormula("Sheet2", "A"+strconv.Itoa(r), "Sheet1!C"+strconv.Itoa(r)+"+Sheet2!B"+strconv.Itoa(r)))
+			assert.NoError(t, f.SetCellFormula("Sheet2", "B"+strconv.Itoa(r), "IF(Sheet1!A"+strconv.Itoa(r)+">100,\"x\",Sheet1!B"+strconv.Itoa(r)+")"))
+		}
+		for cell, formula := range map[string]string{
+			"D1": "A1:A3*10",
+			"E1": "D2+1",
+			"E2": "SUM(D1#)",
+			"E3": "INDIRECT(\"Sheet2!A\"&A2)*2",
+			"E4": "E3+1",
+			"E5": "E6+1",
+			"E6": "E5+1",
+			"E7": "1/0",
+			"F1": "B2*2+Sheet2!A2",
+		} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+		}
+		// The formulas read the formula cells on the other worksheets
+		assert.NoError(t, f.SetCellFormula("Sheet2", "C1", "Sheet1!F1+Sheet1!A2"))
+		assert.NoError(t, f.RebuildCalcChain())
+		return f
+	}
+	values := func(f *File) [][][]string {
+		var result [][][]string
+		for _, sheet := range f.GetSheetList() {
+			rows, err := f.GetRows(sheet)
+			assert.NoError(t, err)
+			result = append(result, rows)
+		}
+		return result
+	}
+	expected := prepare(0)
+	assert.ErrorIs(t, expected.RecalculateAll(), ErrCircularReference)
+	for _, workers := range []int{2, 8} {
+		f := prepare(workers)
+		err := f.RecalculateAll()
+		assert.ErrorIs(t, err, ErrCircularReference)
+		assert.Equal(t, newCircularReferenceError("Sheet1!E5", "Sheet1!E6"), err)
+		assert.Equal(t, values(expected), values(f))
+		// Test recalculate again with the existing spill ranges
+		assert.NoError(t, f.SetCellValue("Sheet1", "A2", 7))
+		assert.NoError(t, expected.SetCellValue("Sheet1", "A2", 7))
+		assert.ErrorIs(t, f.RecalculateAll(), ErrCircularReference)
+		assert.ErrorIs(t, expected.RecalculateAll(), ErrCircularReference)
+		assert.Equal(t, values(expected), values(f))
+		for cell, expected := range map[string]string{"Sheet1!E1": "71", "Sheet1!F1": "176", "Sheet2!C1": "183"} {
+			ref := strings.Split(cell, "!")
+			value, err := f.GetCellValue(ref[0], ref[1])
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value, cell)
+		}
+		expected = prepare(0)
+		assert.ErrorIs(t, expected.RecalculateAll(), ErrCircularReference)
+	}
+	// Test recalculate concurrently with the invalid calculation chain
+	f := prepare(2)
+	f.CalcChain = nil
+	f.Pkg.Store(defaultXMLPathCalcChain, MacintoshCyrillicCharset)
+	assert.EqualError(t, f.recalculateConcurrently(context.Background(), 2), "XML syntax error on line 1: invalid UTF-8")
+}
+
+func TestRecalculateContext(t *testing.T) {
+	prepare := func(opts ...Options) *File {
+		f := NewFile(opts...)
+		assert.NoError(t, f.RegisterFormulaFunc("SLOW", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+			select {
+			case <-ctx.Context.Done():
+			case <-time.After(5 * time.Second):
+			}
+			return FormulaValue{Type: ArgNumber, Number: 1}, nil
+		}))
+		assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "B1", "A1+1"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", "SLOW()+A1"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "B1*2"))
+		assert.NoError(t, f.RebuildCalcChain())
+		return f
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{0, 2} {
+		f := prepare(Options{MaxCalcWorkers: workers, CalcTimeout: 50 * time.Millisecond})
+		start := time.Now()
+		err := f.RecalculateAllContext(context.Background())
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, newFormulaTimeoutError("Sheet1!C1"), err)
+		for cell, expected := range map[string]string{"B1": "2", "C1": "", "D1": "4"} {
+			value, err := f.GetCellValue("Sheet1", cell)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value, cell)
+		}
+		assert.Equal(t, newFormulaTimeoutError("Sheet1!C1"), f.RecalculateSheetContext(context.Background(), "Sheet1"))
+		assert.Equal(t, newFormulaTimeoutError("Sheet1!C1"),
+			f.BatchUpdateAndRecalculateContext(context.Background(), []CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 2}}))
+		value, err := f.GetCellValue("Sheet1", "D1")
+		assert.NoError(t, err)
+		assert.Equal(t, "6", value)
+
+		// Test recalculate with the canceled context, the cached values
+		// should not be changed
+		assert.NoError(t, f.SetCellValue("Sheet1", "A1", 3))
+		assert.ErrorIs(t, f.RecalculateAllContext(canceled), context.Canceled)
+		assert.ErrorIs(t, f.RecalculateSheetContext(canceled, "Sheet1"), context.Canceled)
+		assert.ErrorIs(t, f.BatchUpdateAndRecalculateContext(canceled, []CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 4}}), context.Canceled)
+		value, err = f.GetCellValue("Sheet1", "D1")
+		assert.NoError(t, err)
+		assert.Equal(t, "6", value)
+		assert.ErrorIs(t, f.recalculateConcurrently(canceled, 2), context.Canceled)
+	}
+	// Test the calculation is stopped when the deadline of the context is
+	// exceeded
+	f := prepare()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, f.RecalculateAllContext(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Test the default deadline of calculating a single formula in the
+	// recalculation
+	assert.Equal(t, 5*time.Second, f.calcTimeout())
+	assert.Equal(t, 5*time.Second, NewFile(Options{CalcTimeout: -1}).calcTimeout())
+	assert.Equal(t, 50*time.Millisecond, prepare(Options{CalcTimeout: 50 * time.Millisecond}).calcTimeout())
+
+	// Test the circular references and the formula calculation timeouts
+	// errors
+	var cellErrs calcCellErrors
+	assert.NoError(t, cellErrs.add("Sheet1", "A1", nil))
+	assert.NoError(t, cellErrs.err())
+	assert.NoError(t, cellErrs.add("Sheet1", "A1", newCircularReferenceError("Sheet1!A1")))
+	assert.NoError(t, cellErrs.add("Sheet1", "A2", newFormulaTimeoutError("Sheet1!A2")))
+	assert.Equal(t, ErrSheetNotExist{"SheetN"}, cellErrs.add("SheetN", "A1", ErrSheetNotExist{"SheetN"}))
+	err := cellErrs.err()
+	assert.ErrorIs(t, err, ErrCircularReference)
+	assert.ErrorIs(t, err, ErrFormulaTimeout)
+	assert.EqualError(t, err, "circular reference on cell Sheet1!A1\nformula calculation timed out on cell Sheet1!A2")
+}
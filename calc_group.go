@@ -0,0 +1,646 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// formulaGroupNode is a node in the hierarchy of the groups of the GROUPBY
+// and PIVOTBY functions. The root node contains all rows for the grand
+// total, and the leaf nodes are the distinct combinations of the field
+// values.
+type formulaGroupNode struct {
+	fields   []formulaArg
+	rows     []int
+	parent   *formulaGroupNode
+	children []*formulaGroupNode
+	index    map[string]*formulaGroupNode
+	results  map[int]formulaArg
+}
+
+// formulaGroupFields is the row or column fields of the GROUPBY and PIVOTBY
+// functions.
+type formulaGroupFields struct {
+	names  []formulaArg
+	values [][]formulaArg
+	keys   [][]string
+	total  int
+	sort   []int
+	table  bool
+}
+
+// formulaGroupArgs is the parsed arguments of the GROUPBY and PIVOTBY
+// functions.
+type formulaGroupArgs struct {
+	rows, cols  *formulaGroupFields
+	values      [][]formulaArg
+	valueNames  []formulaArg
+	lambda      *formulaLambda
+	filter      []int
+	showHeaders bool
+	relativeTo  int
+}
+
+// GROUPBY function groups the rows of the values by the row fields, and
+// aggregates each group with the given function, which could be a built-in
+// function name such as SUM or a LAMBDA function. The syntax of the function
+// is:
+//
+//	GROUPBY(row_fields,values,function,[field_headers],[total_depth],[sort_order],[filter_array],[field_relationship])
+func (fn *formulaFuncs) GROUPBY(argsList *list.List) formulaArg {
+	if argsList.Len() < 3 || argsList.Len() > 8 {
+		return newErrorFormulaArg(formulaErrorVALUE, "GROUPBY requires 3 to 8 arguments")
+	}
+	argv := groupFuncArgs(argsList, 8)
+	relationship, errArg := groupIntArg(argv[7], 0)
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	if relationship != 0 && relationship != 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	args, errArg := fn.prepareGroupArgs(argv[0], newEmptyFormulaArg(), argv[1], argv[2], argv[3], argv[6])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	if errArg = args.rows.setOptions(argv[4], argv[5], relationship == 1, len(args.values[0])); errArg.Type == ArgError {
+		return errArg
+	}
+	root := fn.groupTree(args.rows, args.filterRows())
+	if len(root.rows) == 0 {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	fn.sortGroupTree(args, args.rows, root)
+	var mtx [][]formulaArg
+	if args.showHeaders {
+		mtx = append(mtx, append(append([]formulaArg{}, args.rows.names...), args.valueNames...))
+	}
+	for _, node := range root.flatten(args.rows.total, nil) {
+		row := node.labels(len(args.rows.names))
+		for c := range args.valueNames {
+			row = append(row, fn.groupNodeResult(args, node, c))
+		}
+		mtx = append(mtx, row)
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// PIVOTBY function groups the rows of the values by the row fields and the
+// column fields, and aggregates each group with the given function, which
+// could be a built-in function name such as SUM or a LAMBDA function. The
+// syntax of the function is:
+//
+//	PIVOTBY(row_fields,col_fields,values,function,[field_headers],[row_total_depth],[row_sort_order],[col_total_depth],[col_sort_order],[filter_array],[relative_to])
+func (fn *formulaFuncs) PIVOTBY(argsList *list.List) formulaArg {
+	if argsList.Len() < 4 || argsList.Len() > 11 {
+		return newErrorFormulaArg(formulaErrorVALUE, "PIVOTBY requires 4 to 11 arguments")
+	}
+	argv := groupFuncArgs(argsList, 11)
+	args, errArg := fn.prepareGroupArgs(argv[0], argv[1], argv[2], argv[3], argv[4], argv[9])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	if args.cols == nil {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if args.relativeTo, errArg = groupIntArg(argv[10], 0); errArg.Type == ArgError {
+		return errArg
+	}
+	if args.relativeTo < 0 || args.relativeTo > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	m := len(args.values[0])
+	if errArg = args.rows.setOptions(argv[5], argv[6], false, m); errArg.Type == ArgError {
+		return errArg
+	}
+	if errArg = args.cols.setOptions(argv[7], argv[8], false, m); errArg.Type == ArgError {
+		return errArg
+	}
+	rows := args.filterRows()
+	rowRoot, colRoot := fn.groupTree(args.rows, rows), fn.groupTree(args.cols, rows)
+	if len(rowRoot.rows) == 0 {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	fn.sortGroupTree(args, args.rows, rowRoot)
+	fn.sortGroupTree(args, args.cols, colRoot)
+	rowNodes, colNodes := rowRoot.flatten(args.rows.total, nil), colRoot.flatten(args.cols.total, nil)
+	kr, kc := len(args.rows.names), len(args.cols.names)
+	var mtx [][]formulaArg
+	for level := 0; level < kc; level++ {
+		row := make([]formulaArg, kr, kr+len(colNodes)*m)
+		for _, colNode := range colNodes {
+			label := colNode.labels(kc)[level]
+			row = append(row, label)
+			for c := 1; c < m; c++ {
+				row = append(row, newStringFormulaArg(""))
+			}
+		}
+		mtx = append(mtx, row)
+	}
+	if m > 1 {
+		row := make([]formulaArg, kr, kr+len(colNodes)*m)
+		for range colNodes {
+			row = append(row, args.valueNames...)
+		}
+		mtx = append(mtx, row)
+	}
+	for c := range kr {
+		mtx[len(mtx)-1][c] = newStringFormulaArg("")
+		if args.showHeaders {
+			mtx[len(mtx)-1][c] = args.rows.names[c]
+		}
+		for r := 0; r < len(mtx)-1; r++ {
+			mtx[r][c] = newStringFormulaArg("")
+		}
+	}
+	masks := make([][]bool, len(colNodes))
+	for i, colNode := range colNodes {
+		masks[i] = make([]bool, len(args.values))
+		for _, r := range colNode.rows {
+			masks[i][r] = true
+		}
+	}
+	for _, rowNode := range rowNodes {
+		row := rowNode.labels(kr)
+		for i, colNode := range colNodes {
+			subset := intersectGroupRows(rowNode.rows, masks[i])
+			for c := range m {
+				if len(subset) == 0 {
+					row = append(row, newStringFormulaArg(""))
+					continue
+				}
+				row = append(row, fn.groupResult(args, c, subset, fn.pivotRelativeRows(args, rowNode, colNode, rows)))
+			}
+		}
+		mtx = append(mtx, row)
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// PERCENTOF function returns the percentage that a subset makes up of a given
+// data set. The syntax of the function is:
+//
+//	PERCENTOF(data_subset,data_all)
+func (fn *formulaFuncs) PERCENTOF(argsList *list.List) formulaArg {
+	if argsList.Len() != 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "PERCENTOF requires 2 arguments")
+	}
+	subsetArgs, allArgs := list.New(), list.New()
+	subsetArgs.PushBack(argsList.Front().Value.(formulaArg))
+	allArgs.PushBack(argsList.Back().Value.(formulaArg))
+	subset := fn.SUM(subsetArgs)
+	if subset.Type != ArgNumber {
+		return subset
+	}
+	all := fn.SUM(allArgs)
+	if all.Type != ArgNumber {
+		return all
+	}
+	if all.Number == 0 {
+		return newErrorFormulaArg(formulaErrorDIV, formulaErrorDIV)
+	}
+	return newNumberFormulaArg(subset.Number / all.Number)
+}
+
+// groupFuncArgs returns the arguments of the GROUPBY and PIVOTBY functions,
+// the omitted arguments will be filled with the empty value.
+func groupFuncArgs(argsList *list.List, size int) []formulaArg {
+	argv := make([]formulaArg, 0, size)
+	for arg := argsList.Front(); arg != nil; arg = arg.Next() {
+		argv = append(argv, arg.Value.(formulaArg))
+	}
+	for len(argv) < size {
+		argv = append(argv, newEmptyFormulaArg())
+	}
+	return argv
+}
+
+// groupIntArg returns the integer value of the optional argument, the default
+// value will be returned if the argument is omitted.
+func groupIntArg(arg formulaArg, defaultValue int) (int, formulaArg) {
+	if arg.Type == ArgEmpty {
+		return defaultValue, newNumberFormulaArg(float64(defaultValue))
+	}
+	return arrayIntArg(arg)
+}
+
+// hasGroupHeaders returns if the first row of the values is the headers,
+// which is detected by the text values in the first row and the numeric
+// values in the second row.
+func hasGroupHeaders(values [][]formulaArg) bool {
+	if len(values) < 2 {
+		return false
+	}
+	for _, value := range values[0] {
+		if value.Type != ArgString || value.Value() == "" {
+			return false
+		}
+	}
+	for _, value := range values[1] {
+		if value.Type == ArgNumber && !value.Boolean {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareGroupArgs parse the common arguments of the GROUPBY and PIVOTBY
+// functions, the column fields will be nil if the colFields is empty.
+func (fn *formulaFuncs) prepareGroupArgs(rowFields, colFields, values, function, fieldHeaders, filter formulaArg) (*formulaGroupArgs, formulaArg) {
+	for _, arg := range []formulaArg{rowFields, colFields, values, function, filter} {
+		if arg.Type == ArgError {
+			return nil, arg
+		}
+	}
+	if function.Type != ArgLambda || function.lambda == nil {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if params := function.lambda.params; function.lambda.funcName == "" && len(params) != 1 && len(params) != 2 {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	args := &formulaGroupArgs{lambda: function.lambda}
+	valuesMtx := arrayArgMatrix(values)
+	if len(valuesMtx) == 0 || len(valuesMtx[0]) == 0 {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	headers, errArg := groupIntArg(fieldHeaders, -1)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	if headers < -1 || headers > 3 {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if headers == -1 {
+		if headers = 0; hasGroupHeaders(valuesMtx) {
+			headers = 1
+		}
+	}
+	hasHeaders := headers == 1 || headers == 3
+	args.showHeaders = headers == 2 || headers == 3
+	size := len(valuesMtx)
+	if hasHeaders {
+		args.valueNames, valuesMtx = valuesMtx[0], valuesMtx[1:]
+	} else {
+		for c := range valuesMtx[0] {
+			args.valueNames = append(args.valueNames, newStringFormulaArg(fmt.Sprintf("Value %d", c+1)))
+		}
+	}
+	args.values = valuesMtx
+	if args.rows, errArg = fn.prepareGroupFields(rowFields, "Row Field", size, hasHeaders); errArg.Type == ArgError {
+		return nil, errArg
+	}
+	if colFields.Type != ArgEmpty {
+		if args.cols, errArg = fn.prepareGroupFields(colFields, "Col Field", size, hasHeaders); errArg.Type == ArgError {
+			return nil, errArg
+		}
+	}
+	if filter.Type != ArgEmpty {
+		if errArg = args.setFilter(filter, size, hasHeaders); errArg.Type == ArgError {
+			return nil, errArg
+		}
+	}
+	return args, newEmptyFormulaArg()
+}
+
+// prepareGroupFields parse the row or column fields of the GROUPBY and
+// PIVOTBY functions, and returns the group keys of each row. The value index
+// of the fields will be shared with the criteria functions such as SUMIFS, and
+// the group keys of the fields in the cell ranges will be cached.
+func (fn *formulaFuncs) prepareGroupFields(arg formulaArg, prefix string, size int, hasHeaders bool) (*formulaGroupFields, formulaArg) {
+	mtx := arrayArgMatrix(arg)
+	if len(mtx) != size || len(mtx[0]) == 0 {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	fields := &formulaGroupFields{values: mtx}
+	if hasHeaders {
+		fields.names, fields.values = mtx[0], mtx[1:]
+	} else {
+		for c := range mtx[0] {
+			fields.names = append(fields.names, newStringFormulaArg(fmt.Sprintf("%s %d", prefix, c+1)))
+		}
+	}
+	indexKeys := make([]string, len(fields.names))
+	if cr, ok := formulaArgCellRange(arg); ok {
+		for c := range indexKeys {
+			col := cellRange{From: cr.From, To: cr.To}
+			col.From.Col, col.To.Col = cr.From.Col+c, cr.From.Col+c
+			if hasHeaders {
+				col.From.Row++
+			}
+			indexKeys[c] = cellRangeIndexKey(col)
+		}
+	}
+	cacheKey := "GROUPBY|" + strings.Join(indexKeys, "|")
+	if indexKeys[0] != "" {
+		if cached, ok := fn.f.ifsMatchCache.Load(cacheKey); ok {
+			if keys, ok := cached.([][]string); ok && len(keys) == len(fields.values) {
+				fields.keys = keys
+				return fields, newEmptyFormulaArg()
+			}
+		}
+	}
+	fields.keys = make([][]string, len(fields.values))
+	for r := range fields.keys {
+		fields.keys[r] = make([]string, len(fields.names))
+	}
+	for c, indexKey := range indexKeys {
+		column := make([][]formulaArg, len(fields.values))
+		for r, row := range fields.values {
+			column[r] = row[c : c+1]
+		}
+		for value, refs := range fn.rangeValueIndex(indexKey, column, fn.argReads(arg)) {
+			key := strings.ToLower(value)
+			for _, ref := range refs {
+				fields.keys[ref.Row][c] = key
+			}
+		}
+	}
+	if reads := fn.argReads(arg); indexKeys[0] != "" && reads != nil {
+		fn.f.ifsMatchCache.StoreReads(cacheKey, fields.keys, reads)
+	}
+	return fields, newEmptyFormulaArg()
+}
+
+// setFilter parse the filter array of the GROUPBY and PIVOTBY functions, the
+// rows will be excluded if the corresponding values in the filter are FALSE.
+func (args *formulaGroupArgs) setFilter(filter formulaArg, size int, hasHeaders bool) formulaArg {
+	var values []formulaArg
+	for _, row := range arrayArgMatrix(filter) {
+		values = append(values, row...)
+	}
+	if len(values) != size {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if hasHeaders {
+		values = values[1:]
+	}
+	var rows []int
+	for r, value := range values {
+		if value.Type == ArgError {
+			return value
+		}
+		if b := value.ToBool(); b.Type == ArgNumber && b.Number != 0 {
+			rows = append(rows, r)
+		}
+	}
+	if rows == nil {
+		rows = []int{}
+	}
+	args.filter = rows
+	return newEmptyFormulaArg()
+}
+
+// filterRows returns the indexes of the data rows which should be grouped.
+func (args *formulaGroupArgs) filterRows() []int {
+	if args.filter != nil {
+		return args.filter
+	}
+	rows := make([]int, len(args.values))
+	for r := range rows {
+		rows[r] = r
+	}
+	return rows
+}
+
+// setOptions parse the total depth and sort order of the row or column fields,
+// the total depth should be -1, 0 or 1 if the fields are related as a table.
+func (fields *formulaGroupFields) setOptions(total, order formulaArg, table bool, values int) formulaArg {
+	var errArg formulaArg
+	if fields.total, errArg = groupIntArg(total, 1); errArg.Type == ArgError {
+		return errArg
+	}
+	if fields.table = table; table && (fields.total > 1 || fields.total < -1) {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if order.Type == ArgEmpty {
+		return newEmptyFormulaArg()
+	}
+	for _, row := range arrayArgMatrix(order) {
+		for _, arg := range row {
+			idx, errArg := arrayIntArg(arg)
+			if errArg.Type != ArgNumber {
+				return errArg
+			}
+			if idx == 0 || idx > len(fields.names)+values || -idx > len(fields.names)+values {
+				return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+			}
+			fields.sort = append(fields.sort, idx)
+		}
+	}
+	return newEmptyFormulaArg()
+}
+
+// groupTree returns the hierarchy of the groups by given fields and the
+// indexes of the data rows, the groups will be in one level if the fields
+// are related as a table.
+func (fn *formulaFuncs) groupTree(fields *formulaGroupFields, rows []int) *formulaGroupNode {
+	root := &formulaGroupNode{}
+	for _, r := range rows {
+		root.rows = append(root.rows, r)
+		node, keys := root, fields.keys[r]
+		if fields.table {
+			keys = []string{strings.Join(keys, "\x00")}
+		}
+		for level, key := range keys {
+			child, ok := node.index[key]
+			if !ok {
+				child = &formulaGroupNode{fields: append(node.fields[:level:level], fields.values[r][level]), parent: node}
+				if fields.table {
+					child.fields = fields.values[r]
+				}
+				if node.index == nil {
+					node.index = make(map[string]*formulaGroupNode)
+				}
+				node.index[key] = child
+				node.children = append(node.children, child)
+			}
+			child.rows = append(child.rows, r)
+			node = child
+		}
+	}
+	return root
+}
+
+// sortGroupTree sorts the groups in each level of the hierarchy by the sort
+// order of the fields. The sort order refers to the fields followed by the
+// values, and the groups will be sorted by the aggregated values for the
+// sort order which refers to the values. The groups in each level are sorted
+// by the field values in ascending order by default.
+func (fn *formulaFuncs) sortGroupTree(args *formulaGroupArgs, fields *formulaGroupFields, node *formulaGroupNode) {
+	k := len(fields.names)
+	compare := func(a, b *formulaGroupNode, idx int) int {
+		if idx >= k {
+			return fn.compareFormulaArgs(fn.groupNodeResult(args, a, idx-k), fn.groupNodeResult(args, b, idx-k))
+		}
+		if level := len(a.fields) - 1; fields.table || idx == level {
+			return fn.compareFormulaArgs(a.fields[idx], b.fields[idx])
+		}
+		return 0
+	}
+	sort.SliceStable(node.children, func(i, j int) bool {
+		a, b := node.children[i], node.children[j]
+		for _, idx := range fields.sort {
+			order := 1
+			if idx < 0 {
+				idx, order = -idx, -1
+			}
+			if cmp := compare(a, b, idx-1); cmp != 0 {
+				return cmp*order < 0
+			}
+		}
+		for idx := range a.fields {
+			if cmp := compare(a, b, idx); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	for _, child := range node.children {
+		fn.sortGroupTree(args, fields, child)
+	}
+}
+
+// flatten returns the groups in the output order by given total depth. The
+// grand total will be returned if the absolute value of the depth is not
+// less than 1, and the subtotals of the groups in the level n will be
+// returned if it's greater than n. The totals will be placed at the top of
+// the groups if the depth is negative, otherwise at the bottom.
+func (node *formulaGroupNode) flatten(depth int, nodes []*formulaGroupNode) []*formulaGroupNode {
+	if node.parent != nil && len(node.children) == 0 {
+		return append(nodes, node)
+	}
+	total := depth > len(node.fields) || -depth > len(node.fields)
+	if total && depth < 0 {
+		nodes = append(nodes, node)
+	}
+	for _, child := range node.children {
+		nodes = child.flatten(depth, nodes)
+	}
+	if total && depth > 0 {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// labels returns the field values of the group by given number of fields,
+// the label of the grand total is "Total".
+func (node *formulaGroupNode) labels(size int) []formulaArg {
+	labels := make([]formulaArg, size, size+1)
+	for i := range labels {
+		labels[i] = newStringFormulaArg("")
+		if i < len(node.fields) {
+			labels[i] = node.fields[i]
+		}
+	}
+	if node.parent == nil && size > 0 {
+		labels[0] = newStringFormulaArg("Total")
+	}
+	return labels
+}
+
+// intersectGroupRows returns the rows which exist in the mask.
+func intersectGroupRows(rows []int, mask []bool) []int {
+	var result []int
+	for _, r := range rows {
+		if mask[r] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// pivotRelativeRows returns the rows of the data set which the function with
+// two parameters, such as the PERCENTOF, are relative to by given row and
+// column groups of the PIVOTBY function.
+func (fn *formulaFuncs) pivotRelativeRows(args *formulaGroupArgs, rowNode, colNode *formulaGroupNode, rows []int) []int {
+	if !args.relativeRows() {
+		return nil
+	}
+	mask := func(node *formulaGroupNode) []bool {
+		m := make([]bool, len(args.values))
+		for _, r := range node.rows {
+			m[r] = true
+		}
+		return m
+	}
+	switch args.relativeTo {
+	case 1:
+		return rowNode.rows
+	case 2:
+		return rows
+	case 3:
+		if colNode.parent != nil {
+			return intersectGroupRows(rowNode.rows, mask(colNode.parent))
+		}
+		return intersectGroupRows(rowNode.rows, mask(colNode))
+	case 4:
+		if rowNode.parent != nil {
+			return intersectGroupRows(colNode.rows, mask(rowNode.parent))
+		}
+		return intersectGroupRows(colNode.rows, mask(rowNode))
+	default:
+		return colNode.rows
+	}
+}
+
+// relativeRows returns if the function takes the values of the data set the
+// group is relative to as the second argument.
+func (args *formulaGroupArgs) relativeRows() bool {
+	if args.lambda.funcName != "" {
+		return args.lambda.funcName == "PERCENTOF"
+	}
+	return len(args.lambda.params) == 2
+}
+
+// groupNodeResult returns the aggregated value of the given value column in
+// the group, the values in the group are relative to the grand total for
+// the function with two parameters.
+func (fn *formulaFuncs) groupNodeResult(args *formulaGroupArgs, node *formulaGroupNode, c int) formulaArg {
+	if result, ok := node.results[c]; ok {
+		return result
+	}
+	var all []int
+	if args.relativeRows() {
+		root := node
+		for root.parent != nil {
+			root = root.parent
+		}
+		all = root.rows
+	}
+	result := fn.groupResult(args, c, node.rows, all)
+	if node.results == nil {
+		node.results = make(map[int]formulaArg)
+	}
+	node.results[c] = result
+	return result
+}
+
+// groupResult returns the result of the function by given value column and
+// the rows of the group, the values of the rows in all will be passed as the
+// second argument for the function with two parameters.
+func (fn *formulaFuncs) groupResult(args *formulaGroupArgs, c int, rows, all []int) formulaArg {
+	column := func(rows []int) formulaArg {
+		mtx := make([][]formulaArg, len(rows))
+		for i, r := range rows {
+			mtx[i] = []formulaArg{args.values[r][c]}
+		}
+		return newMatrixFormulaArg(mtx)
+	}
+	if args.relativeRows() {
+		return fn.callLambdaValue(args.lambda, column(rows), column(all))
+	}
+	return fn.callLambdaValue(args.lambda, column(rows))
+}
@@ -0,0 +1,116 @@
+package excelize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcGroupByPivotBy(t *testing.T) {
+	cellData := [][]interface{}{
+		{"Region", "Product", "Sales", "Units"},
+		{"East", "A", 10, 1},
+		{"West", "B", 20, 2},
+		{"East", "B", 30, 3},
+		{"west", "A", 40, 4},
+		{"East", "A", 50, 5},
+	}
+	f := prepareCalcData(cellData)
+	formulaList := map[string]string{
+		// GROUPBY
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM))":                           "East,90,West,60,Total,150",
+		"TEXTJOIN(\",\",FALSE,_xlfn.GROUPBY(A2:A6,C2:C6,_xleta.SUM))":              "East,90,West,60,Total,150",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A1:A6,C1:C6,SUM))":                           "East,90,West,60,Total,150",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A1:A6,C1:C6,SUM,3,0))":                       "Region,Sales,East,90,West,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM,2,0))":                       "Row Field 1,Value 1,East,90,West,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM,0,-1))":                      "Total,150,East,90,West,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM,0,0,-1))":                    "West,60,East,90",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM,0,0,2))":                     "West,60,East,90",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,SUM,0,0,-2))":                    "East,90,West,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,LAMBDA(x,MAX(x)),0,0))":          "East,50,West,40",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:D6,SUM,0,0))":                       "East,90,9,West,60,6",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,COUNT,0,0,,C2:C6>15))":           "East,2,West,2",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,PERCENTOF,0,0))":                 "East,0.6,West,0.4",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:A6,C2:C6,LAMBDA(x,y,SUM(x)/SUM(y)),0,0))": "East,0.6,West,0.4",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:B6,C2:C6,SUM,0,2))":                       "East,A,60,East,B,30,East,,90,West,A,40,West,B,20,West,,60,Total,,150",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:B6,C2:C6,SUM,0,-2))":                      "Total,,150,East,,90,East,A,60,East,B,30,West,,60,West,A,40,West,B,20",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:B6,C2:C6,SUM,0,0,{3,1}))":                 "West,B,20,West,A,40,East,B,30,East,A,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(A2:B6,C2:C6,SUM,0,0,3,,1))":                  "West,B,20,East,B,30,west,A,40,East,A,60",
+		"TEXTJOIN(\",\",FALSE,GROUPBY(B2:B6,A2:A6,ARRAYTOTEXT,0,0))":               "A,East, west, East,B,West, East",
+		"TEXTJOIN(\",\",FALSE,BYROW(C2:D3,SUM))":                                   "11,22",
+		"TEXTJOIN(\",\",FALSE,MAP(C2:C3,_xleta.SQRT))":                             "3.1622776601683795,4.47213595499958",
+		"PERCENTOF(1,4)":         "0.25",
+		"PERCENTOF(C2:C3,C2:C6)": "0.2",
+		// PIVOTBY
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,SUM))":                                  ",A,B,Total,East,60,30,90,West,40,20,60,Total,100,50,150",
+		"TEXTJOIN(\",\",FALSE,_xlfn.PIVOTBY(A2:A6,B2:B6,C2:C6,_xleta.SUM,0,0,,-1))":             ",Total,A,B,East,90,60,30,West,60,40,20",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A1:A6,B1:B6,C1:C6,SUM,3,0,,0))":                           "Region,A,B,East,60,30,West,40,20",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,0,,0,-1))":                        ",B,A,East,30,60,West,20,40",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,0,,0,2))":                         ",B,A,East,30,60,West,20,40",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,0,,0,,C2:C6<>20))":                ",A,B,East,60,30,west,40,",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:D6,SUM,0,0,,0))":                           ",A,,B,,,Value 1,Value 2,Value 1,Value 2,East,60,6,30,3,West,40,4,20,2",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,PERCENTOF,0,0,,0,,,2))":                 ",A,B,East,0.4,0.2,West,0.26666666666666666,0.13333333333333333",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,PERCENTOF,0,0,,0,,,1))":                 ",A,B,East,0.6666666666666666,0.3333333333333333,West,0.6666666666666666,0.3333333333333333",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,PERCENTOF,0,1,,1,,,0))":                 ",A,B,Total,East,0.6,0.6,0.6,West,0.4,0.4,0.4,Total,1,1,1",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,LAMBDA(x,y,SUM(x)/SUM(y)),0,0,,0,,,3))": ",A,B,East,0.6666666666666666,0.3333333333333333,West,0.6666666666666666,0.3333333333333333",
+		"TEXTJOIN(\",\",FALSE,PIVOTBY(A2:A6,B2:B6,C2:C6,PERCENTOF,0,0,,0,,,4))":                 ",A,B,East,0.6,0.6,West,0.4,0.4",
+	}
+	for formula, expected := range formulaList {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", formula))
+		result, err := f.CalcCellValue("Sheet1", "F1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	calcError := map[string][]string{
+		"GROUPBY(A2:A6,C2:C6)":                             {"#VALUE!", "GROUPBY requires 3 to 8 arguments"},
+		"GROUPBY(A2:A6,C2:C5,SUM)":                         {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,1)":                           {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,LAMBDA(x,y,z,x))":             {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,SUM,4)":                       {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,SUM,\"\")":                    {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,\"\")":                  {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,3)":                   {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,\"\")":                {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,,C2:C5>1)":            {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,,C2:C6>100)":          {"#CALC!", "#CALC!"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,,NA())":               {"#N/A", "#N/A"},
+		"GROUPBY(A2:A6,C2:C6,SUM,0,1,,C2:C6/0)":            {"#DIV/0!", "#DIV/0!"},
+		"GROUPBY(A2:B6,C2:C6,SUM,0,2,,,1)":                 {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:B6,C2:C6,SUM,0,1,,,2)":                 {"#VALUE!", "#VALUE!"},
+		"GROUPBY(A2:B6,C2:C6,SUM,0,1,,,\"\")":              {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"GROUPBY(NA(),C2:C6,SUM)":                          {"#N/A", "#N/A"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6)":                       {"#VALUE!", "PIVOTBY requires 4 to 11 arguments"},
+		"PIVOTBY(A2:A6,B2:B5,C2:C6,SUM)":                   {"#VALUE!", "#VALUE!"},
+		"PIVOTBY(A2:A6,,C2:C6,SUM)":                        {"#VALUE!", "#VALUE!"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,1,,1,,,5)":        {"#VALUE!", "#VALUE!"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,1,,1,,,\"\")":     {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,1,3)":             {"#VALUE!", "#VALUE!"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,1,,1,3)":          {"#VALUE!", "#VALUE!"},
+		"PIVOTBY(A2:A6,B2:B6,C2:C6,SUM,0,1,,1,,C2:C6>100)": {"#CALC!", "#CALC!"},
+		"PERCENTOF(1)":                                     {"#VALUE!", "PERCENTOF requires 2 arguments"},
+		"PERCENTOF(1,0)":                                   {"#DIV/0!", "#DIV/0!"},
+		"PERCENTOF(NA(),1)":                                {"#N/A", "#N/A"},
+		"PERCENTOF(1,NA())":                                {"#N/A", "#N/A"},
+	}
+	for formula, expected := range calcError {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", formula))
+		result, err := f.CalcCellValue("Sheet1", "F1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+
+	// Test group keys are cached for the cell ranges
+	f = prepareCalcData(cellData)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "GROUPBY(A2:A6,C2:C6,SUM,0,0)"))
+	result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "East", result)
+	_, ok := f.ifsMatchCache.Load("GROUPBY|Sheet1:2:1-6:1")
+	assert.True(t, ok)
+	_, ok = f.rangeIndexCache.Load("Sheet1:2:1-6:1")
+	assert.True(t, ok)
+	result, err = f.GetCellValue("Sheet1", "G2")
+	assert.NoError(t, err)
+	assert.Equal(t, "60", result)
+}
@@ -0,0 +1,433 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ifsKind is the kind of the criteria-aggregation functions, the functions of
+// the same kind share the same rules of matching the criteria and reading
+// the aggregated values.
+type ifsKind byte
+
+// Criteria-aggregation function kinds enumeration.
+const (
+	// ifsKindIfs is the kind of the AVERAGEIFS, COUNTIFS, MAXIFS, MINIFS
+	// and SUMIFS functions.
+	ifsKindIfs ifsKind = iota
+	// ifsKindSumIf is the kind of the SUMIF function.
+	ifsKindSumIf
+	// ifsKindCountIf is the kind of the COUNTIF function.
+	ifsKindCountIf
+	// ifsKindAverageIf is the kind of the AVERAGEIF function.
+	ifsKindAverageIf
+)
+
+// ifsAggregate is the aggregation of the values in the cells which match the
+// criteria, the count is the number of the matched cells, and the sum,
+// numbers, max and min are the aggregations of the numeric values.
+type ifsAggregate struct {
+	positions []int
+	count     int
+	numbers   int
+	sum       float64
+	max, min  float64
+}
+
+// ifsColumn is the distinct values of a criteria range, and the cached
+// results of matching the distinct values with the criteria.
+type ifsColumn struct {
+	index   map[string]int
+	values  []formulaArg
+	matches sync.Map
+}
+
+// ifsTable is the criteria-aggregation table of the formula functions which
+// share the same ranges. The cells in the ranges are grouped by the distinct
+// value combinations of the criteria ranges in a single scan, so that each
+// function call only needs to match the criteria with the distinct values
+// instead of scanning all cells of the ranges.
+type ifsTable struct {
+	kind    ifsKind
+	columns []*ifsColumn
+	groups  map[string]*ifsAggregate
+	ids     map[*ifsAggregate][]int
+	order   []*ifsAggregate
+	values  []float64
+	numeric []bool
+}
+
+// ifsValueKey returns the key of the distinct value of a criteria range, the
+// numbers are identified by the bits of the value instead of the formatted
+// text, which may be rounded.
+func ifsValueKey(arg formulaArg) string {
+	if arg.Type == ArgNumber {
+		key := strconv.FormatUint(math.Float64bits(arg.Number), 16)
+		if arg.Boolean {
+			return "b" + key
+		}
+		return "n" + key
+	}
+	return string(rune('0'+arg.Type)) + arg.String + "\x00" + arg.Error
+}
+
+// id returns the index of the distinct value in the criteria range, and adds
+// the value if it doesn't exist.
+func (col *ifsColumn) id(arg formulaArg) int {
+	key := ifsValueKey(arg)
+	if id, ok := col.index[key]; ok {
+		return id
+	}
+	id := len(col.values)
+	col.index[key] = id
+	col.values = append(col.values, arg)
+	return id
+}
+
+// match returns the indexes of the distinct values which match the given
+// criteria by the rules of the function kind, the results will be cached by
+// the text of the criteria.
+func (col *ifsColumn) match(kind ifsKind, exp formulaArg) map[int]bool {
+	key := exp.Value()
+	if cached, ok := col.matches.Load(key); ok {
+		return cached.(map[int]bool)
+	}
+	criteria, ids := formulaCriteriaParser(exp), make(map[int]bool)
+	for id, val := range col.values {
+		if ifsCriteriaMatch(kind, val, criteria) {
+			ids[id] = true
+		}
+	}
+	col.matches.Store(key, ids)
+	return ids
+}
+
+// ifsCriteriaMatch returns if the cell value matches the criteria, which
+// follows the rules of the formula functions by given function kind.
+func ifsCriteriaMatch(kind ifsKind, val formulaArg, criteria *formulaCriteria) bool {
+	switch kind {
+	case ifsKindIfs:
+		if criteria.Type == criteriaEq {
+			v := val.Value()
+			return v != "" && v == criteria.Condition.Value()
+		}
+	case ifsKindSumIf:
+		if val.Type == ArgEmpty {
+			return false
+		}
+	case ifsKindCountIf:
+		if val.Type == ArgString && criteria.Condition.Type != ArgString {
+			return false
+		}
+	case ifsKindAverageIf:
+		if val.Value() == "" || val.Type == ArgString && criteria.Condition.Type != ArgString {
+			return false
+		}
+	}
+	ok, _ := formulaCriteriaEval(val, criteria)
+	return ok
+}
+
+// ifsNumber returns the numeric value to be aggregated by given criteria
+// range cell and the value range cell, which follows the rules of the
+// formula functions by given function kind.
+func ifsNumber(kind ifsKind, cell formulaArg, value *formulaArg) (float64, bool) {
+	switch kind {
+	case ifsKindIfs:
+		if value != nil {
+			if num := value.ToNumber(); num.Type == ArgNumber {
+				return num.Number, true
+			}
+		}
+	case ifsKindSumIf:
+		if value != nil {
+			cell = *value
+		}
+		if cell.Type == ArgNumber {
+			return cell.Number, true
+		}
+	case ifsKindAverageIf:
+		fromVal := cell.Value()
+		if value != nil {
+			fromVal = value.Value()
+		}
+		if num, err := strconv.ParseFloat(fromVal, 64); err == nil {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// newIfsAggregate returns an empty aggregation.
+func newIfsAggregate() *ifsAggregate {
+	return &ifsAggregate{max: -math.MaxFloat64, min: math.MaxFloat64}
+}
+
+// average returns the average of the numeric values in the aggregation, or
+// the #DIV/0! error with the given message if there are no numeric values.
+func (agg *ifsAggregate) average(msg string) formulaArg {
+	if agg.numbers == 0 {
+		return newErrorFormulaArg(formulaErrorDIV, msg)
+	}
+	return newNumberFormulaArg(agg.sum / float64(agg.numbers))
+}
+
+// maxValue returns the maximum numeric value in the aggregation, or 0 if
+// there are no numeric values.
+func (agg *ifsAggregate) maxValue() formulaArg {
+	if agg.max == -math.MaxFloat64 {
+		return newNumberFormulaArg(0)
+	}
+	return newNumberFormulaArg(agg.max)
+}
+
+// minValue returns the minimum numeric value in the aggregation, or 0 if
+// there are no numeric values.
+func (agg *ifsAggregate) minValue() formulaArg {
+	if agg.min == math.MaxFloat64 {
+		return newNumberFormulaArg(0)
+	}
+	return newNumberFormulaArg(agg.min)
+}
+
+// newIfsTable scans the given ranges once and creates the criteria-aggregation
+// table, returns nil if the ranges are empty or not in the same size.
+func newIfsTable(kind ifsKind, criteriaRanges [][][]formulaArg, valueRange [][]formulaArg) *ifsTable {
+	rows := len(criteriaRanges[0])
+	if rows == 0 {
+		return nil
+	}
+	cols := len(criteriaRanges[0][0])
+	ranges := criteriaRanges
+	if valueRange != nil {
+		ranges = append(ranges[:len(ranges):len(ranges)], valueRange)
+	}
+	for _, mtx := range ranges {
+		if len(mtx) != rows {
+			return nil
+		}
+		for _, row := range mtx {
+			if len(row) != cols {
+				return nil
+			}
+		}
+	}
+	t := &ifsTable{
+		kind: kind, columns: make([]*ifsColumn, len(criteriaRanges)),
+		groups: make(map[string]*ifsAggregate), ids: make(map[*ifsAggregate][]int),
+		values: make([]float64, rows*cols), numeric: make([]bool, rows*cols),
+	}
+	for i := range t.columns {
+		t.columns[i] = &ifsColumn{index: make(map[string]int)}
+	}
+	var key []byte
+	ids := make([]int, len(criteriaRanges))
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			key = key[:0]
+			for i, mtx := range criteriaRanges {
+				ids[i] = t.columns[i].id(mtx[r][c])
+				key = binary.AppendUvarint(key, uint64(ids[i]))
+			}
+			agg, ok := t.groups[string(key)]
+			if !ok {
+				agg = newIfsAggregate()
+				t.groups[string(key)] = agg
+				t.ids[agg] = append([]int(nil), ids...)
+				t.order = append(t.order, agg)
+			}
+			pos := r*cols + c
+			agg.positions = append(agg.positions, pos)
+			agg.count++
+			var value *formulaArg
+			if valueRange != nil {
+				value = &valueRange[r][c]
+			}
+			if num, ok := ifsNumber(kind, criteriaRanges[0][r][c], value); ok {
+				t.values[pos], t.numeric[pos] = num, true
+				agg.numbers++
+				agg.sum += num
+				if agg.max < num {
+					agg.max = num
+				}
+				if agg.min > num {
+					agg.min = num
+				}
+			}
+		}
+	}
+	return t
+}
+
+// aggregate returns the aggregation of the cells which match all criteria.
+// The numbers are summed up in the order of the cells in the ranges, to get
+// the same result as scanning the ranges cell by cell.
+func (t *ifsTable) aggregate(criteria []formulaArg) *ifsAggregate {
+	matches, single := make([]map[int]bool, len(criteria)), true
+	for i, exp := range criteria {
+		if matches[i] = t.columns[i].match(t.kind, exp); len(matches[i]) == 0 {
+			return newIfsAggregate()
+		}
+		single = single && len(matches[i]) == 1
+	}
+	if single {
+		// Each criteria matches only one distinct value, such as the equal
+		// criteria, find the cells by the value combination directly
+		var key []byte
+		for _, ids := range matches {
+			for id := range ids {
+				key = binary.AppendUvarint(key, uint64(id))
+			}
+		}
+		if agg, ok := t.groups[string(key)]; ok {
+			return agg
+		}
+		return newIfsAggregate()
+	}
+	var matched []*ifsAggregate
+	for _, agg := range t.order {
+		ok := true
+		for i, id := range t.ids[agg] {
+			if ok = matches[i][id]; !ok {
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, agg)
+		}
+	}
+	if len(matched) == 1 {
+		return matched[0]
+	}
+	result := newIfsAggregate()
+	for _, agg := range matched {
+		result.positions = append(result.positions, agg.positions...)
+		result.count += agg.count
+		result.numbers += agg.numbers
+		if result.max < agg.max {
+			result.max = agg.max
+		}
+		if result.min > agg.min {
+			result.min = agg.min
+		}
+	}
+	sort.Ints(result.positions)
+	for _, pos := range result.positions {
+		if t.numeric[pos] {
+			result.sum += t.values[pos]
+		}
+	}
+	return result
+}
+
+// ifsAggregate returns the aggregation of the criteria-aggregation function
+// by the table shared by the function calls which refer to the same ranges.
+// The table is created on the second call with the same ranges and stored in
+// the range cache, so it will be dropped once the cells were changed. It
+// returns false if the arguments are not supported, such as the ranges are
+// not the cell ranges in the same worksheet, or the ranges are not in the
+// same size, and the function should scan the ranges by itself.
+func (fn *formulaFuncs) ifsAggregate(kind ifsKind, argsList *list.List) (*ifsAggregate, bool) {
+	var (
+		args       []formulaArg
+		valueRange *formulaArg
+	)
+	for arg := argsList.Front(); arg != nil; arg = arg.Next() {
+		args = append(args, arg.Value.(formulaArg))
+	}
+	switch {
+	case kind == ifsKindIfs && len(args)%2 == 1:
+		valueRange, args = &args[0], args[1:]
+	case kind != ifsKindIfs && len(args) == 3:
+		valueRange, args = &args[2], args[:2]
+	}
+	var (
+		sheet    string
+		key      strings.Builder
+		ranges   []formulaArg
+		criteria []formulaArg
+	)
+	for i := 0; i+1 < len(args); i += 2 {
+		ranges, criteria = append(ranges, args[i]), append(criteria, args[i+1])
+		if kind != ifsKindIfs {
+			continue
+		}
+		// The equal criteria with empty value matches the empty cells or not
+		// depending on the cached range value indexes, scan the ranges
+		if c := formulaCriteriaParser(args[i+1]); c.Type == criteriaEq && c.Condition.Value() == "" {
+			return nil, false
+		}
+	}
+	if valueRange != nil {
+		ranges = append(ranges, *valueRange)
+	}
+	for i, arg := range ranges {
+		if arg.Type != ArgMatrix || arg.cellRanges == nil || arg.cellRanges.Len() != 1 {
+			return nil, false
+		}
+		cr := arg.cellRanges.Front().Value.(cellRange)
+		if sheet == "" {
+			sheet = cr.From.Sheet
+			key.WriteString(sheet)
+			key.WriteString("!IFS:")
+			key.WriteString(strconv.Itoa(int(kind)))
+		}
+		if cr.From.Sheet != sheet || cr.To.Sheet != sheet {
+			return nil, false
+		}
+		if key.WriteString("|"); i == len(criteria) {
+			key.WriteString("=")
+		}
+		key.WriteString(cellRangeIndexKey(cr))
+	}
+	if sheet == "" {
+		return nil, false
+	}
+	reads := fn.argReads(ranges...)
+	if reads == nil {
+		return nil, false
+	}
+	cached, ok := fn.f.rangeCache.Load(key.String())
+	if !ok {
+		// The first call with the ranges, mark the ranges and create the table
+		// when there are other function calls share the ranges
+		fn.f.rangeCache.StoreReads(key.String(), nil, reads)
+		return nil, false
+	}
+	t, ok := cached.(*ifsTable)
+	if !ok {
+		criteriaRanges := make([][][]formulaArg, len(criteria))
+		for i := range criteria {
+			criteriaRanges[i] = ranges[i].Matrix
+		}
+		var values [][]formulaArg
+		if valueRange != nil {
+			values = valueRange.Matrix
+		}
+		if t = newIfsTable(kind, criteriaRanges, values); t == nil {
+			// Mark the ranges unsupported to avoid scanning them again
+			t = &ifsTable{}
+		}
+		fn.f.rangeCache.StoreReads(key.String(), t, reads)
+	}
+	if t.columns == nil {
+		return nil, false
+	}
+	return t.aggregate(criteria), true
+}
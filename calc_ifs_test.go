@@ -0,0 +1,88 @@
+package excelize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcIfsAggregate(t *testing.T) {
+	cellData := [][]interface{}{
+		{"apple", "x", 1},
+		{"Apple", "y", 2},
+		{"banana", "x", 3},
+		{"b?n", "y", 4},
+		{1, "x", 5},
+		{2, "y", 6},
+		{3.5, "x", "text"},
+		{nil, "y", 8},
+		{"3", "x", 9},
+		{true, "y", 10},
+	}
+	formulaList := map[string]string{
+		"SUMIFS(C1:C10,A1:A10,\"apple\",B1:B10,\"x\")":   "1",
+		"SUMIFS(C1:C10,A1:A10,\"?pple\",B1:B10,\"<>x\")": "2",
+		"SUMIFS(C:C,A:A,\"*an*\")":                       "3",
+		"SUMIFS(C1:C10,A1:A10,Sheet2!A1)":                "3",
+		"AVERAGEIFS(C1:C10,B1:B10,\"y\",C1:C10,\">2\")":  "7",
+		"COUNTIFS(A1:A10,\"<>\",B1:B10,\"y\")":           "4",
+		"COUNTIFS(A1:A10,TRUE)":                          "1",
+		"COUNTIFS(B1:B10,\"y\",C1:C10,\">=6\")":          "3",
+		"MAXIFS(C1:C10,B1:B10,\"x\")":                    "9",
+		"MAXIFS(C1:C10,B1:B10,\"z\")":                    "0",
+		"MINIFS(C1:C10,B1:B10,\"y\",A1:A10,\"*\")":       "2",
+		"MINIFS(C1:C10,B1:B10,\"z\")":                    "0",
+		"SUMIF(A1:A10,\"apple\",C1:C10)":                 "1",
+		"SUMIF(C1:C10,\">4\")":                           "38",
+		"COUNTIF(A1:A10,\">=2\")":                        "2",
+		"AVERAGEIF(B1:B10,\"y\",C1:C10)":                 "6",
+		"AVERAGEIF(C1:C10,\"<5\")":                       "2.5",
+		"SUMIFS(C1:C10,A1:A10,\"apple\",B1:B10,\"x\")+1": "2",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		_, err := f.NewSheet("Sheet2")
+		assert.NoError(t, err)
+		assert.NoError(t, f.SetCellValue("Sheet2", "A1", "banana"))
+		// The first formula cell scans the ranges, and the second formula cell
+		// aggregates with the table shared by the formulas with the same ranges
+		for _, cell := range []string{"E1", "E2"} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.NoError(t, err, formula)
+			assert.Equal(t, expected, result, formula)
+		}
+	}
+	calcError := map[string][]string{
+		"AVERAGEIF(A1:A10,\"z\",C1:C10)":  {"#DIV/0!", "#DIV/0!"},
+		"AVERAGEIFS(C1:C10,A1:A10,\"z\")": {"#DIV/0!", "AVERAGEIF divide by zero"},
+	}
+	for formula, expected := range calcError {
+		f := prepareCalcData(cellData)
+		for _, cell := range []string{"E1", "E2"} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.Equal(t, expected[0], result, formula)
+			assert.EqualError(t, err, expected[1], formula)
+		}
+	}
+
+	// Test the shared table is dropped after the cells were changed
+	f := prepareCalcData(cellData)
+	for _, cell := range []string{"E1", "E2", "E3"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "SUMIFS(C1:C10,B1:B10,\"x\")"))
+	}
+	for cell, expected := range map[string]string{"E1": "18", "E2": "18"} {
+		result, err := f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+	assert.NoError(t, f.SetCellValue("Sheet1", "C1", 100))
+	result, err := f.CalcCellValue("Sheet1", "E3")
+	assert.NoError(t, err)
+	assert.Equal(t, "117", result)
+
+	// Test create the table with unsupported ranges
+	assert.Nil(t, newIfsTable(ifsKindIfs, [][][]formulaArg{nil}, nil))
+	assert.Nil(t, newIfsTable(ifsKindIfs, [][][]formulaArg{{{newNumberFormulaArg(1)}}}, [][]formulaArg{{}}))
+}
@@ -0,0 +1,369 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"strings"
+
+	"github.com/xuri/efp"
+)
+
+// intersectionFuncName is the name of the internal pseudo function used to
+// evaluate the intersection operator (a space) between two references, the
+// name can't be typed in the formula.
+const intersectionFuncName = "\x00INTERSECT"
+
+// skipFormulaQuoted returns the index after the string literal or quoted
+// worksheet name which starts at the given index of the formula.
+func skipFormulaQuoted(formula string, start int) int {
+	quote := formula[start]
+	for i := start + 1; i < len(formula); i++ {
+		if formula[i] != quote {
+			continue
+		}
+		if i+1 < len(formula) && formula[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(formula)
+}
+
+// skipFormulaBracket returns the index after the brackets of the structured
+// reference or external workbook reference which starts at the given index of
+// the formula.
+func skipFormulaBracket(formula string, start int) int {
+	var depth int
+	for i := start; i < len(formula); i++ {
+		switch formula[i] {
+		case '\'':
+			if i+1 < len(formula) && depth > 0 {
+				i++ // the escape character of the structured reference
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(formula)
+}
+
+// implicitIntersectionOperandEnd returns the end index of the operand of the
+// implicit intersection operator, the operand may be a reference, a name, a
+// function call or a parenthesized expression.
+func implicitIntersectionOperandEnd(formula string, start int) int {
+	var depth int
+	for i := start; i < len(formula); {
+		switch ch := formula[i]; {
+		case ch == '"' || ch == '\'':
+			i = skipFormulaQuoted(formula, i)
+			continue
+		case ch == '[':
+			i = skipFormulaBracket(formula, i)
+			continue
+		case ch == '(' || ch == '{':
+			depth++
+		case ch == ')' || ch == '}':
+			if depth == 0 {
+				return i
+			}
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case depth == 0 && strings.IndexByte("+-*/^&=<>,;% ", ch) != -1:
+			return i
+		}
+		i++
+	}
+	return len(formula)
+}
+
+// replaceImplicitIntersectionOperator replace the implicit intersection
+// operator in the formula with the SINGLE function, the form in which the
+// spreadsheet application stores the implicit intersection operator in the
+// workbook, such as @A:A as _xlfn.SINGLE(A:A).
+func replaceImplicitIntersectionOperator(formula string) string {
+	if !strings.Contains(formula, "@") {
+		return formula
+	}
+	var sb strings.Builder
+	for i := 0; i < len(formula); {
+		switch formula[i] {
+		case '"', '\'':
+			end := skipFormulaQuoted(formula, i)
+			sb.WriteString(formula[i:end])
+			i = end
+			continue
+		case '[':
+			end := skipFormulaBracket(formula, i)
+			sb.WriteString(formula[i:end])
+			i = end
+			continue
+		case '@':
+			if end := implicitIntersectionOperandEnd(formula, i+1); end > i+1 {
+				sb.WriteString("_xlfn.SINGLE(")
+				sb.WriteString(replaceImplicitIntersectionOperator(formula[i+1 : end]))
+				sb.WriteString(")")
+				i = end
+				continue
+			}
+		}
+		sb.WriteByte(formula[i])
+		i++
+	}
+	return sb.String()
+}
+
+// intersectionOperandStart returns the start index of the operand on the left
+// of the intersection operator token, returns -1 if the token is not an
+// operand.
+func intersectionOperandStart(tokens []efp.Token, end int) int {
+	var depth int
+	for i := end; i >= 0; i-- {
+		token := tokens[i]
+		switch {
+		case token.TType == efp.TokenTypeOperand && token.TSubType == efp.TokenSubTypeRange:
+			if depth == 0 {
+				return i
+			}
+		case isFunctionStopToken(token) || isEndParenthesesToken(token):
+			depth++
+		case isFunctionStartToken(token) || isBeginParenthesesToken(token):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+		if depth <= 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// intersectionOperandEnd returns the end index of the operand on the right of
+// the intersection operator token, returns -1 if the token is not an operand.
+func intersectionOperandEnd(tokens []efp.Token, start int) int {
+	var depth int
+	for i := start; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.TType == efp.TokenTypeOperand && token.TSubType == efp.TokenSubTypeRange:
+			if depth == 0 {
+				return i
+			}
+		case isFunctionStartToken(token) || isBeginParenthesesToken(token):
+			depth++
+		case isFunctionStopToken(token) || isEndParenthesesToken(token):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+		if depth <= 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// foldIntersectionTokens replace the intersection operators and the operands
+// on both sides of each operator in the tokens with the calls of the
+// internal intersection pseudo function, the intersection operator takes
+// precedence over other operators and is left-associative.
+func foldIntersectionTokens(tokens []efp.Token) []efp.Token {
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].TType != efp.TokenTypeOperatorInfix || tokens[i].TSubType != efp.TokenSubTypeIntersection {
+			continue
+		}
+		start, end := -1, -1
+		if i > 0 && i+1 < len(tokens) {
+			start, end = intersectionOperandStart(tokens, i-1), intersectionOperandEnd(tokens, i+1)
+		}
+		if start == -1 || end == -1 {
+			tokens = append(tokens[:i:i], tokens[i+1:]...)
+			i--
+			continue
+		}
+		folded := make([]efp.Token, 0, len(tokens)+2)
+		folded = append(folded, tokens[:start]...)
+		folded = append(folded, efp.Token{TValue: intersectionFuncName, TType: efp.TokenTypeFunction, TSubType: efp.TokenSubTypeStart})
+		folded = append(folded, tokens[start:i]...)
+		folded = append(folded, efp.Token{TValue: ",", TType: efp.TokenTypeArgument})
+		folded = append(folded, tokens[i+1:end+1]...)
+		folded = append(folded, efp.Token{TType: efp.TokenTypeFunction, TSubType: efp.TokenSubTypeStop})
+		tokens = append(folded, tokens[end+1:]...)
+	}
+	return tokens
+}
+
+// formulaArgCellRange returns the cell range of the formula argument which
+// is a reference to a single area, the coordinates of the range will be
+// sorted.
+func formulaArgCellRange(arg formulaArg) (cellRange, bool) {
+	var (
+		cr    cellRange
+		areas int
+	)
+	if arg.cellRanges != nil {
+		if areas = arg.cellRanges.Len(); areas == 1 {
+			cr = arg.cellRanges.Front().Value.(cellRange)
+		}
+	}
+	if arg.cellRefs != nil {
+		if areas += arg.cellRefs.Len(); areas == 1 && arg.cellRefs.Len() == 1 {
+			ref := arg.cellRefs.Front().Value.(cellRef)
+			cr.From, cr.To = ref, ref
+		}
+	}
+	if areas != 1 {
+		return cr, false
+	}
+	if cr.From.Col > cr.To.Col {
+		cr.From.Col, cr.To.Col = cr.To.Col, cr.From.Col
+	}
+	if cr.From.Row > cr.To.Row {
+		cr.From.Row, cr.To.Row = cr.To.Row, cr.From.Row
+	}
+	return cr, true
+}
+
+// cellRangeFormulaArg returns the values of the sub range of the reference
+// formula argument, the values of the cells out of the resolved values of
+// the argument are empty.
+func cellRangeFormulaArg(arg formulaArg, from cellRange, sub cellRange) formulaArg {
+	cellAt := func(col, row int) formulaArg {
+		if arg.Type != ArgMatrix {
+			if col == from.From.Col && row == from.From.Row {
+				return arg
+			}
+			return newEmptyFormulaArg()
+		}
+		r, c := row-from.From.Row, col-from.From.Col
+		if r < len(arg.Matrix) && c < len(arg.Matrix[r]) {
+			return arg.Matrix[r][c]
+		}
+		return newEmptyFormulaArg()
+	}
+	if sub.From == sub.To {
+		result := cellAt(sub.From.Col, sub.From.Row)
+		result.cellRefs, result.cellRanges = list.New(), list.New()
+		result.cellRefs.PushBack(sub.From)
+		return result
+	}
+	matrix := make([][]formulaArg, 0, sub.To.Row-sub.From.Row+1)
+	for row := sub.From.Row; row <= sub.To.Row; row++ {
+		cells := make([]formulaArg, 0, sub.To.Col-sub.From.Col+1)
+		for col := sub.From.Col; col <= sub.To.Col; col++ {
+			cells = append(cells, cellAt(col, row))
+		}
+		matrix = append(matrix, cells)
+	}
+	result := newMatrixFormulaArg(matrix)
+	result.cellRefs, result.cellRanges = list.New(), list.New()
+	result.cellRanges.PushBack(sub)
+	return result
+}
+
+// intersectFormulaArgs returns the intersection of two reference formula
+// arguments, returns the #NULL! error if the references don't intersect.
+func intersectFormulaArgs(lOpd, rOpd formulaArg) formulaArg {
+	for _, arg := range []formulaArg{lOpd, rOpd} {
+		if arg.Type == ArgError {
+			return arg
+		}
+	}
+	l, lOk := formulaArgCellRange(lOpd)
+	r, rOk := formulaArgCellRange(rOpd)
+	if !lOk || !rOk {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if !strings.EqualFold(strings.Trim(l.From.Sheet, "'"), strings.Trim(r.From.Sheet, "'")) {
+		return newErrorFormulaArg(formulaErrorNULL, formulaErrorNULL)
+	}
+	sub := cellRange{
+		From: cellRef{Sheet: l.From.Sheet, Col: max(l.From.Col, r.From.Col), Row: max(l.From.Row, r.From.Row)},
+		To:   cellRef{Sheet: l.From.Sheet, Col: min(l.To.Col, r.To.Col), Row: min(l.To.Row, r.To.Row)},
+	}
+	if sub.From.Col > sub.To.Col || sub.From.Row > sub.To.Row {
+		return newErrorFormulaArg(formulaErrorNULL, formulaErrorNULL)
+	}
+	return cellRangeFormulaArg(lOpd, l, sub)
+}
+
+// implicitIntersection returns the single value of the formula argument by
+// the implicit intersection with the formula cell at the given column and
+// row number. The cell in the same row of the single column reference or the
+// cell in the same column of the single row reference will be returned, the
+// top-left value of the array will be returned for the array value.
+func implicitIntersection(arg formulaArg, col, row int) formulaArg {
+	if arg.Type == ArgError {
+		return arg
+	}
+	cr, ok := formulaArgCellRange(arg)
+	if !ok {
+		if arg.cellRanges != nil && arg.cellRanges.Len() > 0 || arg.cellRefs != nil && arg.cellRefs.Len() > 1 {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		return arrayTopLeftValue(arg)
+	}
+	sub := cellRange{From: cr.From, To: cr.From}
+	if cr.From.Row != cr.To.Row {
+		if cr.From.Col != cr.To.Col || row < cr.From.Row || row > cr.To.Row {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		sub.From.Row = row
+	}
+	if cr.From.Col != cr.To.Col {
+		if col < cr.From.Col || col > cr.To.Col {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		sub.From.Col = col
+	}
+	sub.To = sub.From
+	return cellRangeFormulaArg(arg, cr, sub)
+}
+
+// isEntireRowColumnRef returns if the formula argument is a reference to the
+// entire columns or rows.
+func isEntireRowColumnRef(arg formulaArg) bool {
+	cr, ok := formulaArgCellRange(arg)
+	return ok && (cr.From.Row == 1 && cr.To.Row == TotalRows || cr.From.Col == 1 && cr.To.Col == MaxColumns)
+}
+
+// formulaCellValue returns the single value of the formula cell by given
+// formula result. The implicit intersection with the formula cell will be
+// applied to the reference result of the shared formula or the reference to
+// the entire columns or rows, and the top-left value will be returned for
+// other array results.
+func (f *File) formulaCellValue(sheet, cell string, result formulaArg) formulaArg {
+	col, row, err := CellNameToCoordinates(cell)
+	if err != nil {
+		return arrayTopLeftValue(result)
+	}
+	if isEntireRowColumnRef(result) {
+		return implicitIntersection(result, col, row)
+	}
+	if ws, err := f.workSheetReader(sheet); err == nil {
+		ws.mu.RLock()
+		c := ws.getCellReadOnly(col, row)
+		shared := c != nil && c.F != nil && c.F.T == STCellFormulaTypeShared
+		ws.mu.RUnlock()
+		if shared {
+			return implicitIntersection(result, col, row)
+		}
+	}
+	return arrayTopLeftValue(result)
+}
@@ -0,0 +1,116 @@
+package excelize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcIntersection(t *testing.T) {
+	cellData := [][]interface{}{
+		{1, 10, 100},
+		{2, 20, 200},
+		{3, 30, 300},
+		{4, 40, 400},
+		{5, 50, 500},
+	}
+	for _, c := range []struct {
+		cell, formula, expected string
+	}{
+		{"F3", "@A1:A5", "3"},
+		{"F3", "_xlfn.SINGLE(A1:A5)", "3"},
+		{"F3", "SUM(@A1:A5,1)", "4"},
+		{"F3", "@A1:A5*2+@B1:B5", "36"},
+		{"B7", "@A2:C2", "20"},
+		{"F3", "@A1", "1"},
+		{"F3", "@{7,8,9}", "7"},
+		{"F3", "A:A", "3"},
+		{"B9", "2:2", "20"},
+		{"F3", "SUM(A1:B5 B2:C3)", "50"},
+		{"F3", "A1:C5 B2:B2", "20"},
+		{"F3", "SUM(A1:C5 B1:C5 A2:B3)", "50"},
+		{"F3", "SUM((A1:B5) (B2:C3))", "50"},
+	} {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", c.cell, c.formula))
+		result, err := f.CalcCellValue("Sheet1", c.cell)
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	for _, c := range []struct {
+		cell, formula, expected string
+	}{
+		{"F6", "@A1:A5", formulaErrorVALUE},
+		{"F3", "@A1:B5", formulaErrorVALUE},
+		{"F3", "_xlfn.SINGLE()", formulaErrorVALUE},
+		{"F3", "A1:A2 C1:C2", formulaErrorNULL},
+		{"F3", "SUM(A1:A2 C1:C2)", formulaErrorNULL},
+		{"F3", "SUM(A1:A2 Sheet2!A1:A2)", formulaErrorNULL},
+	} {
+		f := prepareCalcData(cellData)
+		_, err := f.NewSheet("Sheet2")
+		assert.NoError(t, err)
+		assert.NoError(t, f.SetCellFormula("Sheet1", c.cell, c.formula))
+		result, err := f.CalcCellValue("Sheet1", c.cell)
+		assert.Error(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+
+	// Test explicit intersection spills the result with multiple cells
+	f := prepareCalcData(cellData)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "A1:B5 B2:C3"))
+	result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "20", result)
+	result, err = f.GetCellValue("Sheet1", "F2")
+	assert.NoError(t, err)
+	assert.Equal(t, "30", result)
+
+	// Test explicit intersection with the defined names
+	f = prepareCalcData(cellData)
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Jan", RefersTo: "Sheet1!$B$1:$B$5"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Sales", RefersTo: "Sheet1!$A$4:$C$4"}))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "Jan Sales"))
+	result, err = f.CalcCellValue("Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "40", result)
+
+	// Test implicit intersection with the legacy shared formula
+	f = prepareCalcData(cellData)
+	formulaType, ref := STCellFormulaTypeShared, "F1:F5"
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "B1:B5*2", FormulaOpts{Type: &formulaType, Ref: &ref}))
+	for row, expected := range []string{"20", "40", "60", "80", "100"} {
+		cell, _ := CoordinatesToCellName(6, row+1)
+		result, err = f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err, cell)
+		assert.Equal(t, expected, result, cell)
+	}
+
+	// Test implicit intersection on the referenced formula cell
+	f = prepareCalcData(cellData)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F4", "B:B"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "F4+1"))
+	result, err = f.CalcCellValue("Sheet1", "G1")
+	assert.NoError(t, err)
+	assert.Equal(t, "41", result)
+}
+
+func TestReplaceImplicitIntersectionOperator(t *testing.T) {
+	for formula, expected := range map[string]string{
+		"A1+1":                      "A1+1",
+		"@A:A":                      "_xlfn.SINGLE(A:A)",
+		"@A:A+1":                    "_xlfn.SINGLE(A:A)+1",
+		"SUM(@A1:A3,@B1:B3)":        "SUM(_xlfn.SINGLE(A1:A3),_xlfn.SINGLE(B1:B3))",
+		"@INDEX(A:B,0,1)*2":         "_xlfn.SINGLE(INDEX(A:B,0,1))*2",
+		"@'Sheet 1'!A:A":            "_xlfn.SINGLE('Sheet 1'!A:A)",
+		"\"@A1\"&@A:A":              "\"@A1\"&_xlfn.SINGLE(A:A)",
+		"Table1[@Amount]":           "Table1[@Amount]",
+		"@Table1[[#Data],[Amount]]": "_xlfn.SINGLE(Table1[[#Data],[Amount]])",
+		"@(A1:A3)":                  "_xlfn.SINGLE((A1:A3))",
+		"@{1,2}+1":                  "_xlfn.SINGLE({1,2})+1",
+		"@":                         "@",
+	} {
+		assert.Equal(t, expected, replaceImplicitIntersectionOperator(formula), formula)
+	}
+}
@@ -0,0 +1,618 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/xuri/efp"
+)
+
+// argumentFuncName is the name of the internal pseudo function used to
+// evaluate a sub-expression in the function argument mode, which keeps the
+// array and LAMBDA results instead of reducing them to a single value. The
+// leading NUL character guarantees that it never collides with a function
+// name in a formula.
+const argumentFuncName = "\x00ARGUMENT"
+
+// formulaLambda defined the LAMBDA function value, which contains the
+// parameter names, the tokens of the calculation and the context captured
+// when the LAMBDA was defined. The eta reduced LAMBDA, such as the SUM in the
+// formula =BYROW(A1:B3,SUM), contains the built-in function name only.
+type formulaLambda struct {
+	params      []string
+	body        []efp.Token
+	ctx         *calcContext
+	sheet, cell string
+	funcName    string
+}
+
+// newLambdaFormulaArg constructs a LAMBDA formula argument.
+func newLambdaFormulaArg(lambda *formulaLambda) formulaArg {
+	return formulaArg{Type: ArgLambda, lambda: lambda}
+}
+
+// root returns the top-level formula execution context, which holds the
+// iterations state shared by all cells in the current calculation.
+func (ctx *calcContext) root() *calcContext {
+	for ctx != nil && ctx.parent != nil {
+		ctx = ctx.parent
+	}
+	return ctx
+}
+
+// withNames creates a child formula execution context with the given names
+// bound, the names are looked up before the defined names and references.
+func (ctx *calcContext) withNames(names map[string]formulaArg) *calcContext {
+	return &calcContext{parent: ctx, names: names}
+}
+
+// lookupName find the value bound to the name by LET or LAMBDA in the
+// context and its ancestors.
+func (ctx *calcContext) lookupName(name string) (formulaArg, bool) {
+	name = formulaNameKey(name)
+	for ; ctx != nil; ctx = ctx.parent {
+		if arg, ok := ctx.names[name]; ok {
+			return arg, true
+		}
+	}
+	return formulaArg{}, false
+}
+
+// referenceFuncs defined the formula functions which read the references of
+// the arguments instead of the cell values, the formula cells in the
+// references will not be calculated, so the references to the formula cell
+// itself are not circular references.
+var referenceFuncs = map[string]bool{
+	"COLUMN": true, "COLUMNS": true, "FORMULATEXT": true, "ISFORMULA": true,
+	"ISREF": true, "ROW": true, "ROWS": true, "SHEETS": true,
+}
+
+// argsContext returns the formula execution context for resolving the
+// reference arguments of the given formula function.
+func (ctx *calcContext) argsContext(funcName string) *calcContext {
+	if referenceFuncs[formulaFuncName(funcName)] {
+		return &calcContext{parent: ctx, references: true}
+	}
+	return ctx
+}
+
+// formulaNameKey returns the case-insensitive key of the LET and LAMBDA name,
+// the "_xlpm." prefix used in the spreadsheet files will be removed.
+func formulaNameKey(name string) string {
+	name = strings.ToUpper(name)
+	return strings.TrimPrefix(name, "_XLPM.")
+}
+
+// formulaFuncName returns the normalized function name by given function
+// token value, the future function and eta reduced LAMBDA prefixes will be
+// removed.
+func formulaFuncName(name string) string {
+	return strings.NewReplacer("_XLFN.", "", "_XLWS.", "", "_XLETA.", "").Replace(strings.ToUpper(name))
+}
+
+// etaLambda returns the eta reduced LAMBDA function value by given built-in
+// function name, which is used as a function argument without calling it.
+func etaLambda(name string) (formulaArg, bool) {
+	name = formulaFuncName(name)
+	if name == "LET" || name == "LAMBDA" || !reflect.ValueOf(&formulaFuncs{}).MethodByName(strings.ReplaceAll(name, ".", "dot")).IsValid() {
+		return newEmptyFormulaArg(), false
+	}
+	return newLambdaFormulaArg(&formulaLambda{funcName: name}), true
+}
+
+// checkFormulaName checking the name declared by the LET and LAMBDA
+// functions, the name should be a valid defined name and not a cell
+// reference.
+func checkFormulaName(tokens []efp.Token) (string, bool) {
+	if len(tokens) != 1 || tokens[0].TType != efp.TokenTypeOperand ||
+		tokens[0].TSubType != efp.TokenSubTypeRange {
+		return "", false
+	}
+	name := formulaNameKey(tokens[0].TValue)
+	if _, _, err := CellNameToCoordinates(name); err == nil {
+		return "", false
+	}
+	if name == "TRUE" || name == "FALSE" || checkDefinedName(name) != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// splitFuncArgTokens split the tokens of the function call which starts at
+// the given index by the top-level argument separators, returns the tokens
+// of each argument and the index of the function stop token.
+func splitFuncArgTokens(tokens []efp.Token, start int) ([][]efp.Token, int, bool) {
+	var (
+		args  [][]efp.Token
+		arg   []efp.Token
+		depth int
+	)
+	for i := start; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.TSubType == efp.TokenSubTypeStart &&
+			(token.TType == efp.TokenTypeFunction || token.TType == efp.TokenTypeSubexpression) {
+			if depth++; depth == 1 {
+				continue
+			}
+		}
+		if token.TSubType == efp.TokenSubTypeStop &&
+			(token.TType == efp.TokenTypeFunction || token.TType == efp.TokenTypeSubexpression) {
+			if depth--; depth == 0 {
+				if len(arg) > 0 || len(args) > 0 {
+					args = append(args, arg)
+				}
+				return args, i, true
+			}
+		}
+		if depth == 1 && (token.TType == efp.TokenTypeArgument ||
+			(token.TType == efp.TokenTypeOperatorInfix && token.TSubType == efp.TokenSubTypeUnion)) {
+			args, arg = append(args, arg), nil
+			continue
+		}
+		arg = append(arg, token)
+	}
+	return args, len(tokens) - 1, false
+}
+
+// evalInfixExpArg evaluate the tokens of a sub-expression as a function
+// argument, the array and LAMBDA results will be kept, and the formula
+// errors will be returned as the error formula argument.
+func (f *File) evalInfixExpArg(ctx *calcContext, sheet, cell string, tokens []efp.Token) formulaArg {
+	if len(tokens) == 0 {
+		return newEmptyFormulaArg()
+	}
+	wrapped := make([]efp.Token, 0, len(tokens)+2)
+	wrapped = append(wrapped, efp.Token{TValue: argumentFuncName, TType: efp.TokenTypeFunction, TSubType: efp.TokenSubTypeStart})
+	wrapped = append(wrapped, tokens...)
+	wrapped = append(wrapped, efp.Token{TType: efp.TokenTypeFunction, TSubType: efp.TokenSubTypeStop})
+	result, err := f.evalInfixExp(ctx, sheet, cell, wrapped)
+	if err != nil && result.Type != ArgError {
+		return newErrorFormulaArg(formulaErrorVALUE, err.Error())
+	}
+	return result
+}
+
+// parseOperandRange resolve the range operand by given token value, the
+// value may be a name bound by the LET or LAMBDA functions, a defined name
+// or a reference.
+func (f *File) parseOperandRange(ctx *calcContext, sheet, cell, value string) (formulaArg, error) {
+	if arg, ok := ctx.lookupName(value); ok {
+		return arg, nil
+	}
+	if refTo := f.getDefinedNameRefTo(value, sheet); refTo != "" {
+		refTo = strings.TrimPrefix(refTo, "=")
+		ps := efp.ExcelParser()
+		if tokens := ps.Parse(escapeStructuredRefs(replaceImplicitIntersectionOperator(refTo))); len(tokens) != 1 || tokens[0].TSubType != efp.TokenSubTypeRange {
+			arg := f.evalInfixExpArg(ctx.root(), sheet, cell, tokens)
+			if arg.Type == ArgError {
+				return arg, errors.New(arg.Value())
+			}
+			return arg, nil
+		}
+		value = refTo
+	} else if rng := f.tableNameToRange(ctx, sheet, cell, value); rng != "" {
+		value = rng
+	}
+	if isStructuredRef(value) {
+		rng, arg := f.structuredRefToRange(ctx, sheet, cell, value)
+		if arg.Type == ArgError {
+			return arg, nil
+		}
+		value = rng
+	}
+	arg, err := f.parseReference(ctx, sheet, value)
+	if err != nil {
+		if lambda, ok := etaLambda(value); ok {
+			return lambda, nil
+		}
+	}
+	return arg, err
+}
+
+// lookupLambda find the LAMBDA function value by given function name, the
+// names bound by the LET or LAMBDA functions take precedence over the defined
+// names.
+func (f *File) lookupLambda(ctx *calcContext, sheet, cell, name string) (*formulaLambda, bool) {
+	if arg, ok := ctx.lookupName(name); ok {
+		return arg.lambda, arg.Type == ArgLambda
+	}
+	var workbookRefTo, worksheetRefTo string
+	for _, definedName := range f.GetDefinedName() {
+		if !strings.EqualFold(definedName.Name, name) {
+			continue
+		}
+		if definedName.Scope == "Workbook" {
+			workbookRefTo = definedName.RefersTo
+		}
+		if definedName.Scope == sheet {
+			worksheetRefTo = definedName.RefersTo
+		}
+	}
+	refTo := workbookRefTo
+	if worksheetRefTo != "" {
+		refTo = worksheetRefTo
+	}
+	if refTo == "" {
+		return nil, false
+	}
+	ps := efp.ExcelParser()
+	arg := f.evalInfixExpArg(ctx.root(), sheet, cell, ps.Parse(strings.TrimPrefix(refTo, "=")))
+	return arg.lambda, arg.Type == ArgLambda
+}
+
+// callFormulaFunc calls the formula function by given function name and
+// arguments list. The built-in functions take precedence over the
+// user-defined functions registered by the RegisterFormulaFunc, and then the
+// LAMBDA functions defined by the LET function or the defined names.
+func (f *File) callFormulaFunc(ctx *calcContext, sheet, cell, name string, argsList *list.List) formulaArg {
+	switch formulaFuncName(name) {
+	case "LET", "LAMBDA", argumentFuncName:
+		// the arguments of these functions have been evaluated lazily on the
+		// function start, the arguments list contains the result only
+		if argsList.Len() == 0 {
+			return newEmptyFormulaArg()
+		}
+		return argsList.Front().Value.(formulaArg)
+	case intersectionFuncName:
+		if argsList.Len() != 2 {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		return intersectFormulaArgs(argsList.Front().Value.(formulaArg), argsList.Back().Value.(formulaArg))
+	}
+	if volatileFuncs[formulaFuncName(name)] {
+		ctx.markVolatile()
+	}
+	fn := &formulaFuncs{f: f, sheet: sheet, cell: cell, ctx: ctx}
+	funcName := strings.ReplaceAll(formulaFuncName(name), ".", "dot")
+	if !reflect.ValueOf(fn).MethodByName(funcName).IsValid() {
+		if udf, ok := f.lookupUserFormulaFunc(name); ok {
+			return fn.callUserFormulaFunc(udf, argsList)
+		}
+		if lambda, ok := f.lookupLambda(ctx, sheet, cell, name); ok {
+			args := make([]formulaArg, 0, argsList.Len())
+			for arg := argsList.Front(); arg != nil; arg = arg.Next() {
+				args = append(args, arg.Value.(formulaArg))
+			}
+			return fn.callLambda(lambda, args...)
+		}
+	}
+	return callFuncByName(fn, funcName, []reflect.Value{reflect.ValueOf(argsList)})
+}
+
+// evalLazyFunc evaluate the function which arguments should not be evaluated
+// before calling the function, such as LET and LAMBDA. The function starts
+// at the given index of the tokens, returns the result and the index of the
+// last token consumed by the function.
+func (f *File) evalLazyFunc(ctx *calcContext, sheet, cell string, tokens []efp.Token, start int) (formulaArg, int) {
+	name := formulaFuncName(tokens[start].TValue)
+	args, end, ok := splitFuncArgTokens(tokens, start)
+	if !ok {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("formula not valid, the %s function is not closed", name)), end
+	}
+	fn := &formulaFuncs{f: f, sheet: sheet, cell: cell, ctx: ctx}
+	if name == "LET" {
+		return fn.let(args), end
+	}
+	arg := fn.lambda(args)
+	if arg.Type != ArgLambda || end+1 >= len(tokens) || !isBeginParenthesesToken(tokens[end+1]) {
+		return arg, end
+	}
+	// the LAMBDA function is invoked immediately, such as LAMBDA(x,x+1)(2)
+	callArgs, callEnd, ok := splitFuncArgTokens(tokens, end+1)
+	if !ok {
+		return newErrorFormulaArg(formulaErrorVALUE, "formula not valid, the LAMBDA call is not closed"), callEnd
+	}
+	values := make([]formulaArg, 0, len(callArgs))
+	for _, callArg := range callArgs {
+		values = append(values, f.evalInfixExpArg(ctx, sheet, cell, callArg))
+	}
+	return fn.callLambda(arg.lambda, values...), callEnd
+}
+
+// let is an implementation of the formula function LET, the arguments are
+// the tokens of each argument which have not been evaluated.
+func (fn *formulaFuncs) let(args [][]efp.Token) formulaArg {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return newErrorFormulaArg(formulaErrorVALUE, "LET requires an odd number of arguments, at least 3 arguments")
+	}
+	names := map[string]formulaArg{}
+	ctx := fn.ctx.withNames(names)
+	for i := 0; i < len(args)-1; i += 2 {
+		name, ok := checkFormulaName(args[i])
+		if !ok {
+			return newErrorFormulaArg(formulaErrorNAME, "LET requires valid names")
+		}
+		names[name] = fn.f.evalInfixExpArg(ctx, fn.sheet, fn.cell, args[i+1])
+	}
+	return fn.f.evalInfixExpArg(ctx, fn.sheet, fn.cell, args[len(args)-1])
+}
+
+// lambda is an implementation of the formula function LAMBDA, the arguments
+// are the tokens of each argument which have not been evaluated.
+func (fn *formulaFuncs) lambda(args [][]efp.Token) formulaArg {
+	if len(args) < 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, "LAMBDA requires at least 1 argument")
+	}
+	if len(args) > 254 {
+		return newErrorFormulaArg(formulaErrorVALUE, "LAMBDA allows at most 253 parameters")
+	}
+	lambda := &formulaLambda{body: args[len(args)-1], ctx: fn.ctx, sheet: fn.sheet, cell: fn.cell}
+	seen := map[string]bool{}
+	for _, param := range args[:len(args)-1] {
+		name, ok := checkFormulaName(param)
+		if !ok || seen[name] {
+			return newErrorFormulaArg(formulaErrorVALUE, "LAMBDA requires valid and unique parameter names")
+		}
+		seen[name] = true
+		lambda.params = append(lambda.params, name)
+	}
+	if len(lambda.body) == 0 {
+		return newErrorFormulaArg(formulaErrorVALUE, "LAMBDA requires a calculation")
+	}
+	return newLambdaFormulaArg(lambda)
+}
+
+// callLambda invoke the LAMBDA function with the given arguments, the
+// omitted trailing parameters are bound to the empty value.
+func (fn *formulaFuncs) callLambda(lambda *formulaLambda, args ...formulaArg) formulaArg {
+	if lambda == nil {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	if lambda.funcName != "" {
+		argsList := list.New()
+		for _, arg := range args {
+			argsList.PushBack(arg)
+		}
+		return fn.f.callFormulaFunc(fn.ctx, fn.sheet, fn.cell, lambda.funcName, argsList)
+	}
+	if len(args) > len(lambda.params) {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("LAMBDA requires at most %d arguments", len(lambda.params)))
+	}
+	names := make(map[string]formulaArg, len(lambda.params))
+	for i, param := range lambda.params {
+		names[param] = newEmptyFormulaArg()
+		if i < len(args) {
+			names[param] = args[i]
+		}
+	}
+	ctx := lambda.ctx.withNames(names)
+	if ctx.parent == nil {
+		ctx.parent = fn.ctx.root()
+	}
+	return fn.f.evalInfixExpArg(ctx, lambda.sheet, lambda.cell, lambda.body)
+}
+
+// formulaArgToMatrix converts the formula argument to a matrix, the single
+// value will be converted to a 1 x 1 matrix.
+func formulaArgToMatrix(arg formulaArg) [][]formulaArg {
+	if arg.Type == ArgMatrix {
+		return arg.Matrix
+	}
+	return [][]formulaArg{{arg}}
+}
+
+// lambdaArg returns the LAMBDA function value from the argument and checks
+// the number of the LAMBDA parameters.
+func lambdaArg(name string, arg formulaArg, params int) (*formulaLambda, formulaArg) {
+	if arg.Type == ArgError {
+		return nil, arg
+	}
+	if arg.Type != ArgLambda || arg.lambda == nil {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires a LAMBDA function", name))
+	}
+	if arg.lambda.funcName == "" && len(arg.lambda.params) != params {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires a LAMBDA function with %d parameters", name, params))
+	}
+	return arg.lambda, newEmptyFormulaArg()
+}
+
+// callLambdaValue invoke the LAMBDA function which should return a single
+// value, the nested array result will be returned as the #CALC! error.
+func (fn *formulaFuncs) callLambdaValue(lambda *formulaLambda, args ...formulaArg) formulaArg {
+	result := fn.callLambda(lambda, args...)
+	if result.Type == ArgMatrix {
+		if len(result.Matrix) == 1 && len(result.Matrix[0]) == 1 {
+			return result.Matrix[0][0]
+		}
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	if result.Type == ArgLambda {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	return result
+}
+
+// BYCOL function applies a LAMBDA function to each column and returns an
+// array of the results. The syntax of the function is:
+//
+//	BYCOL(array,lambda(column))
+func (fn *formulaFuncs) BYCOL(argsList *list.List) formulaArg {
+	if argsList.Len() != 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "BYCOL requires 2 arguments")
+	}
+	return fn.byRowCol("BYCOL", argsList, true)
+}
+
+// BYROW function applies a LAMBDA function to each row and returns an array
+// of the results. The syntax of the function is:
+//
+//	BYROW(array,lambda(row))
+func (fn *formulaFuncs) BYROW(argsList *list.List) formulaArg {
+	if argsList.Len() != 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "BYROW requires 2 arguments")
+	}
+	return fn.byRowCol("BYROW", argsList, false)
+}
+
+// byRowCol is an implementation of the formula functions BYCOL and BYROW.
+func (fn *formulaFuncs) byRowCol(name string, argsList *list.List, byCol bool) formulaArg {
+	array := argsList.Front().Value.(formulaArg)
+	if array.Type == ArgError {
+		return array
+	}
+	lambda, err := lambdaArg(name, argsList.Back().Value.(formulaArg), 1)
+	if lambda == nil {
+		return err
+	}
+	mtx := formulaArgToMatrix(array)
+	if byCol {
+		mtx = transposeFormulaArgsMatrix(mtx)
+	}
+	results := make([]formulaArg, 0, len(mtx))
+	for _, row := range mtx {
+		vector := [][]formulaArg{row}
+		if byCol {
+			vector = transposeFormulaArgsMatrix(vector)
+		}
+		results = append(results, fn.callLambdaValue(lambda, newMatrixFormulaArg(vector)))
+	}
+	if byCol {
+		return newMatrixFormulaArg([][]formulaArg{results})
+	}
+	return newMatrixFormulaArg(transposeFormulaArgsMatrix([][]formulaArg{results}))
+}
+
+// MAKEARRAY function returns a calculated array of a specified row and column
+// size, by applying a LAMBDA function. The syntax of the function is:
+//
+//	MAKEARRAY(rows,cols,lambda(row,col))
+func (fn *formulaFuncs) MAKEARRAY(argsList *list.List) formulaArg {
+	if argsList.Len() != 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, "MAKEARRAY requires 3 arguments")
+	}
+	rows := argsList.Front().Value.(formulaArg).ToNumber()
+	if rows.Type != ArgNumber {
+		return rows
+	}
+	cols := argsList.Front().Next().Value.(formulaArg).ToNumber()
+	if cols.Type != ArgNumber {
+		return cols
+	}
+	if rows.Number < 1 || cols.Number < 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, "MAKEARRAY requires rows and cols greater than 0")
+	}
+	if rows.Number > TotalRows || cols.Number > MaxColumns {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	lambda, err := lambdaArg("MAKEARRAY", argsList.Back().Value.(formulaArg), 2)
+	if lambda == nil {
+		return err
+	}
+	mtx := make([][]formulaArg, int(rows.Number))
+	for r := range mtx {
+		mtx[r] = make([]formulaArg, int(cols.Number))
+		for c := range mtx[r] {
+			mtx[r][c] = fn.callLambdaValue(lambda, newNumberFormulaArg(float64(r+1)), newNumberFormulaArg(float64(c+1)))
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// MAP function returns an array formed by mapping each value in the arrays to
+// a new value by applying a LAMBDA function. The syntax of the function is:
+//
+//	MAP(array1,[array2],...,lambda)
+func (fn *formulaFuncs) MAP(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "MAP requires at least 2 arguments")
+	}
+	lambda, err := lambdaArg("MAP", argsList.Back().Value.(formulaArg), argsList.Len()-1)
+	if lambda == nil {
+		return err
+	}
+	var arrays [][][]formulaArg
+	for arg := argsList.Front(); arg != argsList.Back(); arg = arg.Next() {
+		if arg.Value.(formulaArg).Type == ArgError {
+			return arg.Value.(formulaArg)
+		}
+		mtx := formulaArgToMatrix(arg.Value.(formulaArg))
+		if len(arrays) > 0 && (len(mtx) != len(arrays[0]) || len(mtx[0]) != len(arrays[0][0])) {
+			return newErrorFormulaArg(formulaErrorVALUE, "MAP requires arrays with the same size")
+		}
+		arrays = append(arrays, mtx)
+	}
+	mtx := make([][]formulaArg, len(arrays[0]))
+	for r, row := range arrays[0] {
+		mtx[r] = make([]formulaArg, len(row))
+		for c := range row {
+			args := make([]formulaArg, len(arrays))
+			for i, array := range arrays {
+				args[i] = array[r][c]
+			}
+			mtx[r][c] = fn.callLambdaValue(lambda, args...)
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// REDUCE function reduces an array to an accumulated value by applying a
+// LAMBDA function to each value. The syntax of the function is:
+//
+//	REDUCE([initial_value],array,lambda(accumulator,value))
+func (fn *formulaFuncs) REDUCE(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, "REDUCE requires 2 or 3 arguments")
+	}
+	return fn.scan("REDUCE", argsList)
+}
+
+// SCAN function scans an array by applying a LAMBDA function to each value
+// and returns an array that has each intermediate value. The syntax of the
+// function is:
+//
+//	SCAN([initial_value],array,lambda(accumulator,value))
+func (fn *formulaFuncs) SCAN(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, "SCAN requires 2 or 3 arguments")
+	}
+	return fn.scan("SCAN", argsList)
+}
+
+// scan is an implementation of the formula functions REDUCE and SCAN. The
+// REDUCE function returns the accumulated value only, which may be an array.
+func (fn *formulaFuncs) scan(name string, argsList *list.List) formulaArg {
+	lambda, err := lambdaArg(name, argsList.Back().Value.(formulaArg), 2)
+	if lambda == nil {
+		return err
+	}
+	acc, array := newEmptyFormulaArg(), argsList.Front().Value.(formulaArg)
+	if argsList.Len() == 3 {
+		acc, array = array, argsList.Front().Next().Value.(formulaArg)
+	}
+	if array.Type == ArgError {
+		return array
+	}
+	mtx := formulaArgToMatrix(array)
+	results := make([][]formulaArg, len(mtx))
+	for r, row := range mtx {
+		results[r] = make([]formulaArg, len(row))
+		for c, value := range row {
+			if name == "REDUCE" {
+				if acc = fn.callLambda(lambda, acc, value); acc.Type == ArgLambda {
+					return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+				}
+				continue
+			}
+			acc = fn.callLambdaValue(lambda, acc, value)
+			results[r][c] = acc
+		}
+	}
+	if name == "REDUCE" {
+		return acc
+	}
+	return newMatrixFormulaArg(results)
+}
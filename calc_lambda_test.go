@@ -0,0 +1,178 @@
+package excelize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcLetAndLambda(t *testing.T) {
+	cellData := [][]interface{}{
+		{1, 4},
+		{2, 5},
+		{3, 6},
+	}
+	formulaList := map[string]string{
+		// LET
+		"LET(x,1,x+1)":                    "2",
+		"_xlfn.LET(_xlpm.x,2,_xlpm.x*3)":  "6",
+		"LET(x,2,y,x*3,x+y)":              "8",
+		"LET(x,2,LET(y,x+1,x*y))":         "6",
+		"LET(x,A1:A3,SUM(x*2))":           "12",
+		"LET(x,A1:B3,SUM(x))":             "21",
+		"LET(a,\"Hello\",a&\" World\")":   "Hello World",
+		"LET(x,5,A1+x)":                   "6",
+		"LET(A,10,A*2)":                   "20",
+		"LET(x,1,y,x+1,z,y+1,SUM(x,y,z))": "6",
+		// LAMBDA
+		"LAMBDA(x,x+1)(5)": "6",
+		"_xlfn.LAMBDA(_xlpm.x,_xlpm.y,_xlpm.x*_xlpm.y)(3,4)": "12",
+		"LAMBDA(x,y,x&y)(\"a\")":                             "a",
+		"LET(f,LAMBDA(x,x*x),f(3))":                          "9",
+		"LET(n,10,f,LAMBDA(x,x+n),f(1))":                     "11",
+		"LET(f,LAMBDA(x,x*2),f(f(3)))":                       "12",
+		"LET(f,LAMBDA(r,SUM(r)),f(A1:B3))":                   "21",
+		"SUM(LET(x,2,x+1),1)":                                "4",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", formula))
+		result, err := f.CalcCellValue("Sheet1", "C1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	formulaErrorList := map[string][]string{
+		"LET(x,1)":               {"#VALUE!", "LET requires an odd number of arguments, at least 3 arguments"},
+		"LET(x,1,y,2)":           {"#VALUE!", "LET requires an odd number of arguments, at least 3 arguments"},
+		"LET(A1,1,A1)":           {"#NAME?", "LET requires valid names"},
+		"LET(1,1,2)":             {"#NAME?", "LET requires valid names"},
+		"LAMBDA()":               {"#VALUE!", "LAMBDA requires at least 1 argument"},
+		"LAMBDA(x,x,x+1)(1)":     {"#VALUE!", "LAMBDA requires valid and unique parameter names"},
+		"LAMBDA(x,x+1)(1,2)":     {"#VALUE!", "LAMBDA requires at most 1 arguments"},
+		"LAMBDA(x,x+1)":          {"#CALC!", "#CALC!"},
+		"LET(f,LAMBDA(x,x+1),f)": {"#CALC!", "#CALC!"},
+	}
+	for formula, expected := range formulaErrorList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", formula))
+		result, err := f.CalcCellValue("Sheet1", "C1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+	// Test the LET bound names are not visible in the referenced cells
+	f := prepareCalcData(cellData)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "LET(x,100,C2)"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C2", "LET(y,1,y+A1)"))
+	result, err := f.CalcCellValue("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+}
+
+func TestCalcNamedLambda(t *testing.T) {
+	f := prepareCalcData([][]interface{}{{1, 2}, {3, 4}})
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Add1", RefersTo: "LAMBDA(x,x+1)"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Hypot", RefersTo: "=LAMBDA(a,b,SQRT(a^2+b^2))"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Twice", RefersTo: "LAMBDA(x,x*2)", Scope: "Sheet1"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Rate", RefersTo: "0.5"}))
+	for formula, expected := range map[string]string{
+		"Add1(5)":                             "6",
+		"add1(A1)":                            "2",
+		"Hypot(3,4)":                          "5",
+		"Twice(Add1(B2))":                     "10",
+		"SUM(A1:B2)*Rate":                     "5",
+		"LET(Twice,LAMBDA(x,x+100),Twice(1))": "101",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", formula))
+		result, err := f.CalcCellValue("Sheet1", "C1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	// Test calling an unknown function
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C1", "Unknown(5)"))
+	result, err := f.CalcCellValue("Sheet1", "C1")
+	assert.Equal(t, "#VALUE!", result)
+	assert.EqualError(t, err, "not support UNKNOWN function")
+}
+
+func TestCalcLambdaHelperFunctions(t *testing.T) {
+	cellData := [][]interface{}{
+		{1, 4},
+		{2, 5},
+		{3, 6},
+	}
+	formulaList := map[string]string{
+		// BYCOL
+		"TEXTJOIN(\",\",TRUE,BYCOL(A1:B3,LAMBDA(c,SUM(c))))":    "6,15",
+		"_xlfn.BYCOL(A1:B3,_xlfn.LAMBDA(_xlpm.c,MAX(_xlpm.c)))": "3",
+		// BYROW
+		"TEXTJOIN(\",\",TRUE,BYROW(A1:B3,LAMBDA(r,SUM(r))))": "5,7,9",
+		"ROWS(BYROW(A1:B3,LAMBDA(r,SUM(r))))":                "3",
+		// MAKEARRAY
+		"TEXTJOIN(\",\",TRUE,MAKEARRAY(2,3,LAMBDA(r,c,r*c)))": "1,2,3,2,4,6",
+		"COLUMNS(MAKEARRAY(2,3,LAMBDA(r,c,r*c)))":             "3",
+		// MAP
+		"TEXTJOIN(\",\",TRUE,MAP(A1:A3,LAMBDA(x,x*2)))":         "2,4,6",
+		"TEXTJOIN(\",\",TRUE,MAP(A1:A3,B1:B3,LAMBDA(x,y,x+y)))": "5,7,9",
+		"SUM(MAP(A1:B3,LAMBDA(x,IF(x>2,x,0))))":                 "18",
+		"MAP(5,LAMBDA(x,x+1))":                                  "6",
+		// REDUCE
+		"REDUCE(0,A1:B3,LAMBDA(a,v,a+v))":           "21",
+		"REDUCE(1,A1:A3,LAMBDA(a,v,a*v))":           "6",
+		"REDUCE(,A1:A3,LAMBDA(a,v,a+v))":            "6",
+		"REDUCE(0,A1:B3,LAMBDA(a,v,IF(v>2,a+1,a)))": "4",
+		"LET(f,LAMBDA(a,v,a+v),REDUCE(10,A1:A3,f))": "16",
+		// SCAN
+		"TEXTJOIN(\",\",TRUE,SCAN(0,A1:A3,LAMBDA(a,v,a+v)))":    "1,3,6",
+		"TEXTJOIN(\",\",TRUE,SCAN(\"\",A1:A3,LAMBDA(a,v,a&v)))": "1,12,123",
+		"TEXTJOIN(\",\",TRUE,SCAN(1,A1:B2,LAMBDA(a,v,a*v)))":    "1,4,8,40",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", formula))
+		result, err := f.CalcCellValue("Sheet1", "C1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	formulaErrorList := map[string][]string{
+		"BYCOL(A1:B3)":                            {"#VALUE!", "BYCOL requires 2 arguments"},
+		"BYCOL(A1:B3,1)":                          {"#VALUE!", "BYCOL requires a LAMBDA function"},
+		"BYROW(A1:B3)":                            {"#VALUE!", "BYROW requires 2 arguments"},
+		"BYROW(A1:B3,LAMBDA(a,b,a))":              {"#VALUE!", "BYROW requires a LAMBDA function with 1 parameters"},
+		"BYROW(A1:B3,LAMBDA(r,r))":                {"#CALC!", "#CALC!"},
+		"MAKEARRAY(1,1)":                          {"#VALUE!", "MAKEARRAY requires 3 arguments"},
+		"MAKEARRAY(0,1,LAMBDA(r,c,r))":            {"#VALUE!", "MAKEARRAY requires rows and cols greater than 0"},
+		"MAKEARRAY(\"\",1,LAMBDA(r,c,r))":         {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"MAKEARRAY(1,\"\",LAMBDA(r,c,r))":         {"#VALUE!", "strconv.ParseFloat: parsing \"\": invalid syntax"},
+		"MAKEARRAY(1048577,1,LAMBDA(r,c,r))":      {"#VALUE!", "#VALUE!"},
+		"MAKEARRAY(1,16385,LAMBDA(r,c,r))":        {"#VALUE!", "#VALUE!"},
+		"MAKEARRAY(1,1,LAMBDA(r,r))":              {"#VALUE!", "MAKEARRAY requires a LAMBDA function with 2 parameters"},
+		"MAP(A1:A3)":                              {"#VALUE!", "MAP requires at least 2 arguments"},
+		"MAP(A1:A3,A1:B3,LAMBDA(x,y,x+y))":        {"#VALUE!", "MAP requires arrays with the same size"},
+		"MAP(A1:A3,1/0,LAMBDA(x,y,x+y))":          {"#DIV/0!", "#DIV/0!"},
+		"REDUCE(A1:A3)":                           {"#VALUE!", "REDUCE requires 2 or 3 arguments"},
+		"REDUCE(0,A1:A3,LAMBDA(a,a))":             {"#VALUE!", "REDUCE requires a LAMBDA function with 2 parameters"},
+		"REDUCE(0,A1:A3,LAMBDA(a,v,LAMBDA(x,x)))": {"#CALC!", "#CALC!"},
+		"SCAN(A1:A3)":                             {"#VALUE!", "SCAN requires 2 or 3 arguments"},
+		"SCAN(0,1/0,LAMBDA(a,v,a+v))":             {"#DIV/0!", "#DIV/0!"},
+	}
+	for formula, expected := range formulaErrorList {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "C1", formula))
+		result, err := f.CalcCellValue("Sheet1", "C1")
+		assert.Equal(t, expected[0], result, formula)
+		assert.EqualError(t, err, expected[1], formula)
+	}
+}
+
+func TestRecalculateAllLambdaHelperFunctions(t *testing.T) {
+	f := prepareCalcData([][]interface{}{{1, 4}, {2, 5}, {3, 6}})
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C1", "REDUCE(0,A1:B3,LAMBDA(a,v,a+v))"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C2", "SUM(MAP(A1:A3,LAMBDA(x,x*x)))"))
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateAll())
+	for cell, expected := range map[string]string{"C1": "21", "C2": "14"} {
+		result, err := f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lookupText is a distinct text in the lookup vector, and the first and last
+// positions of the cells with the text.
+type lookupText struct {
+	text        string
+	first, last int
+}
+
+// lookupIndex is the index of the lookup vector shared by the lookup formula
+// functions which refer to the same range. The vector is extracted once for
+// the binary search, and the exact and wildcard matches are resolved by the
+// distinct values of the vector instead of scanning all cells of the vector.
+type lookupIndex struct {
+	cells    []formulaArg
+	first    map[string]int
+	last     map[string]int
+	texts    []lookupText
+	patterns sync.Map
+}
+
+// lookupNumberKey returns the key of the numeric value in the lookup index.
+func lookupNumberKey(n float64) string {
+	if n == 0 {
+		n = 0 // normalize the negative zero
+	}
+	return "n" + strconv.FormatUint(math.Float64bits(n), 16)
+}
+
+// lookupValueKey returns the key of the lookup value, the lookup value equals
+// the cells with the same key in the lookup index, and it returns false if
+// the lookup value never equals any cell.
+func lookupValueKey(arg formulaArg) (string, bool) {
+	switch arg.Type {
+	case ArgNumber:
+		if math.IsNaN(arg.Number) {
+			return "", false
+		}
+		return lookupNumberKey(arg.Number), true
+	case ArgString:
+		return "s" + strings.ToLower(arg.String), true
+	case ArgEmpty:
+		return "e", true
+	}
+	return "", false
+}
+
+// newLookupIndex creates the index of the lookup vector by a single scan. The
+// cells are indexed in the way of the lookup linear search comparing them
+// with the lookup value: the numeric text and empty cells are also equal to
+// the number lookup value, and the text is compared case-insensitively. It
+// returns nil if the vector contains the cells which couldn't be indexed.
+func newLookupIndex(cells []formulaArg) *lookupIndex {
+	idx := &lookupIndex{
+		cells: cells,
+		first: make(map[string]int, len(cells)),
+		last:  make(map[string]int, len(cells)),
+	}
+	texts := make(map[string]int)
+	add := func(key string, pos int) {
+		if _, ok := idx.first[key]; !ok {
+			idx.first[key] = pos
+		}
+		idx.last[key] = pos
+	}
+	for pos, cell := range cells {
+		switch cell.Type {
+		case ArgNumber, ArgString, ArgEmpty:
+			if num := cell.ToNumber(); num.Type == ArgNumber && !math.IsNaN(num.Number) {
+				add(lookupNumberKey(num.Number), pos)
+			}
+		case ArgError:
+			continue
+		default:
+			return nil
+		}
+		if cell.Type == ArgNumber {
+			continue
+		}
+		key, _ := lookupValueKey(cell)
+		add(key, pos)
+		if cell.Type != ArgString {
+			continue
+		}
+		if i, ok := texts[key]; ok {
+			idx.texts[i].last = pos
+			continue
+		}
+		texts[key] = len(idx.texts)
+		idx.texts = append(idx.texts, lookupText{text: key[1:], first: pos, last: pos})
+	}
+	return idx
+}
+
+// match returns the first and last positions of the texts which match the
+// compiled wildcard pattern, the result is cached by the pattern.
+func (idx *lookupIndex) match(re *regexp.Regexp) [2]int {
+	pattern := re.String()
+	if cached, ok := idx.patterns.Load(pattern); ok {
+		return cached.([2]int)
+	}
+	positions := [2]int{-1, -1}
+	for _, t := range idx.texts {
+		if !re.MatchString(t.text) {
+			continue
+		}
+		if positions[0] == -1 || t.first < positions[0] {
+			positions[0] = t.first
+		}
+		if t.last > positions[1] {
+			positions[1] = t.last
+		}
+	}
+	idx.patterns.Store(pattern, positions)
+	return positions
+}
+
+// find returns the position of the first cell, or the last cell for the
+// reverse search, which equals the lookup value. The text cells match the
+// compiled wildcard pattern instead if it's not nil. It returns -1 if no cell
+// was found.
+func (idx *lookupIndex) find(lookupValue formulaArg, re *regexp.Regexp, reverse bool) int {
+	if re != nil {
+		positions := idx.match(re)
+		if reverse {
+			return positions[1]
+		}
+		return positions[0]
+	}
+	positions := idx.first
+	if reverse {
+		positions = idx.last
+	}
+	if key, ok := lookupValueKey(lookupValue); ok {
+		if pos, ok := positions[key]; ok {
+			return pos
+		}
+	}
+	return -1
+}
+
+// lookupVector returns the cells of the first column of the lookup array for
+// the vertical lookup, or the cells of the first row for the horizontal
+// lookup.
+func lookupVector(vertical bool, lookupArray formulaArg) []formulaArg {
+	if !vertical {
+		return lookupArray.Matrix[0]
+	}
+	cells := make([]formulaArg, 0, len(lookupArray.Matrix))
+	for _, row := range lookupArray.Matrix {
+		cells = append(cells, row[0])
+	}
+	return cells
+}
+
+// lookupIndex returns the index of the lookup vector shared by the lookup
+// function calls which refer to the same range. The index is created on the
+// second call with the same vector and stored in the lookup index cache, so it
+// will be dropped once the cells were changed. It returns nil if the lookup array
+// is not a cell range, and the function should scan the lookup array by
+// itself.
+func (fn *formulaFuncs) lookupIndex(vertical bool, lookupArray formulaArg) *lookupIndex {
+	if lookupArray.Type != ArgMatrix || lookupArray.cellRanges == nil || lookupArray.cellRanges.Len() != 1 ||
+		len(lookupArray.Matrix) == 0 || len(lookupArray.Matrix[0]) == 0 {
+		return nil
+	}
+	cr := lookupArray.cellRanges.Front().Value.(cellRange)
+	if cr.From.Sheet != cr.To.Sheet {
+		return nil
+	}
+	size := len(lookupArray.Matrix[0])
+	if vertical {
+		for _, row := range lookupArray.Matrix {
+			if len(row) == 0 {
+				return nil
+			}
+		}
+		size, cr.To.Col = len(lookupArray.Matrix), cr.From.Col
+	} else {
+		cr.To.Row = cr.From.Row
+	}
+	// The vectors of the same cells are shared by the functions refer to the
+	// different tables, such as VLOOKUP and MATCH on the first column
+	key := fmt.Sprintf("%s!LOOKUP:%s#%d", cr.From.Sheet, cellRangeIndexKey(cr), size)
+	// The index reads the vector instead of the whole lookup array, except
+	// the cells read by the formula cells in the lookup array
+	reads, table := fn.argReads(lookupArray), cellRangeArea(lookupArray.cellRanges.Front().Value.(cellRange))
+	if reads == nil {
+		return nil
+	}
+	for i, area := range reads {
+		if area == table {
+			reads[i] = cellRangeArea(cr)
+		}
+	}
+	cached, ok := fn.f.lookupIndexCache.Load(key)
+	if !ok {
+		// The first call with the vector, mark the vector and create the index
+		// when there are other function calls share the vector
+		fn.f.lookupIndexCache.StoreReads(key, nil, reads)
+		return nil
+	}
+	idx, ok := cached.(*lookupIndex)
+	if !ok {
+		if idx = newLookupIndex(lookupVector(vertical, lookupArray)); idx == nil {
+			// Mark the vector unsupported to avoid scanning it again
+			idx = &lookupIndex{}
+		}
+		fn.f.lookupIndexCache.StoreReads(key, idx, reads)
+	}
+	if idx.first == nil {
+		return nil
+	}
+	return idx
+}
+
+// lookupLinear returns the position of the lookup value in the lookup array
+// by the linear search. The exact and wildcard matches are resolved by the
+// shared index of the lookup vector, and the other matches fall back to
+// scanning the lookup array.
+func (fn *formulaFuncs) lookupLinear(vertical bool, lookupValue, lookupArray, matchMode, searchMode formulaArg) (int, bool) {
+	var re *regexp.Regexp
+	if lookupValue.Type == ArgString && matchMode.Number == matchModeWildcard {
+		re = fn.wildcardRegexp(strings.ToLower(lookupValue.String))
+	}
+	if lookupValue.Type != ArgMatrix && (matchMode.Number == matchModeExact || matchMode.Number == matchModeWildcard) {
+		if idx := fn.lookupIndex(vertical, lookupArray); idx != nil {
+			pos := idx.find(lookupValue, re, searchMode.Number != searchModeLinear)
+			return pos, pos != -1
+		}
+	}
+	if re != nil {
+		return lookupWildcardSearch(re, lookupVector(vertical, lookupArray), searchMode)
+	}
+	return lookupLinearSearch(vertical, lookupValue, lookupArray, matchMode, searchMode)
+}
+
+// wildcardRegexp returns the compiled regular expression which matches the
+// whole text with the lower-cased wildcard pattern, the compiled expressions
+// are cached in the regular expression cache of the workbook. The characters
+// except the wildcards are quoted, so the expression always compiles.
+func (fn *formulaFuncs) wildcardRegexp(pattern string) *regexp.Regexp {
+	var exp strings.Builder
+	exp.WriteString("^")
+	for _, char := range pattern {
+		switch char {
+		case '?':
+			exp.WriteString(".")
+		case '*':
+			exp.WriteString(".*")
+		default:
+			exp.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	exp.WriteString("$")
+	if cached, ok := fn.f.regexpCache.Load(exp.String()); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(exp.String())
+	fn.f.regexpCache.Store(exp.String(), re)
+	return re
+}
+
+// lookupWildcardSearch returns the position of the first text cell, or the
+// last text cell for the reverse search, which matches the compiled wildcard
+// pattern. It returns -1 if no cell was found.
+func lookupWildcardSearch(re *regexp.Regexp, cells []formulaArg, searchMode formulaArg) (int, bool) {
+	matchIdx := -1
+	for i, cell := range cells {
+		if cell.Type != ArgString || !re.MatchString(strings.ToLower(cell.Value())) {
+			continue
+		}
+		if matchIdx = i; searchMode.Number == searchModeLinear {
+			break
+		}
+	}
+	return matchIdx, matchIdx != -1
+}
+
+// lookupCells returns the lookup vector of the lookup array for the binary
+// search, the vector is shared by the function calls which refer to the
+// same range.
+func (fn *formulaFuncs) lookupCells(vertical bool, lookupArray formulaArg) []formulaArg {
+	if idx := fn.lookupIndex(vertical, lookupArray); idx != nil {
+		return idx.cells
+	}
+	return lookupVector(vertical, lookupArray)
+}
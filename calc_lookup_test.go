@@ -0,0 +1,166 @@
+package excelize
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcLookupIndex(t *testing.T) {
+	cellData := [][]interface{}{
+		{"apple", 10, 2},
+		{"Apple", 20, 4},
+		{"banana", 30, 6},
+		{"b?n", 40, 8},
+		{1, 50, 10},
+		{2, 60, 12},
+		{3.5, 70, 14},
+		{nil, 80, 16},
+		{"3", 90, 18},
+		{"apple pie", 100, 20},
+		{"c:\\dir\\q", 110},
+		{"apple", "banana", 2, "b?n", "apple pie"},
+		{1, 2, 3, 4, 5},
+	}
+	formulaList := map[string]string{
+		"VLOOKUP(\"apple\",A1:B10,2,FALSE)":                  "10",
+		"VLOOKUP(\"APPLE\",A1:B10,2,FALSE)":                  "10",
+		"VLOOKUP(\"ban*\",A1:B10,2,FALSE)":                   "30",
+		"VLOOKUP(\"b?n\",A1:B10,2,FALSE)":                    "40",
+		"VLOOKUP(\"*pie\",A:B,2,FALSE)":                      "100",
+		"VLOOKUP(2,A1:B10,2,FALSE)":                          "60",
+		"VLOOKUP(3.5,A1:B10,2,FALSE)":                        "70",
+		"VLOOKUP(\"3\",A1:B10,2,FALSE)":                      "90",
+		"VLOOKUP(11,C1:C10,1)":                               "10",
+		"HLOOKUP(\"banana\",A12:E13,2,FALSE)":                "2",
+		"HLOOKUP(\"apple*\",A12:E13,2,FALSE)":                "1",
+		"HLOOKUP(3,A13:E13,1)":                               "3",
+		"XLOOKUP(\"apple\",A1:A10,B1:B10,,0,-1)":             "20",
+		"XLOOKUP(\"a*\",A1:A10,B1:B10,\"none\",2)":           "10",
+		"XLOOKUP(\"a*\",A1:A10,B1:B10,\"none\",2,-1)":        "100",
+		"XLOOKUP(\"x*\",A1:A10,B1:B10,\"none\",2)":           "none",
+		"XLOOKUP(\"c:\\d*\",A1:A11,B1:B11,\"none\",2)":       "110",
+		"XLOOKUP(\"*\\q\",A1:A11,B1:B11,\"none\",2)":         "110",
+		"XMATCH(\"c:\\dir\\?\",A1:A11,2)":                    "11",
+		"XLOOKUP(15,C1:C10,B1:B10,\"none\",-1,2)":            "70",
+		"XMATCH(\"?pple\",A1:A10,2)":                         "1",
+		"XMATCH(2,A1:A10)":                                   "6",
+		"XMATCH(14,C1:C10,0,2)":                              "7",
+		"INDEX(B1:B10,XMATCH(\"banana\",A1:A10))":            "30",
+		"VLOOKUP(\"apple\",A1:B10,2,FALSE)+XMATCH(2,A1:A10)": "16",
+	}
+	for formula, expected := range formulaList {
+		f := prepareCalcData(cellData)
+		// The first formula cell scans the lookup vector, and the second
+		// formula cell finds the value by the index shared by the formulas
+		// with the same vector
+		for _, cell := range []string{"F1", "F2"} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.NoError(t, err, formula)
+			assert.Equal(t, expected, result, formula)
+		}
+	}
+	calcError := map[string][]string{
+		"VLOOKUP(\"app\",A1:B10,2,FALSE)":  {"#N/A", "VLOOKUP no result found"},
+		"VLOOKUP(\"b*z\",A1:B10,2,FALSE)":  {"#N/A", "VLOOKUP no result found"},
+		"VLOOKUP(1,C1:C10,1,TRUE)":         {"#N/A", "VLOOKUP no result found"},
+		"HLOOKUP(\"app\",A12:E13,2,FALSE)": {"#N/A", "HLOOKUP no result found"},
+		"XMATCH(\"pple\",A1:A10,2)":        {"#N/A", "#N/A"},
+	}
+	for formula, expected := range calcError {
+		f := prepareCalcData(cellData)
+		for _, cell := range []string{"F1", "F2"} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.Equal(t, expected[0], result, formula)
+			assert.EqualError(t, err, expected[1], formula)
+		}
+	}
+
+	// Test the shared index is dropped after the cells were changed
+	f := prepareCalcData(cellData)
+	for _, cell := range []string{"F1", "F2", "F3"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "XLOOKUP(\"cherry\",A1:A10,B1:B10,\"none\")"))
+	}
+	for cell, expected := range map[string]string{"F1": "none", "F2": "none"} {
+		result, err := f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+	assert.NoError(t, f.SetCellValue("Sheet1", "A3", "cherry"))
+	result, err := f.CalcCellValue("Sheet1", "F3")
+	assert.NoError(t, err)
+	assert.Equal(t, "30", result)
+
+	// Test the lookup indexes are stored in the lookup index cache by its own
+	// limit instead of evicting the range matrices
+	f = NewFile(Options{CalcCacheLimits: map[CalcCache]CalcCacheLimit{CalcCacheLookupIndexes: {MaxEntries: 2}}})
+	for r := 1; r <= 10; r++ {
+		row := strconv.Itoa(r)
+		assert.NoError(t, f.SetSheetRow("Sheet1", "A"+row, &[]interface{}{"a" + row, "b" + row, "c" + row, r}))
+	}
+	for i, col := range []string{"A", "B", "C"} {
+		for j := 1; j <= 2; j++ {
+			cell := "F" + strconv.Itoa(i*2+j)
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, fmt.Sprintf("XLOOKUP(\"%s5\",%s1:%s10,D1:D10)", strings.ToLower(col), col, col)))
+			result, err := f.CalcCellValue("Sheet1", cell)
+			assert.NoError(t, err)
+			assert.Equal(t, "5", result, cell)
+		}
+	}
+	assert.Equal(t, 2, f.lookupIndexCache.Len())
+	for _, key := range []string{"Sheet1!LOOKUP:Sheet1:1:2-10:2#10", "Sheet1!LOOKUP:Sheet1:1:3-10:3#10"} {
+		_, ok := f.lookupIndexCache.Load(key)
+		assert.True(t, ok, key)
+	}
+	_, ok := f.rangeCache.Load("Sheet1!LOOKUP:Sheet1:1:3-10:3#10")
+	assert.False(t, ok)
+
+	// Test the MATCH exact match doesn't share the index of the different
+	// ranges with the same size, first and last values
+	f = NewFile()
+	for r := 1; r <= 150; r++ {
+		row := strconv.Itoa(r)
+		assert.NoError(t, f.SetSheetRow("Sheet1", "A"+row, &[]interface{}{"k" + row, "v" + row}))
+	}
+	for _, cell := range []string{"B1", "A150"} {
+		assert.NoError(t, f.SetCellValue("Sheet1", cell, "k1"))
+	}
+	assert.NoError(t, f.SetCellValue("Sheet1", "B150", "k150"))
+	for cell, formula := range map[string]string{
+		"D1": "MATCH(\"k50\",A1:A150,0)", "D2": "MATCH(\"k50\",A1:A150,0)",
+		"D3": "MATCH(\"k50\",B1:B150,0)", "D4": "MATCH(\"k50\",B1:B150,0)",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+	}
+	for cell, expected := range map[string]string{"D1": "50", "D2": "50", "D3": "#N/A", "D4": "#N/A"} {
+		result, _ := f.CalcCellValue("Sheet1", cell)
+		assert.Equal(t, expected, result, cell)
+	}
+	// Test the MATCH exact match after the cell in the range was changed
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D5", "MATCH(\"new\",A1:A150,0)"))
+	result, err = f.CalcCellValue("Sheet1", "D5")
+	assert.EqualError(t, err, formulaErrorNA)
+	assert.Equal(t, formulaErrorNA, result)
+	assert.NoError(t, f.SetCellValue("Sheet1", "A60", "new"))
+	for _, cell := range []string{"D5", "D2"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "MATCH(\"new\",A1:A150,0)"))
+		result, err = f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, "60", result, cell)
+	}
+
+	// Test the wildcard pattern with the regular expression characters
+	fn := formulaFuncs{f: f}
+	for pattern, text := range map[string]string{"\\q*": "\\qx", "(a|b)?": "(a|b)c", "[a-z]+": "[a-z]+"} {
+		assert.True(t, fn.wildcardRegexp(pattern).MatchString(text), pattern)
+	}
+	assert.False(t, fn.wildcardRegexp("a.c").MatchString("abc"))
+
+	// Test create the index with unsupported cells
+	assert.Nil(t, newLookupIndex([]formulaArg{{Type: ArgList}}))
+}
@@ -0,0 +1,386 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// pivotSubtotalCaptions defined the captions of the summarize functions in
+// the default name of the pivot table data fields, such as "Sum of Sales".
+var pivotSubtotalCaptions = map[string]string{
+	"average": "Average", "count": "Count", "countNums": "Count", "max": "Max",
+	"min": "Min", "product": "Product", "stdDev": "StdDev", "stdDevp": "StdDevp",
+	"sum": "Sum", "var": "Var", "varp": "Varp",
+}
+
+// pivotFieldFilter defines the items of the pivot table field, the source
+// data rows with the field value in the items are shown by the report filter,
+// or hidden from the pivot table if exclude is true.
+type pivotFieldFilter struct {
+	field   string
+	items   []formulaArg
+	exclude bool
+}
+
+// pivotTableDefinition defines the options of the pivot table and the filters
+// of the pivot table fields.
+type pivotTableDefinition struct {
+	opts    PivotTableOptions
+	filters []pivotFieldFilter
+}
+
+// match returns if the source data value is shown by the field filter.
+func (filter pivotFieldFilter) match(value formulaArg) bool {
+	for _, item := range filter.items {
+		if pivotItemMatch(value, item) {
+			return !filter.exclude
+		}
+	}
+	return filter.exclude
+}
+
+// value returns the formula argument of the shared item in the pivot cache.
+func (item decodePivotCacheItem) value() formulaArg {
+	switch item.XMLName.Local {
+	case "n":
+		return newStringFormulaArg(item.V).ToNumber()
+	case "b":
+		return newBoolFormulaArg(item.V == "1" || strings.EqualFold(item.V, "true"))
+	case "d":
+		if t, err := time.Parse("2006-01-02T15:04:05", item.V); err == nil {
+			num, _ := timeToExcelTime(t, false)
+			return newNumberFormulaArg(num)
+		}
+	case "m":
+		return newEmptyFormulaArg()
+	}
+	return newStringFormulaArg(item.V)
+}
+
+// getPivotFieldFilters returns the filters of the pivot table fields by given
+// pivot table options. The selected item of the report filter field shows
+// the source data rows with the item, and the hidden items of the fields
+// hide the source data rows with these items.
+func (f *File) getPivotFieldFilters(opts *PivotTableOptions) ([]pivotFieldFilter, error) {
+	pt, err := f.pivotTableReader(opts.pivotTableXML)
+	if err != nil || pt.PivotFields == nil {
+		return nil, err
+	}
+	pc := new(decodePivotCacheItems)
+	if content, ok := f.Pkg.Load(opts.pivotCacheXML); ok && content != nil {
+		if err = f.xmlNewDecoder(bytes.NewReader(namespaceStrictToTransitional(content.([]byte)))).
+			Decode(pc); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	selected := map[int]int{}
+	if pt.PageFields != nil {
+		for _, field := range pt.PageFields.PageField {
+			if field != nil && field.Item != nil {
+				selected[field.Fld] = *field.Item
+			}
+		}
+	}
+	var filters []pivotFieldFilter
+	for idx, field := range pt.PivotFields.PivotField {
+		if idx >= len(pc.CacheFields) || field == nil || field.Items == nil {
+			continue
+		}
+		sharedItems := pc.CacheFields[idx].SharedItems.Items
+		item, isSelected := selected[idx]
+		filter := pivotFieldFilter{field: strings.ToLower(strings.TrimSpace(pc.CacheFields[idx].Name)), exclude: !isSelected}
+		for i, fieldItem := range field.Items.Item {
+			if fieldItem == nil || fieldItem.X == nil || *fieldItem.X >= len(sharedItems) ||
+				isSelected && i != item || !isSelected && !fieldItem.H {
+				continue
+			}
+			filter.items = append(filter.items, sharedItems[*fieldItem.X].value())
+		}
+		if isSelected || len(filter.items) > 0 {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+// getPivotTableDefinitions returns the pivot table definitions in the given
+// worksheet.
+func (f *File) getPivotTableDefinitions(sheet string) ([]pivotTableDefinition, error) {
+	pivotTables, err := f.GetPivotTables(sheet)
+	if err != nil {
+		return nil, err
+	}
+	definitions := make([]pivotTableDefinition, 0, len(pivotTables))
+	for _, opts := range pivotTables {
+		filters, err := f.getPivotFieldFilters(&opts)
+		if err != nil {
+			return definitions, err
+		}
+		definitions = append(definitions, pivotTableDefinition{opts: opts, filters: filters})
+	}
+	return definitions, nil
+}
+
+// pivotTableDefinitions returns the pivot table definitions in the given
+// worksheet, the pivot table definitions will be loaded once for each
+// calculation.
+func (f *File) pivotTableDefinitions(ctx *calcContext, sheet string) []pivotTableDefinition {
+	if ctx = ctx.root(); ctx == nil {
+		definitions, _ := f.getPivotTableDefinitions(sheet)
+		return definitions
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	definitions, ok := ctx.pivotTables[sheet]
+	if !ok {
+		if ctx.pivotTables == nil {
+			ctx.pivotTables = make(map[string][]pivotTableDefinition)
+		}
+		definitions, _ = f.getPivotTableDefinitions(sheet)
+		ctx.pivotTables[sheet] = definitions
+	}
+	return definitions
+}
+
+// findPivotTable returns the pivot table which contains the given cell
+// reference.
+func (fn *formulaFuncs) findPivotTable(ref cellRef) (*pivotTableDefinition, bool) {
+	pivotTables := fn.f.pivotTableDefinitions(fn.ctx, strings.Trim(ref.Sheet, "'"))
+	for i := range pivotTables {
+		rangeRef := pivotTables[i].opts.PivotTableRange
+		if idx := strings.LastIndex(rangeRef, "!"); idx != -1 {
+			rangeRef = rangeRef[idx+1:]
+		}
+		coordinates, err := rangeRefToCoordinates(rangeRef)
+		if err != nil {
+			continue
+		}
+		_ = sortCoordinates(coordinates)
+		if ref.Col >= coordinates[0] && ref.Col <= coordinates[2] &&
+			ref.Row >= coordinates[1] && ref.Row <= coordinates[3] {
+			return &pivotTables[i], true
+		}
+	}
+	return nil, false
+}
+
+// pivotDataField returns the data field of the pivot table by given name,
+// the name could be the custom name of the data field, the name of the
+// source field, or the default name such as "Sum of Sales".
+func (fn *formulaFuncs) pivotDataField(opts *PivotTableOptions, name string) (string, string, bool) {
+	name = strings.TrimSpace(name)
+	subtotals := fn.f.getPivotTableFieldsSubtotal(opts.Data)
+	for idx, field := range opts.Data {
+		if strings.EqualFold(name, strings.TrimSpace(field.Name)) || strings.EqualFold(name, field.Data) ||
+			strings.EqualFold(name, pivotSubtotalCaptions[subtotals[idx]]+" of "+field.Data) {
+			return field.Data, subtotals[idx], true
+		}
+	}
+	return "", "", false
+}
+
+// isPivotAxisField returns if the field is a row or column field of the
+// pivot table by given field name.
+func isPivotAxisField(opts *PivotTableOptions, name string) bool {
+	for _, fields := range [][]PivotTableField{opts.Rows, opts.Columns} {
+		for _, field := range fields {
+			if strings.EqualFold(strings.TrimSpace(name), field.Data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pivotItemMatch returns if the value of the source data matches the pivot
+// item, the numeric item matches the numeric value, and other items are
+// compared as case-insensitive text.
+func pivotItemMatch(value, item formulaArg) bool {
+	if item.Type == ArgNumber && value.Type == ArgNumber {
+		return item.Number == value.Number
+	}
+	return strings.EqualFold(strings.TrimSpace(value.Value()), strings.TrimSpace(item.Value()))
+}
+
+// pivotSubtotal returns the aggregated value of the source data values by
+// given summarize function of the pivot table data field.
+func pivotSubtotal(subtotal string, values []formulaArg) formulaArg {
+	var (
+		nums  []float64
+		count int
+	)
+	for _, value := range values {
+		if value.Type == ArgNumber && !value.Boolean {
+			nums = append(nums, value.Number)
+		}
+		if value.Type != ArgEmpty && value.Value() != "" {
+			count++
+		}
+	}
+	var sum float64
+	for _, num := range nums {
+		sum += num
+	}
+	switch subtotal {
+	case "count":
+		return newNumberFormulaArg(float64(count))
+	case "countNums":
+		return newNumberFormulaArg(float64(len(nums)))
+	case "average":
+		if len(nums) == 0 {
+			return newErrorFormulaArg(formulaErrorDIV, formulaErrorDIV)
+		}
+		return newNumberFormulaArg(sum / float64(len(nums)))
+	case "max", "min":
+		if len(nums) == 0 {
+			return newNumberFormulaArg(0)
+		}
+		result := nums[0]
+		for _, num := range nums[1:] {
+			if subtotal == "max" {
+				result = math.Max(result, num)
+				continue
+			}
+			result = math.Min(result, num)
+		}
+		return newNumberFormulaArg(result)
+	case "product":
+		if len(nums) == 0 {
+			return newNumberFormulaArg(0)
+		}
+		result := 1.0
+		for _, num := range nums {
+			result *= num
+		}
+		return newNumberFormulaArg(result)
+	case "stdDev", "stdDevp", "var", "varp":
+		n := float64(len(nums))
+		if subtotal == "stdDev" || subtotal == "var" {
+			n--
+		}
+		if n <= 0 {
+			return newErrorFormulaArg(formulaErrorDIV, formulaErrorDIV)
+		}
+		mean, sumSq := sum/float64(len(nums)), 0.0
+		for _, num := range nums {
+			sumSq += (num - mean) * (num - mean)
+		}
+		if subtotal == "var" || subtotal == "varp" {
+			return newNumberFormulaArg(sumSq / n)
+		}
+		return newNumberFormulaArg(math.Sqrt(sumSq / n))
+	}
+	return newNumberFormulaArg(sum)
+}
+
+// GETPIVOTDATA function extracts the data stored in a pivot table. The value
+// is calculated from the source data range and the fields of the pivot table
+// instead of the cached values of the pivot table, the source data rows
+// filtered out by the report filters and the hidden items of the pivot table
+// are not aggregated. The syntax of the function is:
+//
+//	GETPIVOTDATA(data_field,pivot_table,[field1,item1],...)
+func (fn *formulaFuncs) GETPIVOTDATA(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, "GETPIVOTDATA requires at least 2 arguments")
+	}
+	if argsList.Len()%2 != 0 {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	dataFieldArg, pivotArg := argsList.Front().Value.(formulaArg), argsList.Front().Next().Value.(formulaArg)
+	if dataFieldArg.Type == ArgError {
+		return dataFieldArg
+	}
+	cr, ok := formulaArgCellRange(pivotArg)
+	if !ok {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	pivotTable, ok := fn.findPivotTable(cr.From)
+	if !ok {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	opts := &pivotTable.opts
+	dataField, subtotal, ok := fn.pivotDataField(opts, dataFieldArg.Value())
+	if !ok {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	source, err := fn.f.parseReference(fn.ctx, fn.sheet, opts.pivotDataRange)
+	if err != nil || source.Type != ArgMatrix || len(source.Matrix) < 1 {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	columns := map[string]int{}
+	for col, header := range source.Matrix[0] {
+		columns[strings.ToLower(strings.TrimSpace(header.Value()))] = col
+	}
+	dataCol, ok := columns[strings.ToLower(dataField)]
+	if !ok {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	type criterion struct {
+		col  int
+		item formulaArg
+	}
+	type fieldFilter struct {
+		col    int
+		filter pivotFieldFilter
+	}
+	var (
+		criteria []criterion
+		filters  []fieldFilter
+	)
+	for _, filter := range pivotTable.filters {
+		if col, ok := columns[filter.field]; ok {
+			filters = append(filters, fieldFilter{col: col, filter: filter})
+		}
+	}
+	for arg := argsList.Front().Next().Next(); arg != nil; arg = arg.Next().Next() {
+		field, item := arg.Value.(formulaArg), arg.Next().Value.(formulaArg)
+		for _, arg := range []formulaArg{field, item} {
+			if arg.Type == ArgError {
+				return arg
+			}
+		}
+		col, ok := columns[strings.ToLower(strings.TrimSpace(field.Value()))]
+		if !ok || !isPivotAxisField(opts, field.Value()) {
+			return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+		}
+		criteria = append(criteria, criterion{col: col, item: item})
+	}
+	var values []formulaArg
+	for _, row := range source.Matrix[1:] {
+		matched := true
+		for _, c := range criteria {
+			if c.col >= len(row) || !pivotItemMatch(row[c.col], c.item) {
+				matched = false
+				break
+			}
+		}
+		for _, c := range filters {
+			if matched && c.col < len(row) && !c.filter.match(row[c.col]) {
+				matched = false
+			}
+		}
+		if matched && dataCol < len(row) {
+			values = append(values, row[dataCol])
+		}
+	}
+	if len(values) == 0 {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	return pivotSubtotal(subtotal, values)
+}
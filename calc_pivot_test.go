@@ -0,0 +1,191 @@
+package excelize
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcGETPIVOTDATA(t *testing.T) {
+	f := NewFile()
+	for idx, row := range [][]interface{}{
+		{"Region", "Type", "Year", "Sales", "Note"},
+		{"East", "Meat", 2024, 100, "a"},
+		{"West", "Meat", 2024, 200, nil},
+		{"East", "Dairy", 2025, 300, "b"},
+		{"East", "Meat", 2025, 400, "c"},
+		{"West", "Dairy", 2025, "n/a", "d"},
+	} {
+		cell, _ := CoordinatesToCellName(1, idx+1)
+		assert.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	_, err := f.NewSheet("Sheet2")
+	assert.NoError(t, err)
+	assert.NoError(t, f.AddPivotTable(&PivotTableOptions{
+		DataRange:       "Sheet1!A1:E6",
+		PivotTableRange: "Sheet2!A1:E10",
+		Rows:            []PivotTableField{{Data: "Region"}, {Data: "Year"}},
+		Columns:         []PivotTableField{{Data: "Type"}},
+		Data:            []PivotTableField{{Data: "Sales", Name: "Total Sales"}},
+	}))
+	assert.NoError(t, f.AddPivotTable(&PivotTableOptions{
+		DataRange:       "Sheet1!A1:E6",
+		PivotTableRange: "Sheet2!H1:K10",
+		Rows:            []PivotTableField{{Data: "Type"}},
+		Data: []PivotTableField{
+			{Data: "Sales", Subtotal: "Average"},
+			{Data: "Note", Subtotal: "Count"},
+			{Data: "Year", Subtotal: "Max"},
+		},
+	}))
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"GETPIVOTDATA(\"Total Sales\",Sheet2!A1)", "1000"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!$B$3)", "1000"},
+		{"GETPIVOTDATA(\"Sum of Sales\",Sheet2!A1)", "1000"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",\"East\")", "800"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"region\",\"east\",\"Type\",\"Meat\")", "500"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",\"East\",\"Year\",2025)", "700"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Year\",\"2024\")", "300"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",\"West\",\"Type\",\"Dairy\")", "0"},
+		{"GETPIVOTDATA(\"Average of Sales\",Sheet2!I5)", "250"},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!H1:K10,\"Type\",\"Dairy\")", "300"},
+		{"GETPIVOTDATA(\"Count of Note\",Sheet2!H1,\"Type\",\"Meat\")", "2"},
+		{"GETPIVOTDATA(\"Year\",Sheet2!H1,\"Type\",\"Meat\")", "2025"},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet2", "Z1", c.formula))
+		result, err := f.CalcCellValue("Sheet2", "Z1")
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	// Test the source data rows filtered out by the report filter and the
+	// hidden items are not aggregated
+	assert.NoError(t, f.AddPivotTable(&PivotTableOptions{
+		DataRange:       "Sheet1!A1:E6",
+		PivotTableRange: "Sheet2!N3:P10",
+		Filter:          []PivotTableField{{Data: "Region"}},
+		Rows:            []PivotTableField{{Data: "Type"}},
+		Data:            []PivotTableField{{Data: "Sales"}},
+	}))
+	pivotTables, err := f.GetPivotTables("Sheet2")
+	assert.NoError(t, err)
+	opts := pivotTables[2]
+	pc, err := f.pivotCacheReader(opts.pivotCacheXML)
+	assert.NoError(t, err)
+	pc.CacheFields.CacheField[0].SharedItems = &xlsxSharedItems{Count: 2, S: []xlsxString{{V: "East"}, {V: "West"}}}
+	content, err := xml.Marshal(pc)
+	assert.NoError(t, err)
+	f.Pkg.Store(opts.pivotCacheXML, content)
+	filterPivotTable := func(selected *int, hidden bool) {
+		pt, err := f.pivotTableReader(opts.pivotTableXML)
+		assert.NoError(t, err)
+		pt.PageFields.PageField[0].Item = selected
+		pt.PivotFields.PivotField[0].Items = &xlsxItems{Count: 3, Item: []*xlsxItem{
+			{X: intPtr(0), H: hidden}, {X: intPtr(1)}, {T: "default"},
+		}}
+		content, err := xml.Marshal(pt)
+		assert.NoError(t, err)
+		f.Pkg.Store(opts.pivotTableXML, content)
+	}
+	for _, c := range []struct {
+		selected *int
+		hidden   bool
+		expected map[string]string
+	}{
+		{nil, false, map[string]string{"Meat": "700", "Dairy": "300"}},
+		{intPtr(0), false, map[string]string{"Meat": "500", "Dairy": "300"}},
+		{intPtr(1), false, map[string]string{"Meat": "200", "Dairy": "0"}},
+		{nil, true, map[string]string{"Meat": "200", "Dairy": "0"}},
+	} {
+		filterPivotTable(c.selected, c.hidden)
+		for item, expected := range c.expected {
+			formula := "GETPIVOTDATA(\"Sales\",Sheet2!N3,\"Type\",\"" + item + "\")"
+			assert.NoError(t, f.SetCellFormula("Sheet2", "Z1", formula))
+			result, err := f.CalcCellValue("Sheet2", "Z1")
+			assert.NoError(t, err, formula)
+			assert.Equal(t, expected, result, formula)
+		}
+	}
+	// Test the pivot tables are loaded once for each calculation
+	assert.NoError(t, f.SetCellFormula("Sheet2", "Z1", "GETPIVOTDATA(\"Sales\",Sheet2!N3)+GETPIVOTDATA(\"Sales\",Sheet2!A1)"))
+	ctx := &calcContext{iterations: make(map[string]uint), iterationsCache: make(map[string]formulaArg)}
+	arg, err := f.calcCellValue(ctx, "Sheet2", "Z1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1200", arg.Value())
+	assert.Len(t, ctx.pivotTables["Sheet2"], 3)
+	definitions, err := f.getPivotTableDefinitions("SheetN")
+	assert.Nil(t, definitions)
+	assert.EqualError(t, err, "sheet SheetN does not exist")
+
+	// Test the result is calculated by the source data instead of the cache
+	assert.NoError(t, f.SetCellValue("Sheet1", "D2", 1100))
+	assert.NoError(t, f.SetCellFormula("Sheet2", "Z1", "GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",\"East\")"))
+	result, err := f.CalcCellValue("Sheet2", "Z1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1800", result)
+
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"GETPIVOTDATA(\"Sales\")", formulaErrorVALUE},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\")", formulaErrorREF},
+		{"GETPIVOTDATA(\"Sales\",\"A1\")", formulaErrorREF},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!G1)", formulaErrorREF},
+		{"GETPIVOTDATA(\"Profit\",Sheet2!A1)", formulaErrorREF},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Note\",\"a\")", formulaErrorREF},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",\"North\")", formulaErrorREF},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!A1,\"Region\",1/0)", formulaErrorDIV},
+		{"GETPIVOTDATA(1/0,Sheet2!A1)", formulaErrorDIV},
+		{"GETPIVOTDATA(\"Sales\",Sheet2!H1,\"Type\",\"Dairy\",\"Region\",\"West\")", formulaErrorREF},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet2", "Z1", c.formula))
+		result, err := f.CalcCellValue("Sheet2", "Z1")
+		assert.Error(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	// Test get the pivot table filters with the invalid pivot table
+	f.Pkg.Store(opts.pivotTableXML, MacintoshCyrillicCharset)
+	_, err = f.getPivotFieldFilters(&opts)
+	assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
+}
+
+func TestPivotSubtotal(t *testing.T) {
+	values := []formulaArg{newNumberFormulaArg(2), newNumberFormulaArg(4), newStringFormulaArg("x"), newEmptyFormulaArg()}
+	for subtotal, expected := range map[string]string{
+		"sum": "6", "count": "3", "countNums": "2", "average": "3", "max": "4", "min": "2",
+		"product": "8", "stdDev": "1.4142135623730951", "stdDevp": "1", "var": "2", "varp": "1",
+	} {
+		assert.Equal(t, expected, pivotSubtotal(subtotal, values).Value(), subtotal)
+	}
+	empty := []formulaArg{newStringFormulaArg("x")}
+	for subtotal, expected := range map[string]string{
+		"average": formulaErrorDIV, "var": formulaErrorDIV, "max": "0", "product": "0",
+	} {
+		assert.Equal(t, expected, pivotSubtotal(subtotal, empty).Value(), subtotal)
+	}
+}
+
+func TestPivotFieldFilter(t *testing.T) {
+	for _, c := range []struct {
+		item     decodePivotCacheItem
+		expected formulaArg
+	}{
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "n"}, V: "2.5"}, newNumberFormulaArg(2.5)},
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "b"}, V: "1"}, newBoolFormulaArg(true)},
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "d"}, V: "2024-01-01T00:00:00"}, newNumberFormulaArg(45292)},
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "d"}, V: "x"}, newStringFormulaArg("x")},
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "m"}}, newEmptyFormulaArg()},
+		{decodePivotCacheItem{XMLName: xml.Name{Local: "s"}, V: "East"}, newStringFormulaArg("East")},
+	} {
+		assert.Equal(t, c.expected, c.item.value(), c.item.XMLName.Local)
+	}
+	filter := pivotFieldFilter{items: []formulaArg{newNumberFormulaArg(2024), newStringFormulaArg("East")}}
+	assert.True(t, filter.match(newStringFormulaArg("east")))
+	assert.True(t, filter.match(newNumberFormulaArg(2024)))
+	assert.False(t, filter.match(newStringFormulaArg("West")))
+	filter.exclude = true
+	assert.False(t, filter.match(newStringFormulaArg("East")))
+	assert.True(t, filter.match(newStringFormulaArg("West")))
+}
@@ -0,0 +1,230 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+)
+
+// compileFormulaRegexp returns the compiled regular expression by given
+// pattern and case sensitivity, the compiled patterns will be cached and
+// shared by the formulas, so that the formulas filled in a column don't
+// compile the same pattern for each cell.
+func (fn *formulaFuncs) compileFormulaRegexp(pattern formulaArg, caseSensitivity formulaArg) (*regexp.Regexp, formulaArg) {
+	if pattern.Type == ArgError {
+		return nil, pattern
+	}
+	if pattern.Type == ArgMatrix || pattern.Type == ArgList {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	insensitive, errArg := groupIntArg(caseSensitivity, 0)
+	if errArg.Type == ArgError {
+		return nil, errArg
+	}
+	if insensitive != 0 && insensitive != 1 {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	expr := pattern.Value()
+	if insensitive == 1 {
+		expr = "(?i)" + expr
+	}
+	if cached, ok := fn.f.regexpCache.Load(expr); ok {
+		return cached.(*regexp.Regexp), newEmptyFormulaArg()
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, err.Error())
+	}
+	fn.f.regexpCache.Store(expr, re)
+	return re, newEmptyFormulaArg()
+}
+
+// mapFormulaArg returns the result of the function applied to each value of
+// the array argument, or the result of the function applied to the single
+// value argument.
+func mapFormulaArg(arg formulaArg, fn func(value formulaArg) formulaArg) formulaArg {
+	if arg.Type != ArgMatrix {
+		if arg.Type == ArgList {
+			arg = newMatrixFormulaArg([][]formulaArg{arg.List})
+		} else {
+			return fn(arg)
+		}
+	}
+	mtx := make([][]formulaArg, len(arg.Matrix))
+	for r, row := range arg.Matrix {
+		mtx[r] = make([]formulaArg, len(row))
+		for c, value := range row {
+			mtx[r][c] = fn(value)
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// REGEXTEST function checks whether any part of the supplied text matches a
+// regular expression. The case_sensitivity 0 for case sensitive match, and 1
+// for case insensitive match. The syntax of the function is:
+//
+//	REGEXTEST(text,pattern,[case_sensitivity])
+func (fn *formulaFuncs) REGEXTEST(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 3 {
+		return newErrorFormulaArg(formulaErrorVALUE, "REGEXTEST requires 2 or 3 arguments")
+	}
+	argv := groupFuncArgs(argsList, 3)
+	re, errArg := fn.compileFormulaRegexp(argv[1], argv[2])
+	if re == nil {
+		return errArg
+	}
+	return mapFormulaArg(argv[0], func(text formulaArg) formulaArg {
+		if text.Type == ArgError {
+			return text
+		}
+		return newBoolFormulaArg(re.MatchString(text.Value()))
+	})
+}
+
+// REGEXEXTRACT function extracts strings within the supplied text that match
+// a regular expression. The return_mode 0 returns the first match, 1 returns
+// all matches as an array, and 2 returns the capturing groups of the first
+// match as an array. The syntax of the function is:
+//
+//	REGEXEXTRACT(text,pattern,[return_mode],[case_sensitivity])
+func (fn *formulaFuncs) REGEXEXTRACT(argsList *list.List) formulaArg {
+	if argsList.Len() < 2 || argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, "REGEXEXTRACT requires 2 to 4 arguments")
+	}
+	argv := groupFuncArgs(argsList, 4)
+	re, errArg := fn.compileFormulaRegexp(argv[1], argv[3])
+	if re == nil {
+		return errArg
+	}
+	mode, errArg := groupIntArg(argv[2], 0)
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	if mode < 0 || mode > 2 {
+		return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	text := argv[0]
+	if mode != 0 && (text.Type == ArgMatrix || text.Type == ArgList) {
+		return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+	}
+	return mapFormulaArg(text, func(text formulaArg) formulaArg {
+		if text.Type == ArgError {
+			return text
+		}
+		switch mode {
+		case 1:
+			matches := re.FindAllString(text.Value(), -1)
+			if len(matches) == 0 {
+				return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+			}
+			mtx := make([][]formulaArg, len(matches))
+			for i, match := range matches {
+				mtx[i] = []formulaArg{newStringFormulaArg(match)}
+			}
+			return newMatrixFormulaArg(mtx)
+		case 2:
+			groups := re.FindStringSubmatch(text.Value())
+			if groups == nil {
+				return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+			}
+			if len(groups) > 1 {
+				groups = groups[1:]
+			}
+			row := make([]formulaArg, len(groups))
+			for i, group := range groups {
+				row[i] = newStringFormulaArg(group)
+			}
+			return newMatrixFormulaArg([][]formulaArg{row})
+		default:
+			loc := re.FindStringIndex(text.Value())
+			if loc == nil {
+				return newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+			}
+			return newStringFormulaArg(text.Value()[loc[0]:loc[1]])
+		}
+	})
+}
+
+// regexpReplacement converts the replacement string of the REGEXREPLACE
+// function to the template of the regular expression, the numbered group
+// reference such as $1 will be converted to ${1}, so that the following
+// characters will not be taken as a part of the group name, and other dollar
+// signs will be taken as literal characters.
+func regexpReplacement(replacement string) string {
+	var sb strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '$' {
+			sb.WriteByte(replacement[i])
+			continue
+		}
+		j := i + 1
+		for j < len(replacement) && replacement[j] >= '0' && replacement[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			if sb.WriteString("$$"); j < len(replacement) && replacement[j] == '$' {
+				i++
+			}
+			continue
+		}
+		sb.WriteString("${" + replacement[i+1:j] + "}")
+		i = j - 1
+	}
+	return sb.String()
+}
+
+// REGEXREPLACE function replaces strings within the supplied text that match
+// a regular expression with the replacement. The occurrence 0 replaces all
+// matches, a positive number replaces the nth match, and a negative number
+// replaces the nth match from the end. The syntax of the function is:
+//
+//	REGEXREPLACE(text,pattern,replacement,[occurrence],[case_sensitivity])
+func (fn *formulaFuncs) REGEXREPLACE(argsList *list.List) formulaArg {
+	if argsList.Len() < 3 || argsList.Len() > 5 {
+		return newErrorFormulaArg(formulaErrorVALUE, "REGEXREPLACE requires 3 to 5 arguments")
+	}
+	argv := groupFuncArgs(argsList, 5)
+	re, errArg := fn.compileFormulaRegexp(argv[1], argv[4])
+	if re == nil {
+		return errArg
+	}
+	if argv[2].Type == ArgError {
+		return argv[2]
+	}
+	occurrence, errArg := groupIntArg(argv[3], 0)
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	template := regexpReplacement(argv[2].Value())
+	return mapFormulaArg(argv[0], func(text formulaArg) formulaArg {
+		if text.Type == ArgError {
+			return text
+		}
+		str := text.Value()
+		if occurrence == 0 {
+			return newStringFormulaArg(re.ReplaceAllString(str, template))
+		}
+		matches := re.FindAllStringSubmatchIndex(str, -1)
+		idx := occurrence - 1
+		if occurrence < 0 {
+			idx = len(matches) + occurrence
+		}
+		if idx < 0 || idx >= len(matches) {
+			return newStringFormulaArg(str)
+		}
+		match := matches[idx]
+		return newStringFormulaArg(str[:match[0]] + string(re.ExpandString(nil, template, str, match)) + str[match[1]:])
+	})
+}
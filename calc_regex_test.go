@@ -0,0 +1,93 @@
+package excelize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcRegexFunctions(t *testing.T) {
+	cellData := [][]interface{}{
+		{"Order A-123 shipped", "abc@example.com"},
+		{"order b-45 pending", "ABC@EXAMPLE.COM"},
+		{"no code", "invalid"},
+	}
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"_xlfn.REGEXTEST(A1,\"[A-Z]-\\d+\")", "TRUE"},
+		{"_xlfn.REGEXTEST(A2,\"[A-Z]-\\d+\")", "FALSE"},
+		{"_xlfn.REGEXTEST(A2,\"[A-Z]-\\d+\",1)", "TRUE"},
+		{"_xlfn.REGEXTEST(123,\"^\\d+$\")", "TRUE"},
+		{"TEXTJOIN(\",\",FALSE,_xlfn.REGEXTEST(B1:B3,\"^[a-z]+@[a-z]+\\.com$\",1))", "TRUE,TRUE,FALSE"},
+		{"_xlfn.REGEXEXTRACT(A1,\"\\d+\")", "123"},
+		{"_xlfn.REGEXEXTRACT(A1,\"[a-z]+\",0,1)", "Order"},
+		{"TEXTJOIN(\",\",FALSE,_xlfn.REGEXEXTRACT(\"a1b22c333\",\"\\d+\",1))", "1,22,333"},
+		{"ROWS(_xlfn.REGEXEXTRACT(\"a1b22c333\",\"\\d+\",1))", "3"},
+		{"TEXTJOIN(\",\",FALSE,_xlfn.REGEXEXTRACT(A1,\"([A-Z])-(\\d+)\",2))", "A,123"},
+		{"COLUMNS(_xlfn.REGEXEXTRACT(A1,\"([A-Z])-(\\d+)(x)?\",2))", "3"},
+		{"TEXTJOIN(\",\",FALSE,_xlfn.REGEXEXTRACT(A1:A2,\"\\d+\"))", "123,45"},
+		{"_xlfn.REGEXREPLACE(A1,\"\\d\",\"#\")", "Order A-### shipped"},
+		{"_xlfn.REGEXREPLACE(A1,\"\\d\",\"#\",2)", "Order A-1#3 shipped"},
+		{"_xlfn.REGEXREPLACE(A1,\"\\d\",\"#\",-1)", "Order A-12# shipped"},
+		{"_xlfn.REGEXREPLACE(A1,\"\\d\",\"#\",4)", "Order A-123 shipped"},
+		{"_xlfn.REGEXREPLACE(A1,\"([A-Z])-(\\d+)\",\"$2_$1\")", "Order 123_A shipped"},
+		{"_xlfn.REGEXREPLACE(A2,\"ORDER\",\"Item\",0,1)", "Item b-45 pending"},
+		{"_xlfn.REGEXREPLACE(\"a.b\",\"\\.\",\"$$\")", "a$b"},
+		{"TEXTJOIN(\",\",FALSE,_xlfn.REGEXREPLACE(B1:B2,\"@.*\",\"\"))", "abc,ABC"},
+	} {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "D1")
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"_xlfn.REGEXTEST(A1)", formulaErrorVALUE},
+		{"_xlfn.REGEXTEST(A1,\"(\")", formulaErrorVALUE},
+		{"_xlfn.REGEXTEST(A1,\"a\",2)", formulaErrorVALUE},
+		{"_xlfn.REGEXTEST(A1,\"a\",\"x\")", formulaErrorVALUE},
+		{"_xlfn.REGEXTEST(A1,1/0)", formulaErrorDIV},
+		{"_xlfn.REGEXEXTRACT(A3,\"\\d+\")", formulaErrorNA},
+		{"_xlfn.REGEXEXTRACT(A3,\"\\d+\",1)", formulaErrorNA},
+		{"_xlfn.REGEXEXTRACT(A3,\"(\\d+)\",2)", formulaErrorNA},
+		{"_xlfn.REGEXEXTRACT(A1,\"\\d+\",3)", formulaErrorVALUE},
+		{"_xlfn.REGEXEXTRACT(A1:A2,\"\\d+\",1)", formulaErrorCALC},
+		{"_xlfn.REGEXEXTRACT(A1)", formulaErrorVALUE},
+		{"_xlfn.REGEXREPLACE(A1,\"a\")", formulaErrorVALUE},
+		{"_xlfn.REGEXREPLACE(A1,\"a\",1/0)", formulaErrorDIV},
+		{"_xlfn.REGEXREPLACE(A1,\"a\",\"b\",\"x\")", formulaErrorVALUE},
+	} {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "D1")
+		assert.Error(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+
+	// Test the compiled patterns are shared by the formulas
+	f := prepareCalcData(cellData)
+	for _, cell := range []string{"D1", "D2", "D3"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "_xlfn.REGEXTEST(A1,\"\\d+\")"))
+		_, err := f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, f.regexpCache.Len())
+}
+
+func TestRegexpReplacement(t *testing.T) {
+	for replacement, expected := range map[string]string{
+		"x":      "x",
+		"$1a":    "${1}a",
+		"$12$3":  "${12}${3}",
+		"$$":     "$$",
+		"$":      "$$",
+		"a$b":    "a$$b",
+		"${1}":   "$${1}",
+		"$0-$10": "${0}-${10}",
+	} {
+		assert.Equal(t, expected, regexpReplacement(replacement), replacement)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+)
+
+// linestCollinearTolerance is the relative tolerance of the pivot in the
+// sweep operator, the independent variable which is a linear combination of
+// the previous variables will be removed from the regression model.
+const linestCollinearTolerance = 1e-10
+
+// linestNumberMatrix converts the formula argument to a number matrix for
+// the LINEST and LOGEST functions.
+func linestNumberMatrix(arg formulaArg) ([][]float64, formulaArg) {
+	switch arg.Type {
+	case ArgError:
+		return nil, arg
+	case ArgMatrix:
+		if len(arg.Matrix) == 0 || len(arg.Matrix[0]) == 0 {
+			return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		return newNumberMatrix(arg, false)
+	}
+	num := arg.ToNumber()
+	if num.Type != ArgNumber {
+		return nil, newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+	}
+	return [][]float64{{num.Number}}, newEmptyFormulaArg()
+}
+
+// linestVariables returns the observations of the dependent variable and the
+// independent variables as the rows of the observations by given known y's
+// and known x's. Each column of the known x's is a variable if the known y's
+// is a single column, each row of the known x's is a variable if the known
+// y's is a single row.
+func linestVariables(knownY, knownX [][]float64) ([]float64, [][]float64, formulaArg) {
+	rows, cols := len(knownY), len(knownY[0])
+	var (
+		y   []float64
+		x   [][]float64
+		seq float64
+	)
+	for _, row := range knownY {
+		if len(row) != cols {
+			return nil, nil, newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+		}
+		y = append(y, row...)
+	}
+	if knownX == nil {
+		for range y {
+			seq++
+			x = append(x, []float64{seq})
+		}
+		return y, x, newEmptyFormulaArg()
+	}
+	xRows, xCols := len(knownX), len(knownX[0])
+	switch {
+	case xRows == rows && xCols == cols:
+		for _, row := range knownX {
+			for _, value := range row {
+				x = append(x, []float64{value})
+			}
+		}
+	case cols == 1 && xRows == rows:
+		for _, row := range knownX {
+			x = append(x, append([]float64{}, row...))
+		}
+	case rows == 1 && xCols == cols:
+		for c := 0; c < cols; c++ {
+			obs := make([]float64, xRows)
+			for r := range knownX {
+				obs[r] = knownX[r][c]
+			}
+			x = append(x, obs)
+		}
+	default:
+		return nil, nil, newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	return y, x, newEmptyFormulaArg()
+}
+
+// linestSweep applies the sweep operator on the given pivot of the symmetric
+// matrix.
+func linestSweep(mtx [][]float64, k int) {
+	d := mtx[k][k]
+	for j := range mtx[k] {
+		mtx[k][j] /= d
+	}
+	for i := range mtx {
+		if i == k {
+			continue
+		}
+		b := mtx[i][k]
+		for j := range mtx[i] {
+			mtx[i][j] -= b * mtx[k][j]
+		}
+		mtx[i][k] = -b / d
+	}
+	mtx[k][k] = 1 / d
+}
+
+// linest calculates the least squares regression by given observations of
+// the dependent variable and the independent variables, returns the result
+// matrix of the LINEST function. The collinear independent variables will
+// be removed from the model, and the coefficients and the standard errors of
+// those variables will be zero.
+func linest(y []float64, x [][]float64, constant, stats bool) [][]formulaArg {
+	n, k := len(y), len(x[0])
+	meanX, meanY := make([]float64, k), 0.0
+	if constant {
+		for i := range y {
+			meanY += y[i]
+			for j := 0; j < k; j++ {
+				meanX[j] += x[i][j]
+			}
+		}
+		meanY /= float64(n)
+		for j := range meanX {
+			meanX[j] /= float64(n)
+		}
+	}
+	// cross product matrix of the centered variables, the last row and column
+	// for the dependent variable
+	mtx := make([][]float64, k+1)
+	for i := range mtx {
+		mtx[i] = make([]float64, k+1)
+	}
+	for obs := range y {
+		row := make([]float64, k+1)
+		for j := 0; j < k; j++ {
+			row[j] = x[obs][j] - meanX[j]
+		}
+		row[k] = y[obs] - meanY
+		for i := range row {
+			for j := range row {
+				mtx[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	sst, diag := mtx[k][k], make([]float64, k)
+	for j := range diag {
+		diag[j] = mtx[j][j]
+	}
+	swept, params, minObs := make([]bool, k), 0, n
+	if constant {
+		minObs--
+	}
+	for j := 0; j < k; j++ {
+		if params < minObs && mtx[j][j] > linestCollinearTolerance*diag[j] {
+			linestSweep(mtx, j)
+			swept[j], params = true, params+1
+		}
+	}
+	slopes, intercept := make([]float64, k), 0.0
+	for j := 0; j < k; j++ {
+		if swept[j] {
+			slopes[j] = mtx[j][k]
+		}
+	}
+	if constant {
+		intercept = meanY
+		for j := 0; j < k; j++ {
+			intercept -= slopes[j] * meanX[j]
+		}
+	}
+	result := make([]formulaArg, k+1)
+	for j := 0; j < k; j++ {
+		result[k-1-j] = newNumberFormulaArg(slopes[j])
+	}
+	result[k] = newNumberFormulaArg(intercept)
+	if !stats {
+		return [][]formulaArg{result}
+	}
+	na := newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+	numErr := newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+	mtxStats := make([][]formulaArg, 5)
+	mtxStats[0] = result
+	for r := 1; r < 5; r++ {
+		mtxStats[r] = make([]formulaArg, k+1)
+		for c := range mtxStats[r] {
+			mtxStats[r][c] = na
+		}
+	}
+	ssResid := math.Max(mtx[k][k], 0)
+	if ssResid < sst*1e-15 {
+		ssResid = 0
+	}
+	ssReg := sst - ssResid
+	df := n - params
+	if constant {
+		df--
+	}
+	r2 := 1.0
+	if sst != 0 {
+		r2 = ssReg / sst
+	}
+	mtxStats[2][0], mtxStats[3][1] = newNumberFormulaArg(r2), newNumberFormulaArg(float64(df))
+	mtxStats[4][0], mtxStats[4][1] = newNumberFormulaArg(ssReg), newNumberFormulaArg(ssResid)
+	if df == 0 {
+		for c := 0; c <= k; c++ {
+			mtxStats[1][c] = numErr
+		}
+		mtxStats[2][1], mtxStats[3][0] = numErr, numErr
+		return mtxStats
+	}
+	mse := ssResid / float64(df)
+	mtxStats[2][1] = newNumberFormulaArg(math.Sqrt(mse))
+	if mtxStats[3][0] = numErr; ssResid != 0 && params > 0 {
+		mtxStats[3][0] = newNumberFormulaArg((ssReg / float64(params)) / mse)
+	}
+	interceptVar := 0.0
+	if constant {
+		interceptVar = 1 / float64(n)
+	}
+	for i := 0; i < k; i++ {
+		stdErr := 0.0
+		if swept[i] {
+			stdErr = math.Sqrt(mse * mtx[i][i])
+		}
+		mtxStats[1][k-1-i] = newNumberFormulaArg(stdErr)
+		for j := 0; j < k; j++ {
+			if swept[i] && swept[j] {
+				interceptVar += meanX[i] * mtx[i][j] * meanX[j]
+			}
+		}
+	}
+	if constant {
+		mtxStats[1][k] = newNumberFormulaArg(math.Sqrt(mse * interceptVar))
+	}
+	return mtxStats
+}
+
+// linestLogest is an implementation of the formula functions LINEST and
+// LOGEST.
+func (fn *formulaFuncs) linestLogest(name string, argsList *list.List) formulaArg {
+	if argsList.Len() < 1 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s requires at least 1 argument", name))
+	}
+	if argsList.Len() > 4 {
+		return newErrorFormulaArg(formulaErrorVALUE, fmt.Sprintf("%s allows at most 4 arguments", name))
+	}
+	argv := groupFuncArgs(argsList, 4)
+	knownY, errArg := linestNumberMatrix(argv[0])
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	var knownX [][]float64
+	if argv[1].Type != ArgEmpty {
+		if knownX, errArg = linestNumberMatrix(argv[1]); errArg.Type == ArgError {
+			return errArg
+		}
+	}
+	constArg, statsArg := newBoolFormulaArg(true), newBoolFormulaArg(false)
+	if argv[2].Type != ArgEmpty {
+		if constArg = argv[2].ToBool(); constArg.Type != ArgNumber {
+			return constArg
+		}
+	}
+	if argv[3].Type != ArgEmpty {
+		if statsArg = argv[3].ToBool(); statsArg.Type != ArgNumber {
+			return statsArg
+		}
+	}
+	y, x, errArg := linestVariables(knownY, knownX)
+	if errArg.Type == ArgError {
+		return errArg
+	}
+	if name == "LOGEST" {
+		for i, value := range y {
+			if value <= 0 {
+				return newErrorFormulaArg(formulaErrorNUM, formulaErrorNUM)
+			}
+			y[i] = math.Log(value)
+		}
+	}
+	mtx := linest(y, x, constArg.Number == 1, statsArg.Number == 1)
+	if name == "LOGEST" {
+		for c, coef := range mtx[0] {
+			mtx[0][c] = newNumberFormulaArg(math.Exp(coef.Number))
+		}
+	}
+	return newMatrixFormulaArg(mtx)
+}
+
+// LINEST function calculates the statistics for a straight line that best
+// fits the supplied data by using the least squares method, and returns an
+// array that describes the line. The syntax of the function is:
+//
+//	LINEST(known_y's,[known_x's],[const],[stats])
+func (fn *formulaFuncs) LINEST(argsList *list.List) formulaArg {
+	return fn.linestLogest("LINEST", argsList)
+}
+
+// LOGEST function calculates an exponential curve that fits the supplied
+// data and returns an array of values that describes the curve, the
+// statistics are calculated by the natural logarithm of the known y's. The
+// syntax of the function is:
+//
+//	LOGEST(known_y's,[known_x's],[const],[stats])
+func (fn *formulaFuncs) LOGEST(argsList *list.List) formulaArg {
+	return fn.linestLogest("LOGEST", argsList)
+}
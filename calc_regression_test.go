@@ -0,0 +1,105 @@
+package excelize
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcLINESTAndLOGEST(t *testing.T) {
+	cellData := [][]interface{}{
+		{"Floor space", "Offices", "Entrances", "Age", "Assessed value", "Month", "Units"},
+		{2310, 2, 2, 20, 142000, 11, 33100},
+		{2333, 2, 2, 12, 144000, 12, 47300},
+		{2356, 3, 1.5, 33, 151000, 13, 69000},
+		{2379, 3, 2, 43, 150000, 14, 102000},
+		{2402, 2, 3, 53, 139000, 15, 150000},
+		{2425, 4, 2, 23, 169000, 16, 220000},
+		{2448, 2, 1.5, 99, 126000},
+		{2471, 2, 2, 34, 142900},
+		{2494, 3, 3, 23, 163000},
+		{2517, 4, 4, 55, 169000},
+		{2540, 2, 3, 22, 149000},
+	}
+	for _, c := range []struct {
+		cell, formula string
+		expected      [][]float64
+	}{
+		{"I1", "LINEST(E2:E12,A2:D12,TRUE,TRUE)", [][]float64{
+			{-234.2371645, 2553.21066, 12529.76817, 27.64138737, 52317.83051},
+			{13.26801148, 530.6691519, 400.0668382, 5.429374042, 12237.3616},
+			{0.996747993, 970.5784629},
+			{459.7536742, 6},
+			{1732393319, 5652135.316},
+		}},
+		{"I1", "LOGEST(G2:G7,F2:F7,TRUE,TRUE)", [][]float64{
+			{1.463275628, 495.3047702},
+			{0.002633403, 0.035834408},
+			{0.999808620, 0.011016315},
+			{20896.80110, 4},
+			{2.536018830, 0.000485437},
+		}},
+		{"I1", "LINEST({1,9,5,7},{0,4,2,3})", [][]float64{{2, 1}}},
+		{"I1", "LINEST({3;5;7})", [][]float64{{2, 1}}},
+		{"I1", "LINEST({2,4,6},{1,2,3},FALSE)", [][]float64{{2, 0}}},
+		{"I1", "LOGEST({2,4,8},{1,2,3},FALSE)", [][]float64{{2, 1}}},
+		{"I1", "LINEST({1;2;3;5},{1,2;2,4;3,6;4,8},TRUE,TRUE)", [][]float64{
+			{0, 1.3, -0.5}, {0, 0.173205081, 0.474341649}, {0.965714286, 0.387298335}, {56.3333333, 2}, {8.45, 0.3},
+		}},
+	} {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", c.cell, c.formula))
+		topLeft, _, err := f.calcFormulaCell(context.Background(), "Sheet1", c.cell)
+		assert.NoError(t, err, c.formula)
+		col, row, _ := CellNameToCoordinates(c.cell)
+		for r, values := range c.expected {
+			for i, expected := range values {
+				cell, _ := CoordinatesToCellName(col+i, row+r)
+				result, err := f.GetCellValue("Sheet1", cell)
+				assert.NoError(t, err)
+				if r == 0 && i == 0 {
+					result = topLeft
+				}
+				actual, err := strconv.ParseFloat(result, 64)
+				assert.NoError(t, err, c.formula, cell)
+				assert.InDelta(t, expected, actual, 1e-5*max(1, expected), c.formula, cell)
+			}
+		}
+	}
+	// Test the statistics which are not applicable
+	f := prepareCalcData(cellData)
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"INDEX(LINEST(E2:E12,A2:A12,TRUE,TRUE),3,2)", "13123.5989151166"},
+		{"INDEX(LINEST(E2:E12,A2:B12,TRUE,TRUE),5,3)", formulaErrorNA},
+		{"INDEX(LINEST(E2:E12,A2:A12,FALSE,TRUE),2,2)", formulaErrorNA},
+		{"INDEX(LINEST({1,2},{1,2},TRUE,TRUE),2,1)", formulaErrorNUM},
+		{"INDEX(LINEST({1,2,3},{1,2,3},TRUE,TRUE),4,1)", formulaErrorNUM},
+		{"ROWS(LINEST(E2:E12,A2:D12))", "1"},
+		{"COLUMNS(LINEST(E2:E12,A2:D12))", "5"},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "I1", c.formula))
+		result, _ := f.CalcCellValue("Sheet1", "I1")
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"LINEST()", formulaErrorVALUE},
+		{"LINEST(E2:E12,A2:D12,TRUE,TRUE,1)", formulaErrorVALUE},
+		{"LINEST(A1:A3)", formulaErrorVALUE},
+		{"LINEST(E2:E12,A2:D11)", formulaErrorREF},
+		{"LINEST(E2:E12,A2:D12,\"x\")", formulaErrorVALUE},
+		{"LINEST(E2:E12,A2:D12,TRUE,\"x\")", formulaErrorVALUE},
+		{"LINEST(E2:E12,1/0)", formulaErrorDIV},
+		{"LOGEST({1,0,2})", formulaErrorNUM},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "I1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "I1")
+		assert.Error(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+}
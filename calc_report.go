@@ -0,0 +1,171 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCalcReportTopN defined the default number of the slowest formulas
+// recorded in the calculation report.
+const defaultCalcReportTopN = 10
+
+// CalcReport directly maps the statistics of the formulas calculation. Attach
+// the report to a context by the WithCalcReport function, and pass the
+// context to the context-aware calculation functions, such as
+// CalcCellValueContext, CalcCellValuesContext, RecalculateAllContext,
+// RecalculateSheetContext and BatchUpdateAndRecalculateContext, the report
+// will be filled during the calculation. The TopN field specifies the maximum
+// number of the slowest formulas to be recorded, which default to 10.
+//
+// FormulasEvaluated is the number of the evaluated formula cells, the cells
+// whose results were loaded from the calculation cache are counted by the
+// CacheHits, and the cells whose results were calculated by the batch
+// optimization of the RecalculateAll function are counted by the BatchHits.
+// Errors contains the formula cells calculated with errors, and
+// SlowestFormulas contains the slowest evaluated formula cells in descending
+// order of the duration.
+//
+// The report is safe to be shared by the concurrent calculations of the
+// different workbooks, read the fields after the calculations returned.
+type CalcReport struct {
+	TopN              int
+	FormulasEvaluated int
+	CacheHits         int
+	BatchHits         int
+	Errors            []CalcCellError
+	SlowestFormulas   []CalcFormulaTiming
+	mu                sync.Mutex
+	batched           map[string]bool
+}
+
+// CalcCellError directly maps the error of calculating a formula cell. The
+// Value field is the formula error value of the cell, such as #REF! or
+// #NAME?, which is empty if the formula wasn't evaluated to a result. The Err
+// field is the error returned by the calculation, for example, the message
+// of the unsupported function, the ErrCircularReference or the
+// ErrFormulaTimeout error.
+type CalcCellError struct {
+	Sheet   string
+	Cell    string
+	Formula string
+	Value   string
+	Err     error
+}
+
+// CalcFormulaTiming directly maps the duration of evaluating a formula cell.
+type CalcFormulaTiming struct {
+	Sheet    string
+	Cell     string
+	Formula  string
+	Duration time.Duration
+}
+
+// calcReportKey is the context key of the calculation report.
+type calcReportKey struct{}
+
+// WithCalcReport returns a copy of the given context with the calculation
+// report attached. For example, recalculate all formulas in the workbook and
+// get the 5 slowest formulas:
+//
+//	report := &excelize.CalcReport{TopN: 5}
+//	err := f.RecalculateAllContext(excelize.WithCalcReport(context.Background(), report))
+//	for _, timing := range report.SlowestFormulas {
+//	    fmt.Println(timing.Sheet, timing.Cell, timing.Formula, timing.Duration)
+//	}
+func WithCalcReport(ctx context.Context, report *CalcReport) context.Context {
+	return context.WithValue(ctx, calcReportKey{}, report)
+}
+
+// calcReportFromContext returns the calculation report attached to the given
+// context, or nil if the report doesn't exist.
+func calcReportFromContext(ctx context.Context) *CalcReport {
+	report, _ := ctx.Value(calcReportKey{}).(*CalcReport)
+	return report
+}
+
+// topN returns the maximum number of the slowest formulas to be recorded.
+func (r *CalcReport) topN() int {
+	if r.TopN > 0 {
+		return r.TopN
+	}
+	return defaultCalcReportTopN
+}
+
+// markBatched marks the formula cells calculated by the batch optimization,
+// the keys of the given results are in the "Sheet!Cell" format.
+func (r *CalcReport) markBatched(results map[string]float64) {
+	if r == nil || len(results) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.batched == nil {
+		r.batched = make(map[string]bool, len(results))
+	}
+	for cell := range results {
+		r.batched[cell] = true
+	}
+}
+
+// record adds the calculation of the formula cell to the report by given
+// duration, whether the result was loaded from the calculation cache, the
+// formatted result and the error of the calculation.
+func (r *CalcReport) record(f *File, sheet, cell string, duration time.Duration, cached bool, result string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if cached {
+		if r.batched[sheet+"!"+cell] {
+			r.BatchHits++
+		} else {
+			r.CacheHits++
+		}
+		r.mu.Unlock()
+		return
+	}
+	r.FormulasEvaluated++
+	n := len(r.SlowestFormulas)
+	slow := n < r.topN() || duration > r.SlowestFormulas[n-1].Duration
+	r.mu.Unlock()
+	if !slow && err == nil {
+		return
+	}
+	formula, _ := f.getCellFormulaReadOnly(sheet, cell, true)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		cellErr := CalcCellError{Sheet: sheet, Cell: cell, Formula: formula, Err: err}
+		if strings.HasPrefix(result, "#") && !errors.Is(err, ErrCircularReference) && !errors.Is(err, ErrFormulaTimeout) {
+			cellErr.Value = result
+		}
+		r.Errors = append(r.Errors, cellErr)
+	}
+	if slow {
+		timing := CalcFormulaTiming{Sheet: sheet, Cell: cell, Formula: formula, Duration: duration}
+		idx := sort.Search(len(r.SlowestFormulas), func(i int) bool {
+			return r.SlowestFormulas[i].Duration < duration
+		})
+		r.SlowestFormulas = append(r.SlowestFormulas, CalcFormulaTiming{})
+		copy(r.SlowestFormulas[idx+1:], r.SlowestFormulas[idx:])
+		r.SlowestFormulas[idx] = timing
+		if len(r.SlowestFormulas) > r.topN() {
+			r.SlowestFormulas = r.SlowestFormulas[:r.topN()]
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package excelize
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcReport(t *testing.T) {
+	prepare := func(workers int) *File {
+		f := NewFile(Options{MaxCalcWorkers: workers})
+		assert.NoError(t, f.RegisterFormulaFunc("SLEEP", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+			time.Sleep(time.Duration(args[0].Number) * time.Millisecond)
+			return args[0], nil
+		}))
+		for r := 1; r <= 12; r++ {
+			row := strconv.Itoa(r)
+			assert.NoError(t, f.SetSheetRow("Sheet1", "A"+row, &[]interface{}{"k" + strconv.Itoa(r%3), r, "k" + strconv.Itoa(r%3)}))
+			assert.NoError(t, f.SetCellFormula("Sheet1", "D"+row, "SUMIFS(Sheet1!$B:$B,Sheet1!$A:$A,C"+row+")"))
+		}
+		for cell, formula := range map[string]string{
+			"E1": "1/0",
+			"E2": "UNKNOWN(1)",
+			"E3": "SLEEP(30)",
+			"E4": "SLEEP(10)",
+			"E5": "E6",
+			"E6": "E5",
+			"E7": "B1*2",
+		} {
+			assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+		}
+		assert.NoError(t, f.RebuildCalcChain())
+		return f
+	}
+	for _, workers := range []int{0, 2} {
+		f := prepare(workers)
+		report := &CalcReport{TopN: 2}
+		assert.ErrorIs(t, f.RecalculateAllContext(WithCalcReport(context.Background(), report)), ErrCircularReference)
+		// The SUMIFS formulas are evaluated with the shared criteria-aggregation
+		// table instead of the batch optimization
+		assert.Equal(t, 19, report.FormulasEvaluated)
+		assert.Zero(t, report.BatchHits)
+		assert.Equal(t, 0, report.CacheHits)
+		assert.Len(t, report.SlowestFormulas, 2)
+		assert.ElementsMatch(t, []string{"E3", "E4"}, []string{report.SlowestFormulas[0].Cell, report.SlowestFormulas[1].Cell})
+		assert.GreaterOrEqual(t, report.SlowestFormulas[0].Duration, report.SlowestFormulas[1].Duration)
+		for _, timing := range report.SlowestFormulas {
+			if timing.Cell == "E3" {
+				assert.Equal(t, CalcFormulaTiming{Sheet: "Sheet1", Cell: "E3", Formula: "SLEEP(30)", Duration: timing.Duration}, timing)
+				assert.GreaterOrEqual(t, timing.Duration, 30*time.Millisecond)
+			}
+		}
+		assert.Len(t, report.Errors, 4)
+		errs := map[string]CalcCellError{}
+		for _, cellErr := range report.Errors {
+			errs[cellErr.Cell] = cellErr
+		}
+		assert.Equal(t, CalcCellError{Sheet: "Sheet1", Cell: "E1", Formula: "1/0", Value: "#DIV/0!", Err: errors.New("#DIV/0!")}, errs["E1"])
+		assert.Equal(t, "#VALUE!", errs["E2"].Value)
+		assert.EqualError(t, errs["E2"].Err, "not support UNKNOWN function")
+		assert.ErrorIs(t, errs["E5"].Err, ErrCircularReference)
+		assert.Empty(t, errs["E5"].Value)
+		assert.ErrorIs(t, errs["E6"].Err, ErrCircularReference)
+
+		// Test the results loaded from the calculation cache
+		report = &CalcReport{}
+		results, err := f.CalcCellValuesContext(WithCalcReport(context.Background(), report), "Sheet1", []string{"D1", "E7"}, Options{RawCellValue: true})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"D1": "22", "E7": "2"}, results)
+		assert.Equal(t, 2, report.CacheHits)
+		assert.Zero(t, report.FormulasEvaluated)
+	}
+
+	// Test the reports of the incremental and sheet recalculation
+	f := prepare(0)
+	report := &CalcReport{}
+	ctx := WithCalcReport(context.Background(), report)
+	assert.ErrorIs(t, f.RecalculateSheetContext(ctx, "Sheet1"), ErrCircularReference)
+	assert.Equal(t, 19, report.FormulasEvaluated)
+	assert.Len(t, report.SlowestFormulas, 10)
+	report = &CalcReport{}
+	assert.NoError(t, f.BatchUpdateAndRecalculateContext(WithCalcReport(context.Background(), report),
+		[]CellUpdate{{Sheet: "Sheet1", Cell: "B1", Value: 5}}))
+	assert.Equal(t, 13, report.FormulasEvaluated)
+	assert.Empty(t, report.Errors)
+
+	// Test the report without any calculation
+	var nilReport *CalcReport
+	nilReport.record(f, "Sheet1", "A1", time.Second, false, "", nil)
+	nilReport.markBatched(map[string]float64{"Sheet1!A1": 1})
+	assert.Nil(t, calcReportFromContext(context.Background()))
+}
@@ -0,0 +1,462 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dynamicArrayMetadataType defined the name of the metadata type and future
+// metadata for the dynamic array formula cells.
+const dynamicArrayMetadataType = "XLDAPR"
+
+// spillRangeOperatorExp matching the spill range operator, such as A1#,
+// Sheet1!$A$1# or 'Sheet 1'!A1#.
+var spillRangeOperatorExp = regexp.MustCompile(`(^|[^A-Za-z0-9_.$'\]])((?:'(?:[^']|'')+'|[A-Za-z0-9_.]+)!)?(\$?[A-Za-z]{1,3}\$?\d+)#`)
+
+// replaceSpillRangeOperator replace the spill range operator in the formula
+// with the ANCHORARRAY function, the form in which the spreadsheet
+// application stores the spill range references in the workbook.
+func replaceSpillRangeOperator(formula string) string {
+	if !strings.Contains(formula, "#") {
+		return formula
+	}
+	parts := strings.Split(formula, "\"")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = spillRangeOperatorExp.ReplaceAllString(parts[i], "${1}_xlfn.ANCHORARRAY(${2}${3})")
+	}
+	return strings.Join(parts, "\"")
+}
+
+// arrayTopLeftValue returns the top-left element of the array formula
+// argument, other kinds of the formula argument will be returned directly.
+func arrayTopLeftValue(arg formulaArg) formulaArg {
+	if arg.Type != ArgMatrix {
+		return arg
+	}
+	if len(arg.Matrix) == 0 || len(arg.Matrix[0]) == 0 {
+		return newEmptyFormulaArg()
+	}
+	return arg.Matrix[0][0]
+}
+
+// anchorArrayResolver calc the whole array result of the dynamic array
+// formula by given worksheet name and anchor cell reference.
+func (f *File) anchorArrayResolver(ctx *calcContext, sheet, cell string) (formulaArg, error) {
+	ref := fmt.Sprintf("%s!%s", sheet, cell)
+	ctx = ctx.root()
+	ctx.mu.Lock()
+	arg, ok := ctx.iterationsCache[ref]
+	ctx.mu.Unlock()
+	if ok {
+		return arg, nil
+	}
+	if ctx.entry == ref {
+		return newErrorFormulaArg(formulaErrorREF, formulaErrorREF), nil
+	}
+	return f.calcCellValue(ctx, sheet, cell)
+}
+
+// isDynamicArrayCell returns if the cell metadata of the given cell refers to
+// the dynamic array properties.
+func isDynamicArrayCell(metadata *xlsxMetadata, c *xlsxC) bool {
+	if c.Cm == nil || metadata == nil || metadata.CellMetadata == nil || metadata.MetadataTypes == nil {
+		return false
+	}
+	if idx := int(*c.Cm) - 1; idx >= 0 && idx < len(metadata.CellMetadata.Bk) {
+		for _, rc := range metadata.CellMetadata.Bk[idx].Rc {
+			if rc.T > 0 && rc.T <= len(metadata.MetadataTypes.MetadataType) &&
+				metadata.MetadataTypes.MetadataType[rc.T-1].Name == dynamicArrayMetadataType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setDynamicArrayMetadata provides a function to prepare the metadata part
+// with the dynamic array properties, and returns the index of the cell
+// metadata record for the dynamic array formula cells.
+func (f *File) setDynamicArrayMetadata() (uint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	metadata, err := f.metadataReader()
+	if err != nil {
+		return 0, err
+	}
+	if metadata.MetadataTypes == nil {
+		metadata.MetadataTypes = &xlsxMetadataTypes{}
+	}
+	typeIdx := -1
+	for i, metadataType := range metadata.MetadataTypes.MetadataType {
+		if metadataType.Name == dynamicArrayMetadataType {
+			typeIdx = i + 1
+			break
+		}
+	}
+	futureIdx := -1
+	for i, futureMetadata := range metadata.FutureMetadata {
+		if futureMetadata.Name == dynamicArrayMetadataType && len(futureMetadata.Bk) > 0 {
+			futureIdx = i
+			break
+		}
+	}
+	if typeIdx != -1 && futureIdx != -1 && metadata.CellMetadata != nil {
+		for i, bk := range metadata.CellMetadata.Bk {
+			for _, rc := range bk.Rc {
+				if rc.T == typeIdx && rc.V == 0 {
+					return uint(i + 1), err
+				}
+			}
+		}
+	}
+	if typeIdx == -1 {
+		metadata.MetadataTypes.MetadataType = append(metadata.MetadataTypes.MetadataType, xlsxMetadataType{
+			Name: dynamicArrayMetadataType, MinSupportedVersion: 120000, Copy: true, PasteAll: true,
+			PasteValues: true, Merge: true, SplitFirst: true, RowColShift: true, ClearFormats: true,
+			ClearComments: true, Assign: true, Coerce: true, CellMeta: true,
+		})
+		typeIdx = len(metadata.MetadataTypes.MetadataType)
+	}
+	metadata.MetadataTypes.Count = len(metadata.MetadataTypes.MetadataType)
+	if futureIdx == -1 {
+		metadata.FutureMetadata = append(metadata.FutureMetadata, xlsxFutureMetadata{
+			Name: dynamicArrayMetadataType, Count: 1,
+			Bk: []xlsxFutureMetadataBlock{{ExtLst: &xlsxInnerXML{
+				Content: fmt.Sprintf(`<ext uri="%s"><xda:dynamicArrayProperties fDynamic="1" fCollapsed="0"/></ext>`, ExtURIDynamicArrayProperties),
+			}}},
+		})
+	}
+	if metadata.CellMetadata == nil {
+		metadata.CellMetadata = &xlsxMetadataBlocks{}
+	}
+	metadata.CellMetadata.Bk = append(metadata.CellMetadata.Bk, xlsxMetadataBlock{Rc: []xlsxMetadataRecord{{T: typeIdx, V: 0}}})
+	metadata.CellMetadata.Count = len(metadata.CellMetadata.Bk)
+	if _, ok := f.xmlAttr.Load(defaultXMLMetadata); !ok {
+		attrs := getRootElement(xml.NewDecoder(bytes.NewReader(f.readXML(defaultXMLMetadata))))
+		if attrs == nil {
+			attrs = []xml.Attr{NameSpaceSpreadSheet}
+		}
+		f.xmlAttr.Store(defaultXMLMetadata, attrs)
+	}
+	f.addNameSpaces(defaultXMLMetadata, NameSpaceDynamicArray)
+	var output bytes.Buffer
+	if err = xml.NewEncoder(&output).EncodeElement(metadata, xml.StartElement{
+		Name: xml.Name{Space: NameSpaceSpreadSheet.Value, Local: "metadata"},
+	}); err != nil {
+		return 0, err
+	}
+	f.saveFileList(defaultXMLMetadata, f.replaceNameSpaceBytes(defaultXMLMetadata, output.Bytes()))
+	if err = f.addContentTypePart(0, "metadata"); err != nil {
+		return 0, err
+	}
+	relPath := f.getWorkbookRelsPath()
+	rels, err := f.relsReader(relPath)
+	if err != nil {
+		return 0, err
+	}
+	var exist bool
+	if rels != nil {
+		for _, rel := range rels.Relationships {
+			if rel.Type == SourceRelationshipSheetMetadata {
+				exist = true
+			}
+		}
+	}
+	if !exist {
+		f.addRels(relPath, SourceRelationshipSheetMetadata, "metadata.xml", "")
+	}
+	return uint(metadata.CellMetadata.Count), err
+}
+
+// spillFormulaResult provides a function to write the array result of the
+// dynamic array formula into the spill range which starts from the anchor
+// cell, and returns the value of the anchor cell. The formula result will be
+// the #SPILL! error if any non-empty cells or merged cells in the spill range.
+func (f *File) spillFormulaResult(sheet, cell string, result formulaArg) (formulaArg, error) {
+	ws, err := f.workSheetReader(sheet)
+	if err != nil {
+		return result, err
+	}
+	col, row, err := CellNameToCoordinates(cell)
+	if err != nil {
+		return result, err
+	}
+	ws.mu.RLock()
+	anchor := ws.getCellReadOnly(col, row)
+	if anchor == nil || anchor.F == nil || anchor.F.T == STCellFormulaTypeShared ||
+		(anchor.F.T == STCellFormulaTypeArray && anchor.Cm == nil) {
+		ws.mu.RUnlock()
+		if anchor != nil && anchor.F != nil && anchor.F.T == STCellFormulaTypeShared {
+			return implicitIntersection(result, col, row), err
+		}
+		return arrayTopLeftValue(result), err
+	}
+	oldRef := anchor.F.Ref
+	ws.mu.RUnlock()
+	if isEntireRowColumnRef(result) {
+		result = implicitIntersection(result, col, row)
+	}
+	rows, cols := 1, 1
+	if result.Type == ArgMatrix && len(result.Matrix) > 0 {
+		rows, cols = len(result.Matrix), 0
+		for _, r := range result.Matrix {
+			cols = max(cols, len(r))
+		}
+		if cols == 0 {
+			rows, cols = 1, 1
+		}
+	}
+	if rows == 1 && cols == 1 {
+		if oldRef != "" {
+			err = f.clearSpillRange(ws, sheet, cell, oldRef, nil, true)
+		}
+		return arrayTopLeftValue(result), err
+	}
+	var cm uint
+	if cm, err = f.setDynamicArrayMetadata(); err != nil {
+		return result, err
+	}
+	coordinates := []int{col, row, col + cols - 1, row + rows - 1}
+	ws.mu.Lock()
+	blocked := ws.isSpillRangeBlocked(coordinates, oldRef)
+	ws.mu.Unlock()
+	if blocked {
+		coordinates = nil
+	}
+	if err = f.clearSpillRange(ws, sheet, cell, oldRef, coordinates, false); err != nil {
+		return result, err
+	}
+	ws.mu.Lock()
+	ref := cell
+	if !blocked {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				if r == 0 && c == 0 {
+					continue
+				}
+				arg := newErrorFormulaArg(formulaErrorNA, formulaErrorNA)
+				if c < len(result.Matrix[r]) {
+					arg = result.Matrix[r][c]
+				}
+				ws.prepareSheetXML(col+c, row+r)
+				spillCell := &ws.SheetData.Row[row+r-1].C[col+c-1]
+				spillCell.setSpillValue(arg)
+				ws.spillValues.Store(spillCell.R, spillValue{t: spillCell.T, v: spillCell.V})
+			}
+		}
+		ref, _ = coordinatesToRangeRef(coordinates)
+	}
+	ws.prepareSheetXML(col, row)
+	anchor = &ws.SheetData.Row[row-1].C[col-1]
+	anchor.F.T, anchor.F.Ref, anchor.Cm = STCellFormulaTypeArray, ref, &cm
+	ws.mu.Unlock()
+	var spilled []string
+	for r := 0; r < rows && !blocked; r++ {
+		for c := 0; c < cols; c++ {
+			if name, _ := CoordinatesToCellName(col+c, row+r); name != cell {
+				spilled = append(spilled, name)
+			}
+		}
+	}
+	f.clearCellCache(sheet, spilled...)
+	if blocked {
+		return newErrorFormulaArg(formulaErrorSPILL, formulaErrorSPILL), errors.New(formulaErrorSPILL)
+	}
+	return arrayTopLeftValue(result), err
+}
+
+// getCellReadOnly returns the cell by given coordinates without creating the
+// row and cell if not exists.
+func (ws *xlsxWorksheet) getCellReadOnly(col, row int) *xlsxC {
+	if row < 1 || row > len(ws.SheetData.Row) || col < 1 || col > len(ws.SheetData.Row[row-1].C) {
+		return nil
+	}
+	return &ws.SheetData.Row[row-1].C[col-1]
+}
+
+// isSpillRangeBlocked returns if the spill range of the dynamic array formula
+// overlaps with the merged cells or non-empty cells which not in the previous
+// spill range of the anchor cell.
+func (ws *xlsxWorksheet) isSpillRangeBlocked(coordinates []int, oldRef string) bool {
+	if coordinates[2] > MaxColumns || coordinates[3] > TotalRows {
+		return true
+	}
+	if ws.MergeCells != nil {
+		for _, mergeCell := range ws.MergeCells.Cells {
+			if mergeCell == nil {
+				continue
+			}
+			rect, err := rangeRefToCoordinates(mergeCell.Ref)
+			if err != nil {
+				continue
+			}
+			_ = sortCoordinates(rect)
+			if rect[0] <= coordinates[2] && rect[2] >= coordinates[0] && rect[1] <= coordinates[3] && rect[3] >= coordinates[1] {
+				return true
+			}
+		}
+	}
+	oldRange, _ := rangeRefToCoordinates(oldRef)
+	for row := coordinates[1]; row <= coordinates[3]; row++ {
+		for col := coordinates[0]; col <= coordinates[2]; col++ {
+			if col == coordinates[0] && row == coordinates[1] {
+				continue
+			}
+			c := ws.getCellReadOnly(col, row)
+			if c == nil {
+				continue
+			}
+			if c.F != nil || c.f != "" {
+				return true
+			}
+			inOldRange := len(oldRange) == 4 && col >= oldRange[0] && col <= oldRange[2] && row >= oldRange[1] && row <= oldRange[3]
+			if (!inOldRange || !ws.isSpillCell(c)) && (c.V != "" || c.IS != nil) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clearSpillRange clears the values of the cells in the previous spill range
+// of the anchor cell which not in the given new spill range. The dynamic
+// array properties of the anchor cell will be removed if reset is true.
+func (f *File) clearSpillRange(ws *xlsxWorksheet, sheet, cell, oldRef string, coordinates []int, reset bool) error {
+	ws.mu.Lock()
+	oldRange, err := rangeRefToCoordinates(oldRef)
+	var cleared []string
+	if err == nil {
+		for row := oldRange[1]; row <= oldRange[3]; row++ {
+			for col := oldRange[0]; col <= oldRange[2]; col++ {
+				if len(coordinates) == 4 && col >= coordinates[0] && col <= coordinates[2] && row >= coordinates[1] && row <= coordinates[3] {
+					continue
+				}
+				c := ws.getCellReadOnly(col, row)
+				if c == nil || c.R == cell || c.F != nil || !ws.isSpillCell(c) {
+					continue
+				}
+				ws.spillValues.Delete(c.R)
+				c.T, c.V, c.IS = "", "", nil
+				cleared = append(cleared, c.R)
+			}
+		}
+	}
+	if reset {
+		if col, row, err := CellNameToCoordinates(cell); err == nil {
+			if anchor := ws.getCellReadOnly(col, row); anchor != nil && anchor.F != nil {
+				anchor.F.T, anchor.F.Ref, anchor.Cm = "", "", nil
+			}
+		}
+	}
+	ws.mu.Unlock()
+	f.clearCellCache(sheet, cleared...)
+	return nil
+}
+
+// spillValue is the type and value of the cell written by the dynamic array
+// formula in its spill range.
+type spillValue struct {
+	t, v string
+}
+
+// isSpillCell returns if the value of the cell in the previous spill range
+// was written by the dynamic array formula. The cells changed or set by the
+// cell setters after spilling block the spill range instead of being
+// overwritten.
+func (ws *xlsxWorksheet) isSpillCell(c *xlsxC) bool {
+	written, ok := ws.spillValues.Load(c.R)
+	return ok && c.IS == nil && written.(spillValue) == spillValue{t: c.T, v: c.V}
+}
+
+// loadSpillValues records the values of the cells in the spill ranges of the
+// dynamic array formulas as written by the formulas, it should be called
+// after the worksheet was loaded from the workbook.
+func (ws *xlsxWorksheet) loadSpillValues() {
+	for r := range ws.SheetData.Row {
+		for c := range ws.SheetData.Row[r].C {
+			anchor := &ws.SheetData.Row[r].C[c]
+			if anchor.F == nil || anchor.F.T != STCellFormulaTypeArray || anchor.Cm == nil || anchor.F.Ref == "" {
+				continue
+			}
+			coordinates, err := rangeRefToCoordinates(anchor.F.Ref)
+			if err != nil {
+				continue
+			}
+			for row := coordinates[1]; row <= coordinates[3]; row++ {
+				for col := coordinates[0]; col <= coordinates[2]; col++ {
+					if cell := ws.getCellReadOnly(col, row); cell != nil && cell != anchor && cell.F == nil && cell.IS == nil {
+						ws.spillValues.Store(cell.R, spillValue{t: cell.T, v: cell.V})
+					}
+				}
+			}
+		}
+	}
+}
+
+// adjustSpillValues moves the recorded values of the cells in the spill
+// ranges when inserting or deleting rows or columns, the records of the
+// deleted cells will be removed.
+func (ws *xlsxWorksheet) adjustSpillValues(dir adjustDirection, num, offset int) {
+	moved := map[string]interface{}{}
+	ws.spillValues.Range(func(cell, value interface{}) bool {
+		ws.spillValues.Delete(cell)
+		col, row, err := CellNameToCoordinates(cell.(string))
+		if err != nil {
+			return true
+		}
+		pos := &row
+		if dir == columns {
+			pos = &col
+		}
+		if *pos >= num {
+			if offset < 0 && *pos < num-offset {
+				return true
+			}
+			*pos += offset
+		}
+		name, _ := CoordinatesToCellName(col, row)
+		moved[name] = value
+		return true
+	})
+	for cell, value := range moved {
+		ws.spillValues.Store(cell, value)
+	}
+}
+
+// setSpillValue set the value of the cell in the spill range by given formula
+// argument.
+func (c *xlsxC) setSpillValue(arg formulaArg) {
+	c.IS = nil
+	switch arg.Type {
+	case ArgNumber:
+		if arg.Boolean {
+			c.T, c.V = setCellBool(arg.Number != 0)
+			return
+		}
+		c.T, c.V = "", strconv.FormatFloat(arg.Number, 'f', -1, 64)
+	case ArgString:
+		c.setStr(arg.String)
+	case ArgError:
+		c.T, c.V = "e", arg.String
+	case ArgMatrix, ArgLambda:
+		c.T, c.V = "e", formulaErrorCALC
+	default:
+		c.T, c.V = "", ""
+	}
+}
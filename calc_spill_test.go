@@ -0,0 +1,353 @@
+package excelize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcSpillFormulaResult(t *testing.T) {
+	cellData := [][]interface{}{
+		{1, 4},
+		{2, 5},
+		{3, 6},
+	}
+	for formula, expected := range map[string][][]string{
+		"A1:B3*2":                  {{"2", "8"}, {"4", "10"}, {"6", "12"}},
+		"A1:A3+1":                  {{"2"}, {"3"}, {"4"}},
+		"-A1:A3":                   {{"-1"}, {"-2"}, {"-3"}},
+		"A1:A3&\"x\"":              {{"1x"}, {"2x"}, {"3x"}},
+		"A1:A3+B1:B3":              {{"5"}, {"7"}, {"9"}},
+		"A1:A3*TRANSPOSE(B1:B2)":   {{"4", "5"}, {"8", "10"}, {"12", "15"}},
+		"A1:A3/A1:A2":              {{"1"}, {"1"}, {"#N/A"}},
+		"TRANSPOSE(A1:A3)":         {{"1", "2", "3"}},
+		"A1:A3>1":                  {{"FALSE"}, {"TRUE"}, {"TRUE"}},
+		"MAP(A1:A3,LAMBDA(x,x^2))": {{"1"}, {"4"}, {"9"}},
+		"SUM(A1:B3)":               {{"21"}},
+	} {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "D1", formula))
+		result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "D1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected[0][0], result, formula)
+		for r, row := range expected {
+			for c, value := range row {
+				cell, _ := CoordinatesToCellName(4+c, 1+r)
+				if cell == "D1" {
+					continue
+				}
+				result, err := f.GetCellValue("Sheet1", cell)
+				assert.NoError(t, err, formula)
+				assert.Equal(t, value, result, formula, cell)
+			}
+		}
+	}
+	// Test the spill range operator
+	f := prepareCalcData(cellData)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "A1:B3*2"))
+	for formula, expected := range map[string]string{
+		"SUM(D1#)":                       "42",
+		"SUM(Sheet1!$D$1#)":              "42",
+		"ROWS(D1#)":                      "3",
+		"COLUMNS(_xlfn.ANCHORARRAY(D1))": "2",
+		"\"D1#\"":                        "D1#",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "G1", formula))
+		result, err := f.CalcCellValue("Sheet1", "G1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "\"D1#\""))
+	formula, err := f.GetCellFormula("Sheet1", "G1")
+	assert.NoError(t, err)
+	assert.Equal(t, "\"D1#\"", formula)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "SUM(D1#)"))
+	formula, err = f.GetCellFormula("Sheet1", "G1")
+	assert.NoError(t, err)
+	assert.Equal(t, "SUM(_xlfn.ANCHORARRAY(D1))", formula)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "ISREF(A1#)"))
+	result, err := f.CalcCellValue("Sheet1", "G1")
+	assert.NoError(t, err)
+	assert.Equal(t, "FALSE", result)
+
+	// Test the spill range shrinks after the formula changed
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "A1:A2*2"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	for cell, expected := range map[string]string{"D2": "4", "D3": "", "E1": "", "E3": ""} {
+		result, err := f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "SUM(A1:A2)"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result)
+	result, err = f.GetCellValue("Sheet1", "D2")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	ws, ok := f.Sheet.Load("xl/worksheets/sheet1.xml")
+	assert.True(t, ok)
+	assert.Empty(t, ws.(*xlsxWorksheet).SheetData.Row[0].C[3].F.Ref)
+	assert.Nil(t, ws.(*xlsxWorksheet).SheetData.Row[0].C[3].Cm)
+
+	// Test the formula result will not be spilled by the CalcFormulaValue and
+	// CalcCellValue functions
+	f = prepareCalcData(cellData)
+	result, err = f.CalcFormulaValue("Sheet1", "D1", "A1:B3*2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	result, err = f.GetCellValue("Sheet1", "D2")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "A1:B3*2"))
+	result, err = f.CalcCellValue("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	result, err = f.GetCellValue("Sheet1", "D2")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	_, ok = f.Pkg.Load(defaultXMLMetadata)
+	assert.False(t, ok)
+	// Test the recalculation spills the result after calculated by the
+	// CalcCellValue function
+	assert.NoError(t, f.recalculateCell(context.Background(), "Sheet1", "D1"))
+	result, err = f.GetCellValue("Sheet1", "E3")
+	assert.NoError(t, err)
+	assert.Equal(t, "12", result)
+}
+
+func TestCalcSpillBlocked(t *testing.T) {
+	f := prepareCalcData([][]interface{}{{1}, {2}, {3}})
+	assert.NoError(t, f.SetCellValue("Sheet1", "C3", "blocker"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C1", "A1:A3*2"))
+	result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "C1")
+	assert.EqualError(t, err, formulaErrorSPILL)
+	assert.Equal(t, formulaErrorSPILL, result)
+	result, err = f.GetCellValue("Sheet1", "C2")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	// Test the spill range will be written after the blocker removed
+	assert.NoError(t, f.SetCellValue("Sheet1", "C3", nil))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "C1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	result, err = f.GetCellValue("Sheet1", "C3")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", result)
+	// Test the value typed into the existing spill range blocks the spill
+	assert.NoError(t, f.SetCellValue("Sheet1", "C3", "x"))
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateAll())
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "C1")
+	assert.EqualError(t, err, formulaErrorSPILL)
+	assert.Equal(t, formulaErrorSPILL, result)
+	for cell, expected := range map[string]string{"C2": "", "C3": "x"} {
+		result, err = f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+	assert.NoError(t, f.SetCellValue("Sheet1", "C3", nil))
+	assert.NoError(t, f.RecalculateAll())
+	result, err = f.GetCellValue("Sheet1", "C3")
+	assert.NoError(t, err)
+	assert.Equal(t, "6", result)
+	// Test the spill range blocked by the formula and merged cells
+	assert.NoError(t, f.SetCellFormula("Sheet1", "E2", "1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "E1", "A1:A3"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "E1")
+	assert.EqualError(t, err, formulaErrorSPILL)
+	assert.Equal(t, formulaErrorSPILL, result)
+	assert.NoError(t, f.MergeCell("Sheet1", "G2", "G3"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "A1:A3"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "G1")
+	assert.EqualError(t, err, formulaErrorSPILL)
+	assert.Equal(t, formulaErrorSPILL, result)
+	// Test the spill range exceeds the worksheet
+	assert.NoError(t, f.SetCellFormula("Sheet1", "XFD1", "TRANSPOSE(A1:A3)"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "XFD1")
+	assert.EqualError(t, err, formulaErrorSPILL)
+	assert.Equal(t, formulaErrorSPILL, result)
+}
+
+func TestCalcSpillMetadata(t *testing.T) {
+	f := prepareCalcData([][]interface{}{{1, 4}, {2, 5}, {3, 6}})
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "A1:B3*2"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "G1", "A1:B1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "H1", "A1:A2"))
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateAll())
+	for cell, expected := range map[string]string{"D1": "2", "E3": "12", "G1": "", "H1": "1", "H2": "2"} {
+		result, err := f.GetCellValue("Sheet1", cell)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, cell)
+	}
+	path := filepath.Join("test", "TestCalcSpillMetadata.xlsx")
+	assert.NoError(t, f.SaveAs(path))
+	assert.NoError(t, f.Close())
+
+	f, err := OpenFile(path)
+	assert.NoError(t, err)
+	metadata, err := f.metadataReader()
+	assert.NoError(t, err)
+	assert.Len(t, metadata.MetadataTypes.MetadataType, 1)
+	assert.Equal(t, dynamicArrayMetadataType, metadata.MetadataTypes.MetadataType[0].Name)
+	assert.Len(t, metadata.FutureMetadata, 1)
+	assert.Len(t, metadata.CellMetadata.Bk, 1)
+	content, ok := f.Pkg.Load(defaultXMLMetadata)
+	assert.True(t, ok)
+	assert.True(t, bytes.Contains(content.([]byte), []byte(`xmlns:xda="http://schemas.microsoft.com/office/spreadsheetml/2017/dynamicarray"`)))
+	assert.True(t, bytes.Contains(content.([]byte), []byte(`<xda:dynamicArrayProperties fDynamic="1" fCollapsed="0"/>`)))
+	contentTypes, err := f.contentTypesReader()
+	assert.NoError(t, err)
+	var hasContentType bool
+	for _, override := range contentTypes.Overrides {
+		if override.PartName == "/xl/metadata.xml" {
+			hasContentType = override.ContentType == ContentTypeSpreadSheetMLSheetMetadata
+		}
+	}
+	assert.True(t, hasContentType)
+	rels, err := f.relsReader(f.getWorkbookRelsPath())
+	assert.NoError(t, err)
+	var hasRel bool
+	for _, rel := range rels.Relationships {
+		if rel.Type == SourceRelationshipSheetMetadata {
+			hasRel = rel.Target == "metadata.xml"
+		}
+	}
+	assert.True(t, hasRel)
+	ws, err := f.workSheetReader("Sheet1")
+	assert.NoError(t, err)
+	anchor := ws.SheetData.Row[0].C[3]
+	assert.Equal(t, STCellFormulaTypeArray, anchor.F.T)
+	assert.Equal(t, "D1:E3", anchor.F.Ref)
+	assert.NotNil(t, anchor.Cm)
+	assert.Equal(t, uint(1), *anchor.Cm)
+	// Test the spilled cells will not be transformed as the array formula
+	formula, err := f.GetCellFormula("Sheet1", "E2")
+	assert.NoError(t, err)
+	assert.Empty(t, formula)
+	result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "J1", "SUM(D1#)"))
+	result, err = f.CalcCellValue("Sheet1", "J1")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", result)
+	// Test the cell metadata record will be reused
+	cm, err := f.setDynamicArrayMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), cm)
+	assert.NoError(t, f.Close())
+
+	// Test the legacy array formula will not be spilled
+	f = prepareCalcData([][]interface{}{{1}, {2}})
+	formulaType, ref := STCellFormulaTypeArray, "B1:B1"
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B1", "A1:A2*2", FormulaOpts{Type: &formulaType, Ref: &ref}))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "B1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	result, err = f.GetCellValue("Sheet1", "B2")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	_, ok = f.Pkg.Load(defaultXMLMetadata)
+	assert.False(t, ok)
+
+	// Test the dynamic array metadata with unsupported charset
+	f = NewFile()
+	f.Pkg.Store(defaultXMLMetadata, MacintoshCyrillicCharset)
+	_, err = f.setDynamicArrayMetadata()
+	assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A1", "{1;2}"))
+	result, _, err = f.calcFormulaCell(context.Background(), "Sheet1", "A1")
+	assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
+	assert.Empty(t, result)
+}
+
+func TestReplaceSpillRangeOperator(t *testing.T) {
+	for formula, expected := range map[string]string{
+		"A1#":                   "_xlfn.ANCHORARRAY(A1)",
+		"SUM($B$2#)+C3#":        "SUM(_xlfn.ANCHORARRAY($B$2))+_xlfn.ANCHORARRAY(C3)",
+		"Sheet1!A1#":            "_xlfn.ANCHORARRAY(Sheet1!A1)",
+		"'My Sheet'!A1#":        "_xlfn.ANCHORARRAY('My Sheet'!A1)",
+		"COUNTA(\"A1#\",A1#)":   "COUNTA(\"A1#\",_xlfn.ANCHORARRAY(A1))",
+		"IFERROR(A1,#N/A)":      "IFERROR(A1,#N/A)",
+		"Table1[#Totals]":       "Table1[#Totals]",
+		"_xlfn.ANCHORARRAY(A1)": "_xlfn.ANCHORARRAY(A1)",
+	} {
+		assert.Equal(t, expected, replaceSpillRangeOperator(formula), formula)
+	}
+}
+
+func TestCalcSpillRangeOwnership(t *testing.T) {
+	prepare := func() *File {
+		f := NewFile()
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A1", "SEQUENCE(3)"))
+		assert.NoError(t, f.RebuildCalcChain())
+		assert.NoError(t, f.RecalculateAll())
+		return f
+	}
+	reopen := func(f *File) *File {
+		buf, err := f.WriteToBuffer()
+		assert.NoError(t, err)
+		f, err = OpenReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+		return f
+	}
+	checkCells := func(f *File, expected map[string]string) {
+		for cell, value := range expected {
+			if value == formulaErrorSPILL {
+				result, _, err := f.calcFormulaCell(context.Background(), "Sheet1", cell)
+				assert.EqualError(t, err, formulaErrorSPILL, cell)
+				assert.Equal(t, formulaErrorSPILL, result, cell)
+				continue
+			}
+			result, err := f.GetCellValue("Sheet1", cell)
+			assert.NoError(t, err, cell)
+			assert.Equal(t, value, result, cell)
+		}
+	}
+	// Test recalculate the spill range loaded from the workbook
+	f := reopen(prepare())
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A1", "SEQUENCE(3,1,10)"))
+	assert.NoError(t, f.RecalculateAll())
+	checkCells(f, map[string]string{"A1": "10", "A2": "11", "A3": "12"})
+	// Test the values typed into the spill range loaded from the workbook
+	// block the spill, including the value equals to the spilled value
+	for _, value := range []interface{}{"x", 2} {
+		f = reopen(prepare())
+		assert.NoError(t, f.SetCellValue("Sheet1", "A2", value))
+		assert.NoError(t, f.RecalculateAll())
+		checkCells(f, map[string]string{"A1": formulaErrorSPILL, "A2": fmt.Sprint(value), "A3": ""})
+	}
+	// Test the values typed into the spill range moved by inserting rows and
+	// columns block the spill
+	f = prepare()
+	assert.NoError(t, f.InsertRows("Sheet1", 1, 1))
+	assert.NoError(t, f.InsertCols("Sheet1", "A", 1))
+	assert.NoError(t, f.RecalculateAll())
+	checkCells(f, map[string]string{"B2": "1", "B3": "2", "B4": "3"})
+	assert.NoError(t, f.SetCellValue("Sheet1", "B4", "x"))
+	assert.NoError(t, f.RecalculateAll())
+	checkCells(f, map[string]string{"B2": formulaErrorSPILL, "B3": "", "B4": "x"})
+	// Test the spill range after deleting the row in the spill range
+	f = prepare()
+	assert.NoError(t, f.SetCellValue("Sheet1", "A3", "x"))
+	assert.NoError(t, f.RemoveRow("Sheet1", 2))
+	assert.NoError(t, f.RecalculateAll())
+	checkCells(f, map[string]string{"A1": formulaErrorSPILL, "A2": "x"})
+	// Test the copied worksheet keeps the spill range
+	f = prepare()
+	idx, err := f.NewSheet("Sheet2")
+	assert.NoError(t, err)
+	assert.NoError(t, f.CopySheet(0, idx))
+	ws, err := f.workSheetReader("Sheet2")
+	assert.NoError(t, err)
+	_, ok := ws.spillValues.Load("A3")
+	assert.True(t, ok)
+}
@@ -0,0 +1,511 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// structuredRefSeparator is used to replace the item separator in the
+	// structured references before tokenizing the formula, so that the
+	// structured reference will be parsed as a single range operand.
+	structuredRefSeparator = '\x1f'
+
+	structuredRefAll     = "#All"
+	structuredRefData    = "#Data"
+	structuredRefHeaders = "#Headers"
+	structuredRefTotals  = "#Totals"
+	structuredRefThisRow = "#This Row"
+)
+
+// structuredRefItems defined the special item specifiers of the structured
+// references.
+var structuredRefItems = map[string]string{
+	"#all":      structuredRefAll,
+	"#data":     structuredRefData,
+	"#headers":  structuredRefHeaders,
+	"#totals":   structuredRefTotals,
+	"#this row": structuredRefThisRow,
+}
+
+// structuredRef defines the parsed structured reference, such as
+// Table1[[#Headers],[Qty]:[Price]] or [@Qty].
+type structuredRef struct {
+	Table   string
+	Items   []string
+	Columns []string
+}
+
+// tableDefinition defines the table and the worksheet name which the table
+// belongs to.
+type tableDefinition struct {
+	Sheet string
+	Table *xlsxTable
+}
+
+// isStructuredRefNameChar returns whether the rune can be used in the table
+// name of the structured references.
+func isStructuredRefNameChar(r byte) bool {
+	return r == '_' || r == '.' || r == '\\' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= 0x80
+}
+
+// structuredRefEnd returns the end position of the bracketed part in the
+// formula which starts at the given position, the single quotation mark is
+// used as the escape character inside the brackets. It returns -1 if the
+// brackets are not closed.
+func structuredRefEnd(formula string, start int) int {
+	var depth int
+	for i := start; i < len(formula); i++ {
+		switch formula[i] {
+		case '\'':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// findStructuredRefs returns the positions of the structured references in the
+// formula, the string literals in the formula will be skipped.
+func findStructuredRefs(formula string) [][]int {
+	var (
+		refs     [][]int
+		inString bool
+	)
+	for i := 0; i < len(formula); i++ {
+		switch c := formula[i]; {
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '\'':
+			// skip the quoted worksheet name
+			if end := strings.IndexByte(formula[i+1:], '\''); end != -1 {
+				i += end + 1
+			}
+		case c == '[':
+			start := i
+			for start > 0 && isStructuredRefNameChar(formula[start-1]) {
+				start--
+			}
+			end := structuredRefEnd(formula, i)
+			if end == -1 {
+				return refs
+			}
+			// skip the external workbook references, such as [1]Sheet1!A1
+			if end < len(formula) && (isStructuredRefNameChar(formula[end]) || formula[end] == '!' || formula[end] == '\'') {
+				i = end - 1
+				continue
+			}
+			if start < i && formula[start] >= '0' && formula[start] <= '9' {
+				start = i
+			}
+			refs = append(refs, []int{start, end})
+			i = end - 1
+		}
+	}
+	return refs
+}
+
+// escapeStructuredRefs replace the item separators and the spaces between the
+// items of the structured references in the formula, so that each structured
+// reference will be tokenized as a single range operand.
+func escapeStructuredRefs(formula string) string {
+	refs := findStructuredRefs(formula)
+	if len(refs) == 0 {
+		return formula
+	}
+	var (
+		b    strings.Builder
+		prev int
+	)
+	b.Grow(len(formula))
+	for _, ref := range refs {
+		b.WriteString(formula[prev:ref[0]])
+		var depth int
+		for i := ref[0]; i < ref[1]; i++ {
+			switch c := formula[i]; {
+			case c == '\'' && depth > 0:
+				b.WriteByte(c)
+				if i++; i < ref[1] {
+					b.WriteByte(formula[i])
+				}
+				continue
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+			case depth == 1 && c == ',':
+				b.WriteByte(structuredRefSeparator)
+				continue
+			case depth == 1 && c == ' ':
+				continue
+			}
+			b.WriteByte(formula[i])
+		}
+		prev = ref[1]
+	}
+	b.WriteString(formula[prev:])
+	return b.String()
+}
+
+// isStructuredRef returns whether the reference is a structured reference.
+func isStructuredRef(ref string) bool {
+	refs := findStructuredRefs(ref)
+	return len(refs) == 1 && refs[0][0] == 0 && refs[0][1] == len(ref)
+}
+
+// unescapeStructuredRefName returns the column name by given escaped name of
+// the structured reference.
+func unescapeStructuredRefName(name string) string {
+	if !strings.ContainsRune(name, '\'') {
+		return strings.TrimSpace(name)
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\'' && i+1 < len(name) {
+			i++
+		}
+		b.WriteByte(name[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// escapeStructuredRefName returns the escaped column name for the structured
+// reference.
+func escapeStructuredRefName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune("[]#'", r) {
+			b.WriteByte('\'')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// parseStructuredRefItem append the item specifier or column name to the
+// structured reference by given item text.
+func (ref *structuredRef) parseStructuredRefItem(item string) error {
+	if strings.HasPrefix(item, "#") {
+		special, ok := structuredRefItems[strings.ToLower(strings.TrimSpace(item))]
+		if !ok {
+			return errors.New(formulaErrorREF)
+		}
+		ref.Items = append(ref.Items, special)
+		return nil
+	}
+	ref.Columns = append(ref.Columns, unescapeStructuredRefName(item))
+	return nil
+}
+
+// parseStructuredRefList parse the list of bracketed items in the structured
+// reference, such as [#Headers],[Qty]:[Price].
+func (ref *structuredRef) parseStructuredRefList(list string) error {
+	expectItem := true
+	for i := 0; i < len(list); {
+		switch c := list[i]; {
+		case c == ' ':
+			i++
+		case c == ',' || c == structuredRefSeparator:
+			if expectItem {
+				return errors.New(formulaErrorREF)
+			}
+			expectItem = true
+			i++
+		case c == ':':
+			if expectItem || len(ref.Columns) == 0 {
+				return errors.New(formulaErrorREF)
+			}
+			expectItem = true
+			i++
+		case c == '[':
+			end := structuredRefEnd(list, i)
+			if end == -1 || !expectItem {
+				return errors.New(formulaErrorREF)
+			}
+			if err := ref.parseStructuredRefItem(list[i+1 : end-1]); err != nil {
+				return err
+			}
+			expectItem, i = false, end
+		default:
+			return errors.New(formulaErrorREF)
+		}
+	}
+	if len(ref.Columns) > 2 || len(ref.Items)+len(ref.Columns) > 0 && expectItem {
+		return errors.New(formulaErrorREF)
+	}
+	return nil
+}
+
+// parseStructuredRef parse the structured reference, such as Table1[Qty],
+// Table1[[#Totals],[Qty]] or [@Qty].
+func parseStructuredRef(text string) (*structuredRef, error) {
+	idx := strings.IndexByte(text, '[')
+	if idx == -1 || !strings.HasSuffix(text, "]") {
+		return nil, errors.New(formulaErrorREF)
+	}
+	ref := &structuredRef{Table: text[:idx]}
+	inner := strings.TrimSpace(text[idx+1 : len(text)-1])
+	switch {
+	case inner == "":
+	case inner[0] == '@':
+		ref.Items = append(ref.Items, structuredRefThisRow)
+		if rest := strings.TrimSpace(inner[1:]); strings.HasPrefix(rest, "[") {
+			if err := ref.parseStructuredRefList(rest); err != nil {
+				return nil, err
+			}
+		} else if rest != "" {
+			ref.Columns = append(ref.Columns, unescapeStructuredRefName(rest))
+		}
+	case inner[0] != '[':
+		if strings.ContainsAny(strings.NewReplacer("'[", "", "']", "").Replace(inner), "[]") {
+			return nil, errors.New(formulaErrorREF)
+		}
+		if err := ref.parseStructuredRefItem(inner); err != nil {
+			return nil, err
+		}
+	default:
+		if err := ref.parseStructuredRefList(inner); err != nil {
+			return nil, err
+		}
+	}
+	return ref, nil
+}
+
+// String returns the text of the structured reference.
+func (ref *structuredRef) String() string {
+	var (
+		thisRow bool
+		items   []string
+		columns []string
+	)
+	for _, item := range ref.Items {
+		if item == structuredRefThisRow && len(ref.Items) == 1 {
+			thisRow = true
+			continue
+		}
+		items = append(items, "["+item+"]")
+	}
+	for _, column := range ref.Columns {
+		columns = append(columns, escapeStructuredRefName(column))
+	}
+	simple := len(columns) == 1 && !strings.ContainsAny(columns[0], " ,:[]'#@")
+	switch {
+	case thisRow && len(columns) == 0:
+		return ref.Table + "[@]"
+	case thisRow && simple:
+		return ref.Table + "[@" + columns[0] + "]"
+	case thisRow:
+		return ref.Table + "[@[" + strings.Join(columns, "]:[") + "]]"
+	case len(items) == 0 && len(columns) == 0:
+		return ref.Table + "[]"
+	case len(items) == 1 && len(columns) == 0:
+		return ref.Table + items[0]
+	case len(items) == 0 && len(columns) == 1:
+		return ref.Table + "[" + columns[0] + "]"
+	}
+	if len(columns) > 0 {
+		items = append(items, "["+strings.Join(columns, "]:[")+"]")
+	}
+	return ref.Table + "[" + strings.Join(items, ",") + "]"
+}
+
+// getTableDefinitions provides a function to get all table definitions in the
+// workbook.
+func (f *File) getTableDefinitions() ([]tableDefinition, error) {
+	var definitions []tableDefinition
+	for _, sheet := range f.GetSheetList() {
+		ws, err := f.workSheetReader(sheet)
+		if err != nil {
+			continue
+		}
+		if ws.TableParts == nil {
+			continue
+		}
+		for _, tbl := range ws.TableParts.TableParts {
+			if tbl == nil {
+				continue
+			}
+			target := f.getSheetRelationshipsTargetByID(sheet, tbl.RID)
+			content, ok := f.Pkg.Load(strings.ReplaceAll(target, "..", "xl"))
+			if !ok {
+				continue
+			}
+			t := new(xlsxTable)
+			if err := f.xmlNewDecoder(bytes.NewReader(namespaceStrictToTransitional(content.([]byte)))).
+				Decode(t); err != nil && err != io.EOF {
+				return definitions, err
+			}
+			definitions = append(definitions, tableDefinition{Sheet: sheet, Table: t})
+		}
+	}
+	return definitions, nil
+}
+
+// tableDefinitions returns the table definitions in the workbook, the table
+// definitions will be loaded once for each calculation.
+func (f *File) tableDefinitions(ctx *calcContext) []tableDefinition {
+	if ctx = ctx.root(); ctx == nil {
+		definitions, _ := f.getTableDefinitions()
+		return definitions
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.tables == nil {
+		definitions, _ := f.getTableDefinitions()
+		ctx.tables = &definitions
+	}
+	return *ctx.tables
+}
+
+// findTableDefinition returns the table definition by given table name, or
+// the table which contains the given cell if the table name is empty.
+func findTableDefinition(definitions []tableDefinition, name, sheet, cell string) (tableDefinition, []int, bool) {
+	col, row, err := CellNameToCoordinates(cell)
+	for _, definition := range definitions {
+		coordinates, e := rangeRefToCoordinates(definition.Table.Ref)
+		if e != nil {
+			continue
+		}
+		if name != "" {
+			if strings.EqualFold(definition.Table.Name, name) || strings.EqualFold(definition.Table.DisplayName, name) {
+				return definition, coordinates, true
+			}
+			continue
+		}
+		if err == nil && definition.Sheet == sheet && coordinates[0] <= col && col <= coordinates[2] &&
+			coordinates[1] <= row && row <= coordinates[3] {
+			return definition, coordinates, true
+		}
+	}
+	return tableDefinition{}, nil, false
+}
+
+// tableColumnIndex returns the index of the table column by given column name,
+// the column name is case-insensitive. It returns -1 if the column not exists.
+func tableColumnIndex(t *xlsxTable, name string) int {
+	if t.TableColumns == nil {
+		return -1
+	}
+	for idx, column := range t.TableColumns.TableColumn {
+		if strings.EqualFold(column.Name, name) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// structuredRefToRange convert the structured reference to the cell range
+// reference with worksheet name by given worksheet name and cell reference of
+// the formula. It returns the formula error if the structured reference is
+// invalid.
+func (f *File) structuredRefToRange(ctx *calcContext, sheet, cell, text string) (string, formulaArg) {
+	ref, err := parseStructuredRef(strings.ReplaceAll(text, string(structuredRefSeparator), ","))
+	if err != nil {
+		return "", newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	definition, coordinates, ok := findTableDefinition(f.tableDefinitions(ctx), ref.Table, sheet, cell)
+	if !ok {
+		return "", newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	t, headerRows := definition.Table, 1
+	if t.HeaderRowCount != nil {
+		headerRows = *t.HeaderRowCount
+	}
+	x1, y1, x2, y2 := coordinates[0], coordinates[1], coordinates[2], coordinates[3]
+	dataStart, dataEnd := y1+headerRows, y2-t.TotalsRowCount
+	fromRow, toRow := -1, -1
+	expand := func(from, to int) {
+		if from > to {
+			return
+		}
+		if fromRow == -1 || from < fromRow {
+			fromRow = from
+		}
+		if to > toRow {
+			toRow = to
+		}
+	}
+	if len(ref.Items) == 0 {
+		ref.Items = []string{structuredRefData}
+	}
+	for _, item := range ref.Items {
+		switch item {
+		case structuredRefAll:
+			expand(y1, y2)
+		case structuredRefData:
+			expand(dataStart, dataEnd)
+		case structuredRefHeaders:
+			expand(y1, y1+headerRows-1)
+		case structuredRefTotals:
+			expand(dataEnd+1, y2)
+		case structuredRefThisRow:
+			_, row, err := CellNameToCoordinates(cell)
+			if err != nil || len(ref.Items) > 1 || row < dataStart || row > dataEnd {
+				return "", newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+			}
+			expand(row, row)
+		}
+	}
+	if fromRow == -1 {
+		return "", newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	fromCol, toCol := x1, x2
+	for i, column := range ref.Columns {
+		idx := tableColumnIndex(t, column)
+		if idx == -1 {
+			return "", newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+		}
+		if i == 0 {
+			fromCol, toCol = x1+idx, x1+idx
+			continue
+		}
+		fromCol, toCol = min(fromCol, x1+idx), max(toCol, x1+idx)
+	}
+	rng, e := CoordinatesToCellName(fromCol, fromRow)
+	if e == nil && (fromCol != toCol || fromRow != toRow) {
+		rng, e = coordinatesToRangeRef([]int{fromCol, fromRow, toCol, toRow})
+	}
+	if e != nil {
+		return "", newErrorFormulaArg(formulaErrorREF, formulaErrorREF)
+	}
+	return fmt.Sprintf("%s!%s", definition.Sheet, rng), newStringFormulaArg(rng)
+}
+
+// tableNameToRange convert the table name to the data range reference of the
+// table, it returns an empty string if the table not exists.
+func (f *File) tableNameToRange(ctx *calcContext, sheet, cell, name string) string {
+	if name == "" || strings.ContainsAny(name, "!:$ []") {
+		return ""
+	}
+	if _, _, _, err := parseRef(name); err == nil {
+		return ""
+	}
+	if _, _, ok := findTableDefinition(f.tableDefinitions(ctx), name, sheet, cell); !ok {
+		return ""
+	}
+	rng, arg := f.structuredRefToRange(ctx, sheet, cell, name+"[]")
+	if arg.Type == ArgError {
+		return ""
+	}
+	return rng
+}
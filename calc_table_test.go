@@ -0,0 +1,128 @@
+package excelize
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prepareCalcTableData creates a workbook with the table named Sales on the
+// range A1:D5 with a totals row, the table columns are Item, Qty, Unit Price
+// and Amount.
+func prepareCalcTableData(t *testing.T) *File {
+	f := NewFile()
+	for cell, row := range map[string][]interface{}{
+		"A1": {"Item", "Qty", "Unit Price", "Amount"},
+		"A2": {"Apple", 2, 3},
+		"A3": {"Banana", 4, 5},
+		"A4": {"Cherry", 6, 7},
+		"A5": {"Total"},
+	} {
+		assert.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	assert.NoError(t, f.AddTable("Sheet1", &Table{Range: "A1:D5", Name: "Sales"}))
+	// Set the last row of the table as totals row
+	tables, err := f.GetTables("Sheet1")
+	assert.NoError(t, err)
+	content, ok := f.Pkg.Load(tables[0].tableXML)
+	assert.True(t, ok)
+	var tbl xlsxTable
+	assert.NoError(t, xml.Unmarshal(content.([]byte), &tbl))
+	tbl.TotalsRowCount = 1
+	output, err := xml.Marshal(tbl)
+	assert.NoError(t, err)
+	f.Pkg.Store(tables[0].tableXML, output)
+	for _, cell := range []string{"D2", "D3", "D4"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "[@Qty]*[@[Unit Price]]"))
+	}
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B5", "SUBTOTAL(109,[Qty])"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D5", "SUM(Sales[Amount])"))
+	return f
+}
+
+func TestCalcStructuredReference(t *testing.T) {
+	f := prepareCalcTableData(t)
+	for cell, expected := range map[string]string{"D2": "6", "D3": "20", "D4": "42", "B5": "12", "D5": "68"} {
+		result, err := f.CalcCellValue("Sheet1", cell)
+		assert.NoError(t, err, cell)
+		assert.Equal(t, expected, result, cell)
+	}
+	for formula, expected := range map[string]string{
+		"SUM(Sales[Qty])":                         "12",
+		"SUM(sales[qty])":                         "12",
+		"SUM(Sales[[Qty]:[Unit Price]])":          "27",
+		"SUM(Sales[[#Data], [Qty]:[Unit Price]])": "27",
+		"SUM(Sales[[#Totals],[Qty]])":             "12",
+		"Sales[[#Headers],[Unit Price]]":          "Unit Price",
+		"COUNTA(Sales[#Headers])":                 "4",
+		"ROWS(Sales[#All])":                       "5",
+		"ROWS(Sales[])":                           "3",
+		"ROWS(Sales)":                             "3",
+		"COLUMNS(Sales[#Data])":                   "4",
+		"ROWS(Sales[[#Headers],[#Data],[Qty]])":   "4",
+		"ROWS(Sales[[#Data],[#Totals],[Qty]])":    "4",
+		"SUMIF(Sales[Item],\"B*\",Sales[Amount])": "20",
+		"INDEX(Sales[Item],2)":                    "Banana",
+		"IF(SUM(Sales[Qty])>10,\"a,[b]\",\"c\")":  "a,[b]",
+		"Sales[Qty]":                              "2",
+		"SUM(Sales[Missing])":                     "#REF!",
+		"SUM(Unknown[Qty])":                       "#REF!",
+		"SUM(Sales[#Unknown])":                    "#REF!",
+		"SUM([Qty])":                              "#REF!",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", formula))
+		result, _ := f.CalcCellValue("Sheet1", "F1")
+		assert.Equal(t, expected, result, formula)
+	}
+	// Test this row references in and out of the table data rows
+	for cell, expected := range map[string]string{"F2": "2", "F3": "4", "F1": "#VALUE!", "F5": "#VALUE!"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, "Sales[@Qty]"))
+		result, _ := f.CalcCellValue("Sheet1", cell)
+		assert.Equal(t, expected, result, cell)
+	}
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F3", "SUM(Sales[@[Qty]:[Unit Price]])"))
+	result, err := f.CalcCellValue("Sheet1", "F3")
+	assert.NoError(t, err)
+	assert.Equal(t, "9", result)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F3", "Sales[[#This Row], [Item]]"))
+	result, err = f.CalcCellValue("Sheet1", "F3")
+	assert.NoError(t, err)
+	assert.Equal(t, "Banana", result)
+	// Test the structured reference in the defined name
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Quantities", RefersTo: "Sales[[#Data],[Qty]]"}))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "SUM(Quantities)"))
+	result, err = f.CalcCellValue("Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "12", result)
+}
+
+func TestParseStructuredRef(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Sales[Qty]":                      "Sales[Qty]",
+		"Sales[]":                         "Sales[]",
+		"[@Qty]":                          "[@Qty]",
+		"[@]":                             "[@]",
+		"Sales[@[Unit Price]]":            "Sales[@[Unit Price]]",
+		"Sales[[#this row],[Qty]]":        "Sales[@Qty]",
+		"Sales[#totals]":                  "Sales[#Totals]",
+		"Sales[[#Headers],[Qty]:[Price]]": "Sales[[#Headers],[Qty]:[Price]]",
+		"Sales[ [#Headers] , [#Data] ]":   "Sales[[#Headers],[#Data]]",
+		"Sales[[Qty]:[Price]]":            "Sales[[Qty]:[Price]]",
+		"Sales['#Count]":                  "Sales['#Count]",
+		"Sales[[a'[b']]]":                 "Sales[a'[b']]",
+	} {
+		ref, err := parseStructuredRef(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, ref.String(), text)
+	}
+	ref, err := parseStructuredRef("Sales['#Count]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"#Count"}, ref.Columns)
+	for _, text := range []string{"Sales", "Sales[#Bad]", "Sales[[Qty],]", "Sales[,[Qty]]", "Sales[:[Qty]]", "Sales[[a]:[b]:[c]]", "Sales[[a][b]]", "Sales[[a]x]", "Sales[[a]"} {
+		_, err := parseStructuredRef(text)
+		assert.EqualError(t, err, formulaErrorREF, text)
+	}
+	assert.Equal(t, [][]int{{4, 14}, {15, 21}}, findStructuredRefs("SUM(Sales[Qty],[@Qty],\"[x]\",[1]Sheet1!A1,'a[1]'!A1)"))
+	assert.Equal(t, "SUM(Sales[[#Totals]\x1f[Qty]],1)", escapeStructuredRefs("SUM(Sales[[#Totals], [Qty]],1)"))
+}
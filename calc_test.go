@@ -2,10 +2,13 @@ package excelize
 
 import (
 	"container/list"
+	"context"
 	"math"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/xuri/efp"
@@ -68,12 +71,6 @@ func TestCalcCellValue(t *testing.T) {
 		"{1}+2":                 "3",
 		"1+{2}":                 "3",
 		"{1}+{2}":               "3",
-		"A1+(B1-C1)":            "5",
-		"A1+(C1-B1)":            "-3",
-		"A1&B1&C1":              "14",
-		"B1+C1":                 "4",
-		"C1+B1":                 "4",
-		"C1+C1":                 "0",
 		"\"A\"=\"A\"":           "TRUE",
 		"\"A\"<>\"A\"":          "FALSE",
 		"TRUE()&FALSE()":        "TRUEFALSE",
@@ -679,7 +676,6 @@ func TestCalcCellValue(t *testing.T) {
 		"IMPRODUCT(\"\",3,SUM(6))":             "18",
 		"IMPRODUCT(\"1-i\",\"5+10i\",2)":       "30+10i",
 		"IMPRODUCT(COMPLEX(5,2),COMPLEX(0,1))": "-2+5i",
-		"IMPRODUCT(A1:C1)":                     "4",
 		// MINVERSE
 		"MINVERSE(A1:B2)": "-0",
 		// MMULT
@@ -727,7 +723,6 @@ func TestCalcCellValue(t *testing.T) {
 		"PRODUCT(3,6)":            "18",
 		"PRODUCT(\"3\",\"6\")":    "18",
 		"PRODUCT(PRODUCT(1),3,6)": "18",
-		"PRODUCT(C1:C2)":          "1",
 		// QUOTIENT
 		"QUOTIENT(5,2)":             "2",
 		"QUOTIENT(4.5,3.1)":         "1",
@@ -1066,13 +1061,9 @@ func TestCalcCellValue(t *testing.T) {
 		// COUNTBLANK
 		"COUNTBLANK(MUNIT(1))": "0",
 		"COUNTBLANK(1)":        "0",
-		"COUNTBLANK(B1:C1)":    "1",
-		"COUNTBLANK(C1)":       "0",
 		// COUNTIF
-		"COUNTIF(D1:D9,\"Jan\")":     "4",
-		"COUNTIF(D1:D9,\"<>Jan\")":   "5",
-		"COUNTIF(A1:F9,\">=50000\")": "2",
-		"COUNTIF(A1:F9,TRUE)":        "0",
+		"COUNTIF(D1:D9,\"Jan\")":   "4",
+		"COUNTIF(D1:D9,\"<>Jan\")": "5",
 		// COUNTIFS
 		"COUNTIFS(A1:A9,2,D1:D9,\"Jan\")":          "1",
 		"COUNTIFS(F1:F9,\">20000\",D1:D9,\"Jan\")": "4",
@@ -1087,7 +1078,6 @@ func TestCalcCellValue(t *testing.T) {
 		"CRITBINOM(100,0.5,90%)":  "56",
 		// DEVSQ
 		"DEVSQ(1,3,5,2,9,7)": "47.5",
-		"DEVSQ(A1:D2)":       "10",
 		// FISHER
 		"FISHER(-0.9)":    "-1.47221948958322",
 		"FISHER(-0.25)":   "-0.255412811882995",
@@ -1238,7 +1228,6 @@ func TestCalcCellValue(t *testing.T) {
 		"LARGE(A1:A5,1)": "3",
 		"LARGE(A1:B5,2)": "4",
 		"LARGE(A1,1)":    "1",
-		"LARGE(A1:F2,1)": "36693",
 		// MAX
 		"MAX(1)":           "1",
 		"MAX(TRUE())":      "1",
@@ -1341,18 +1330,15 @@ func TestCalcCellValue(t *testing.T) {
 		// SKEW
 		"SKEW(1,2,3,4,3)": "-0.404796008910937",
 		"SKEW(A1:B2)":     "0",
-		"SKEW(A1:D3)":     "0",
 		// SKEW.P
 		"SKEW.P(1,2,3,4,3)": "-0.27154541788364",
 		"SKEW.P(A1:B2)":     "0",
-		"SKEW.P(A1:D3)":     "0",
 		// SLOPE
 		"SLOPE(A1:A4,B1:B4)": "1",
 		// SMALL
 		"SMALL(A1:A5,1)": "0",
 		"SMALL(A1:B5,2)": "1",
 		"SMALL(A1,1)":    "1",
-		"SMALL(A1:F2,1)": "1",
 		// STANDARDIZE
 		"STANDARDIZE(5.5,5,2)":   "0.25",
 		"STANDARDIZE(12,15,1.5)": "-2",
@@ -1389,23 +1375,10 @@ func TestCalcCellValue(t *testing.T) {
 		// TRIMMEAN
 		"TRIMMEAN(A1:B4,10%)": "2.5",
 		"TRIMMEAN(A1:B4,70%)": "2.5",
-		// VAR
-		"VAR(1,3,5,0,C1)":      "4.91666666666667",
-		"VAR(1,3,5,0,C1,TRUE)": "4",
-		// VARA
-		"VARA(1,3,5,0,C1)":      "4.91666666666667",
-		"VARA(1,3,5,0,C1,TRUE)": "4",
 		// VARP
-		"VARP(A1:A5)":           "1.25",
-		"VARP(1,3,5,0,C1,TRUE)": "3.2",
+		"VARP(A1:A5)": "1.25",
 		// VAR.P
 		"VAR.P(A1:A5)": "1.25",
-		// VAR.S
-		"VAR.S(1,3,5,0,C1)":      "4.91666666666667",
-		"VAR.S(1,3,5,0,C1,TRUE)": "4",
-		// VARPA
-		"VARPA(1,3,5,0,C1)":      "3.6875",
-		"VARPA(1,3,5,0,C1,TRUE)": "3.2",
 		// WEIBULL
 		"WEIBULL(1,3,1,FALSE)":  "1.10363832351433",
 		"WEIBULL(2,5,1.5,TRUE)": "0.985212776817482",
@@ -1703,10 +1676,6 @@ func TestCalcCellValue(t *testing.T) {
 		"WEEKNUM(\"01/01/2017\",21)": "52",
 		"WEEKNUM(\"01/01/2021\",21)": "53",
 		// Text Functions
-		// ARRAYTOTEXT
-		"ARRAYTOTEXT(A1:D2)":   "1, 4, , Month, 2, 5, , Jan",
-		"ARRAYTOTEXT(A1:D2,0)": "1, 4, , Month, 2, 5, , Jan",
-		"ARRAYTOTEXT(A1:D2,1)": "{1,4,,\"Month\";2,5,,\"Jan\"}",
 		// BAHTTEXT
 		"BAHTTEXT(-1.1)":    "\u0e25\u0e1a\u0e2b\u0e19\u0e36\u0e48\u0e07\u0e1a\u0e32\u0e17\u0e2a\u0e34\u0e1a\u0e2a\u0e15\u0e32\u0e07\u0e04\u0e4c",
 		"BAHTTEXT(0)":       "\u0e28\u0e39\u0e19\u0e22\u0e4c\u0e1a\u0e32\u0e17\u0e16\u0e49\u0e27\u0e19",
@@ -1804,12 +1773,10 @@ func TestCalcCellValue(t *testing.T) {
 		"LEFTB(\"Original Text\",13)": "Original Text",
 		"LEFTB(\"Original Text\",20)": "Original Text",
 		// LEN
-		"LEN(\"\")":              "0",
-		"LEN(D1)":                "5",
-		"LEN(\"テキスト\")":          "4",
-		"LEN(\"オリジナルテキスト\")":     "9",
-		"LEN(7+LEN(A1&B1&C1))":   "1",
-		"LEN(8+LEN(A1+(C1-B1)))": "2",
+		"LEN(\"\")":          "0",
+		"LEN(D1)":            "5",
+		"LEN(\"テキスト\")":      "4",
+		"LEN(\"オリジナルテキスト\")": "9",
 		// LENB
 		"LENB(\"\")":          "0",
 		"LENB(D1)":            "5",
@@ -1921,8 +1888,6 @@ func TestCalcCellValue(t *testing.T) {
 		// TEXTJOIN
 		"TEXTJOIN(\"-\",TRUE,1,2,3,4)":  "1-2-3-4",
 		"TEXTJOIN(A4,TRUE,A1:B2)":       "1040205",
-		"TEXTJOIN(\",\",FALSE,A1:C2)":   "1,4,,2,5,",
-		"TEXTJOIN(\",\",TRUE,A1:C2)":    "1,4,2,5",
 		"TEXTJOIN(\",\",TRUE,MUNIT(2))": "1,0,0,1",
 		// TRIM
 		"TRIM(\" trim text \")": "trim text",
@@ -2296,6 +2261,71 @@ func TestCalcCellValue(t *testing.T) {
 		assert.NoError(t, err, formula)
 		assert.Equal(t, expected, result, formula)
 	}
+	// Test the formulas refer to the cell C1, the formula cell C1 would be a
+	// circular reference, so calculate them in the cell A30 out of the
+	// referenced ranges
+	circularCalc := map[string]string{
+		"A1+(B1-C1)": "5",
+		"A1+(C1-B1)": "-3",
+		"A1&B1&C1":   "14",
+		"B1+C1":      "4",
+		"C1+B1":      "4",
+		"C1+C1":      "0",
+		// IMPRODUCT
+		"IMPRODUCT(A1:C1)": "4",
+		// PRODUCT
+		"PRODUCT(C1:C2)": "1",
+		// COUNTBLANK
+		"COUNTBLANK(B1:C1)": "1",
+		"COUNTBLANK(C1)":    "0",
+		// COUNTIF
+		"COUNTIF(A1:F9,\">=50000\")": "2",
+		// COUNTIF
+		"COUNTIF(A1:F9,TRUE)": "0",
+		// DEVSQ
+		"DEVSQ(A1:D2)": "10",
+		// LARGE
+		"LARGE(A1:F2,1)": "36693",
+		// SKEW
+		"SKEW(A1:D3)": "0",
+		// SKEW.P
+		"SKEW.P(A1:D3)": "0",
+		// SMALL
+		"SMALL(A1:F2,1)": "1",
+		// VAR
+		"VAR(1,3,5,0,C1)":      "4.91666666666667",
+		"VAR(1,3,5,0,C1,TRUE)": "4",
+		// VARA
+		"VARA(1,3,5,0,C1)":      "4.91666666666667",
+		"VARA(1,3,5,0,C1,TRUE)": "4",
+		// VARP
+		"VARP(1,3,5,0,C1,TRUE)": "3.2",
+		// VAR.S
+		"VAR.S(1,3,5,0,C1)":      "4.91666666666667",
+		"VAR.S(1,3,5,0,C1,TRUE)": "4",
+		// VARPA
+		"VARPA(1,3,5,0,C1)":      "3.6875",
+		"VARPA(1,3,5,0,C1,TRUE)": "3.2",
+		// ARRAYTOTEXT
+		"ARRAYTOTEXT(A1:D2)":   "1, 4, , Month, 2, 5, , Jan",
+		"ARRAYTOTEXT(A1:D2,0)": "1, 4, , Month, 2, 5, , Jan",
+		"ARRAYTOTEXT(A1:D2,1)": "{1,4,,\"Month\";2,5,,\"Jan\"}",
+		// LEN
+		"LEN(7+LEN(A1&B1&C1))":   "1",
+		"LEN(8+LEN(A1+(C1-B1)))": "2",
+		// TEXTJOIN
+		"TEXTJOIN(\",\",FALSE,A1:C2)": "1,4,,2,5,",
+		"TEXTJOIN(\",\",TRUE,A1:C2)":  "1,4,2,5",
+		// SUM
+		"SUM(B1:D1)": "4",
+	}
+	for formula, expected := range circularCalc {
+		f := prepareCalcData(cellData)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A30", formula))
+		result, err := f.CalcCellValue("Sheet1", "A30")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
 	mathCalcError := map[string][]string{
 		"1/0":        {"#DIV/0!", "#DIV/0!"},
 		"1^\"text\"": {"#VALUE!", "strconv.ParseFloat: parsing \"text\": invalid syntax"},
@@ -4702,7 +4732,6 @@ func TestCalcCellValue(t *testing.T) {
 		"1+SUM(SUM(A1+A2/A3)*(2-3),2)":   "1.33333333333333",
 		"A1/A2/SUM(A1:A2:B1)":            "0.0416666666666667",
 		"A1/A2/SUM(A1:A2:B1)*A3":         "0.125",
-		"SUM(B1:D1)":                     "4",
 		"SUM(\"X\")":                     "0",
 	}
 	for formula, expected := range referenceCalc {
@@ -6492,7 +6521,6 @@ func TestCalcNETWORKDAYSandWORKDAY(t *testing.T) {
 		"NETWORKDAYS.INTL(\"01/01/2020\",\"09/12/2020\",17)":       "219",
 		"NETWORKDAYS.INTL(\"01/01/2020\",\"09/12/2020\",1,A1:A12)": "178",
 		"NETWORKDAYS.INTL(\"01/01/2020\",\"09/12/2020\",1,B1:B12)": "178",
-		"NETWORKDAYS.INTL(\"01/01/2020\",\"09/12/2020\",1,C1:C2)":  "183",
 		"WORKDAY(\"12/01/2015\",25)":                               "42374",
 		"WORKDAY(\"01/01/2020\",123,B1:B12)":                       "44006",
 		"WORKDAY.INTL(\"12/01/2015\",0)":                           "42339",
@@ -6527,6 +6555,13 @@ func TestCalcNETWORKDAYSandWORKDAY(t *testing.T) {
 		assert.NoError(t, err, formula)
 		assert.Equal(t, expected, result, formula)
 	}
+	// Test the holidays in the cells C1:C2, calculate it out of the range to
+	// avoid circular reference
+	assert.NoError(t, f.SetCellValue("Sheet1", "C1", "text1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "NETWORKDAYS.INTL(\"01/01/2020\",\"09/12/2020\",1,C1:C2)"))
+	result, err := f.CalcCellValue("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "183", result)
 	calcError := map[string][]string{
 		"NETWORKDAYS()": {"#VALUE!", "NETWORKDAYS requires at least 2 arguments"},
 		"NETWORKDAYS(\"01/01/2020\",\"09/12/2020\",2,\"\")":             {"#VALUE!", "NETWORKDAYS requires at most 3 arguments"},
@@ -6824,6 +6859,66 @@ func TestCalcCellResolver(t *testing.T) {
 	assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
 }
 
+func TestCalcCircularReference(t *testing.T) {
+	f := NewFile()
+	// Test calculate interest depends on the ending balance
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1000))
+	assert.NoError(t, f.SetCellValue("Sheet1", "A2", 0.1))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A3", "A4*A2"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A4", "A1+A3"))
+	// Test calculate circular reference without iterative calculation
+	result, err := f.CalcCellValue("Sheet1", "A4")
+	assert.EqualError(t, err, "circular reference on cell Sheet1!A4")
+	assert.ErrorIs(t, err, ErrCircularReference)
+	assert.Empty(t, result)
+	_, err = f.CalcFormulaValue("Sheet1", "A5", "A4*2")
+	assert.ErrorIs(t, err, ErrCircularReference)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A6", "SUM(A5:A7)"))
+	_, err = f.CalcCellValue("Sheet1", "A6")
+	assert.EqualError(t, err, "circular reference on cell Sheet1!A6")
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.EqualError(t, f.RecalculateAll(), "circular reference on cell Sheet1!A3, Sheet1!A4, Sheet1!A6")
+	assert.EqualError(t, f.RecalculateSheet("Sheet1"), "circular reference on cell Sheet1!A3, Sheet1!A4, Sheet1!A6")
+	// Test calculate circular reference with iterative calculation
+	iterate := true
+	assert.NoError(t, f.SetCalcProps(&CalcPropsOptions{Iterate: &iterate}))
+	result, err = f.CalcCellValue("Sheet1", "A4", Options{RawCellValue: true})
+	assert.NoError(t, err)
+	value, err := strconv.ParseFloat(result, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1000/0.9, value, 0.001)
+	assert.NoError(t, f.RecalculateAll())
+	for cell, expected := range map[string]float64{"A3": 100 / 0.9, "A4": 1000 / 0.9} {
+		result, err = f.GetCellValue("Sheet1", cell, Options{RawCellValue: true})
+		assert.NoError(t, err)
+		value, err = strconv.ParseFloat(result, 64)
+		assert.NoError(t, err)
+		assert.InDelta(t, expected, value, 0.001, cell)
+	}
+	assert.NoError(t, f.RecalculateSheet("Sheet1"))
+	// Test calculate circular reference with iterate count limit
+	iterateCount, iterateDelta := uint(10), 0.5
+	assert.NoError(t, f.SetCalcProps(&CalcPropsOptions{Iterate: &iterate, IterateCount: &iterateCount, IterateDelta: &iterateDelta}))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B1", "B1+1"))
+	result, err = f.CalcCellValue("Sheet1", "B1")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", result)
+	// Test calculate indirect circular reference with iterate delta limit
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C1", "C2/2"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C2", "C1+1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "C3", "C2"))
+	result, err = f.CalcCellValue("Sheet1", "C3", Options{RawCellValue: true})
+	assert.NoError(t, err)
+	value, err = strconv.ParseFloat(result, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2, value, 0.5)
+	// Test calculate circular reference with non-numeric result
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D1", "D1&\"A\""))
+	result, err = f.CalcCellValue("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, "AAAAAAAAAA", result)
+}
+
 func TestEvalInfixExp(t *testing.T) {
 	f := NewFile()
 	arg, err := f.evalInfixExp(nil, "Sheet1", "A1", []efp.Token{
@@ -6835,7 +6930,7 @@ func TestEvalInfixExp(t *testing.T) {
 
 func TestParseToken(t *testing.T) {
 	f := NewFile()
-	assert.Equal(t, formulaErrorNAME, f.parseToken(nil, "Sheet1",
+	assert.Equal(t, formulaErrorNAME, f.parseToken(nil, "Sheet1", "",
 		efp.Token{TSubType: efp.TokenSubTypeRange, TValue: "1A"}, nil, nil,
 	).Error())
 }
@@ -7341,3 +7436,85 @@ func TestCalcMAXCrossSheetDateFormulas(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "45976", max, "MAX should return the latest date (45976)")
 }
+
+func TestCalcCellValueContext(t *testing.T) {
+	f := NewFile()
+	assert.NoError(t, f.RegisterFormulaFunc("SLOW", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+		select {
+		case <-ctx.Context.Done():
+		case <-time.After(5 * time.Second):
+		}
+		return FormulaValue{Type: ArgNumber, Number: 1}, nil
+	}))
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B1", "A1+1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B2", "SLOW()+A1"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B3", "SUM(B1:B2)"))
+
+	result, err := f.CalcCellValueContext(context.Background(), "Sheet1", "B1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result)
+	// Test calculate with the canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = f.CalcCellValueContext(ctx, "Sheet1", "A1")
+	assert.ErrorIs(t, err, context.Canceled)
+	// Test the calculation is stopped when the deadline of the context is
+	// exceeded
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = f.CalcCellValueContext(ctx, "Sheet1", "B3")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	// Test calculate the formula exceeds the deadline of the CalcTimeout option
+	_, err = f.CalcCellValueContext(context.Background(), "Sheet1", "B2", Options{CalcTimeout: 50 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrFormulaTimeout)
+	assert.Equal(t, newFormulaTimeoutError("Sheet1!B2"), err)
+
+	// Test calculate multiple cells with the timeout and canceled context
+	results, err := f.CalcCellValuesContext(context.Background(), "Sheet1", []string{"B1", "B2"}, Options{CalcTimeout: 50 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrFormulaTimeout)
+	assert.EqualError(t, err, "failed to calculate B2: formula calculation timed out on cell Sheet1!B2")
+	assert.Equal(t, map[string]string{"B1": "2"}, results)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	results, err = f.CalcCellValuesContext(ctx, "Sheet1", []string{"B1", "B2"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+
+	// Test cancel the calculation of the large range
+	calcCtx := &calcContext{context: ctx}
+	_, err = f.rangeResolverParallel(calcCtx, "Sheet1", &xlsxWorksheet{}, []int{1, 1000, 1, 1})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = f.rangeResolverSerial(calcCtx.withNames(nil), "Sheet1", &xlsxWorksheet{}, []int{1, 1, 1, 1})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCalcOmittedArguments(t *testing.T) {
+	f := NewFile()
+	for formula, expected := range map[string]string{
+		"IF(TRUE,,2)":                "",
+		"IF(FALSE,1,)":               "",
+		"IFERROR(1/0,)":              "",
+		"IFNA(NA(),)":                "",
+		"CHOOSE(2,1,)":               "",
+		"ROUND(1.5,)":                "2",
+		"SUBSTITUTE(\"abc\",\"b\",)": "ac",
+		"ADDRESS(1,1,)":              "$A$1",
+		"ADDRESS(1,1,,FALSE)":        "R1C1",
+		"SUM(1,,2,)":                 "3",
+		"CONCAT(\"a\",,\"b\",)":      "ab",
+		"SUM()":                      "0",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A1", formula))
+		result, err := f.CalcCellValue("Sheet1", "A1")
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, result, formula)
+	}
+	// Test the function without arguments doesn't get the omitted argument
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A1", "LEN()"))
+	result, err := f.CalcCellValue("Sheet1", "A1")
+	assert.EqualError(t, err, "LEN requires 1 string argument")
+	assert.Equal(t, formulaErrorVALUE, result)
+}
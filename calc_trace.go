@@ -0,0 +1,448 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormulaReferenceType is the type of the reference traced by the
+// GetPrecedents and GetDependents functions.
+type FormulaReferenceType byte
+
+// This section defines the formula reference types.
+const (
+	FormulaReferenceCell FormulaReferenceType = iota
+	FormulaReferenceRange
+	FormulaReferenceName
+	FormulaReferenceTable
+	FormulaReferenceDynamic
+)
+
+// FormulaReference directly maps the reference traced by the GetPrecedents
+// and GetDependents functions. The Ref field is the cell reference or range
+// reference without the dollar signs for the cell and range types, the name
+// for the name type, the structured reference for the table type, and the
+// function call such as INDIRECT(B1) for the dynamic type. The Sheet field
+// is the worksheet name of the reference, or the scope of the defined name
+// which is empty for the workbook scope. The Direct field indicates whether
+// the reference is read by the formula directly, otherwise it's read by the
+// formula through the other formula cells.
+type FormulaReference struct {
+	Type   FormulaReferenceType
+	Sheet  string
+	Ref    string
+	Direct bool
+}
+
+// formulaArea defined the cell range area of the worksheet which read by a
+// formula.
+type formulaArea struct {
+	sheet          string
+	x1, y1, x2, y2 int
+}
+
+// formulaCell defined the formula cell in the calculation chain.
+type formulaCell struct {
+	sheet, cell string
+}
+
+// formulaCellRefs defined the resolved references of the formula cell, the
+// dynamic field indicates the formula contains the references which can't
+// be resolved without calculation, such as the OFFSET and INDIRECT
+// functions, and the volatile field indicates the formula calls the volatile
+// functions.
+type formulaCellRefs struct {
+	refs     []FormulaReference
+	areas    []formulaArea
+	dynamic  bool
+	volatile bool
+}
+
+// formulaTracer defined the state for tracing the formula references in the
+// workbook, the resolved references of each formula cell will be cached.
+type formulaTracer struct {
+	f      *File
+	ctx    *calcContext
+	sheets []string
+	names  []DefinedName
+	cells  []formulaCell
+	refs   map[formulaCell]*formulaCellRefs
+}
+
+// dynamicRefFuncs defined the functions which returns the reference which
+// can't be resolved without calculation.
+var dynamicRefFuncs = map[string]bool{"INDIRECT": true, "OFFSET": true}
+
+// contains returns whether the area contains the given cell.
+func (area formulaArea) contains(sheet string, col, row int) bool {
+	return area.sheet == sheet && area.x1 <= col && col <= area.x2 && area.y1 <= row && row <= area.y2
+}
+
+// newFormulaTracer creates a formula tracer, the formula cells will be
+// loaded from the calculation chain, or from the worksheets if the
+// calculation chain not exists.
+func (f *File) newFormulaTracer() (*formulaTracer, error) {
+	t := &formulaTracer{
+		f: f, ctx: &calcContext{}, sheets: f.GetSheetList(), names: f.GetDefinedName(),
+		refs: map[formulaCell]*formulaCellRefs{},
+	}
+	calcChain, err := f.calcChainReader()
+	if err != nil {
+		return t, err
+	}
+	if calcChain != nil && len(calcChain.C) > 0 {
+		sheetMap, sheetID := f.GetSheetMap(), -1
+		for _, c := range calcChain.C {
+			if c.I != 0 {
+				sheetID = c.I
+			}
+			if sheet, ok := sheetMap[sheetID]; ok {
+				t.cells = append(t.cells, formulaCell{sheet: sheet, cell: c.R})
+			}
+		}
+		return t, nil
+	}
+	for _, sheet := range t.sheets {
+		ws, err := f.workSheetReader(sheet)
+		if err != nil {
+			return t, err
+		}
+		for _, row := range ws.SheetData.Row {
+			for _, c := range row.C {
+				if c.F != nil {
+					t.cells = append(t.cells, formulaCell{sheet: sheet, cell: c.R})
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+// expandSheets returns the worksheet names by given worksheet name of the
+// reference, the worksheet name is case-insensitive, and the 3D reference
+// such as Sheet1:Sheet3 will be expanded to all worksheets between them.
+func (t *formulaTracer) expandSheets(sheet string) []string {
+	from, to, ok := strings.Cut(sheet, ":")
+	if !ok {
+		for _, name := range t.sheets {
+			if strings.EqualFold(name, sheet) {
+				return []string{name}
+			}
+		}
+		return []string{sheet}
+	}
+	start, end := -1, -1
+	for idx, name := range t.sheets {
+		if strings.EqualFold(name, from) {
+			start = idx
+		}
+		if strings.EqualFold(name, to) {
+			end = idx
+		}
+	}
+	if start == -1 || end == -1 {
+		return nil
+	}
+	if start > end {
+		start, end = end, start
+	}
+	return t.sheets[start : end+1]
+}
+
+// formulaRefCoordinates returns the coordinates of the cell reference, range
+// reference, entire column or row reference.
+func formulaRefCoordinates(ref string) ([]int, bool) {
+	parts := strings.Split(strings.ReplaceAll(ref, "$", ""), ":")
+	if len(parts) > 2 {
+		return nil, false
+	}
+	if len(parts) == 1 {
+		col, row, err := CellNameToCoordinates(parts[0])
+		return []int{col, row, col, row}, err == nil
+	}
+	coordinates := make([]int, 4)
+	for i, part := range parts {
+		if col, row, err := CellNameToCoordinates(part); err == nil {
+			coordinates[i*2], coordinates[i*2+1] = col, row
+			continue
+		}
+		if formulaRowRefExp.MatchString(part) {
+			row, _ := strconv.Atoi(part)
+			coordinates[i*2], coordinates[i*2+1] = []int{1, MaxColumns}[i], row
+			continue
+		}
+		col, err := ColumnNameToNumber(part)
+		if err != nil {
+			return nil, false
+		}
+		coordinates[i*2], coordinates[i*2+1] = col, []int{1, TotalRows}[i]
+	}
+	_ = sortCoordinates(coordinates)
+	return coordinates, true
+}
+
+// lookupDefinedName find the defined name by given name and the worksheet
+// name of the formula, the name in the worksheet scope takes precedence over
+// the name in the workbook scope.
+func (t *formulaTracer) lookupDefinedName(name, scope, sheet string) (DefinedName, bool) {
+	scopes := []string{sheet, "Workbook"}
+	if scope != "" {
+		scopes = []string{scope}
+	}
+	for _, s := range scopes {
+		for _, dn := range t.names {
+			if strings.EqualFold(dn.Name, name) && strings.EqualFold(dn.Scope, s) {
+				return dn, true
+			}
+		}
+	}
+	return DefinedName{}, false
+}
+
+// addRef append the reference to the resolved references of the formula
+// cell, the duplicate references will be ignored.
+func (refs *formulaCellRefs) addRef(ref FormulaReference) {
+	for _, r := range refs.refs {
+		if r.Type == ref.Type && r.Sheet == ref.Sheet && r.Ref == ref.Ref {
+			return
+		}
+	}
+	refs.refs = append(refs.refs, ref)
+}
+
+// addArea append the reference and the cell range area to the resolved
+// references of the formula cell by given worksheet name and reference.
+func (refs *formulaCellRefs) addArea(sheet, ref string) {
+	coordinates, ok := formulaRefCoordinates(ref)
+	if !ok {
+		return
+	}
+	typ, ref := FormulaReferenceCell, strings.ReplaceAll(ref, "$", "")
+	if strings.Contains(ref, ":") {
+		typ = FormulaReferenceRange
+	}
+	refs.addRef(FormulaReference{Type: typ, Sheet: sheet, Ref: ref, Direct: true})
+	refs.areas = append(refs.areas, formulaArea{
+		sheet: sheet, x1: coordinates[0], y1: coordinates[1], x2: coordinates[2], y2: coordinates[3],
+	})
+}
+
+// collect walks the syntax tree of the formula and collect the references
+// of the formula by given worksheet name and cell reference of the formula,
+// the defined names in the visited map will be skipped to avoid the
+// circular reference of the defined names.
+func (t *formulaTracer) collect(refs *formulaCellRefs, node *FormulaNode, sheet, cell string, visited map[string]bool) {
+	node.Walk(func(n *FormulaNode) bool {
+		switch n.Type {
+		case FormulaNodeReference:
+			refSheet := n.Sheet
+			if refSheet == "" {
+				refSheet = sheet
+			}
+			for _, s := range t.expandSheets(refSheet) {
+				refs.addArea(s, n.Value)
+			}
+		case FormulaNodeName:
+			t.collectName(refs, n, sheet, cell, visited)
+		case FormulaNodeStructuredReference:
+			rng, arg := t.f.structuredRefToRange(t.ctx, sheet, cell, n.Value)
+			if arg.Type == ArgError {
+				break
+			}
+			refSheet, ref, _ := strings.Cut(rng, "!")
+			refs.addRef(FormulaReference{Type: FormulaReferenceTable, Sheet: refSheet, Ref: n.Value, Direct: true})
+			refs.addArea(refSheet, ref)
+		case FormulaNodeFunction:
+			refs.volatile = refs.volatile || volatileFuncs[formulaFuncName(n.Value)]
+			if dynamicRefFuncs[formulaFuncName(n.Value)] {
+				refs.dynamic = true
+				refs.addRef(FormulaReference{Type: FormulaReferenceDynamic, Sheet: sheet, Ref: n.String(), Direct: true})
+			}
+		}
+		return true
+	})
+}
+
+// collectName collect the references of the defined name or the table name
+// in the formula, the name which is neither a defined name nor a table name,
+// such as the parameters of the LET and LAMBDA functions will be ignored.
+func (t *formulaTracer) collectName(refs *formulaCellRefs, n *FormulaNode, sheet, cell string, visited map[string]bool) {
+	if dn, ok := t.lookupDefinedName(n.Value, n.Sheet, sheet); ok {
+		scope := dn.Scope
+		if scope == "Workbook" {
+			scope = ""
+		}
+		refs.addRef(FormulaReference{Type: FormulaReferenceName, Sheet: scope, Ref: dn.Name, Direct: true})
+		key := scope + "!" + strings.ToUpper(dn.Name)
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		if node, err := ParseFormula(dn.RefersTo); err == nil {
+			t.collect(refs, node, sheet, cell, visited)
+		}
+		return
+	}
+	if n.Sheet != "" {
+		return
+	}
+	if rng := t.f.tableNameToRange(t.ctx, sheet, cell, n.Value); rng != "" {
+		refSheet, ref, _ := strings.Cut(rng, "!")
+		refs.addRef(FormulaReference{Type: FormulaReferenceTable, Sheet: refSheet, Ref: n.Value, Direct: true})
+		refs.addArea(refSheet, ref)
+	}
+}
+
+// references returns the resolved direct references of the formula cell.
+func (t *formulaTracer) references(fc formulaCell) *formulaCellRefs {
+	if refs, ok := t.refs[fc]; ok {
+		return refs
+	}
+	refs := &formulaCellRefs{}
+	t.refs[fc] = refs
+	formula, err := t.f.GetCellFormula(fc.sheet, fc.cell)
+	if err != nil || formula == "" {
+		return refs
+	}
+	if node, err := ParseFormula(formula); err == nil {
+		t.collect(refs, node, fc.sheet, fc.cell, map[string]bool{})
+	}
+	return refs
+}
+
+// GetPrecedents provides a function to get the precedents of the formula
+// cell by given worksheet name and cell reference, which are the cells,
+// ranges, defined names and table references read by the formula directly
+// or through the other formula cells. The references across worksheets, the
+// defined names and the table references will be resolved to the cell range
+// of the worksheets, and the references returned by the OFFSET and INDIRECT
+// functions will be returned as the dynamic references which can't be
+// resolved without calculation. The formula cells are located by the
+// calculation chain of the workbook, or by the worksheets if the calculation
+// chain not exists. It returns an empty list if the cell has no formula. For
+// example, get the precedents of the cell C1 on Sheet1:
+//
+//	precedents, err := f.GetPrecedents("Sheet1", "C1")
+func (f *File) GetPrecedents(sheet, cell string) ([]FormulaReference, error) {
+	formula, err := f.GetCellFormula(sheet, cell)
+	if err != nil || formula == "" {
+		return nil, err
+	}
+	if _, _, err = CellNameToCoordinates(cell); err != nil {
+		return nil, err
+	}
+	t, err := f.newFormulaTracer()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		result  formulaCellRefs
+		root    = formulaCell{sheet: sheet, cell: cell}
+		queue   = []formulaCell{root}
+		visited = map[formulaCell]bool{root: true}
+	)
+	for len(queue) > 0 {
+		fc := queue[0]
+		queue = queue[1:]
+		refs := t.references(fc)
+		for _, ref := range refs.refs {
+			ref.Direct = fc == root
+			result.addRef(ref)
+		}
+		for _, c := range t.cells {
+			if visited[c] {
+				continue
+			}
+			col, row, err := CellNameToCoordinates(c.cell)
+			if err != nil {
+				continue
+			}
+			for _, area := range refs.areas {
+				if area.contains(c.sheet, col, row) {
+					visited[c] = true
+					queue = append(queue, c)
+					break
+				}
+			}
+		}
+	}
+	return result.refs, nil
+}
+
+// GetDependents provides a function to get the dependents of the cell by
+// given worksheet name and cell reference, which are the formula cells that
+// would change if the cell changed, including the formula cells which read
+// the cell directly or through the other formula cells, defined names and
+// table references. The formula cells which contain the OFFSET and INDIRECT
+// functions could read any cell, so they will be returned as the dynamic
+// dependents. The formula cells are located by the calculation chain of the
+// workbook, or by the worksheets if the calculation chain not exists. For
+// example, get the dependents of the cell A1 on Sheet1:
+//
+//	dependents, err := f.GetDependents("Sheet1", "A1")
+func (f *File) GetDependents(sheet, cell string) ([]FormulaReference, error) {
+	if _, err := f.workSheetReader(sheet); err != nil {
+		return nil, err
+	}
+	col, row, err := CellNameToCoordinates(cell)
+	if err != nil {
+		return nil, err
+	}
+	t, err := f.newFormulaTracer()
+	if err != nil {
+		return nil, err
+	}
+	type dependent struct {
+		formulaCell
+		col, row int
+		dynamic  bool
+	}
+	var (
+		result  []FormulaReference
+		root    = formulaCell{sheet: sheet, cell: strings.ToUpper(strings.ReplaceAll(cell, "$", ""))}
+		queue   = []dependent{{formulaCell: root, col: col, row: row}}
+		visited = map[formulaCell]bool{root: true}
+	)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, c := range t.cells {
+			if visited[c] {
+				continue
+			}
+			refs, hit := t.references(c), false
+			for _, area := range refs.areas {
+				if hit = area.contains(cur.sheet, cur.col, cur.row); hit {
+					break
+				}
+			}
+			if !hit && !refs.dynamic {
+				continue
+			}
+			x, y, err := CellNameToCoordinates(c.cell)
+			if err != nil {
+				continue
+			}
+			visited[c] = true
+			next := dependent{formulaCell: c, col: x, row: y, dynamic: cur.dynamic || !hit}
+			typ := FormulaReferenceCell
+			if next.dynamic {
+				typ = FormulaReferenceDynamic
+			}
+			result = append(result, FormulaReference{Type: typ, Sheet: c.sheet, Ref: c.cell, Direct: cur.formulaCell == root})
+			queue = append(queue, next)
+		}
+	}
+	return result, nil
+}
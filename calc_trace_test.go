@@ -0,0 +1,136 @@
+package excelize
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPrecedentsAndDependents(t *testing.T) {
+	f := NewFile()
+	_, err := f.NewSheet("Sheet 2")
+	assert.NoError(t, err)
+	_, err = f.NewSheet("Sheet3")
+	assert.NoError(t, err)
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]interface{}{"Name", "Qty"}))
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]interface{}{"x", 1}))
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A3", &[]interface{}{"y", 2}))
+	assert.NoError(t, f.AddTable("Sheet1", &Table{Range: "A1:B3", Name: "Table1"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Rate", RefersTo: "Sheet1!$D$1"}))
+	assert.NoError(t, f.SetDefinedName(&DefinedName{Name: "Local", RefersTo: "Sheet1!$D$2+Rate", Scope: "Sheet 2"}))
+	for cell, formula := range map[string]string{
+		"C1": "SUM(Table1[Qty])*Rate",
+		"C2": "C1+'Sheet 2'!A1",
+		"C3": "INDIRECT(\"A\"&B2)+OFFSET(A1,1,1)",
+		"C4": "SUM(C:C)",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+	}
+	assert.NoError(t, f.SetCellFormula("Sheet 2", "A1", "Local*SUM(Sheet1:Sheet3!E1)"))
+	assert.NoError(t, f.SetCellFormula("Sheet3", "A1", "sheet1!C2+$B$1:B2"))
+
+	precedents, err := f.GetPrecedents("Sheet1", "C1")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceTable, Sheet: "Sheet1", Ref: "Table1[Qty]", Direct: true},
+		{Type: FormulaReferenceRange, Sheet: "Sheet1", Ref: "B2:B3", Direct: true},
+		{Type: FormulaReferenceName, Ref: "Rate", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "D1", Direct: true},
+	}, precedents)
+	precedents, err = f.GetPrecedents("Sheet1", "C2")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C1", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet 2", Ref: "A1", Direct: true},
+		{Type: FormulaReferenceTable, Sheet: "Sheet1", Ref: "Table1[Qty]"},
+		{Type: FormulaReferenceRange, Sheet: "Sheet1", Ref: "B2:B3"},
+		{Type: FormulaReferenceName, Ref: "Rate"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "D1"},
+		{Type: FormulaReferenceName, Sheet: "Sheet 2", Ref: "Local"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "D2"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "E1"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet 2", Ref: "E1"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet3", Ref: "E1"},
+	}, precedents)
+	precedents, err = f.GetPrecedents("Sheet1", "C3")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceDynamic, Sheet: "Sheet1", Ref: "INDIRECT(\"A\"&B2)", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "B2", Direct: true},
+		{Type: FormulaReferenceDynamic, Sheet: "Sheet1", Ref: "OFFSET(A1,1,1)", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "A1", Direct: true},
+	}, precedents)
+	// Test get precedents of the cell without formula
+	precedents, err = f.GetPrecedents("Sheet1", "A1")
+	assert.NoError(t, err)
+	assert.Nil(t, precedents)
+
+	dependents, err := f.GetDependents("Sheet1", "D1")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C1", Direct: true},
+		{Type: FormulaReferenceDynamic, Sheet: "Sheet1", Ref: "C3", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet 2", Ref: "A1", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C2"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C4"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet3", Ref: "A1"},
+	}, dependents)
+	dependents, err = f.GetDependents("Sheet3", "$E$1")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceDynamic, Sheet: "Sheet1", Ref: "C3", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet 2", Ref: "A1", Direct: true},
+		{Type: FormulaReferenceDynamic, Sheet: "Sheet1", Ref: "C4"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C2"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet3", Ref: "A1"},
+	}, dependents)
+
+	// Test trace the formula cells by the calculation chain
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "D1"))
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.SaveAs(filepath.Join("test", "TestGetPrecedentsAndDependents.xlsx")))
+	dependents, err = f.GetDependents("Sheet1", "B2")
+	assert.NoError(t, err)
+	assert.Equal(t, []FormulaReference{
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C1", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C3", Direct: true},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C2"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet1", Ref: "C4"},
+		{Type: FormulaReferenceCell, Sheet: "Sheet3", Ref: "A1"},
+	}, dependents)
+
+	// Test trace the formulas with invalid parameters
+	_, err = f.GetPrecedents("SheetN", "A1")
+	assert.EqualError(t, err, "sheet SheetN does not exist")
+	_, err = f.GetDependents("SheetN", "A1")
+	assert.EqualError(t, err, "sheet SheetN does not exist")
+	_, err = f.GetDependents("Sheet1", "A")
+	assert.Equal(t, newCellNameToCoordinatesError("A", newInvalidCellNameError("A")), err)
+	for _, trace := range []func(sheet, cell string) ([]FormulaReference, error){f.GetPrecedents, f.GetDependents} {
+		f.CalcChain = nil
+		f.Pkg.Store(defaultXMLPathCalcChain, MacintoshCyrillicCharset)
+		_, err = trace("Sheet1", "C1")
+		assert.EqualError(t, err, "XML syntax error on line 1: invalid UTF-8")
+	}
+}
+
+func TestFormulaRefCoordinates(t *testing.T) {
+	for ref, expected := range map[string][]int{
+		"B2":      {2, 2, 2, 2},
+		"$C$3:A1": {1, 1, 3, 3},
+		"B:A":     {1, 1, 2, TotalRows},
+		"2:$1":    {1, 1, MaxColumns, 2},
+	} {
+		coordinates, ok := formulaRefCoordinates(ref)
+		assert.True(t, ok, ref)
+		assert.Equal(t, expected, coordinates, ref)
+	}
+	for _, ref := range []string{"A1:B2:C3", "Name", "A1:Name", "#REF!"} {
+		_, ok := formulaRefCoordinates(ref)
+		assert.False(t, ok, ref)
+	}
+	tracer := &formulaTracer{sheets: []string{"Sheet1", "Sheet2", "Sheet3"}}
+	assert.Equal(t, []string{"Sheet2", "Sheet3"}, tracer.expandSheets("Sheet3:sheet2"))
+	assert.Nil(t, tracer.expandSheets("Sheet1:SheetN"))
+}
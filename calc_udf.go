@@ -0,0 +1,214 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// FormulaValue directly maps the evaluated argument and the result of the
+// user-defined formula function. The Type field specifies which of the
+// other fields holds the value: ArgNumber for the Number field, which is a
+// boolean value when the Boolean field is true; ArgString for the String
+// field; ArgError for the error value such as "#N/A" in the String field;
+// ArgMatrix for the two-dimensional array in the Matrix field; and ArgEmpty
+// for the omitted argument or the empty cell.
+type FormulaValue struct {
+	Type    ArgType
+	Number  float64
+	String  string
+	Boolean bool
+	Matrix  [][]FormulaValue
+}
+
+// FormulaFuncContext directly maps the calling context of the user-defined
+// formula function, which contains the workbook and the worksheet name and
+// the cell reference of the formula being calculated. The Context will be
+// done when the calculation is canceled or exceeds the deadline, the
+// long-running function should return as soon as possible after that.
+type FormulaFuncContext struct {
+	Context context.Context
+	File    *File
+	Sheet   string
+	Cell    string
+}
+
+// FormulaFunc defined the callback of the user-defined formula function. The
+// callback receives the evaluated arguments, returns the value or the array
+// of the function. The returned error will be converted to the #VALUE!
+// formula error with the error message.
+type FormulaFunc func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error)
+
+// userFormulaFuncName returns the key of the user-defined formula function
+// by given function name, the "_xludf." prefix used in the spreadsheet files
+// will be removed.
+func userFormulaFuncName(name string) string {
+	return strings.TrimPrefix(formulaFuncName(name), "_XLUDF.")
+}
+
+// checkUserFormulaFuncName checks the name of the user-defined formula
+// function, the name should start with a letter and contain letters, numbers,
+// periods and underscores only, and should not conflict with the built-in
+// functions.
+func checkUserFormulaFuncName(name string) error {
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return ErrFormulaFuncName
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' {
+			return ErrFormulaFuncName
+		}
+	}
+	switch name {
+	case "LET", "LAMBDA":
+		return ErrFormulaFuncName
+	}
+	if reflect.ValueOf(&formulaFuncs{}).MethodByName(strings.ReplaceAll(name, ".", "dot")).IsValid() {
+		return ErrFormulaFuncName
+	}
+	return nil
+}
+
+// RegisterFormulaFunc provides a function to register a user-defined formula
+// function by given function name and callback, the function name is
+// case-insensitive. The registered function will be used in the formula
+// calculation of the workbook, including the CalcCellValue, the
+// RecalculateAll and the batch calculation functions. The built-in function
+// names can't be registered, and register the same name again will replace
+// the previous callback. For example, register a function named "DOUBLE":
+//
+//	err := f.RegisterFormulaFunc("DOUBLE", func(ctx excelize.FormulaFuncContext, args []excelize.FormulaValue) (excelize.FormulaValue, error) {
+//	    if len(args) != 1 || args[0].Type != excelize.ArgNumber {
+//	        return excelize.FormulaValue{Type: excelize.ArgError, String: "#VALUE!"}, nil
+//	    }
+//	    return excelize.FormulaValue{Type: excelize.ArgNumber, Number: args[0].Number * 2}, nil
+//	})
+func (f *File) RegisterFormulaFunc(name string, fn FormulaFunc) error {
+	if fn == nil {
+		return ErrParameterRequired
+	}
+	name = userFormulaFuncName(name)
+	if err := checkUserFormulaFuncName(name); err != nil {
+		return err
+	}
+	f.userFormulaFuncs.Store(name, fn)
+	f.clearCalcCaches()
+	return nil
+}
+
+// UnregisterFormulaFunc provides a function to remove the user-defined
+// formula function by given function name.
+func (f *File) UnregisterFormulaFunc(name string) {
+	f.userFormulaFuncs.Delete(userFormulaFuncName(name))
+	f.clearCalcCaches()
+}
+
+// lookupUserFormulaFunc find the registered user-defined formula function by
+// given function name.
+func (f *File) lookupUserFormulaFunc(name string) (FormulaFunc, bool) {
+	if fn, ok := f.userFormulaFuncs.Load(userFormulaFuncName(name)); ok {
+		return fn.(FormulaFunc), true
+	}
+	return nil, false
+}
+
+// callUserFormulaFunc calls the user-defined formula function by given
+// callback and arguments list.
+func (fn *formulaFuncs) callUserFormulaFunc(udf FormulaFunc, argsList *list.List) formulaArg {
+	args := make([]FormulaValue, 0, argsList.Len())
+	for arg := argsList.Front(); arg != nil; arg = arg.Next() {
+		args = append(args, newFormulaValue(arg.Value.(formulaArg)))
+	}
+	ctx := context.Background()
+	if root := fn.ctx.root(); root != nil && root.context != nil {
+		ctx = root.context
+	}
+	result, err := udf(FormulaFuncContext{Context: ctx, File: fn.f, Sheet: fn.sheet, Cell: fn.cell}, args)
+	if err != nil {
+		return newErrorFormulaArg(formulaErrorVALUE, err.Error())
+	}
+	return result.formulaArg()
+}
+
+// newFormulaValue converts the formula argument to the value of the
+// user-defined formula function argument, the list will be converted to a
+// single row array.
+func newFormulaValue(arg formulaArg) FormulaValue {
+	switch arg.Type {
+	case ArgNumber:
+		return FormulaValue{Type: ArgNumber, Number: arg.Number, Boolean: arg.Boolean}
+	case ArgString:
+		return FormulaValue{Type: ArgString, String: arg.String}
+	case ArgError:
+		return FormulaValue{Type: ArgError, String: arg.String}
+	case ArgList, ArgMatrix:
+		rows := arg.Matrix
+		if arg.Type == ArgList {
+			rows = [][]formulaArg{arg.List}
+		}
+		mtx := make([][]FormulaValue, len(rows))
+		for r, row := range rows {
+			mtx[r] = make([]FormulaValue, len(row))
+			for c, cell := range row {
+				mtx[r][c] = newFormulaValue(cell)
+			}
+		}
+		return FormulaValue{Type: ArgMatrix, Matrix: mtx}
+	case ArgLambda:
+		return FormulaValue{Type: ArgError, String: formulaErrorVALUE}
+	}
+	return FormulaValue{Type: ArgEmpty}
+}
+
+// formulaArg converts the value returned by the user-defined formula
+// function to the formula argument.
+func (v FormulaValue) formulaArg() formulaArg {
+	switch v.Type {
+	case ArgNumber:
+		if v.Boolean {
+			return newBoolFormulaArg(v.Number != 0)
+		}
+		return newNumberFormulaArg(v.Number)
+	case ArgString:
+		return newStringFormulaArg(v.String)
+	case ArgError:
+		if v.String == "" {
+			return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+		}
+		return newErrorFormulaArg(v.String, v.String)
+	case ArgMatrix:
+		if len(v.Matrix) == 0 || len(v.Matrix[0]) == 0 {
+			return newErrorFormulaArg(formulaErrorCALC, formulaErrorCALC)
+		}
+		mtx := make([][]formulaArg, len(v.Matrix))
+		for r, row := range v.Matrix {
+			if len(row) != len(v.Matrix[0]) {
+				return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+			}
+			mtx[r] = make([]formulaArg, len(row))
+			for c, cell := range row {
+				if cell.Type == ArgMatrix {
+					return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+				}
+				mtx[r][c] = cell.formulaArg()
+			}
+		}
+		return newMatrixFormulaArg(mtx)
+	case ArgEmpty:
+		return newEmptyFormulaArg()
+	}
+	return newErrorFormulaArg(formulaErrorVALUE, formulaErrorVALUE)
+}
@@ -0,0 +1,155 @@
+package excelize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterFormulaFunc(t *testing.T) {
+	f := NewFile()
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]interface{}{1, 2, "x", true}))
+	var calls []string
+	assert.NoError(t, f.RegisterFormulaFunc("double", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+		calls = append(calls, ctx.Sheet+"!"+ctx.Cell)
+		if len(args) != 1 {
+			return FormulaValue{}, errors.New("DOUBLE requires 1 argument")
+		}
+		if args[0].Type != ArgNumber {
+			return FormulaValue{Type: ArgError, String: formulaErrorVALUE}, nil
+		}
+		return FormulaValue{Type: ArgNumber, Number: args[0].Number * 2}, nil
+	}))
+	assert.NoError(t, f.RegisterFormulaFunc("_xludf.DESCRIBE", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+		var desc []string
+		for _, arg := range args {
+			switch arg.Type {
+			case ArgMatrix:
+				desc = append(desc, fmt.Sprintf("%dx%d", len(arg.Matrix), len(arg.Matrix[0])))
+			case ArgNumber:
+				desc = append(desc, fmt.Sprintf("number:%g:%t", arg.Number, arg.Boolean))
+			case ArgEmpty:
+				desc = append(desc, "empty")
+			default:
+				desc = append(desc, arg.String)
+			}
+		}
+		return FormulaValue{Type: ArgString, String: strings.Join(desc, ",")}, nil
+	}))
+	assert.NoError(t, f.RegisterFormulaFunc("My.Seq", func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+		return FormulaValue{Type: ArgMatrix, Matrix: [][]FormulaValue{
+			{{Type: ArgNumber, Number: 1}, {Type: ArgString, String: "a"}},
+			{{Type: ArgNumber, Number: 1, Boolean: true}, {Type: ArgEmpty}},
+		}}, nil
+	}))
+	for _, c := range []struct {
+		formula, expected string
+	}{
+		{"DOUBLE(A1)+1", "3"},
+		{"SUM(DOUBLE(B1),DOUBLE(4))", "12"},
+		{"_xludf.DOUBLE(2)", "4"},
+		{"DESCRIBE(A1:B2,C1,D1,{1,2},1/0,E1)", "2x2,x,number:1:true,1x2,#DIV/0!,empty"},
+		{"ROWS(MY.SEQ())", "2"},
+		{"INDEX(MY.SEQ(),2,1)", "TRUE"},
+		{"INDEX(MY.SEQ(),1,2)", "a"},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "F1")
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	assert.Equal(t, "Sheet1!F1", calls[0])
+	// Test the user-defined function in the recalculation
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F2", "DOUBLE(A1)*10"))
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateAll())
+	result, err := f.GetCellValue("Sheet1", "F2")
+	assert.NoError(t, err)
+	assert.Equal(t, "20", result)
+	// Test the user-defined function returns an error
+	for _, c := range []struct {
+		formula, expected, err string
+	}{
+		{"DOUBLE(C1)", formulaErrorVALUE, formulaErrorVALUE},
+		{"DOUBLE()", formulaErrorVALUE, "DOUBLE requires 1 argument"},
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "F1")
+		assert.EqualError(t, err, c.err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+	}
+	// Test unregister the user-defined function
+	f.UnregisterFormulaFunc("Double")
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "DOUBLE(1)"))
+	_, err = f.CalcCellValue("Sheet1", "F1")
+	assert.EqualError(t, err, "not support DOUBLE function")
+	// Test re-register the user-defined function used in the criteria and
+	// lookup ranges
+	kind := func(value string) FormulaFunc {
+		return func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+			return FormulaValue{Type: ArgString, String: value}, nil
+		}
+	}
+	assert.NoError(t, f.RegisterFormulaFunc("KIND", kind("x")))
+	for r := 5; r <= 7; r++ {
+		assert.NoError(t, f.SetCellValue("Sheet1", fmt.Sprintf("A%d", r), r))
+		assert.NoError(t, f.SetCellFormula("Sheet1", fmt.Sprintf("B%d", r), "KIND()"))
+	}
+	for _, c := range []struct {
+		formula, expected, replaced string
+	}{
+		{"SUMIFS(A5:A7,B5:B7,\"x\")", "18", "0"},
+		{"XLOOKUP(\"x\",B5:B7,A5:A7,\"none\")", "5", "none"},
+	} {
+		assert.NoError(t, f.RegisterFormulaFunc("KIND", kind("x")))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "F1", c.formula))
+		result, err := f.CalcCellValue("Sheet1", "F1")
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.expected, result, c.formula)
+		assert.NoError(t, f.RegisterFormulaFunc("KIND", kind("y")))
+		result, err = f.CalcCellValue("Sheet1", "F1")
+		assert.NoError(t, err, c.formula)
+		assert.Equal(t, c.replaced, result, c.formula)
+	}
+	// Test unregister the user-defined function used in the criteria range
+	assert.NoError(t, f.RegisterFormulaFunc("KIND", kind("x")))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "F1", "COUNTIFS(B5:B7,\"x\")"))
+	result, err = f.CalcCellValue("Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result)
+	f.UnregisterFormulaFunc("KIND")
+	result, err = f.CalcCellValue("Sheet1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0", result)
+	// Test register the user-defined function with invalid parameters
+	udf := func(ctx FormulaFuncContext, args []FormulaValue) (FormulaValue, error) {
+		return FormulaValue{}, nil
+	}
+	assert.Equal(t, ErrParameterRequired, f.RegisterFormulaFunc("FOO", nil))
+	for _, name := range []string{"", "1FOO", "FOO BAR", "SUM", "_xlfn.XLOOKUP", "forecast.ets", "LAMBDA"} {
+		assert.Equal(t, ErrFormulaFuncName, f.RegisterFormulaFunc(name, udf), name)
+	}
+}
+
+func TestFormulaValue(t *testing.T) {
+	for _, c := range []struct {
+		value    FormulaValue
+		expected string
+	}{
+		{FormulaValue{Type: ArgError}, formulaErrorVALUE},
+		{FormulaValue{Type: ArgError, String: formulaErrorNA}, formulaErrorNA},
+		{FormulaValue{Type: ArgMatrix}, formulaErrorCALC},
+		{FormulaValue{Type: ArgMatrix, Matrix: [][]FormulaValue{{{}, {}}, {{}}}}, formulaErrorVALUE},
+		{FormulaValue{Type: ArgMatrix, Matrix: [][]FormulaValue{{{Type: ArgMatrix}}}}, formulaErrorVALUE},
+		{FormulaValue{Type: ArgUnknown}, formulaErrorVALUE},
+		{FormulaValue{Type: ArgEmpty}, ""},
+	} {
+		assert.Equal(t, c.expected, c.value.formulaArg().Value())
+	}
+	assert.Equal(t, FormulaValue{Type: ArgMatrix, Matrix: [][]FormulaValue{{{Type: ArgString, String: "a"}}}},
+		newFormulaValue(newListFormulaArg([]formulaArg{newStringFormulaArg("a")})))
+	assert.Equal(t, FormulaValue{Type: ArgError, String: formulaErrorVALUE}, newFormulaValue(newLambdaFormulaArg(&formulaLambda{})))
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// volatileFuncs defined the volatile formula functions, the formulas which
+// call these functions and their dependents will be calculated again on every
+// calculation instead of using the cached results.
+var volatileFuncs = map[string]bool{
+	"CELL": true, "INDIRECT": true, "NOW": true, "OFFSET": true, "RAND": true, "RANDBETWEEN": true, "TODAY": true,
+}
+
+// volatileRead is the mark of the volatile function call in the cell areas
+// read by the calculation, the results calculated with the mark will not be
+// cached.
+var volatileRead = formulaArea{x1: -1, y1: -1, x2: -1, y2: -1}
+
+// markVolatile marks the calculation read the volatile function.
+func (ctx *calcContext) markVolatile() {
+	ctx.readArea(volatileRead)
+}
+
+// volatileSince returns whether the calculation read the volatile functions
+// since the given number of the read areas.
+func (ctx *calcContext) volatileSince(from int) bool {
+	if ctx = ctx.root(); ctx == nil {
+		return false
+	}
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, area := range ctx.reads[min(from, len(ctx.reads)):] {
+		if area == volatileRead {
+			return true
+		}
+	}
+	return false
+}
+
+// volatileValuesKey is the context key of the volatile values of the
+// recalculation.
+type volatileValuesKey struct{}
+
+// volatileValues is the results of the volatile formula cells calculated in
+// a recalculation, each volatile formula cell is calculated once in the
+// recalculation and its dependents read the same result.
+type volatileValues struct {
+	mu     sync.Mutex
+	values map[string]formulaArg
+}
+
+// withVolatileValues returns a copy of the given context with the volatile
+// values of a new recalculation attached, the context already with the
+// volatile values will be returned directly.
+func withVolatileValues(ctx context.Context) context.Context {
+	if volatileValuesFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, volatileValuesKey{}, &volatileValues{values: make(map[string]formulaArg)})
+}
+
+// volatileValuesFromContext returns the volatile values attached to the given
+// context, or nil if the context isn't in a recalculation.
+func volatileValuesFromContext(ctx context.Context) *volatileValues {
+	if ctx == nil {
+		return nil
+	}
+	values, _ := ctx.Value(volatileValuesKey{}).(*volatileValues)
+	return values
+}
+
+// load returns the result of the volatile formula cell calculated in the
+// recalculation by given cell reference.
+func (v *volatileValues) load(ref string) (formulaArg, bool) {
+	if v == nil {
+		return formulaArg{}, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	arg, ok := v.values[ref]
+	return arg, ok
+}
+
+// store records the result of the volatile formula cell calculated in the
+// recalculation by given cell reference.
+func (v *volatileValues) store(ref string, arg formulaArg) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[ref] = arg
+}
+
+// isVolatileFormula returns whether the formula may call the volatile
+// functions.
+func isVolatileFormula(formula string) bool {
+	formula = strings.ToUpper(formula)
+	for name := range volatileFuncs {
+		if strings.Contains(formula, name+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// setVolatileCell records whether the formula cell read the volatile
+// functions on the last calculation.
+func (f *File) setVolatileCell(sheet, cell string, volatile bool) {
+	if key := sheet + "!" + cell; volatile {
+		f.volatileCells.Store(key, true)
+	} else {
+		f.volatileCells.Delete(key)
+	}
+}
+
+// isVolatileCell returns whether the formula cell read the volatile functions
+// on the last calculation, or its formula may call the volatile functions.
+func (f *File) isVolatileCell(sheet, cell, formula string) bool {
+	if _, ok := f.volatileCells.Load(sheet + "!" + cell); ok {
+		return true
+	}
+	return isVolatileFormula(formula)
+}
+
+// now returns the current time by the Clock option of the workbook.
+func (f *File) now() time.Time {
+	if f.options != nil && f.options.Clock != nil {
+		return f.options.Clock()
+	}
+	return time.Now()
+}
+
+// random returns a random number generator and a function to release it, the
+// generator uses the RandSource option of the workbook, or a new source
+// seeded with the current time.
+func (f *File) random() (*rand.Rand, func()) {
+	if f.options == nil || f.options.RandSource == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano())), func() {}
+	}
+	f.randMu.Lock()
+	return rand.New(f.options.RandSource), f.randMu.Unlock
+}
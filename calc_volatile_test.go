@@ -0,0 +1,165 @@
+package excelize
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcVolatile(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	f := NewFile(Options{Clock: func() time.Time { return now }, RandSource: rand.NewSource(1)})
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]interface{}{1, 2, 3}))
+	for cell, formula := range map[string]string{
+		"B2": "TODAY()",
+		"C2": "NOW()",
+		"D2": "B2+1",
+		"E2": "SUM(A1:C1)",
+		"F2": "RAND()",
+		"G2": "RANDBETWEEN(1,1000)",
+		"H2": "SUM(OFFSET(A1,0,0,1,2))",
+		"I2": "E2+H2",
+	} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+	}
+	calc := func(cell string) string {
+		result, err := f.CalcCellValue("Sheet1", cell, Options{RawCellValue: true})
+		assert.NoError(t, err, cell)
+		return result
+	}
+	cached := func(cell string) bool {
+		_, ok := f.calcCache.Load("Sheet1!" + cell + "!raw=true")
+		return ok
+	}
+	// Test the NOW and TODAY functions with the clock of the workbook
+	assert.Equal(t, "45366", calc("B2"))
+	assert.Equal(t, "45366.5", calc("C2"))
+	assert.Equal(t, "45367", calc("D2"))
+	assert.Equal(t, "6", calc("E2"))
+	assert.Equal(t, "9", calc("I2"))
+	for _, cell := range []string{"B2", "C2", "D2", "I2"} {
+		assert.False(t, cached(cell), cell)
+	}
+	assert.True(t, cached("E2"))
+
+	// Test the volatile formulas and their dependents are calculated again
+	now = now.Add(36 * time.Hour)
+	assert.Equal(t, "45368", calc("B2"))
+	assert.Equal(t, "45368", calc("C2"))
+	assert.Equal(t, "45369", calc("D2"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D2", "SUM(B1:C1)"))
+	assert.Equal(t, "5", calc("D2"))
+	assert.True(t, cached("D2"))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "D2", "B2+1"))
+
+	// Test the RAND and RANDBETWEEN functions with the random source of the
+	// workbook are reproducible
+	expected := make([]string, 4)
+	for i := 0; i < len(expected); i += 2 {
+		expected[i], expected[i+1] = calc("F2"), calc("G2")
+	}
+	assert.NotEqual(t, expected[0], expected[2])
+	f.options.RandSource = rand.NewSource(1)
+	for i := 0; i < len(expected); i += 2 {
+		assert.Equal(t, expected[i], calc("F2"))
+		assert.Equal(t, expected[i+1], calc("G2"))
+	}
+
+	// Test the volatile formulas are calculated by the recalculation
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateSheet("Sheet1"))
+	value, err := f.GetCellValue("Sheet1", "D2", Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "45369", value)
+	now = now.Add(24 * time.Hour)
+	assert.NoError(t, f.RecalculateSheet("Sheet1"))
+	for cell, expected := range map[string]string{"B2": "45369", "D2": "45370", "E2": "6", "I2": "9"} {
+		value, err = f.GetCellValue("Sheet1", cell, Options{RawCellValue: true})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, cell)
+	}
+	// Test the volatile formulas are calculated with the dependents of the
+	// updated cells
+	now = now.Add(24 * time.Hour)
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 10}}))
+	for cell, expected := range map[string]string{"B2": "45370", "D2": "45371", "E2": "15", "I2": "27"} {
+		value, err = f.GetCellValue("Sheet1", cell, Options{RawCellValue: true})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, cell)
+	}
+	// Test the dependents read the same result of the volatile formula in
+	// the recalculation
+	for _, workers := range []int{0, 4} {
+		f = NewFile(Options{MaxCalcWorkers: workers})
+		_, err = f.NewSheet("Sheet2")
+		assert.NoError(t, err)
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A3", "RAND()"))
+		assert.NoError(t, f.SetCellFormula("Sheet1", "A4", "A3+1"))
+		assert.NoError(t, f.SetCellFormula("Sheet2", "A1", "Sheet1!A3+2"))
+		assert.NoError(t, f.RebuildCalcChain())
+		for i := 0; i < 3; i++ {
+			if i == 2 {
+				assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 1}}))
+			} else {
+				assert.NoError(t, f.RecalculateAll())
+			}
+			values := make([]float64, 3)
+			for i, ref := range [][]string{{"Sheet1", "A3"}, {"Sheet1", "A4"}, {"Sheet2", "A1"}} {
+				value, err := f.GetCellValue(ref[0], ref[1], Options{RawCellValue: true})
+				assert.NoError(t, err)
+				values[i], err = strconv.ParseFloat(value, 64)
+				assert.NoError(t, err)
+			}
+			assert.InDelta(t, values[0]+1, values[1], 1e-9, workers)
+			assert.InDelta(t, values[0]+2, values[2], 1e-9, workers)
+		}
+	}
+	assert.True(t, isVolatileFormula("sum(offset(A1,0,0))"))
+	assert.False(t, isVolatileFormula("SUM(A1:A2)"))
+}
+
+func TestVolatileDepsWriter(t *testing.T) {
+	f := NewFile()
+	_, err := f.NewSheet("Sheet2")
+	assert.NoError(t, err)
+	assert.NoError(t, f.SetCellFormula("Sheet1", "A1", `RTD("server",,"topic")`))
+	f.Pkg.Store(defaultXMLPathVolatileDeps, []byte(fmt.Sprintf(`<volTypes xmlns="%s"><volType type="realTimeData"><main first="server"><tp><v>1</v><tr r="A1" s="1"/><tr r="B1" s="1"/><tr r="A1" s="2"/><tr r="A1" s="3"/></tp><tp><v>2</v><tr r="B2" s="1"/></tp></main></volType><volType type="olapFunctions"><main first="cube"><tp><v>3</v><tr r="C1" s="1"/></tp></main></volType></volTypes>`, NameSpaceSpreadSheet.Value)))
+	content, err := f.contentTypesReader()
+	assert.NoError(t, err)
+	content.Overrides = append(content.Overrides, xlsxOverride{PartName: "/" + defaultXMLPathVolatileDeps, ContentType: ContentTypeSpreadSheetMLVolatileDependencies})
+	// Test remove the dependencies of the cells without formulas
+	f.volatileDepsWriter()
+	require.Len(t, f.VolatileDeps.VolType, 1)
+	require.Len(t, f.VolatileDeps.VolType[0].Main[0].Tp, 1)
+	assert.Equal(t, []xlsxVolTopicRef{{R: "A1", S: 1}}, f.VolatileDeps.VolType[0].Main[0].Tp[0].Tr)
+
+	// Test remove the part without any dependencies
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+	buf, err := f.WriteToBuffer()
+	assert.NoError(t, err)
+	assert.Nil(t, f.VolatileDeps)
+	f, err = OpenReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	_, ok := f.Pkg.Load(defaultXMLPathVolatileDeps)
+	assert.False(t, ok)
+	content, err = f.contentTypesReader()
+	assert.NoError(t, err)
+	for _, override := range content.Overrides {
+		assert.NotEqual(t, ContentTypeSpreadSheetMLVolatileDependencies, override.ContentType)
+	}
+	assert.NoError(t, f.Close())
+
+	// Test keep the part with unsupported charset
+	f = NewFile()
+	f.Pkg.Store(defaultXMLPathVolatileDeps, MacintoshCyrillicCharset)
+	f.volatileDepsWriter()
+	data, ok := f.Pkg.Load(defaultXMLPathVolatileDeps)
+	assert.True(t, ok)
+	assert.Equal(t, MacintoshCyrillicCharset, data)
+}
@@ -13,9 +13,12 @@ package excelize
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -102,12 +105,62 @@ func (f *File) volatileDepsReader() (*xlsxVolTypes, error) {
 }
 
 // volatileDepsWriter provides a function to save xl/volatileDependencies.xml
-// after serialize structure.
+// after serialize structure. The volatile dependencies will be synchronized
+// with the formula cells before saving, and the part will be removed if
+// there are no volatile dependencies.
 func (f *File) volatileDepsWriter() {
-	if f.VolatileDeps != nil {
-		output, _ := xml.Marshal(f.VolatileDeps)
-		f.saveFileList(defaultXMLPathVolatileDeps, output)
+	if f.VolatileDeps == nil {
+		if _, ok := f.Pkg.Load(defaultXMLPathVolatileDeps); !ok {
+			return
+		}
+		if _, err := f.volatileDepsReader(); err != nil {
+			// keep the part which can't be parsed
+			f.VolatileDeps = nil
+			return
+		}
+	}
+	if f.syncVolatileDeps(); len(f.VolatileDeps.VolType) == 0 {
+		f.VolatileDeps = nil
+		f.Pkg.Delete(defaultXMLPathVolatileDeps)
+		_ = f.removeContentTypesPart(ContentTypeSpreadSheetMLVolatileDependencies, "/"+defaultXMLPathVolatileDeps)
+		_, _ = f.deleteWorkbookRels(SourceRelationshipVolatileDependencies, strings.TrimPrefix(defaultXMLPathVolatileDeps, "xl/"))
+		return
+	}
+	output, _ := xml.Marshal(f.VolatileDeps)
+	f.saveFileList(defaultXMLPathVolatileDeps, output)
+}
+
+// syncVolatileDeps removes the cell references of the volatile dependencies
+// which refer to the deleted worksheets or the cells without formulas, and
+// removes the topics, the main and the types without any cell references.
+func (f *File) syncVolatileDeps() {
+	sheetMap, volTypes := f.GetSheetMap(), f.VolatileDeps.VolType[:0]
+	for _, volType := range f.VolatileDeps.VolType {
+		mains := volType.Main[:0]
+		for _, main := range volType.Main {
+			topics := main.Tp[:0]
+			for _, topic := range main.Tp {
+				refs := topic.Tr[:0]
+				for _, ref := range topic.Tr {
+					if sheet, ok := sheetMap[ref.S]; ok {
+						if formula, err := f.GetCellFormula(sheet, ref.R); err == nil && formula != "" {
+							refs = append(refs, ref)
+						}
+					}
+				}
+				if topic.Tr = refs; len(refs) > 0 {
+					topics = append(topics, topic)
+				}
+			}
+			if main.Tp = topics; len(topics) > 0 {
+				mains = append(mains, main)
+			}
+		}
+		if volType.Main = mains; len(mains) > 0 {
+			volTypes = append(volTypes, volType)
+		}
 	}
+	f.VolatileDeps.VolType = volTypes
 }
 
 // deleteVolTopicRef provides a function to remove cell reference on the
@@ -305,7 +358,7 @@ func (f *File) UpdateCellAndRecalculate(sheet, cell string) error {
 
 	// If calcChain doesn't exist or is empty, just recalculate the specified cell if it has a formula
 	if calcChain == nil || len(calcChain.C) == 0 {
-		return f.recalculateCell(sheet, cell)
+		return f.recalculateCell(context.Background(), sheet, cell)
 	}
 
 	// Find the cell in calcChain
@@ -313,17 +366,18 @@ func (f *File) UpdateCellAndRecalculate(sheet, cell string) error {
 
 	// If cell is in calcChain, recalculate from that point onward
 	if cellIndex != -1 {
-		return f.recalculateFromIndex(calcChain, sheetID, cellIndex)
+		return f.recalculateFromIndex(context.Background(), calcChain, sheetID, cellIndex)
 	}
 
 	// Cell not in calcChain - it's a value cell or not tracked
 	// Recalculate ALL formulas in this sheet (they might depend on this cell)
-	return f.recalculateAllInSheet(calcChain, sheetID)
+	return f.recalculateAllInSheet(context.Background(), calcChain, sheetID)
 }
 
 // recalculateFromIndex recalculates all cells starting from the given index
-// in the calculation chain that belong to the same sheet.
-func (f *File) recalculateFromIndex(calcChain *xlsxCalcChain, sheetID, startIndex int) error {
+// in the calculation chain that belong to the same sheet, and stops when the
+// given context is canceled.
+func (f *File) recalculateFromIndex(ctx context.Context, calcChain *xlsxCalcChain, sheetID, startIndex int) error {
 	// Get sheet name from sheetID
 	sheetName := f.GetSheetMap()[sheetID]
 	if sheetName == "" {
@@ -332,6 +386,7 @@ func (f *File) recalculateFromIndex(calcChain *xlsxCalcChain, sheetID, startInde
 
 	// Track current sheet ID (for handling I=0 case)
 	currentSheetID := sheetID
+	var cellErrs calcCellErrors
 
 	// Recalculate cells starting from startIndex
 	for i := startIndex; i < len(calcChain.C); i++ {
@@ -347,17 +402,19 @@ func (f *File) recalculateFromIndex(calcChain *xlsxCalcChain, sheetID, startInde
 			continue
 		}
 
-		// Recalculate the cell
-		if err := f.recalculateCell(sheetName, c.R); err != nil {
+		// Recalculate the cell, continue with the remaining cells on circular
+		// reference or formula calculation timeout
+		if err := cellErrs.add(sheetName, c.R, f.recalculateCell(ctx, sheetName, c.R)); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return cellErrs.err()
 }
 
-// recalculateAllInSheet recalculates all cells in the calcChain for a given sheet.
-func (f *File) recalculateAllInSheet(calcChain *xlsxCalcChain, sheetID int) error {
+// recalculateAllInSheet recalculates all cells in the calcChain for a given
+// sheet, and stops when the given context is canceled.
+func (f *File) recalculateAllInSheet(ctx context.Context, calcChain *xlsxCalcChain, sheetID int) error {
 	// Get sheet name from sheetID
 	sheetName := f.GetSheetMap()[sheetID]
 	if sheetName == "" {
@@ -366,6 +423,7 @@ func (f *File) recalculateAllInSheet(calcChain *xlsxCalcChain, sheetID int) erro
 
 	// Track current sheet ID (for handling I=0 case)
 	currentSheetID := -1
+	var cellErrs calcCellErrors
 
 	// Recalculate all cells in the sheet
 	for i := range calcChain.C {
@@ -381,40 +439,96 @@ func (f *File) recalculateAllInSheet(calcChain *xlsxCalcChain, sheetID int) erro
 			continue
 		}
 
-		// Recalculate the cell
-		if err := f.recalculateCell(sheetName, c.R); err != nil {
+		// Recalculate the cell, continue with the remaining cells on circular
+		// reference or formula calculation timeout
+		if err := cellErrs.add(sheetName, c.R, f.recalculateCell(ctx, sheetName, c.R)); err != nil {
 			return err
 		}
 	}
 
+	return cellErrs.err()
+}
+
+// calcCellErrors collects the formula cells with the circular references or
+// the formula calculation timeouts during the recalculation.
+type calcCellErrors struct {
+	circular, timeout []string
+}
+
+// add records the cell if the given error is the circular reference or the
+// formula calculation timeout error, and returns the other errors.
+func (e *calcCellErrors) add(sheet, cell string, err error) error {
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrCircularReference):
+		e.circular = append(e.circular, sheet+"!"+cell)
+	case errors.Is(err, ErrFormulaTimeout):
+		e.timeout = append(e.timeout, sheet+"!"+cell)
+	default:
+		return err
+	}
 	return nil
 }
 
-// recalculateCell recalculates a single formula cell and updates its cache.
-func (f *File) recalculateCell(sheet, cell string) error {
-	calcStart := time.Now()
+// err returns the circular reference and the formula calculation timeout
+// errors of the recorded cells.
+func (e *calcCellErrors) err() error {
+	var errs []error
+	if len(e.circular) > 0 {
+		errs = append(errs, newCircularReferenceError(e.circular...))
+	}
+	if len(e.timeout) > 0 {
+		errs = append(errs, newFormulaTimeoutError(e.timeout...))
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// recalculateCell recalculates a single formula cell and updates its cache,
+// the cache will not be changed if the given context is canceled.
+func (f *File) recalculateCell(ctx context.Context, sheet, cell string) error {
+	result, ok, err := f.calcFormulaCell(ctx, sheet, cell)
+	if !ok {
+		return err
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return f.storeCalcResult(sheet, cell, result, err)
+}
+
+// calcTimeout returns the maximum duration of calculating a single formula in
+// the recalculation by the CalcTimeout option, which defaults to 5 seconds.
+func (f *File) calcTimeout() time.Duration {
+	if f.options == nil || f.options.CalcTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return f.options.CalcTimeout
+}
 
+// calcFormulaCell calculates a single formula cell with the raw cell values
+// without updating its cache, and returns false if the cell doesn't exist or
+// doesn't have a formula.
+func (f *File) calcFormulaCell(ctx context.Context, sheet, cell string) (string, bool, error) {
 	ws, err := f.workSheetReader(sheet)
 	if err != nil {
-		return err
+		return "", false, err
 	}
 
 	// Check if the cell has a formula
-	col, row, err := CellNameToCoordinates(cell)
+	_, row, err := CellNameToCoordinates(cell)
 	if err != nil {
-		return err
+		return "", false, err
 	}
 
 	var cellRef *xlsxC
-	var formula string
 	for i := range ws.SheetData.Row {
 		if ws.SheetData.Row[i].R == row {
 			for j := range ws.SheetData.Row[i].C {
 				if ws.SheetData.Row[i].C[j].R == cell {
 					cellRef = &ws.SheetData.Row[i].C[j]
-					if cellRef.F != nil {
-						formula = cellRef.F.Content
-					}
 					break
 				}
 			}
@@ -424,27 +538,35 @@ func (f *File) recalculateCell(sheet, cell string) error {
 
 	// If cell doesn't exist or doesn't have a formula, nothing to do
 	if cellRef == nil || cellRef.F == nil {
-		return nil
-	}
-
-	// 检查缓存
-	cacheKey := sheet + "!" + cell + "!raw=true"
-	cacheHit := false
-	if _, ok := f.calcCache.Load(cacheKey); ok {
-		cacheHit = true
+		return "", false, nil
 	}
 
 	// Calculate the formula value using raw values (not formatted)
-	result, err := f.CalcCellValue(sheet, cell, Options{RawCellValue: true})
-	calcDuration := time.Since(calcStart)
-
-	// 记录统计
-	recordCellCalc(sheet, cell, formula, result, calcDuration, cacheHit)
+	result, err := f.calcCellResult(ctx, sheet, cell, true, Options{RawCellValue: true, CalcTimeout: f.calcTimeout()})
+	return result, true, err
+}
 
+// storeCalcResult updates the cache of the formula cell by given calculation
+// result and error.
+func (f *File) storeCalcResult(sheet, cell, result string, err error) error {
+	ws, wsErr := f.workSheetReader(sheet)
+	if wsErr != nil {
+		return wsErr
+	}
+	col, row, coordErr := CellNameToCoordinates(cell)
+	if coordErr != nil {
+		return coordErr
+	}
 	if err != nil {
-		// If calculation fails, clear the cache instead of returning error
-		cellRef.V = ""
-		cellRef.T = ""
+		// If calculation fails, clear the cache instead of returning error, the
+		// cells may be reallocated by the spilled dynamic array formula
+		if cellRef := ws.getCellReadOnly(col, row); cellRef != nil {
+			cellRef.V = ""
+			cellRef.T = ""
+		}
+		if errors.Is(err, ErrCircularReference) || errors.Is(err, ErrFormulaTimeout) {
+			return err
+		}
 		return nil
 	}
 
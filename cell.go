@@ -97,9 +97,10 @@ func (f *File) GetCellType(sheet, cell string) (CellType, error) {
 }
 
 // SetCellValue provides a function to set the value of a cell. This function
-// is concurrency safe. The specified coordinates should not be in the first
-// row of the table, a complex number can be set with string text. The
-// following shows the supported data types:
+// is concurrency safe. Setting the header cell of the table renames the table
+// column, and the structured references to the column, such as Table1[Price],
+// in the formulas and defined names. A complex number can be set with string
+// text. The following shows the supported data types:
 //
 //	int
 //	int8
@@ -155,7 +156,10 @@ func (f *File) SetCellValue(sheet, cell string, value interface{}) error {
 	default:
 		err = f.SetCellStr(sheet, cell, fmt.Sprint(value))
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	return f.renameTableColumn(sheet, cell)
 }
 
 // String extracts characters from a string item.
@@ -182,19 +186,22 @@ func (c *xlsxC) hasValue() bool {
 
 // removeFormula delete formula for the cell.
 func (f *File) removeFormula(c *xlsxC, ws *xlsxWorksheet, sheet string) error {
-	// When removing formula due to SetCellValue, clear entire calcCache and rangeCache
-	// to ensure all dependent formulas are recalculated
+	// When removing formula due to SetCellValue, clear the cached items which
+	// read the cell to ensure all dependent formulas are recalculated
 	// Skip cache clearing if in batch mode (will be cleared once after batch completes)
 	f.mu.Lock()
 	inBatch := f.inBatchMode
 	f.mu.Unlock()
 
 	if !inBatch {
-		f.calcCache.Clear()
-		f.rangeCache.Clear()
+		f.clearCellCache(sheet, c.R)
 	}
+	// The value set by the user blocks the spill range of the dynamic array
+	// formula instead of being overwritten
+	ws.spillValues.Delete(c.R)
 	if c.F != nil && c.Vm == nil {
 		sheetID := f.getSheetID(sheet)
+		f.deleteDependencyGraphCell(sheet, c.R)
 		if err := f.deleteCalcChain(sheetID, c.R); err != nil {
 			return err
 		}
@@ -205,6 +212,7 @@ func (f *File) removeFormula(c *xlsxC, ws *xlsxWorksheet, sheet string) error {
 					if cell.F != nil && cell.F.Si != nil && *cell.F.Si == *si {
 						ws.SheetData.Row[r].C[col].F = nil
 						ws.formulaSI.Delete(si)
+						f.deleteDependencyGraphCell(sheet, cell.R)
 						_ = f.deleteCalcChain(sheetID, cell.R)
 					}
 				}
@@ -268,6 +276,7 @@ func (f *File) setCellTimeFunc(sheet, cell string, value time.Time) error {
 	if isNum, err = c.setCellTime(value, date1904); err != nil {
 		return err
 	}
+	ws.spillValues.Delete(c.R)
 	if isNum {
 		_ = f.setDefaultTimeStyle(sheet, cell, getTimeNumFmt(value))
 	}
@@ -836,9 +845,11 @@ func (f *File) SetCellFormula(sheet, cell, formula string, opts ...FormulaOpts)
 	if formula == "" {
 		ws.deleteSharedFormula(c)
 		c.F = nil
+		f.deleteDependencyGraphCell(sheet, c.R)
 		return f.deleteCalcChain(f.getSheetID(sheet), cell)
 	}
 
+	formula = replaceSpillRangeOperator(formula)
 	if c.F != nil {
 		c.F.Content = formula
 	} else {
@@ -867,6 +878,11 @@ func (f *File) SetCellFormula(sheet, cell, formula string, opts ...FormulaOpts)
 			c.F.Ref = *opt.Ref
 		}
 	}
+	if len(opts) > 0 {
+		f.clearDependencyGraph()
+	} else {
+		f.updateDependencyGraph(sheet, c.R)
+	}
 	c.T, c.IS = "str", nil
 	return err
 }
@@ -911,6 +927,10 @@ func (ws *xlsxWorksheet) setArrayFormula(sheet string, formula *xlsxF, definedNa
 // formula as the normal formula.
 func (f *File) setArrayFormulaCells() error {
 	definedNames := f.GetDefinedName()
+	metadata, err := f.metadataReader()
+	if err != nil {
+		return err
+	}
 	for _, sheetN := range f.GetSheetList() {
 		ws, err := f.workSheetReader(sheetN)
 		if err != nil {
@@ -921,7 +941,7 @@ func (f *File) setArrayFormulaCells() error {
 		}
 		for _, row := range ws.SheetData.Row {
 			for _, cell := range row.C {
-				if cell.F != nil && cell.F.T == STCellFormulaTypeArray {
+				if cell.F != nil && cell.F.T == STCellFormulaTypeArray && !isDynamicArrayCell(metadata, &cell) {
 					if err = ws.setArrayFormula(sheetN, cell.F, definedNames); err != nil {
 						return err
 					}
@@ -1410,6 +1430,7 @@ func (f *File) SetCellRichText(sheet, cell string, runs []RichTextRun) error {
 	}
 	// Use fine-grained cache clearing for single cell rich text changes
 	f.clearCellCache(sheet, cell)
+	ws.spillValues.Delete(c.R)
 	for idx, strItem := range sst.SI {
 		if reflect.DeepEqual(strItem, si) {
 			c.T, c.V = "s", strconv.Itoa(idx)
@@ -1,17 +1,11 @@
 package excelize
 
-import (
-	"fmt"
-)
-
 // SafeCheckRow is a safer version of checkRow that handles index out of range errors
 // This is a temporary fix until the root cause is addressed
 func (ws *xlsxWorksheet) SafeCheckRow() error {
 	defer func() {
-		if r := recover(); r != nil {
-			// Log the panic but don't crash the program
-			fmt.Printf("⚠️  SafeCheckRow recovered from panic: %v\n", r)
-		}
+		// Recover from the panic and don't crash the program
+		_ = recover()
 	}()
 
 	for rowIdx := range ws.SheetData.Row {
@@ -82,10 +76,8 @@ func (ws *xlsxWorksheet) SafeCheckRow() error {
 					return err
 				}
 
-				// Boundary check
+				// Boundary check, skip the invalid cell reference
 				if colNum-1 < 0 || colNum-1 >= len(ws.SheetData.Row[rowIdx].C) {
-					fmt.Printf("⚠️  Skipping invalid cell reference: %s (colNum=%d, len=%d)\n",
-						colData.R, colNum, len(ws.SheetData.Row[rowIdx].C))
 					continue
 				}
 
@@ -29,6 +29,10 @@ var (
 	ErrCellCharsLength = fmt.Errorf("cell value must be 0-%d characters", TotalCellChars)
 	// ErrCellStyles defined the error message on cell styles exceeds the limit.
 	ErrCellStyles = fmt.Errorf("the cell styles exceeds the %d limit", MaxCellStyles)
+	// ErrCircularReference defined the error message on the formula refers to
+	// its own cell directly or indirectly while the iterative calculation is
+	// disabled.
+	ErrCircularReference = errors.New("circular reference")
 	// ErrColumnNumber defined the error message on receive an invalid column
 	// number.
 	ErrColumnNumber = fmt.Errorf("the column number must be greater than or equal to %d and less than or equal to %d", MinColumns, MaxColumns)
@@ -64,6 +68,16 @@ var (
 	// ErrFormControlValue defined the error message for receiving a scroll
 	// value exceeds limit.
 	ErrFormControlValue = fmt.Errorf("scroll value must be an integer from 0 to %d", MaxFormControlValue)
+	// ErrFormulaFuncName defined the error message on receive the invalid
+	// user-defined formula function name, or the name conflicts with the
+	// built-in formula functions.
+	ErrFormulaFuncName = errors.New("the formula function name is invalid or conflicts with the built-in function")
+	// ErrFormulaSyntax defined the error message on receive the formula with
+	// the syntax error.
+	ErrFormulaSyntax = errors.New("formula syntax error")
+	// ErrFormulaTimeout defined the error message on the calculation of the
+	// formula exceeds the deadline specified by the CalcTimeout option.
+	ErrFormulaTimeout = errors.New("formula calculation timed out")
 	// ErrGroupSheets defined the error message on group sheets.
 	ErrGroupSheets = errors.New("group worksheet must contain an active worksheet")
 	// ErrImgExt defined the error message on receive an unsupported image
@@ -205,6 +219,12 @@ func newCellNameToCoordinatesError(cell string, err error) error {
 	return fmt.Errorf("cannot convert cell %q to coordinates: %v", cell, err)
 }
 
+// newCircularReferenceError defined the error message on the circular
+// reference detected on the given cells.
+func newCircularReferenceError(cells ...string) error {
+	return fmt.Errorf("%w on cell %s", ErrCircularReference, strings.Join(cells, ", "))
+}
+
 // newCoordinatesToCellNameError defined the error message on converts [X, Y]
 // coordinates to alpha-numeric cell name.
 func newCoordinatesToCellNameError(col, row int) error {
@@ -217,6 +237,18 @@ func newFieldLengthError(name string) error {
 	return fmt.Errorf("field %s must be less than or equal to 255 characters", name)
 }
 
+// newFormulaSyntaxError defined the error message on receive the formula
+// with the syntax error at the given position.
+func newFormulaSyntaxError(formula string, pos int) error {
+	return fmt.Errorf("%w at position %d in formula %q", ErrFormulaSyntax, pos, formula)
+}
+
+// newFormulaTimeoutError defined the error message on the calculation of the
+// formulas on the given cells exceeds the deadline.
+func newFormulaTimeoutError(cells ...string) error {
+	return fmt.Errorf("%w on cell %s", ErrFormulaTimeout, strings.Join(cells, ", "))
+}
+
 // newInvalidAutoFilterColumnError defined the error message on receiving the
 // incorrect index of column.
 func newInvalidAutoFilterColumnError(col string) error {
@@ -235,6 +267,12 @@ func newInvalidAutoFilterOperatorError(op, exp string) error {
 	return fmt.Errorf("the operator %q in expression %q is not valid in relation to Blanks/NonBlanks", op, exp)
 }
 
+// newInvalidCalcCacheError defined the error message on receiving the
+// invalid calculation cache name.
+func newInvalidCalcCacheError(name CalcCache) error {
+	return fmt.Errorf("invalid calculation cache name %q", name)
+}
+
 // newInvalidCellNameError defined the error message on receiving the invalid
 // cell name.
 func newInvalidCellNameError(cell string) error {
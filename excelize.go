@@ -18,11 +18,14 @@ import (
 	"encoding/xml"
 	"io"
 	"io/fs"
+	"log/slog"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/net/html/charset"
 )
@@ -42,11 +45,17 @@ type File struct {
 	streams          map[string]*StreamWriter
 	tempFiles        sync.Map
 	xmlAttr          sync.Map
-	calcCache        sync.Map
+	calcCache        *lruCache // Cache for formula results and resolved cell values
 	rangeCache       *lruCache // LRU cache for range matrices to limit memory usage
-	matchIndexCache  sync.Map  // Cache for MATCH hash indexes: key -> map[string]int
-	ifsMatchCache    sync.Map  // Cache for SUMIFS/COUNTIFS criteria matching: key -> []cellRef
-	rangeIndexCache  sync.Map  // Cache for range value indexes: rangeKey -> map[value][]cellRef
+	regexpCache      *lruCache // LRU cache for compiled regular expressions of the formulas
+	ifsMatchCache    *lruCache // Cache for SUMIFS/COUNTIFS criteria matching: key -> []cellRef, and GROUPBY/PIVOTBY group keys
+	rangeIndexCache  *lruCache // Cache for range value indexes: rangeKey -> map[value][]cellRef
+	lookupIndexCache *lruCache // Cache for lookup vector indexes shared by the lookup functions
+	userFormulaFuncs sync.Map  // Registered user-defined formula functions: name -> FormulaFunc
+	calcGraphMu      sync.Mutex
+	calcGraph        *dependencyGraph // Dependency graph of the formula cells in the calculation chain
+	volatileCells    sync.Map         // Formula cells which read the volatile functions: "sheet!cell" -> bool
+	randMu           sync.Mutex
 	CalcChain        *xlsxCalcChain
 	CharsetReader    func(charset string, input io.Reader) (rdr io.Reader, err error)
 	Comments         map[string]*xlsxComments
@@ -82,6 +91,51 @@ type ZipWriter interface {
 // MaxCalcIterations specifies the maximum iterations for iterative
 // calculation, the default value is 0.
 //
+// MaxCalcWorkers specifies the maximum number of goroutines for recalculating
+// formulas by the RecalculateAll function. If the value is greater than 1, the
+// formulas will be grouped by the dependency levels, and the formulas in the
+// same level will be evaluated concurrently, otherwise the formulas will be
+// recalculated serially in the topological order of the dependency graph, and
+// the formulas have the same precedence are ordered by the calculation chain.
+// The default value is 0.
+//
+// CalcTimeout specifies the maximum duration of calculating a single formula.
+// The calculation of the formula will be stopped when the deadline is
+// exceeded, and the ErrFormulaTimeout error will be returned for that cell.
+// The default value is 0, which means no deadline, except for the
+// recalculation functions, such as RecalculateAll, RecalculateSheet and
+// BatchUpdateAndRecalculate, which stop the formulas calculation after 5
+// seconds by default.
+//
+// LogHandler specifies the handler of the structured logs of the workbook,
+// such as the progress and the slow formulas of the recalculation. The logs
+// will be discarded if the value is nil, which is the default.
+//
+// Metrics specifies the receiver of the counters and the timers of the
+// workbook, such as the parse, save and formula calculation durations and
+// the calculation cache hits. The metrics will be discarded if the value is
+// nil, which is the default.
+//
+// CalcCacheLimits specifies the limits of the number of items and the
+// estimated memory size of the calculation caches by the cache names, such as
+// CalcCacheResults and CalcCacheRanges. The least recently used items will be
+// evicted once a cache exceeds its limits. By default, the CalcCacheRanges is
+// limited to 50 items, the CalcCacheRegexps is limited to 256 items, the
+// CalcCacheLookupIndexes is limited to 100 items, and the others are
+// unlimited. The limits take effect on creating or opening the
+// workbook.
+//
+// Clock specifies the function which returns the current time for the NOW and
+// TODAY formula functions, such as returning a fixed time to get the stable
+// calculation results in tests. The time.Now will be used if the value is
+// nil, which is the default.
+//
+// RandSource specifies the source of the random numbers for the RAND and
+// RANDBETWEEN formula functions, such as the rand.NewSource with a fixed seed
+// to get the reproducible calculation results. The source will not be used
+// concurrently. A new source seeded with the current time will be used for
+// each random number if the value is nil, which is the default.
+//
 // Password specifies the password of the spreadsheet in plain text.
 //
 // RawCellValue specifies if apply the number format for the cell value or get
@@ -116,6 +170,13 @@ type ZipWriter interface {
 // format code these effect by the system's local language settings.
 type Options struct {
 	MaxCalcIterations     uint
+	MaxCalcWorkers        int
+	CalcTimeout           time.Duration
+	LogHandler            slog.Handler
+	Metrics               Metrics
+	CalcCacheLimits       map[CalcCache]CalcCacheLimit
+	Clock                 func() time.Time
+	RandSource            rand.Source
 	Password              string
 	RawCellValue          bool
 	UnzipSizeLimit        int64
@@ -168,7 +229,12 @@ func newFile() *File {
 		Relationships:    sync.Map{},
 		CharsetReader:    charset.NewReaderLabel,
 		ZipWriter:        func(w io.Writer) ZipWriter { return zip.NewWriter(w) },
+		calcCache:        newLRUCache(0),
 		rangeCache:       newLRUCache(50), // Limit to 50 range matrices to control memory
+		regexpCache:      newLRUCache(256),
+		ifsMatchCache:    newLRUCache(0),
+		rangeIndexCache:  newLRUCache(0),
+		lookupIndexCache: newLRUCache(100),
 	}
 }
 
@@ -196,6 +262,7 @@ func (f *File) checkOpenReaderOptions() error {
 // OpenReader read data stream from io.Reader and return a populated
 // spreadsheet file.
 func OpenReader(r io.Reader, opts ...Options) (*File, error) {
+	start := time.Now()
 	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
@@ -205,6 +272,7 @@ func OpenReader(r io.Reader, opts ...Options) (*File, error) {
 	if err = f.checkOpenReaderOptions(); err != nil {
 		return nil, err
 	}
+	f.setCalcCacheLimits()
 	if bytes.Contains(b, oleIdentifier) {
 		if b, err = Decrypt(b, f.options); err != nil {
 			return nil, ErrWorkbookFileFormat
@@ -235,6 +303,9 @@ func OpenReader(r io.Reader, opts ...Options) (*File, error) {
 		return f, err
 	}
 	f.Theme, err = f.themeReader()
+	duration := time.Since(start)
+	f.metrics().Observe(MetricParseDuration, duration)
+	f.logger().Debug("workbook opened", "bytes", len(b), "sheets", sheetCount, "duration", duration)
 	return f, err
 }
 
@@ -328,6 +399,7 @@ func (f *File) workSheetReader(sheet string) (ws *xlsxWorksheet, err error) {
 		}
 		f.checked.Store(name, true)
 	}
+	ws.loadSpillValues()
 	f.Sheet.Store(name, ws)
 	return
 }
@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // NewFile provides a function to create new file by default template.
@@ -52,6 +53,7 @@ func NewFile(opts ...Options) *File {
 	f.Sheet.Store("xl/worksheets/sheet1.xml", ws)
 	f.Theme, _ = f.themeReader()
 	f.options = f.getOptions(opts...)
+	f.setCalcCacheLimits()
 	return f
 }
 
@@ -135,7 +137,7 @@ func (f *File) WriteTo(w io.Writer, opts ...Options) (int64, error) {
 // WriteToBuffer provides a function to get bytes.Buffer from the saved file,
 // and it allocates space in memory. Be careful when the file size is large.
 func (f *File) WriteToBuffer() (*bytes.Buffer, error) {
-	buf := new(bytes.Buffer)
+	buf, start := new(bytes.Buffer), time.Now()
 	zw := f.ZipWriter(buf)
 
 	if err := f.writeToZip(zw); err != nil {
@@ -154,6 +156,9 @@ func (f *File) WriteToBuffer() (*bytes.Buffer, error) {
 		buf.Reset()
 		buf.Write(b)
 	}
+	duration := time.Since(start)
+	f.metrics().Observe(MetricSaveDuration, duration)
+	f.logger().Debug("workbook saved", "bytes", buf.Len(), "duration", duration)
 	return buf, nil
 }
 
@@ -161,9 +166,9 @@ func (f *File) WriteToBuffer() (*bytes.Buffer, error) {
 func (f *File) writeToZip(zw ZipWriter) error {
 	f.calcChainWriter()
 	f.commentsWriter()
+	f.volatileDepsWriter()
 	f.contentTypesWriter()
 	f.drawingsWriter()
-	f.volatileDepsWriter()
 	f.vmlDrawingWriter()
 	f.workBookWriter()
 	f.workSheetWriter()
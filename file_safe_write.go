@@ -102,9 +102,9 @@ func (f *File) writeToZipNonDestructive(zw ZipWriter) error {
 	// These writers don't modify worksheet state, safe to call directly
 	f.calcChainWriter()
 	f.commentsWriter()
+	f.volatileDepsWriter()
 	f.contentTypesWriter()
 	f.drawingsWriter()
-	f.volatileDepsWriter()
 	f.vmlDrawingWriter()
 	f.workBookWriter()
 
@@ -6,7 +6,6 @@ package excelize
 
 import (
 	"os"
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -250,7 +249,7 @@ func TestCalcCellValueCacheKeyWithRawValue(t *testing.T) {
 
 	t.Run("cache keys are different for raw vs formatted", func(t *testing.T) {
 		// Clear any existing cache
-		f.calcCache = sync.Map{}
+		f.calcCache.Clear()
 
 		// First call with default (formatted)
 		v1, err := f.CalcCellValue("Sheet1", "A2")
@@ -275,7 +274,7 @@ func TestCalcCellValueCacheKeyWithRawValue(t *testing.T) {
 
 	t.Run("cache works correctly with different call orders", func(t *testing.T) {
 		// Clear cache and test opposite order
-		f.calcCache = sync.Map{}
+		f.calcCache.Clear()
 
 		// First call with RawCellValue=true
 		v1, err := f.CalcCellValue("Sheet1", "A2", Options{RawCellValue: true})
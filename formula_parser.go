@@ -0,0 +1,776 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// FormulaNodeType is the type of the formula syntax tree node.
+type FormulaNodeType byte
+
+// This section defines the formula syntax tree node types.
+const (
+	FormulaNodeUnknown FormulaNodeType = iota
+	FormulaNodeNumber
+	FormulaNodeText
+	FormulaNodeLogical
+	FormulaNodeError
+	FormulaNodeArray
+	FormulaNodeArrayRow
+	FormulaNodeReference
+	FormulaNodeName
+	FormulaNodeStructuredReference
+	FormulaNodeFunction
+	FormulaNodeInvoke
+	FormulaNodePrefix
+	FormulaNodeInfix
+	FormulaNodePostfix
+	FormulaNodeParentheses
+	FormulaNodeEmpty
+)
+
+// FormulaNode directly maps the node of the formula syntax tree. The meaning
+// of the Value and Children fields depends on the node type:
+//
+//	FormulaNodeNumber               Value is the number text, such as 1.5E+3
+//	FormulaNodeText                 Value is the unescaped string literal
+//	FormulaNodeLogical              Value is TRUE or FALSE
+//	FormulaNodeError                Value is the error literal, such as #N/A
+//	FormulaNodeArray                Children are the FormulaNodeArrayRow nodes
+//	FormulaNodeArrayRow             Children are the elements of the row
+//	FormulaNodeReference            Value is the cell or range reference, such
+//	                                as $A$1, A1:B2, A:A or 1:1
+//	FormulaNodeName                 Value is the defined name, or the name
+//	                                declared by LET and LAMBDA functions
+//	FormulaNodeStructuredReference  Value is the structured reference, such as
+//	                                Table1[[#This Row],[Qty]]
+//	FormulaNodeFunction             Value is the function name as written, such
+//	                                as _xlfn.XLOOKUP, Children are arguments
+//	FormulaNodeInvoke               The first child is the called expression,
+//	                                such as a LAMBDA function, and the rest
+//	                                children are arguments
+//	FormulaNodePrefix               Value is the operator +, - or @, the only
+//	                                child is the operand
+//	FormulaNodeInfix                Value is the operator, the space for the
+//	                                intersection and the comma for the union,
+//	                                Children are the left and right operands
+//	FormulaNodePostfix              Value is the operator % or #, the only
+//	                                child is the operand
+//	FormulaNodeParentheses          The only child is the enclosed expression
+//	FormulaNodeEmpty                The omitted function argument
+//
+// The Sheet field is the unquoted worksheet name of the reference and the
+// name, which could be a 3D reference such as Sheet1:Sheet3, or contains the
+// external workbook index, such as [1]Sheet1.
+type FormulaNode struct {
+	Type     FormulaNodeType
+	Value    string
+	Sheet    string
+	Children []*FormulaNode
+}
+
+// formulaTokenKind is the type of the token of the formula parser.
+type formulaTokenKind byte
+
+// This section defines the token types of the formula parser.
+const (
+	formulaTokenEOF formulaTokenKind = iota
+	formulaTokenNumber
+	formulaTokenText
+	formulaTokenLogical
+	formulaTokenError
+	formulaTokenWord
+	formulaTokenStructuredRef
+	formulaTokenFunction
+	formulaTokenOperator
+	formulaTokenOpen
+	formulaTokenClose
+	formulaTokenBraceOpen
+	formulaTokenBraceClose
+	formulaTokenComma
+	formulaTokenSemicolon
+)
+
+// formulaToken defined the token of the formula parser, the space field
+// indicates whether the token is preceded by the whitespace.
+type formulaToken struct {
+	kind        formulaTokenKind
+	text, sheet string
+	pos         int
+	space       bool
+}
+
+// formulaParser defined the formula parser state.
+type formulaParser struct {
+	formula string
+	tokens  []formulaToken
+	idx     int
+}
+
+var (
+	// formulaCellRefExp matching the cell reference, such as A1 or $A$1.
+	formulaCellRefExp = regexp.MustCompile(`^\$?[A-Za-z]{1,3}\$?[1-9][0-9]*$`)
+	// formulaColumnRefExp matching the column of the entire column reference.
+	formulaColumnRefExp = regexp.MustCompile(`^\$?[A-Za-z]{1,3}$`)
+	// formulaRowRefExp matching the row of the entire row reference.
+	formulaRowRefExp = regexp.MustCompile(`^\$?[1-9][0-9]*$`)
+	// formulaErrors defined the error literals in the formula.
+	formulaErrors = []string{
+		formulaErrorDIV, formulaErrorNAME, formulaErrorNA, formulaErrorNUM, formulaErrorVALUE, formulaErrorREF,
+		formulaErrorNULL, formulaErrorSPILL, formulaErrorCALC, formulaErrorGETTINGDATA,
+	}
+	// formulaBinaryOperators defined the binary operators in the order of
+	// the precedence from low to high.
+	formulaBinaryOperators = [][]string{{"=", "<>", "<", ">", "<=", ">="}, {"&"}, {"+", "-"}, {"*", "/"}, {"^"}}
+)
+
+// isFormulaWordChar returns whether the byte can be used in the names,
+// references and function names of the formula.
+func isFormulaWordChar(c byte) bool {
+	return c == '_' || c == '.' || c == '\\' || c == '$' || c == '?' || c >= '0' && c <= '9' ||
+		c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= 0x80
+}
+
+// scanFormulaQuoted returns the index after the string literal or quoted
+// worksheet name which starts at the given index of the formula, and
+// whether the quotation is closed.
+func scanFormulaQuoted(formula string, start int) (int, bool) {
+	quote := formula[start]
+	for i := start + 1; i < len(formula); i++ {
+		if formula[i] != quote {
+			continue
+		}
+		if i+1 < len(formula) && formula[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1, true
+	}
+	return len(formula), false
+}
+
+// scanFormulaWord returns the index after the name, reference or function
+// name which starts at the given index of the formula, the external
+// workbook index in the brackets will be included, such as [1]Sheet1.
+func scanFormulaWord(formula string, start int) int {
+	i := start
+	if i < len(formula) && formula[i] == '[' {
+		i = skipFormulaBracket(formula, i)
+	}
+	for i < len(formula) && isFormulaWordChar(formula[i]) {
+		i++
+	}
+	return i
+}
+
+// scanFormulaNumber returns the index after the number which starts at the
+// given index of the formula, such as 1, .5 or 1.5E+3.
+func scanFormulaNumber(formula string, start int) int {
+	i := start
+	digits := func() {
+		for i < len(formula) && formula[i] >= '0' && formula[i] <= '9' {
+			i++
+		}
+	}
+	if digits(); i < len(formula) && formula[i] == '.' {
+		i++
+		digits()
+	}
+	if i < len(formula) && (formula[i] == 'E' || formula[i] == 'e') {
+		j := i + 1
+		if j < len(formula) && (formula[j] == '+' || formula[j] == '-') {
+			j++
+		}
+		if j < len(formula) && formula[j] >= '0' && formula[j] <= '9' {
+			i = j
+			digits()
+		}
+	}
+	return i
+}
+
+// tokenize split the formula into the tokens.
+func (p *formulaParser) tokenize() error {
+	formula := p.formula
+	for i := 0; i < len(formula); {
+		var space bool
+		for i < len(formula) && strings.IndexByte(" \t\r\n", formula[i]) != -1 {
+			i, space = i+1, true
+		}
+		if i >= len(formula) {
+			break
+		}
+		token := formulaToken{pos: i, space: space}
+		switch c := formula[i]; {
+		case c == '"':
+			end, ok := scanFormulaQuoted(formula, i)
+			if !ok {
+				return newFormulaSyntaxError(formula, i)
+			}
+			token.kind, token.text = formulaTokenText, strings.ReplaceAll(formula[i+1:end-1], `""`, `"`)
+			i = end
+		case c == '\'':
+			end, ok := scanFormulaQuoted(formula, i)
+			if !ok || end >= len(formula) || formula[end] != '!' {
+				return newFormulaSyntaxError(formula, i)
+			}
+			token.sheet = strings.ReplaceAll(formula[i+1:end-1], "''", "'")
+			if i = p.scanQualified(&token, end+1); i == -1 {
+				return newFormulaSyntaxError(formula, end+1)
+			}
+		case c == '#':
+			if prev := p.lastToken(); !space && prev != nil && (prev.kind == formulaTokenWord || prev.kind == formulaTokenClose) {
+				token.kind, token.text = formulaTokenOperator, "#"
+				i++
+				break
+			}
+			if token.text = formulaErrorLiteral(formula[i:]); token.text == "" {
+				return newFormulaSyntaxError(formula, i)
+			}
+			token.kind = formulaTokenError
+			i += len(token.text)
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(formula) && formula[i+1] >= '0' && formula[i+1] <= '9':
+			end := scanFormulaNumber(formula, i)
+			if end < len(formula) && isFormulaWordChar(formula[end]) {
+				return newFormulaSyntaxError(formula, i)
+			}
+			token.kind, token.text = formulaTokenNumber, formula[i:end]
+			i = end
+		case c == '[' || isFormulaWordChar(c):
+			if i = p.scanQualified(&token, i); i == -1 {
+				return newFormulaSyntaxError(formula, token.pos)
+			}
+		case c == '(':
+			token.kind, token.text = formulaTokenOpen, "("
+			i++
+		case c == ')':
+			token.kind, token.text = formulaTokenClose, ")"
+			i++
+		case c == '{':
+			token.kind, token.text = formulaTokenBraceOpen, "{"
+			i++
+		case c == '}':
+			token.kind, token.text = formulaTokenBraceClose, "}"
+			i++
+		case c == ',':
+			token.kind, token.text = formulaTokenComma, ","
+			i++
+		case c == ';':
+			token.kind, token.text = formulaTokenSemicolon, ";"
+			i++
+		case strings.IndexByte("+-*/^&=<>%@:", c) != -1:
+			token.kind, token.text = formulaTokenOperator, string(c)
+			if i+1 < len(formula) {
+				if op := formula[i : i+2]; op == "<>" || op == "<=" || op == ">=" {
+					token.text = op
+				}
+			}
+			i += len(token.text)
+		default:
+			return newFormulaSyntaxError(formula, i)
+		}
+		p.tokens = append(p.tokens, token)
+	}
+	p.tokens = append(p.tokens, formulaToken{kind: formulaTokenEOF, pos: len(formula)})
+	return nil
+}
+
+// lastToken returns the last token has been scanned.
+func (p *formulaParser) lastToken() *formulaToken {
+	if len(p.tokens) == 0 {
+		return nil
+	}
+	return &p.tokens[len(p.tokens)-1]
+}
+
+// formulaErrorLiteral returns the error literal at the start of the given
+// text, returns empty string if the text is not started with an error.
+func formulaErrorLiteral(text string) string {
+	for _, literal := range formulaErrors {
+		if len(text) >= len(literal) && strings.EqualFold(text[:len(literal)], literal) {
+			return literal
+		}
+	}
+	return ""
+}
+
+// scanQualified scan the name, reference, structured reference or function
+// name which starts at the given index of the formula, the worksheet name
+// prefix, such as Sheet1!A1 or Sheet1:Sheet3!A1 will be recorded in the
+// token. It returns the index after the token, or -1 if the text is invalid.
+func (p *formulaParser) scanQualified(token *formulaToken, start int) int {
+	formula := p.formula
+	if token.sheet != "" && formulaErrorLiteral(formula[start:]) == formulaErrorREF {
+		token.kind, token.text = formulaTokenWord, formulaErrorREF
+		return start + len(formulaErrorREF)
+	}
+	end := scanFormulaWord(formula, start)
+	if end == start {
+		return -1
+	}
+	if end < len(formula) && token.sheet == "" {
+		// the worksheet name, or the 3D reference, such as Sheet1:Sheet3!A1
+		if formula[end] == '!' {
+			token.sheet = formula[start:end]
+			return p.scanQualified(token, end+1)
+		}
+		if formula[end] == ':' {
+			if next := scanFormulaWord(formula, end+1); next > end+1 && next < len(formula) && formula[next] == '!' {
+				token.sheet = formula[start:next]
+				return p.scanQualified(token, next+1)
+			}
+		}
+	}
+	word := formula[start:end]
+	switch {
+	case end < len(formula) && formula[end] == '[' && token.sheet == "" || formula[start] == '[' && end == skipFormulaBracket(formula, start):
+		if formula[end-1] != ']' {
+			end = skipFormulaBracket(formula, end)
+		}
+		if formula[end-1] != ']' {
+			return -1
+		}
+		token.kind, token.text = formulaTokenStructuredRef, formula[start:end]
+	case end < len(formula) && formula[end] == '(' && token.sheet == "":
+		token.kind, token.text = formulaTokenFunction, word
+		end++
+	case token.sheet == "" && (strings.EqualFold(word, "TRUE") || strings.EqualFold(word, "FALSE")):
+		token.kind, token.text = formulaTokenLogical, strings.ToUpper(word)
+	default:
+		token.kind, token.text = formulaTokenWord, word
+	}
+	return end
+}
+
+// peek returns the current token of the parser.
+func (p *formulaParser) peek() formulaToken {
+	return p.tokens[p.idx]
+}
+
+// next returns the current token of the parser and move to the next token.
+func (p *formulaParser) next() formulaToken {
+	token := p.tokens[p.idx]
+	if token.kind != formulaTokenEOF {
+		p.idx++
+	}
+	return token
+}
+
+// isOperator returns whether the current token is one of the given
+// operators.
+func (p *formulaParser) isOperator(ops ...string) bool {
+	token := p.peek()
+	if token.kind != formulaTokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if token.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// expect consume the token of the given kind, returns an error if the
+// current token is not the expected one.
+func (p *formulaParser) expect(kind formulaTokenKind) error {
+	if token := p.next(); token.kind != kind {
+		return newFormulaSyntaxError(p.formula, token.pos)
+	}
+	return nil
+}
+
+// parseBinary parse the binary operators expression by given precedence
+// level of the operators.
+func (p *formulaParser) parseBinary(level int) (*FormulaNode, error) {
+	if level == len(formulaBinaryOperators) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(formulaBinaryOperators[level]...) {
+		op := p.next().text
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &FormulaNode{Type: FormulaNodeInfix, Value: op, Children: []*FormulaNode{left, right}}
+	}
+	return left, nil
+}
+
+// parseExpr parse the expression.
+func (p *formulaParser) parseExpr() (*FormulaNode, error) {
+	return p.parseBinary(0)
+}
+
+// parseUnary parse the prefix operators, the negation operator takes
+// precedence over the exponentiation operator, such as -2^2 equals 4.
+func (p *formulaParser) parseUnary() (*FormulaNode, error) {
+	if p.isOperator("+", "-", "@") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &FormulaNode{Type: FormulaNodePrefix, Value: op, Children: []*FormulaNode{operand}}, nil
+	}
+	node, err := p.parseIntersection()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("%") {
+		node = &FormulaNode{Type: FormulaNodePostfix, Value: p.next().text, Children: []*FormulaNode{node}}
+	}
+	return node, nil
+}
+
+// isOperandStart returns whether the token could be the start of an operand.
+func (token formulaToken) isOperandStart() bool {
+	switch token.kind {
+	case formulaTokenEOF, formulaTokenOperator, formulaTokenClose, formulaTokenBraceClose,
+		formulaTokenComma, formulaTokenSemicolon:
+		return false
+	}
+	return true
+}
+
+// parseIntersection parse the intersection operator, which is the space
+// between two references, such as A1:B2 B1:C3.
+func (p *formulaParser) parseIntersection() (*FormulaNode, error) {
+	left, err := p.parseRange()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token.space && token.isOperandStart(); token = p.peek() {
+		right, err := p.parseRange()
+		if err != nil {
+			return nil, err
+		}
+		left = &FormulaNode{Type: FormulaNodeInfix, Value: " ", Children: []*FormulaNode{left, right}}
+	}
+	return left, nil
+}
+
+// rangeRefPart returns the reference text and the kind of the operand of
+// the range operator, the kind 1 for the cell reference, 2 for the column
+// and 3 for the row. It returns 0 if the node can't be a part of the range
+// reference.
+func rangeRefPart(node *FormulaNode) int {
+	switch node.Type {
+	case FormulaNodeReference:
+		if formulaCellRefExp.MatchString(node.Value) {
+			return 1
+		}
+	case FormulaNodeName:
+		if formulaColumnRefExp.MatchString(node.Value) {
+			return 2
+		}
+		if formulaRowRefExp.MatchString(node.Value) {
+			return 3
+		}
+	case FormulaNodeNumber:
+		if formulaRowRefExp.MatchString(node.Value) {
+			return 3
+		}
+	}
+	return 0
+}
+
+// parseRange parse the range operator, the cell references, entire column or
+// row references on both sides of the operator will be merged into a single
+// range reference, such as A1:B2, A:A and 1:1.
+func (p *formulaParser) parseRange() (*FormulaNode, error) {
+	left, err := p.parseSpill()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(":") {
+		p.next()
+		right, err := p.parseSpill()
+		if err != nil {
+			return nil, err
+		}
+		if kind := rangeRefPart(left); kind != 0 && kind == rangeRefPart(right) && right.Sheet == "" {
+			left = &FormulaNode{Type: FormulaNodeReference, Value: left.Value + ":" + right.Value, Sheet: left.Sheet}
+			continue
+		}
+		left = &FormulaNode{Type: FormulaNodeInfix, Value: ":", Children: []*FormulaNode{left, right}}
+	}
+	return left, nil
+}
+
+// parseSpill parse the spill range operator, such as A1#.
+func (p *formulaParser) parseSpill() (*FormulaNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("#") {
+		node = &FormulaNode{Type: FormulaNodePostfix, Value: p.next().text, Children: []*FormulaNode{node}}
+	}
+	return node, nil
+}
+
+// parseArgs parse the arguments of the function until the closing
+// parenthesis, the opening parenthesis has been consumed.
+func (p *formulaParser) parseArgs() ([]*FormulaNode, error) {
+	var args []*FormulaNode
+	if p.peek().kind == formulaTokenClose {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg := &FormulaNode{Type: FormulaNodeEmpty}
+		if kind := p.peek().kind; kind != formulaTokenComma && kind != formulaTokenClose {
+			var err error
+			if arg, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, arg)
+		switch token := p.next(); token.kind {
+		case formulaTokenComma:
+		case formulaTokenClose:
+			return args, nil
+		default:
+			return nil, newFormulaSyntaxError(p.formula, token.pos)
+		}
+	}
+}
+
+// parseArray parse the array constant, the opening brace has been consumed.
+func (p *formulaParser) parseArray() (*FormulaNode, error) {
+	row := &FormulaNode{Type: FormulaNodeArrayRow}
+	array := &FormulaNode{Type: FormulaNodeArray, Children: []*FormulaNode{row}}
+	for {
+		element, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		row.Children = append(row.Children, element)
+		switch token := p.next(); token.kind {
+		case formulaTokenComma:
+		case formulaTokenSemicolon:
+			row = &FormulaNode{Type: FormulaNodeArrayRow}
+			array.Children = append(array.Children, row)
+		case formulaTokenBraceClose:
+			return array, nil
+		default:
+			return nil, newFormulaSyntaxError(p.formula, token.pos)
+		}
+	}
+}
+
+// parseInvoke parse the call of the function result or parenthesized
+// expression result, such as LAMBDA(x,x+1)(2).
+func (p *formulaParser) parseInvoke(node *FormulaNode) (*FormulaNode, error) {
+	for token := p.peek(); token.kind == formulaTokenOpen && !token.space; token = p.peek() {
+		p.next()
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		node = &FormulaNode{Type: FormulaNodeInvoke, Children: append([]*FormulaNode{node}, args...)}
+	}
+	return node, nil
+}
+
+// parsePrimary parse the constants, references, names, function calls,
+// array constants and parenthesized expressions.
+func (p *formulaParser) parsePrimary() (*FormulaNode, error) {
+	token := p.next()
+	switch token.kind {
+	case formulaTokenNumber:
+		return &FormulaNode{Type: FormulaNodeNumber, Value: token.text}, nil
+	case formulaTokenText:
+		return &FormulaNode{Type: FormulaNodeText, Value: token.text}, nil
+	case formulaTokenLogical:
+		return &FormulaNode{Type: FormulaNodeLogical, Value: token.text}, nil
+	case formulaTokenError:
+		return &FormulaNode{Type: FormulaNodeError, Value: token.text}, nil
+	case formulaTokenStructuredRef:
+		return &FormulaNode{Type: FormulaNodeStructuredReference, Value: token.text}, nil
+	case formulaTokenWord:
+		if formulaCellRefExp.MatchString(token.text) || token.text == formulaErrorREF {
+			return &FormulaNode{Type: FormulaNodeReference, Value: token.text, Sheet: token.sheet}, nil
+		}
+		return &FormulaNode{Type: FormulaNodeName, Value: token.text, Sheet: token.sheet}, nil
+	case formulaTokenFunction:
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return p.parseInvoke(&FormulaNode{Type: FormulaNodeFunction, Value: token.text, Children: args})
+	case formulaTokenOpen:
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		for p.peek().kind == formulaTokenComma {
+			p.next()
+			right, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			node = &FormulaNode{Type: FormulaNodeInfix, Value: ",", Children: []*FormulaNode{node, right}}
+		}
+		if err = p.expect(formulaTokenClose); err != nil {
+			return nil, err
+		}
+		return p.parseInvoke(&FormulaNode{Type: FormulaNodeParentheses, Children: []*FormulaNode{node}})
+	case formulaTokenBraceOpen:
+		return p.parseArray()
+	}
+	return nil, newFormulaSyntaxError(p.formula, token.pos)
+}
+
+// ParseFormula provides a function to parse the formula to the syntax tree,
+// the formula could be started with or without the equal sign. The syntax
+// tree contains function calls, references, ranges, names, structured
+// references, constants and operators, and the String function of the root
+// node prints the formula without the equal sign. The operators precedence
+// and the parentheses in the formula are kept in the syntax tree, so the
+// printed formula is the same as the given formula except the insignificant
+// whitespaces and the unnecessary quotation marks of the worksheet names.
+// For example, replace all references to the worksheet Sheet1 with the
+// worksheet Sheet2:
+//
+//	node, err := excelize.ParseFormula("SUM(Sheet1!A1:B2,'Sheet 3'!C1)")
+//	if err != nil {
+//	    fmt.Println(err)
+//	    return
+//	}
+//	node.Walk(func(n *excelize.FormulaNode) bool {
+//	    if n.Type == excelize.FormulaNodeReference && n.Sheet == "Sheet1" {
+//	        n.Sheet = "Sheet2"
+//	    }
+//	    return true
+//	})
+//	fmt.Println(node) // SUM(Sheet2!A1:B2,'Sheet 3'!C1)
+func ParseFormula(formula string) (*FormulaNode, error) {
+	p := &formulaParser{formula: formula}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if p.isOperator("=") {
+		p.next()
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != formulaTokenEOF {
+		return nil, newFormulaSyntaxError(formula, token.pos)
+	}
+	return node, nil
+}
+
+// Walk traverses the syntax tree in depth-first order, the given function
+// will be called for each node, and the children of the node will be skipped
+// if the function returns false.
+func (node *FormulaNode) Walk(fn func(node *FormulaNode) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range node.Children {
+		child.Walk(fn)
+	}
+}
+
+// formulaSheetPrefix returns the worksheet name prefix of the reference in
+// the formula, the worksheet name will be enclosed in single quotation marks
+// if it contains spaces or special characters, or looks like a reference.
+func formulaSheetPrefix(sheet string) string {
+	if sheet == "" {
+		return ""
+	}
+	name := sheet
+	if strings.HasPrefix(name, "[") {
+		name = name[skipFormulaBracket(name, 0):]
+	}
+	quote := name == ""
+	for _, part := range strings.Split(name, ":") {
+		if part == "" || formulaCellRefExp.MatchString(part) || formulaRowRefExp.MatchString(part) ||
+			strings.EqualFold(part, "TRUE") || strings.EqualFold(part, "FALSE") ||
+			unicode.IsDigit(rune(part[0])) || part[0] == '.' || strings.IndexFunc(part, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_' && r != '.'
+		}) != -1 {
+			quote = true
+		}
+	}
+	if quote && name != "" {
+		return "'" + strings.ReplaceAll(sheet, "'", "''") + "'!"
+	}
+	return sheet + "!"
+}
+
+// String returns the formula text of the syntax tree node.
+func (node *FormulaNode) String() string {
+	var b strings.Builder
+	node.print(&b)
+	return b.String()
+}
+
+// print writes the formula text of the syntax tree node to the builder.
+func (node *FormulaNode) print(b *strings.Builder) {
+	printList := func(children []*FormulaNode, sep string) {
+		for i, child := range children {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			child.print(b)
+		}
+	}
+	switch node.Type {
+	case FormulaNodeText:
+		b.WriteString(`"` + strings.ReplaceAll(node.Value, `"`, `""`) + `"`)
+	case FormulaNodeArray:
+		b.WriteString("{")
+		printList(node.Children, ";")
+		b.WriteString("}")
+	case FormulaNodeArrayRow:
+		printList(node.Children, ",")
+	case FormulaNodeReference, FormulaNodeName:
+		b.WriteString(formulaSheetPrefix(node.Sheet) + node.Value)
+	case FormulaNodeFunction:
+		b.WriteString(node.Value + "(")
+		printList(node.Children, ",")
+		b.WriteString(")")
+	case FormulaNodeInvoke:
+		if len(node.Children) > 0 {
+			node.Children[0].print(b)
+			b.WriteString("(")
+			printList(node.Children[1:], ",")
+			b.WriteString(")")
+		}
+	case FormulaNodePrefix:
+		b.WriteString(node.Value)
+		printList(node.Children, "")
+	case FormulaNodeInfix:
+		printList(node.Children, node.Value)
+	case FormulaNodePostfix:
+		printList(node.Children, "")
+		b.WriteString(node.Value)
+	case FormulaNodeParentheses:
+		b.WriteString("(")
+		printList(node.Children, "")
+		b.WriteString(")")
+	default:
+		b.WriteString(node.Value)
+	}
+}
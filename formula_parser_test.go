@@ -0,0 +1,128 @@
+package excelize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormula(t *testing.T) {
+	for _, formula := range []string{
+		`SUM(A1:B2,Sheet1!C3,'My Sheet'!$D$4:E5)*-2%+"a""b"&TRUE`,
+		`IF(A1>=2,,#N/A)`,
+		`A:A+$1:$3-Sheet1!B:C`,
+		`_xlfn.XLOOKUP(x,MyName,1E+3,.5)`,
+		`_xlfn.LAMBDA(_xlpm.x,_xlpm.y,_xlpm.x+_xlpm.y)(1,2)`,
+		`{1,-2;"a",TRUE}`,
+		`SUM((A1:A2,B1),A1:B2 B1:C3)`,
+		`-2^2<>4=FALSE`,
+		`Table1[[#This Row],[Col A]]+Table1[Col]+[@Qty]+Table1[#All]`,
+		`'It''s'!A1+Sheet1:Sheet3!B2+'Sheet 1:Sheet 3'!C3`,
+		`[1]Sheet1!A1+'[1]Sheet 1'!A1`,
+		`@A1:A3+A1#+Sheet1!B1#`,
+		`A1:INDEX(B:B,2)`,
+		`Sheet1!A1:Sheet1!B2`,
+		`F()+G(,)`,
+		`((1))%%`,
+		`'2024'!A1+'A1'!A1+'TRUE'!A1`,
+		`Sheet1!#REF!+#REF!`,
+	} {
+		node, err := ParseFormula(formula)
+		assert.NoError(t, err, formula)
+		assert.Equal(t, formula, node.String(), formula)
+	}
+	for formula, expected := range map[string]string{
+		`=SUM( A1 , B1 )`: `SUM(A1,B1)`,
+		`'Sheet1'!A1`:     `Sheet1!A1`,
+		"\tA1 \n+ 1":      `A1+1`,
+		`true+sum(1)`:     `TRUE+sum(1)`,
+	} {
+		node, err := ParseFormula(formula)
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, node.String(), formula)
+	}
+	// Test the syntax tree of the formula
+	node, err := ParseFormula(`SUM(Sheet1!A1:B2,-2^2,"x",A1 B1)`)
+	assert.NoError(t, err)
+	assert.Equal(t, &FormulaNode{Type: FormulaNodeFunction, Value: "SUM", Children: []*FormulaNode{
+		{Type: FormulaNodeReference, Value: "A1:B2", Sheet: "Sheet1"},
+		{Type: FormulaNodeInfix, Value: "^", Children: []*FormulaNode{
+			{Type: FormulaNodePrefix, Value: "-", Children: []*FormulaNode{{Type: FormulaNodeNumber, Value: "2"}}},
+			{Type: FormulaNodeNumber, Value: "2"},
+		}},
+		{Type: FormulaNodeText, Value: "x"},
+		{Type: FormulaNodeInfix, Value: " ", Children: []*FormulaNode{
+			{Type: FormulaNodeReference, Value: "A1"},
+			{Type: FormulaNodeReference, Value: "B1"},
+		}},
+	}}, node)
+	node, err = ParseFormula(`1+2*3&A:A`)
+	assert.NoError(t, err)
+	assert.Equal(t, "&", node.Value)
+	assert.Equal(t, "+", node.Children[0].Value)
+	assert.Equal(t, "*", node.Children[0].Children[1].Value)
+	assert.Equal(t, &FormulaNode{Type: FormulaNodeReference, Value: "A:A"}, node.Children[1])
+	for formula, expected := range map[string]FormulaNodeType{
+		`MyName`:         FormulaNodeName,
+		`Sheet1!MyName`:  FormulaNodeName,
+		`R1C1`:           FormulaNodeName,
+		`XFD1048576`:     FormulaNodeReference,
+		`Table1[Col]`:    FormulaNodeStructuredReference,
+		`#DIV/0!`:        FormulaNodeError,
+		`{1}`:            FormulaNodeArray,
+		`LAMBDA(x,x)(1)`: FormulaNodeInvoke,
+		`(1)`:            FormulaNodeParentheses,
+		`FALSE`:          FormulaNodeLogical,
+		`A1%`:            FormulaNodePostfix,
+	} {
+		node, err := ParseFormula(formula)
+		assert.NoError(t, err, formula)
+		assert.Equal(t, expected, node.Type, formula)
+	}
+	// Test walk the syntax tree and rewrite the references
+	node, err = ParseFormula(`SUM(Sheet1!A1:B2,'Sheet 3'!C1,Sheet1!Name)`)
+	assert.NoError(t, err)
+	var refs []string
+	node.Walk(func(n *FormulaNode) bool {
+		if n.Type == FormulaNodeReference && n.Sheet == "Sheet1" {
+			n.Sheet = "New Sheet"
+		}
+		if n.Type == FormulaNodeReference {
+			refs = append(refs, n.Value)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"A1:B2", "C1"}, refs)
+	assert.Equal(t, `SUM('New Sheet'!A1:B2,'Sheet 3'!C1,Sheet1!Name)`, node.String())
+	var count int
+	node.Walk(func(n *FormulaNode) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+	// Test parse the formula with syntax errors
+	for formula, pos := range map[string]int{
+		``:            0,
+		`=`:           1,
+		`1+`:          2,
+		`SUM(1`:       5,
+		`SUM(1))`:     6,
+		`"abc`:        0,
+		`'Sheet1'A1`:  0,
+		`#UNKNOWN`:    0,
+		`1A`:          0,
+		`{1,2`:        4,
+		`(1,2`:        4,
+		`()`:          1,
+		`A1 ~`:        3,
+		`Table1[Col`:  0,
+		`'Sheet1'!+1`: 9,
+		`SUM(1;2)`:    5,
+		`{1 2 3`:      6,
+	} {
+		_, err := ParseFormula(formula)
+		assert.True(t, errors.Is(err, ErrFormulaSyntax), formula)
+		assert.Equal(t, newFormulaSyntaxError(formula, pos), err, formula)
+	}
+}
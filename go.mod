@@ -3,6 +3,7 @@ module github.com/xuri/excelize/v2
 go 1.24.0
 
 require (
+	github.com/google/uuid v1.6.0
 	github.com/richardlehane/mscfb v1.0.4
 	github.com/stretchr/testify v1.11.1
 	github.com/tiendc/go-deepcopy v1.7.1
@@ -16,7 +17,6 @@ require (
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/richardlehane/msoleps v1.0.4 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
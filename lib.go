@@ -913,6 +913,8 @@ func assignFieldValue(field string, immutable, mutable reflect.Value) {
 		mutable.FieldByName(field).SetBool(immutable.Bool())
 	case reflect.Int:
 		mutable.FieldByName(field).SetInt(immutable.Int())
+	case reflect.Float64:
+		mutable.FieldByName(field).SetFloat(immutable.Float())
 	default:
 		mutable.FieldByName(field).SetString(immutable.String())
 	}
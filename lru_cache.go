@@ -6,31 +6,60 @@ package excelize
 
 import (
 	"container/list"
+	"slices"
+	"strings"
 	"sync"
 )
 
 // lruCache implements a thread-safe LRU (Least Recently Used) cache
 // with a maximum size limit. When the cache is full, the least recently
-// used item is evicted to make room for new items.
+// used item is evicted to make room for new items. The capacity limits the
+// number of items, and the maxBytes limits the estimated memory size of the
+// items, zero means no limit.
 type lruCache struct {
-	mu       sync.RWMutex
-	capacity int
-	cache    map[string]*list.Element
-	lruList  *list.List
+	mu        sync.RWMutex
+	capacity  int
+	maxBytes  int64
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
+	cache     map[string]*list.Element
+	lruList   *list.List
+	buckets   map[graphBucket]map[*lruEntry]struct{}
+	untracked map[*lruEntry]struct{}
 }
 
-// lruEntry represents a key-value pair in the LRU cache
+// lruEntry represents a key-value pair in the LRU cache, the reads is the
+// cell areas read to create the value, and the untracked entry doesn't know
+// which cells it depends on. The areas is the reads with the lower-cased
+// worksheet names for the cache invalidation.
 type lruEntry struct {
-	key   string
-	value interface{}
+	key     string
+	value   interface{}
+	size    int64
+	reads   []formulaArea
+	areas   []formulaArea
+	tracked bool
+}
+
+// lruCacheStats represents the statistics of the LRU cache
+type lruCacheStats struct {
+	entries   int
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
 }
 
 // newLRUCache creates a new LRU cache with the specified capacity
 func newLRUCache(capacity int) *lruCache {
 	return &lruCache{
-		capacity: capacity,
-		cache:    make(map[string]*list.Element),
-		lruList:  list.New(),
+		capacity:  capacity,
+		cache:     make(map[string]*list.Element),
+		lruList:   list.New(),
+		buckets:   make(map[graphBucket]map[*lruEntry]struct{}),
+		untracked: make(map[*lruEntry]struct{}),
 	}
 }
 
@@ -43,52 +72,166 @@ func (c *lruCache) Load(key string) (interface{}, bool) {
 	if elem, ok := c.cache[key]; ok {
 		// Move to front (most recently used)
 		c.lruList.MoveToFront(elem)
+		c.hits++
 		return elem.Value.(*lruEntry).value, true
 	}
+	c.misses++
 	return nil, false
 }
 
-// Store adds or updates a value in the cache. If the cache is at capacity,
-// the least recently used item is evicted. Returns true if an item was evicted.
-func (c *lruCache) Store(key string, value interface{}) bool {
+// LoadReads retrieves a value and the cell areas read to create it from the
+// cache like the Load function.
+func (c *lruCache) LoadReads(key string) (interface{}, []formulaArea, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// If key exists, update and move to front
 	if elem, ok := c.cache[key]; ok {
 		c.lruList.MoveToFront(elem)
-		elem.Value.(*lruEntry).value = value
-		return false
+		c.hits++
+		entry := elem.Value.(*lruEntry)
+		return entry.value, entry.reads, true
 	}
+	c.misses++
+	return nil, nil, false
+}
 
-	// Check capacity and evict if necessary
-	evicted := false
-	if c.lruList.Len() >= c.capacity {
-		// Remove least recently used (back of list)
-		oldest := c.lruList.Back()
-		if oldest != nil {
-			c.lruList.Remove(oldest)
-			oldKey := oldest.Value.(*lruEntry).key
-			delete(c.cache, oldKey)
-			evicted = true
+// Store adds or updates a value in the cache without the read cells, the
+// item will be removed on any cell changes. If the cache is over the limits,
+// the least recently used items are evicted, and the item larger than the
+// bytes limit will not be stored. Returns true if an item was evicted.
+func (c *lruCache) Store(key string, value interface{}) bool {
+	return c.StoreReads(key, value, nil)
+}
+
+// StoreReads adds or updates a value in the cache like the Store function,
+// and records the cell areas read to create the value, the item will be
+// removed once any cell in the areas was changed. The nil reads means the
+// read cells are unknown.
+func (c *lruCache) StoreReads(key string, value interface{}, reads []formulaArea) bool {
+	size := cacheEntrySize(key, value) + formulaAreasSize(reads)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBytes > 0 && size > c.maxBytes {
+		if elem, ok := c.cache[key]; ok {
+			c.remove(elem)
 		}
+		return false
+	}
+	// If key exists, update and move to front
+	if elem, ok := c.cache[key]; ok {
+		c.lruList.MoveToFront(elem)
+		entry := elem.Value.(*lruEntry)
+		c.bytes += size - entry.size
+		c.unindex(entry)
+		entry.value, entry.size, entry.reads, entry.tracked = value, size, reads, reads != nil
+		c.index(entry)
+		return c.evict()
 	}
 
 	// Add new entry to front
-	entry := &lruEntry{key: key, value: value}
+	entry := &lruEntry{key: key, value: value, size: size, reads: reads, tracked: reads != nil}
 	elem := c.lruList.PushFront(entry)
 	c.cache[key] = elem
+	c.bytes += size
+	c.index(entry)
+
+	return c.evict()
+}
 
+// index adds the entry into the buckets of the read cell areas, the caller
+// must hold the lock.
+func (c *lruCache) index(entry *lruEntry) {
+	if !entry.tracked {
+		c.untracked[entry] = struct{}{}
+		return
+	}
+	entry.areas = make([]formulaArea, len(entry.reads))
+	for i, area := range entry.reads {
+		area.sheet = strings.ToLower(area.sheet)
+		entry.areas[i] = area
+		for _, bucket := range area.bucketsOf() {
+			if c.buckets[bucket] == nil {
+				c.buckets[bucket] = map[*lruEntry]struct{}{}
+			}
+			c.buckets[bucket][entry] = struct{}{}
+		}
+	}
+}
+
+// unindex removes the entry from the buckets of the read cell areas, the
+// caller must hold the lock.
+func (c *lruCache) unindex(entry *lruEntry) {
+	delete(c.untracked, entry)
+	for _, area := range entry.areas {
+		for _, bucket := range area.bucketsOf() {
+			if delete(c.buckets[bucket], entry); len(c.buckets[bucket]) == 0 {
+				delete(c.buckets, bucket)
+			}
+		}
+	}
+	entry.areas = nil
+}
+
+// remove removes the element from the cache, the caller must hold the lock.
+func (c *lruCache) remove(elem *list.Element) {
+	entry := elem.Value.(*lruEntry)
+	c.lruList.Remove(elem)
+	delete(c.cache, entry.key)
+	c.bytes -= entry.size
+	c.unindex(entry)
+}
+
+// evict removes the least recently used items until the cache is within the
+// limits, the caller must hold the lock. Returns true if an item was evicted.
+func (c *lruCache) evict() bool {
+	evicted := false
+	for c.lruList.Len() > 0 && (c.capacity > 0 && c.lruList.Len() > c.capacity ||
+		c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		// Remove least recently used (back of list)
+		c.remove(c.lruList.Back())
+		c.evictions++
+		evicted = true
+	}
 	return evicted
 }
 
-// Clear removes all items from the cache
+// SetLimits changes the maximum number of items and the maximum estimated
+// bytes of the cache, zero means no limit. The least recently used items are
+// evicted if the cache is over the new limits.
+func (c *lruCache) SetLimits(capacity int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity, c.maxBytes = capacity, maxBytes
+	c.evict()
+}
+
+// Stats returns the number of items, the estimated bytes and the counters of
+// the cache.
+func (c *lruCache) Stats() lruCacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return lruCacheStats{
+		entries:   c.lruList.Len(),
+		bytes:     c.bytes,
+		hits:      c.hits,
+		misses:    c.misses,
+		evictions: c.evictions,
+	}
+}
+
+// Clear removes all items from the cache, the counters are kept.
 func (c *lruCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.cache = make(map[string]*list.Element)
 	c.lruList = list.New()
+	c.buckets = make(map[graphBucket]map[*lruEntry]struct{})
+	c.untracked = make(map[*lruEntry]struct{})
+	c.bytes = 0
 }
 
 // Len returns the current number of items in the cache
@@ -119,9 +262,44 @@ func (c *lruCache) Delete(key string) bool {
 	defer c.mu.Unlock()
 
 	if elem, ok := c.cache[key]; ok {
-		c.lruList.Remove(elem)
-		delete(c.cache, key)
+		c.remove(elem)
 		return true
 	}
 	return false
 }
+
+// DeleteReading removes the items which read any of the given changed cells,
+// and the items without the read cells. Only the items in the buckets of the
+// changed cells will be visited. Returns the number of the removed items.
+func (c *lruCache) DeleteReading(changed changedCells) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := make([]*lruEntry, 0, len(c.untracked))
+	for entry := range c.untracked {
+		stale = append(stale, entry)
+	}
+	seen := map[*lruEntry]bool{}
+	for sheet, refs := range changed {
+		for _, ref := range refs {
+			block := (ref.Row - 1) / graphBlockRows
+			for _, bucket := range []graphBucket{
+				{sheet, ref.Col, block}, {sheet, ref.Col, -1}, {sheet, -1, block}, {sheet, -1, -1},
+			} {
+				for entry := range c.buckets[bucket] {
+					if seen[entry] {
+						continue
+					}
+					seen[entry] = true
+					if slices.ContainsFunc(entry.areas, changed.covers) {
+						stale = append(stale, entry)
+					}
+				}
+			}
+		}
+	}
+	for _, entry := range stale {
+		c.remove(c.cache[entry.key])
+	}
+	return len(stale)
+}
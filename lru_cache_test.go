@@ -144,3 +144,86 @@ func TestLRUCacheRange(t *testing.T) {
 		t.Errorf("Expected to iterate 2 items before stopping, got %d", count)
 	}
 }
+
+func TestLRUCacheLimits(t *testing.T) {
+	cache := newLRUCache(0)
+	cache.SetLimits(0, 20)
+
+	// Test eviction when the bytes limit exceeded
+	cache.Store("key1", "value1")
+	cache.Store("key2", "value2")
+	if evicted := cache.Store("key3", "value3"); !evicted {
+		t.Error("Expected eviction when the bytes limit exceeded")
+	}
+	if stats := cache.Stats(); stats.entries != 2 || stats.bytes != 20 || stats.evictions != 1 {
+		t.Errorf("Expected 2 entries with 20 bytes and 1 eviction, got %+v", stats)
+	}
+
+	// Test the item larger than the bytes limit will not be stored
+	cache.Store("key2", "a value larger than the limit")
+	if _, ok := cache.Load("key2"); ok {
+		t.Error("Expected key2 to be removed")
+	}
+	if stats := cache.Stats(); stats.entries != 1 || stats.bytes != 10 || stats.hits != 0 || stats.misses != 1 {
+		t.Errorf("Expected 1 entry with 10 bytes and 1 miss, got %+v", stats)
+	}
+
+	// Test shrink the cache by the new limits
+	cache.Store("key4", "value4")
+	cache.SetLimits(1, 0)
+	if _, ok := cache.Load("key4"); !ok || cache.Len() != 1 {
+		t.Error("Expected only key4 to be in cache")
+	}
+}
+
+func TestLRUCacheDeleteReading(t *testing.T) {
+	cache := newLRUCache(0)
+	cache.StoreReads("A1", 1, []formulaArea{{sheet: "Sheet1", x1: 1, y1: 1, x2: 1, y2: 1}})
+	cache.StoreReads("B:B", 2, []formulaArea{{sheet: "SHEET1", x1: 2, y1: 1, x2: 2, y2: TotalRows}})
+	cache.StoreReads("A2:Z3", 3, []formulaArea{{sheet: "Sheet1", x1: 1, y1: 2, x2: 26, y2: 3}})
+	cache.StoreReads("Sheet2!A1", 4, []formulaArea{{sheet: "Sheet2", x1: 1, y1: 1, x2: 1, y2: 1}})
+	cache.StoreReads("none", 5, []formulaArea{})
+	cache.Store("untracked", 6)
+
+	// Test remove the items read the changed cells and the untracked items
+	changed, ok := newChangedCells("Sheet1", []string{"B3"})
+	if !ok {
+		t.Fatal("Expected valid changed cells")
+	}
+	if deleted := cache.DeleteReading(changed); deleted != 3 {
+		t.Errorf("Expected 3 items to be removed, got %d", deleted)
+	}
+	for key, exists := range map[string]bool{
+		"A1": true, "B:B": false, "A2:Z3": false, "Sheet2!A1": true, "none": true, "untracked": false,
+	} {
+		if _, ok := cache.Load(key); ok != exists {
+			t.Errorf("Expected %s in cache to be %t", key, exists)
+		}
+	}
+
+	// Test the changed cells outside the read areas
+	changed, _ = newChangedCells("sheet1", []string{"A2", "C1048576"})
+	if deleted := cache.DeleteReading(changed); deleted != 0 {
+		t.Errorf("Expected no item to be removed, got %d", deleted)
+	}
+	changed, _ = newChangedCells("sheet2", []string{"A1"})
+	if deleted := cache.DeleteReading(changed); deleted != 1 {
+		t.Errorf("Expected 1 item to be removed, got %d", deleted)
+	}
+
+	// Test the replaced and removed items are not indexed
+	cache.StoreReads("A1", 1, []formulaArea{{sheet: "Sheet1", x1: 3, y1: 1, x2: 3, y2: 1}})
+	changed, _ = newChangedCells("Sheet1", []string{"A1"})
+	if deleted := cache.DeleteReading(changed); deleted != 0 {
+		t.Errorf("Expected no item to be removed, got %d", deleted)
+	}
+	cache.Delete("A1")
+	if len(cache.buckets) != 0 || len(cache.untracked) != 0 {
+		t.Errorf("Expected empty index, got %d buckets and %d untracked items", len(cache.buckets), len(cache.untracked))
+	}
+	cache.Store("untracked", 6)
+	cache.Clear()
+	if len(cache.buckets) != 0 || len(cache.untracked) != 0 {
+		t.Errorf("Expected empty index, got %d buckets and %d untracked items", len(cache.buckets), len(cache.untracked))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 - 2025 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to and
+// read from XLAM / XLSM / XLSX / XLTM / XLTX files. Supports reading and
+// writing spreadsheet documents generated by Microsoft Excel™ 2007 and later.
+// Supports complex components by high compatibility, and provided streaming
+// API for generating or reading data from a worksheet with huge amounts of
+// data. This library needs Go version 1.24.0 or later.
+
+package excelize
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Metrics defined the interface to receive the counters and the timers of the
+// workbook, which could be specified by the Metrics field of the Options. The
+// metrics are identified by the names such as MetricCalcFormulas and
+// MetricSaveDuration. The methods may be called concurrently, and should
+// return quickly.
+type Metrics interface {
+	// Count adds the delta to the counter by given metric name.
+	Count(name string, delta int64)
+	// Observe records the duration of the timer by given metric name.
+	Observe(name string, duration time.Duration)
+}
+
+// Metric names of the counters and the timers reported to the Metrics.
+const (
+	// MetricParseDuration is the timer of opening and parsing the workbook.
+	MetricParseDuration = "excelize.parse.duration"
+	// MetricSaveDuration is the timer of serializing and saving the workbook.
+	MetricSaveDuration = "excelize.save.duration"
+	// MetricCalcDuration is the timer of evaluating a formula cell.
+	MetricCalcDuration = "excelize.calc.duration"
+	// MetricCalcFormulas is the counter of the evaluated formula cells.
+	MetricCalcFormulas = "excelize.calc.formulas"
+	// MetricCalcErrors is the counter of the formula cells calculated with
+	// errors.
+	MetricCalcErrors = "excelize.calc.errors"
+	// MetricCalcCacheHits is the counter of the formula cells whose results
+	// were loaded from the calculation cache.
+	MetricCalcCacheHits = "excelize.calc.cache_hits"
+	// MetricCalcBatchFormulas is the counter of the formula cells calculated
+	// by the batch optimization of the RecalculateAll function.
+	MetricCalcBatchFormulas = "excelize.calc.batch_formulas"
+)
+
+// discardLogger is the logger used when the LogHandler option is not
+// specified, which discards all logs.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// nopMetrics is the metrics used when the Metrics option is not specified,
+// which discards all counters and timers.
+type nopMetrics struct{}
+
+// Count implements the Metrics interface and discards the counter.
+func (nopMetrics) Count(string, int64) {}
+
+// Observe implements the Metrics interface and discards the timer.
+func (nopMetrics) Observe(string, time.Duration) {}
+
+// logger returns the structured logger of the workbook by the LogHandler
+// option, the logs will be discarded if the handler is not specified.
+func (f *File) logger() *slog.Logger {
+	if f.options == nil || f.options.LogHandler == nil {
+		return discardLogger
+	}
+	return slog.New(f.options.LogHandler)
+}
+
+// metrics returns the metrics of the workbook by the Metrics option, the
+// counters and the timers will be discarded if the metrics is not specified.
+func (f *File) metrics() Metrics {
+	if f.options == nil || f.options.Metrics == nil {
+		return nopMetrics{}
+	}
+	return f.options.Metrics
+}
+
+// recordCalc reports the calculation of the formula cell to the metrics of
+// the workbook and the calculation report attached to the given report, by
+// given duration, whether the result was loaded from the calculation cache,
+// the formatted result and the error of the calculation.
+func (f *File) recordCalc(report *CalcReport, sheet, cell string, duration time.Duration, cached bool, result string, err error) {
+	metrics := f.metrics()
+	if cached {
+		metrics.Count(MetricCalcCacheHits, 1)
+	} else {
+		metrics.Count(MetricCalcFormulas, 1)
+		metrics.Observe(MetricCalcDuration, duration)
+		if err != nil {
+			metrics.Count(MetricCalcErrors, 1)
+		}
+	}
+	report.record(f, sheet, cell, duration, cached, result, err)
+	recordCellCalc(f, sheet, cell, result, duration, cached)
+}
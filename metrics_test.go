@@ -0,0 +1,88 @@
+package excelize
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMetrics is the in-memory metrics for testing.
+type testMetrics struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	timers   map[string]int
+}
+
+func (m *testMetrics) Count(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] += delta
+}
+
+func (m *testMetrics) Observe(name string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timers[name]++
+}
+
+func TestMetrics(t *testing.T) {
+	// Test the logs and the metrics are discarded by default
+	f := NewFile()
+	assert.Equal(t, discardLogger, f.logger())
+	assert.Equal(t, nopMetrics{}, f.metrics())
+	nopMetrics{}.Count(MetricCalcFormulas, 1)
+	nopMetrics{}.Observe(MetricCalcDuration, time.Second)
+
+	var logs bytes.Buffer
+	metrics := &testMetrics{counters: map[string]int64{}, timers: map[string]int{}}
+	opts := Options{LogHandler: slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}), Metrics: metrics}
+	f = NewFile(opts)
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+	for cell, formula := range map[string]string{"B1": "A1+1", "B2": "1/0", "B3": "B1*2"} {
+		assert.NoError(t, f.SetCellFormula("Sheet1", cell, formula))
+	}
+	assert.NoError(t, f.RebuildCalcChain())
+	assert.NoError(t, f.RecalculateAll())
+	result, err := f.CalcCellValue("Sheet1", "B3", Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "4", result)
+	assert.Equal(t, map[string]int64{MetricCalcFormulas: 3, MetricCalcErrors: 1, MetricCalcCacheHits: 1}, metrics.counters)
+	assert.Equal(t, map[string]int{MetricCalcDuration: 3}, metrics.timers)
+	assert.Contains(t, logs.String(), "msg=\"recalculation started\" formulas=3")
+	assert.Contains(t, logs.String(), "msg=\"recalculation completed\" formulas=2")
+
+	// Test the parse and save durations
+	buf, err := f.WriteToBuffer()
+	assert.NoError(t, err)
+	_, err = OpenReader(buf, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, metrics.timers[MetricSaveDuration])
+	assert.Equal(t, 1, metrics.timers[MetricParseDuration])
+	assert.Contains(t, logs.String(), "msg=\"workbook saved\"")
+	assert.Contains(t, logs.String(), "msg=\"workbook opened\"")
+}
+
+func TestBatchDebugStats(t *testing.T) {
+	f := NewFile()
+	assert.NoError(t, f.SetCellValue("Sheet1", "A1", 1))
+	assert.NoError(t, f.SetCellFormula("Sheet1", "B1", "A1+1"))
+	assert.NoError(t, f.RebuildCalcChain())
+	EnableBatchDebug()
+	defer DisableBatchDebug()
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 2}}))
+	stats := GetBatchDebugStats()
+	assert.Equal(t, 1, stats.TotalCells)
+	assert.Equal(t, 1, stats.CacheMisses)
+	assert.Equal(t, &CellStats{Cell: "Sheet1!B1", CalcCount: 1, CalcDuration: stats.CellStats["Sheet1!B1"].CalcDuration,
+		Formula: "A1+1", Result: "3"}, stats.CellStats["Sheet1!B1"])
+
+	// Test the statistics will not be collected after disabled
+	DisableBatchDebug()
+	assert.NoError(t, f.BatchUpdateAndRecalculate([]CellUpdate{{Sheet: "Sheet1", Cell: "A1", Value: 3}}))
+	assert.Equal(t, stats, GetBatchDebugStats())
+	assert.Equal(t, 1, stats.CellStats["Sheet1!B1"].CalcCount)
+}
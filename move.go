@@ -65,8 +65,8 @@ func (f *File) MoveRow(sheet string, fromRow, toRow int) error {
 	}
 
 	// Clear caches
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 
 	return nil
 }
@@ -388,8 +388,8 @@ func (f *File) MoveCol(sheet string, fromCol, toCol string) error {
 	}
 
 	// Clear caches
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 
 	return nil
 }
@@ -656,8 +656,8 @@ func (f *File) MoveRows(sheet string, fromRow, count, toRow int) error {
 	}
 
 	// Clear caches
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 
 	return nil
 }
@@ -961,8 +961,8 @@ func (f *File) MoveCols(sheet string, fromCol string, count int, toCol string) e
 	}
 
 	// Clear caches
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 
 	return nil
 }
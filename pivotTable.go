@@ -163,8 +163,7 @@ func (f *File) AddPivotTable(opts *PivotTableOptions) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
 	pivotTableID := f.countPivotTables() + 1
 	pivotCacheID := f.countPivotCache() + 1
 
@@ -1063,8 +1062,7 @@ func (f *File) DeletePivotTable(sheet, name string) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
 	pivotTableCaches := map[string]int{}
 	pivotTables, _ := f.getPivotTables()
 	for _, sheetPivotTables := range pivotTables {
@@ -24,6 +24,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tiendc/go-deepcopy"
 )
@@ -389,8 +390,8 @@ func (f *File) SetSheetName(source, target string) error {
 	if target == source {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	wb, _ := f.workbookReader()
 	for k, v := range wb.Sheets.Sheet {
 		if v.Name == source {
@@ -633,8 +634,8 @@ func (f *File) DeleteSheet(sheet string) error {
 	if idx, _ := f.GetSheetIndex(sheet); f.SheetCount == 1 || idx == -1 {
 		return nil
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	wb, _ := f.workbookReader()
 	wbRels, _ := f.relsReader(f.getWorkbookRelsPath())
 	activeSheetName := f.GetSheetName(f.GetActiveSheetIndex())
@@ -913,10 +914,14 @@ func (f *File) copySheet(from, to int) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
 	worksheet := &xlsxWorksheet{}
 	deepcopy.Copy(worksheet, sheet)
+	worksheet.spillValues = sync.Map{}
+	sheet.spillValues.Range(func(cell, value interface{}) bool {
+		worksheet.spillValues.Store(cell, value)
+		return true
+	})
 	toSheetID := strconv.Itoa(f.getSheetID(f.GetSheetName(to)))
 	sheetXMLPath := "xl/worksheets/sheet" + toSheetID + ".xml"
 	if len(worksheet.SheetViews.SheetView) > 0 {
@@ -1918,8 +1923,8 @@ func (f *File) SetDefinedName(definedName *DefinedName) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	d := xlsxDefinedName{
 		Name:    definedName.Name,
 		Comment: definedName.Comment,
@@ -1962,8 +1967,8 @@ func (f *File) DeleteDefinedName(definedName *DefinedName) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	if wb.DefinedNames != nil {
 		for idx, dn := range wb.DefinedNames.DefinedName {
 			scope := "Workbook"
@@ -67,7 +67,9 @@ func parseTableOptions(opts *Table) (*Table, error) {
 // Note that the table must be at least two lines including the header. The
 // header cells must contain strings and must be unique, and must set the
 // header row data of the table before calling the AddTable function. Multiple
-// tables range reference that can't have an intersection.
+// tables range reference that can't have an intersection. Renaming the header
+// cell by the SetCellValue function renames the table column, and the
+// structured references to the column in the formulas and defined names.
 //
 // Name: The name of the table, in the same worksheet name of the table should
 // be unique, starts with a letter or underscore (_), doesn't include a
@@ -118,8 +120,8 @@ func (f *File) AddTable(sheet string, table *Table) error {
 		return err
 	}
 	f.addSheetNameSpace(sheet, SourceRelationship)
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	if err = f.addTable(sheet, tableXML, coordinates[0], coordinates[1], coordinates[2], coordinates[3], tableID, options); err != nil {
 		return err
 	}
@@ -179,8 +181,8 @@ func (f *File) DeleteTable(name string) error {
 	if err != nil {
 		return err
 	}
-	f.calcCache.Clear()
-	f.rangeCache.Clear()
+	f.clearCalcCaches()
+	f.clearDependencyGraph()
 	for sheet, tables := range tbls {
 		for _, table := range tables {
 			if table.Name != name {
@@ -403,6 +405,84 @@ func (f *File) addTable(sheet, tableXML string, x1, y1, x2, y2, i int, opts *Tab
 	return err
 }
 
+// tableRange returns the range reference and the header row count of the
+// table by given table XML content, the table columns will not be decoded.
+func (f *File) tableRange(content []byte) (string, int, error) {
+	decoder := f.xmlNewDecoder(bytes.NewReader(namespaceStrictToTransitional(content)))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", 0, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			ref, headerRows := "", 1
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "ref":
+					ref = attr.Value
+				case "headerRowCount":
+					headerRows, _ = strconv.Atoi(attr.Value)
+				}
+			}
+			return ref, headerRows, nil
+		}
+	}
+}
+
+// renameTableColumn provides a function to rename the table column by the
+// header cell of the table, and update the structured references to the
+// column in the formulas and defined names, when the header cell was set.
+func (f *File) renameTableColumn(sheet, cell string) error {
+	f.mu.Lock()
+	ws, err := f.workSheetReader(sheet)
+	f.mu.Unlock()
+	if err != nil || ws.TableParts == nil {
+		return err
+	}
+	col, row, err := CellNameToCoordinates(cell)
+	if err != nil {
+		return err
+	}
+	for _, tbl := range ws.TableParts.TableParts {
+		tableXML := strings.ReplaceAll(f.getSheetRelationshipsTargetByID(sheet, tbl.RID), "..", "xl")
+		content, ok := f.Pkg.Load(tableXML)
+		if !ok {
+			continue
+		}
+		ref, headerRows, err := f.tableRange(content.([]byte))
+		if err != nil {
+			return err
+		}
+		coordinates, err := rangeRefToCoordinates(ref)
+		if err != nil {
+			return err
+		}
+		if headerRows == 0 || row != coordinates[1] || col < coordinates[0] || col > coordinates[2] {
+			continue
+		}
+		t := xlsxTable{}
+		if err = f.xmlNewDecoder(bytes.NewReader(namespaceStrictToTransitional(content.([]byte)))).
+			Decode(&t); err != nil && err != io.EOF {
+			return err
+		}
+		oldColumns := t.TableColumns
+		_ = f.setTableColumns(sheet, true, coordinates[0], coordinates[1], coordinates[2], &t)
+		if oldColumns != nil && col-coordinates[0] < len(oldColumns.TableColumn) &&
+			oldColumns.TableColumn[col-coordinates[0]].Name == t.TableColumns.TableColumn[col-coordinates[0]].Name {
+			return nil
+		}
+		if err = f.adjustTableColumns(sheet, &t, oldColumns, rows, 0, 0, coordinates[0]); err != nil {
+			return err
+		}
+		table, _ := xml.Marshal(t)
+		f.saveFileList(tableXML, table)
+		f.clearCalcCaches()
+		f.clearDependencyGraph()
+		return nil
+	}
+	return nil
+}
+
 // AutoFilter provides the method to add auto filter in a worksheet by given
 // worksheet name, range reference and settings. An auto filter in Excel is a
 // way of filtering a 2D range of data based on some simple criteria. For
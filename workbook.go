@@ -88,6 +88,7 @@ func (f *File) SetCalcProps(opts *CalcPropsOptions) error {
 		wb.CalcPr.IterateCount = int(*opts.IterateCount)
 	}
 	wb.CalcPr.ConcurrentCalc = opts.ConcurrentCalc
+	f.calcCache.Clear()
 	return err
 }
 
@@ -369,6 +370,7 @@ func (f *File) addContentTypePart(index int, contentType string) error {
 		"comments":         "/xl/comments" + strconv.Itoa(index) + ".xml",
 		"customProperties": "/docProps/custom.xml",
 		"drawings":         "/xl/drawings/drawing" + strconv.Itoa(index) + ".xml",
+		"metadata":         "/xl/metadata.xml",
 		"table":            "/xl/tables/table" + strconv.Itoa(index) + ".xml",
 		"pivotTable":       "/xl/pivotTables/pivotTable" + strconv.Itoa(index) + ".xml",
 		"pivotCache":       "/xl/pivotCache/pivotCacheDefinition" + strconv.Itoa(index) + ".xml",
@@ -382,6 +384,7 @@ func (f *File) addContentTypePart(index int, contentType string) error {
 		"comments":         ContentTypeSpreadSheetMLComments,
 		"customProperties": ContentTypeCustomProperties,
 		"drawings":         ContentTypeDrawing,
+		"metadata":         ContentTypeSpreadSheetMLSheetMetadata,
 		"table":            ContentTypeSpreadSheetMLTable,
 		"pivotTable":       ContentTypeSpreadSheetMLPivotTable,
 		"pivotCache":       ContentTypeSpreadSheetMLPivotCacheDefinition,
@@ -240,3 +240,27 @@ type decodeX14PivotCacheDefinition struct {
 	XMLName      xml.Name `xml:"pivotCacheDefinition"`
 	PivotCacheID int      `xml:"pivotCacheId,attr"`
 }
+
+// decodePivotCacheItems defines the structure used to parse the shared items
+// of the cache fields in the pivot cache definition by the order of the items,
+// the pivot table fields refer to the shared items by the index.
+type decodePivotCacheItems struct {
+	XMLName     xml.Name                `xml:"pivotCacheDefinition"`
+	CacheFields []decodePivotCacheField `xml:"cacheFields>cacheField"`
+}
+
+// decodePivotCacheField defines the structure used to parse the name and the
+// shared items of a cache field.
+type decodePivotCacheField struct {
+	Name        string `xml:"name,attr"`
+	SharedItems struct {
+		Items []decodePivotCacheItem `xml:",any"`
+	} `xml:"sharedItems"`
+}
+
+// decodePivotCacheItem defines the structure used to parse a shared item of
+// the cache field, the element name specifies the type of the item.
+type decodePivotCacheItem struct {
+	XMLName xml.Name
+	V       string `xml:"v,attr"`
+}
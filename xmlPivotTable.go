@@ -250,7 +250,7 @@ type xlsxPageFields struct {
 // PivotTable.
 type xlsxPageField struct {
 	Fld    int         `xml:"fld,attr"`
-	Item   int         `xml:"item,attr,omitempty"`
+	Item   *int        `xml:"item,attr"`
 	Hier   int         `xml:"hier,attr,omitempty"`
 	Name   string      `xml:"name,attr,omitempty"`
 	Cap    string      `xml:"cap,attr,omitempty"`
@@ -64,6 +64,7 @@ type xlsxWorksheet struct {
 	TableParts             *xlsxTableParts              `xml:"tableParts"`
 	ExtLst                 *xlsxExtLst                  `xml:"extLst"`
 	DecodeAlternateContent *xlsxInnerXML                `xml:"http://schemas.openxmlformats.org/markup-compatibility/2006 AlternateContent"`
+	spillValues            sync.Map // Values written by the dynamic array formulas: cell -> spillValue
 }
 
 // xlsxDrawing change r:id to rid in the namespace.